@@ -4,11 +4,30 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/config"
 	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/services/automation"
+	"github.com/trsnaqe/gotask/services/denylist"
+	"github.com/trsnaqe/gotask/services/lockout"
+	"github.com/trsnaqe/gotask/services/mailer"
+	"github.com/trsnaqe/gotask/services/mfa"
+	"github.com/trsnaqe/gotask/services/milestone"
+	"github.com/trsnaqe/gotask/services/notification"
+	"github.com/trsnaqe/gotask/services/oidc"
+	"github.com/trsnaqe/gotask/services/password"
+	"github.com/trsnaqe/gotask/services/passwordpolicy"
+	"github.com/trsnaqe/gotask/services/project"
+	"github.com/trsnaqe/gotask/services/session"
+	"github.com/trsnaqe/gotask/services/sprint"
 	"github.com/trsnaqe/gotask/services/task"
+	"github.com/trsnaqe/gotask/services/token"
 	"github.com/trsnaqe/gotask/services/user"
+	"github.com/trsnaqe/gotask/services/verification"
+	"github.com/trsnaqe/gotask/types"
 	"golang.org/x/time/rate"
 )
 
@@ -56,17 +75,121 @@ func (s *APIServer) Run() error {
 
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
+	tokenDenylist := newTokenDenylist(s.db)
+	middlewares.UseDenylist(tokenDenylist)
+	denylist.StartPurger(tokenDenylist, time.Hour)
+
+	mail := newMailer()
+
+	passwordPolicy, err := passwordpolicy.NewPolicy()
+	if err != nil {
+		return err
+	}
+
 	userRepository := user.NewStore(s.db)
-	userService := user.NewHandler(userRepository)
+	sessionRepository := session.NewStore(s.db)
+	middlewares.UseSessions(sessionRepository)
+	mfaRepository := mfa.NewStore(s.db)
+	verificationRepository := verification.NewStore(s.db)
+	verificationService := verification.NewHandler(verificationRepository, userRepository, mail)
+	verificationService.RegisterRoutes(subrouter)
+
+	loginGuard := lockout.NewGuard(lockout.NewStore(s.db), userRepository, mail)
+	lockoutService := lockout.NewHandler(loginGuard, userRepository, sessionRepository)
+	lockoutService.RegisterRoutes(subrouter)
+
+	userService := user.NewHandler(userRepository, sessionRepository, tokenDenylist, mfaRepository, verificationService, loginGuard, passwordPolicy)
 	userService.RegisterRoutes(subrouter)
 
-	taskRepository := task.NewStore(s.db)
-	taskService := task.NewHandler(taskRepository)
+	oidcService := oidc.NewHandler(newOIDCProvider(), oidc.NewStore(s.db), userRepository, userService)
+	oidcService.RegisterRoutes(subrouter)
+
+	mfaService := mfa.NewHandler(mfaRepository, userRepository, loginGuard)
+	mfaService.RegisterRoutes(subrouter)
+
+	sessionService := session.NewHandler(sessionRepository, userRepository)
+	sessionService.RegisterRoutes(subrouter)
+
+	passwordRepository := password.NewStore(s.db)
+	passwordService := password.NewHandler(passwordRepository, userRepository, sessionRepository, mail, passwordPolicy)
+	passwordService.RegisterRoutes(subrouter)
+
+	tokenRepository := token.NewStore(s.db)
+	middlewares.UsePersonalAccessTokens(tokenRepository)
+	tokenService := token.NewHandler(tokenRepository, userRepository)
+	tokenService.RegisterRoutes(subrouter)
+
+	notificationRepository := notification.NewStore(s.db)
+	notificationService := notification.NewHandler(notificationRepository, userRepository)
+	notificationService.RegisterRoutes(subrouter)
+
+	projectRepository := project.NewStore(s.db)
+	projectService := project.NewHandler(projectRepository, userRepository)
+	projectService.RegisterRoutes(subrouter)
+
+	automationRepository := automation.NewStore(s.db)
+	automationEngine := automation.NewEngine(automationRepository, task.NewStore(s.db), projectRepository)
+	automationEngine.StartDueDateWatcher(time.Minute)
+	automationService := automation.NewHandler(automationRepository, projectRepository, userRepository)
+	automationService.RegisterRoutes(subrouter)
+
+	// task changes made through the API run the automation rules
+	taskRepository := automation.NewTaskStore(task.NewStore(s.db), automationEngine)
+	taskService := task.NewHandler(taskRepository, projectRepository, userRepository, notificationRepository)
 	taskService.RegisterRoutes(subrouter)
+	taskService.StartSnoozeWatcher(time.Minute)
+	taskService.StartStaleWatcher(time.Hour)
+
+	sprintRepository := sprint.NewStore(s.db)
+	sprintService := sprint.NewHandler(sprintRepository, taskRepository, userRepository)
+	sprintService.RegisterRoutes(subrouter)
+
+	milestoneRepository := milestone.NewStore(s.db)
+	milestoneService := milestone.NewHandler(milestoneRepository, taskRepository, userRepository)
+	milestoneService.RegisterRoutes(subrouter)
 
-	registerCommonRoutes(subrouter)
+	registerCommonRoutes(subrouter, userRepository)
+	registerWellKnownRoutes(router)
 
 	log.Println("Server is running on", s.address)
 
 	return http.ListenAndServe(s.address, router)
 }
+
+// newMailer returns the mailer configured by MAIL_DRIVER. Without SMTP the
+// emails are written to MAIL_DIR, so the API works locally out of the box.
+func newMailer() types.Mailer {
+	switch config.Envs.MailDriver {
+	case "smtp":
+		return mailer.NewSMTP(config.Envs.SMTPHost, config.Envs.SMTPPort, config.Envs.SMTPUsername, config.Envs.SMTPPassword, config.Envs.MailFrom)
+	case "memory":
+		return mailer.NewMemory()
+	default:
+		return mailer.NewFile(config.Envs.MailDir, config.Envs.MailFrom)
+	}
+}
+
+// newOIDCProvider returns the provider configured by OIDC_ISSUER, or nil when
+// single sign-on is disabled.
+func newOIDCProvider() *oidc.Provider {
+	if config.Envs.OIDCIssuer == "" {
+		return nil
+	}
+	return oidc.NewProvider(
+		config.Envs.OIDCIssuer,
+		config.Envs.OIDCClientID,
+		config.Envs.OIDCClientSecret,
+		config.Envs.OIDCRedirectURL,
+		strings.Fields(config.Envs.OIDCScopes),
+		nil,
+	)
+}
+
+// newTokenDenylist returns the denylist configured by JWT_DENYLIST. The
+// database one is the default as it is shared by all instances.
+func newTokenDenylist(db *sql.DB) types.TokenDenylist {
+	if config.Envs.JWTDenylist == "memory" {
+		return denylist.NewMemory()
+	}
+	return denylist.NewStore(db)
+}
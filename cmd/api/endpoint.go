@@ -8,34 +8,54 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 	_ "github.com/trsnaqe/gotask/docs"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
 )
 
-func registerCommonRoutes(router *mux.Router) {
+// registerWellKnownRoutes registers the routes that live at fixed paths
+// outside of the API prefix.
+func registerWellKnownRoutes(router *mux.Router) {
+	// the public keys other services verify our tokens with
+	router.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		utils.WriteJSON(w, http.StatusOK, auth.CurrentJWKS())
+	}).Methods(http.MethodGet)
+}
+
+func registerCommonRoutes(router *mux.Router, store types.UserStore) {
 
 	// GetLog   get-log
 	// @Summary     Check logs
-	// @Description Endpoint that serves the log file
+	// @Description Endpoint that serves the log file, requires the log:read permission
 	// @Tags        API
 	// @Accept      json
 	// @Produce     json
+	// @Security    jwtKey
 	// @Success     200 {file}   log.txt
 	// @Failure     400 {object} types.ErrorResponse
+	// @Failure     401 {object} types.ErrorResponse
+	// @Failure     403 {object} types.ErrorResponse
 	// @Failure     500 {object} types.ErrorResponse
 	// @Router      /log [get]
-	router.HandleFunc("/log", func(w http.ResponseWriter, r *http.Request) {
+	router.HandleFunc("/log", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionLogRead, func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "app.log")
-	}).Methods(http.MethodGet)
+	}), store)).Methods(http.MethodGet)
 
-	router.HandleFunc("/log",
+	router.HandleFunc("/log", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionLogWrite,
 
 		// DeleteLog   delete-log
 		// @Summary     Delete logs
-		// @Description Endpoint that deletes the content of log file
+		// @Description Endpoint that deletes the content of log file, requires the log:write permission
 		// @Tags        API
 		// @Accept      json
 		// @Produce     json
+		// @Security    jwtKey
 		// @Success     200 {object} string
 		// @Failure     400 {object} types.ErrorResponse
+		// @Failure     401 {object} types.ErrorResponse
+		// @Failure     403 {object} types.ErrorResponse
 		// @Failure     500 {object} types.ErrorResponse
 		// @Router      /log [delete]
 		func(w http.ResponseWriter, r *http.Request) {
@@ -48,7 +68,7 @@ func registerCommonRoutes(router *mux.Router) {
 			defer file.Close()
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("Log file has been deleted"))
-		}).Methods(http.MethodDelete)
+		}), store)).Methods(http.MethodDelete)
 
 	// Health   		health
 	// @Summary     Check health
@@ -68,15 +88,18 @@ func registerCommonRoutes(router *mux.Router) {
 
 	// Metrics   metrics
 	// @Summary     Check metrics
-	// @Description Endpoint that serves the metrics
+	// @Description Endpoint that serves the metrics, requires the metrics:read permission
 	// @Tags        API
 	// @Accept      json
 	// @Produce     json
+	// @Security    jwtKey
 	// @Success     200 {object} string
 	// @Failure     400 {object} types.ErrorResponse
+	// @Failure     401 {object} types.ErrorResponse
+	// @Failure     403 {object} types.ErrorResponse
 	// @Failure     500 {object} types.ErrorResponse
 	// @Router      /metrics [get]
-	router.Handle("/metrics", promhttp.Handler())
+	router.Handle("/metrics", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionMetricsRead, promhttp.Handler().ServeHTTP), store))
 
 	router.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
 
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/services/auth"
+)
+
+// keys manages the token signing keys in JWT_KEYS_DIR.
+//
+//	rotate  generates a new private key, which signs all new tokens from the
+//	        next start of the API on
+//	retire  replaces every private key but the newest with its public key, so
+//	        tokens signed with them are still accepted until they expire
+//
+// Public keys of retired keys can be deleted once the refresh expiration has
+// passed since the rotation.
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("usage: keys rotate|retire [-dir keys] [-alg EdDSA|RS256]")
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	dir := flags.String("dir", "keys", "directory holding the keys")
+	algorithm := flags.String("alg", auth.AlgorithmEdDSA, "algorithm of the new key, EdDSA or RS256")
+	flags.Parse(os.Args[2:])
+
+	switch os.Args[1] {
+	case "rotate":
+		if err := rotate(*dir, *algorithm); err != nil {
+			log.Fatal(err)
+		}
+	case "retire":
+		if err := retire(*dir); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown command %s, should be rotate or retire", os.Args[1])
+	}
+}
+
+func rotate(dir string, algorithm string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	kid, data, err := auth.GenerateKey(algorithm, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		return err
+	}
+
+	fmt.Println("Created signing key", kid)
+	return nil
+}
+
+func retire(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	private := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !strings.HasSuffix(path, ".pub.pem") {
+			private = append(private, path)
+		}
+	}
+	if len(private) == 0 {
+		return fmt.Errorf("no private key found in %s", dir)
+	}
+	sort.Strings(private)
+
+	for _, path := range private[:len(private)-1] {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		public, err := auth.EncodePublicKey(data)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if err := os.WriteFile(strings.TrimSuffix(path, ".pem")+".pub.pem", public, 0644); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		fmt.Println("Retired signing key", strings.TrimSuffix(filepath.Base(path), ".pem"))
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/trsnaqe/gotask/cmd/api"
 	"github.com/trsnaqe/gotask/config"
 	database "github.com/trsnaqe/gotask/db"
+	"github.com/trsnaqe/gotask/services/auth"
 )
 
 func main() {
@@ -35,6 +36,21 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if config.Envs.JWTKeysDir != "" {
+		keys, err := auth.LoadKeySet(config.Envs.JWTKeysDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		auth.UseKeySet(keys)
+		log.Println("Signing tokens with key", keys.SigningKeyID())
+	}
+
+	hasher, err := auth.NewPasswordHasher(config.Envs.PasswordHasher)
+	if err != nil {
+		log.Fatal(err)
+	}
+	auth.UsePasswordHasher(hasher)
+
 	database.InitStorage(db)
 	address := fmt.Sprintf(":%s", config.Envs.Port)
 	server := api.NewAPIServer(address, db)
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS task_links (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    task_id INT UNSIGNED NOT NULL,
+    linked_task_id INT UNSIGNED NOT NULL,
+    type ENUM('relates_to', 'duplicates', 'duplicated_by', 'cloned_from', 'cloned_by') NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_task_links (task_id, linked_task_id, type),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (linked_task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS task_status_history;DROP TABLE IF EXISTS sprint_tasks;DROP TABLE IF EXISTS sprints;
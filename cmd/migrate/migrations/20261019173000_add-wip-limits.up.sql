@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS wip_limits (
+    status ENUM('pending', 'in_progress', 'completed') NOT NULL PRIMARY KEY,
+    max_tasks INT UNSIGNED NOT NULL,
+    updated_by INT UNSIGNED,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (updated_by) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE task_status_history DROP COLUMN reason;
+ALTER TABLE tasks DROP COLUMN reopened_count, DROP COLUMN completed_at, DROP COLUMN started_at;
@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+    ADD COLUMN started_at TIMESTAMP NULL,
+    ADD COLUMN completed_at TIMESTAMP NULL,
+    ADD COLUMN reopened_count INT UNSIGNED NOT NULL DEFAULT 0;
+
+ALTER TABLE task_status_history ADD COLUMN reason VARCHAR(255);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS custom_fields (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    name VARCHAR(64) NOT NULL,
+    type ENUM('text', 'number', 'date', 'select', 'multi_select', 'user') NOT NULL,
+    options JSON,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY (name)
+);
+
+CREATE TABLE IF NOT EXISTS task_custom_field_values (
+    task_id INT UNSIGNED NOT NULL,
+    field_id INT UNSIGNED NOT NULL,
+    value JSON NOT NULL,
+    PRIMARY KEY (task_id, field_id),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (field_id) REFERENCES custom_fields(id) ON DELETE CASCADE
+);
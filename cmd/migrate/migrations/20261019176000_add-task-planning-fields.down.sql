@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS task_labels;
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_assignee;
+ALTER TABLE tasks DROP COLUMN assignee_id, DROP COLUMN priority, DROP COLUMN due_date;
@@ -0,0 +1,12 @@
+ALTER TABLE tasks
+    ADD COLUMN due_date DATETIME NULL,
+    ADD COLUMN priority ENUM('low', 'medium', 'high', 'urgent') NOT NULL DEFAULT 'medium',
+    ADD COLUMN assignee_id INT UNSIGNED NULL,
+    ADD CONSTRAINT fk_tasks_assignee FOREIGN KEY (assignee_id) REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS task_labels (
+    task_id INT UNSIGNED NOT NULL,
+    label VARCHAR(64) NOT NULL,
+    PRIMARY KEY (task_id, label),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS task_pins;
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_snoozed_by;
+ALTER TABLE tasks DROP INDEX idx_tasks_snoozed_until, DROP COLUMN snoozed_by, DROP COLUMN snoozed_until;
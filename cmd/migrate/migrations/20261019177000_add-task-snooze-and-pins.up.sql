@@ -0,0 +1,14 @@
+ALTER TABLE tasks
+    ADD COLUMN snoozed_until DATETIME NULL,
+    ADD COLUMN snoozed_by INT UNSIGNED NULL,
+    ADD INDEX idx_tasks_snoozed_until (snoozed_until),
+    ADD CONSTRAINT fk_tasks_snoozed_by FOREIGN KEY (snoozed_by) REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS task_pins (
+    user_id INT UNSIGNED NOT NULL,
+    task_id INT UNSIGNED NOT NULL,
+    pinned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, task_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
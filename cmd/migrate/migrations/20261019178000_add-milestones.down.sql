@@ -0,0 +1,7 @@
+DELETE FROM task_links WHERE type IN ('blocks', 'blocked_by');
+ALTER TABLE task_links MODIFY type ENUM('relates_to', 'duplicates', 'duplicated_by', 'cloned_from', 'cloned_by') NOT NULL;
+
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_milestone;
+ALTER TABLE tasks DROP COLUMN milestone_id, DROP COLUMN estimate_days, DROP COLUMN start_date;
+
+DROP TABLE IF EXISTS milestones;
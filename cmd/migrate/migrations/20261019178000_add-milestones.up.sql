@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS milestones (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    target_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE tasks
+    ADD COLUMN start_date DATE NULL,
+    ADD COLUMN estimate_days INT UNSIGNED NULL,
+    ADD COLUMN milestone_id INT UNSIGNED NULL,
+    ADD CONSTRAINT fk_tasks_milestone FOREIGN KEY (milestone_id) REFERENCES milestones(id) ON DELETE SET NULL;
+
+ALTER TABLE task_links MODIFY type ENUM('relates_to', 'duplicates', 'duplicated_by', 'cloned_from', 'cloned_by', 'blocks', 'blocked_by') NOT NULL;
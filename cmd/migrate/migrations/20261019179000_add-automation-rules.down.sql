@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS automation_due_events;
+DROP TABLE IF EXISTS automation_runs;
+DROP TABLE IF EXISTS automation_rules;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS automation_rules (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    owner_id INT UNSIGNED NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    `trigger` ENUM('task_created', 'status_changed', 'due_date_passed', 'label_added') NOT NULL,
+    trigger_status ENUM('pending', 'in_progress', 'completed') NULL,
+    trigger_label VARCHAR(64) NULL,
+    conditions JSON NOT NULL,
+    actions JSON NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_automation_rules_trigger (`trigger`, enabled),
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS automation_runs (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    rule_id INT UNSIGNED NOT NULL,
+    task_id INT UNSIGNED NOT NULL,
+    `trigger` ENUM('task_created', 'status_changed', 'due_date_passed', 'label_added') NOT NULL,
+    outcome ENUM('success', 'failed', 'skipped') NOT NULL,
+    message VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_automation_runs_rule (rule_id, created_at),
+    FOREIGN KEY (rule_id) REFERENCES automation_rules(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS automation_due_events (
+    task_id INT UNSIGNED NOT NULL,
+    due_date DATETIME NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, due_date),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_escalations;
+DROP TABLE IF EXISTS stale_thresholds;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS stale_thresholds (
+    status ENUM('pending', 'in_progress') NOT NULL PRIMARY KEY,
+    days INT UNSIGNED NOT NULL,
+    updated_by INT UNSIGNED,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (updated_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS task_escalations (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    task_id INT UNSIGNED NOT NULL,
+    level TINYINT UNSIGNED NOT NULL,
+    user_id INT UNSIGNED NULL,
+    stale_since DATETIME NOT NULL,
+    message VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_task_escalations_level (task_id, stale_since, level),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
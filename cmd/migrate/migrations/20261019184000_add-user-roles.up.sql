@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN role ENUM('admin', 'member', 'viewer') NOT NULL DEFAULT 'member';
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN verified_at DATETIME NULL;
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id INT UNSIGNED NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uq_email_verification_tokens_hash (token_hash),
+    INDEX idx_email_verification_tokens_user (user_id, created_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_created_by;
+ALTER TABLE tasks DROP COLUMN created_by;
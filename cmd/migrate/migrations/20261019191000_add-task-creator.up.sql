@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    ADD COLUMN created_by INT UNSIGNED NULL,
+    ADD CONSTRAINT fk_tasks_created_by FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_parent;
+ALTER TABLE tasks DROP COLUMN parent_id;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    ADD COLUMN parent_id INT UNSIGNED NULL,
+    ADD CONSTRAINT fk_tasks_parent FOREIGN KEY (parent_id) REFERENCES tasks(id) ON DELETE SET NULL;
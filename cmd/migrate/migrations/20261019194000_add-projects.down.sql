@@ -0,0 +1,8 @@
+DELETE FROM custom_fields WHERE project_id IS NOT NULL;
+ALTER TABLE custom_fields DROP FOREIGN KEY fk_custom_fields_project;
+ALTER TABLE custom_fields DROP INDEX project_field_name, DROP COLUMN project_id, ADD UNIQUE KEY (name);
+
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_project;
+ALTER TABLE tasks DROP COLUMN project_id;
+
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    owner_id INT UNSIGNED NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE tasks
+    ADD COLUMN project_id INT UNSIGNED NULL,
+    ADD CONSTRAINT fk_tasks_project FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL;
+
+ALTER TABLE custom_fields
+    ADD COLUMN project_id INT UNSIGNED NULL,
+    ADD CONSTRAINT fk_custom_fields_project FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    DROP INDEX name,
+    ADD UNIQUE KEY project_field_name (project_id, name);
@@ -0,0 +1,7 @@
+DELETE FROM automation_runs WHERE `trigger` IN ('task_deleted', 'custom_field_changed');
+ALTER TABLE automation_runs MODIFY `trigger` ENUM('task_created', 'status_changed', 'due_date_passed', 'label_added') NOT NULL;
+
+DELETE FROM automation_rules WHERE `trigger` IN ('task_deleted', 'custom_field_changed');
+ALTER TABLE automation_rules
+    DROP COLUMN trigger_field,
+    MODIFY `trigger` ENUM('task_created', 'status_changed', 'due_date_passed', 'label_added') NOT NULL;
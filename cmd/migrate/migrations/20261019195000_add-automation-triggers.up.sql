@@ -0,0 +1,5 @@
+ALTER TABLE automation_rules
+    MODIFY `trigger` ENUM('task_created', 'status_changed', 'due_date_passed', 'label_added', 'task_deleted', 'custom_field_changed') NOT NULL,
+    ADD COLUMN trigger_field VARCHAR(64) NULL AFTER trigger_label;
+
+ALTER TABLE automation_runs MODIFY `trigger` ENUM('task_created', 'status_changed', 'due_date_passed', 'label_added', 'task_deleted', 'custom_field_changed') NOT NULL;
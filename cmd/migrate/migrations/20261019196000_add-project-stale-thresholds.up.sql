@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS project_stale_thresholds (
+    project_id INT UNSIGNED NOT NULL,
+    status ENUM('pending', 'in_progress') NOT NULL,
+    days INT UNSIGNED NOT NULL,
+    updated_by INT UNSIGNED,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, status),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (updated_by) REFERENCES users(id) ON DELETE SET NULL
+);
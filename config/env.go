@@ -10,15 +10,58 @@ import (
 )
 
 type Config struct {
-	PublicHost           string
-	Port                 string
-	DBUser               string
-	DBPassword           string
-	DBAddress            string
-	DBName               string
-	JWTSecret            string
-	JWTAccessExpiration  int64
-	JWTRefreshExpiration int64
+	PublicHost                      string
+	Port                            string
+	DBUser                          string
+	DBPassword                      string
+	DBAddress                       string
+	DBName                          string
+	JWTSecret                       string
+	JWTAccessExpiration             int64
+	JWTRefreshExpiration            int64
+	JWTIssuer                       string
+	JWTAudience                     string
+	JWTClockSkew                    int64
+	JWTKeysDir                      string
+	JWTDenylist                     string
+	AdminEmails                     string
+	TOTPIssuer                      string
+	MailDriver                      string
+	MailFrom                        string
+	MailDir                         string
+	SMTPHost                        string
+	SMTPPort                        int64
+	SMTPUsername                    string
+	SMTPPassword                    string
+	PasswordResetURL                string
+	PasswordResetExpiration         int64
+	EmailVerificationRequired       bool
+	EmailVerificationURL            string
+	EmailVerificationExpiration     int64
+	EmailVerificationResendInterval int64
+	OIDCIssuer                      string
+	OIDCClientID                    string
+	OIDCClientSecret                string
+	OIDCRedirectURL                 string
+	OIDCScopes                      string
+	LoginMaxFailures                int64
+	LoginIPMaxFailures              int64
+	LoginLockoutDuration            int64
+	LoginBackoffBase                int64
+	PasswordHasher                  string
+	Argon2Memory                    int64
+	Argon2Time                      int64
+	Argon2Parallelism               int64
+	BcryptCost                      int64
+	PasswordMinLength               int64
+	PasswordMaxLength               int64
+	PasswordRequireLowercase        bool
+	PasswordRequireUppercase        bool
+	PasswordRequireDigit            bool
+	PasswordRequireSymbol           bool
+	PasswordDisallowEmail           bool
+	PasswordMinStrength             int64
+	PasswordBreachList              string
 }
 
 var Envs = initConfig()
@@ -26,17 +69,89 @@ var Envs = initConfig()
 func initConfig() Config {
 	godotenv.Load()
 	return Config{
-		PublicHost:           getEnv("PUBLIC_HOST"),
-		Port:                 getEnv("PORT"),
-		DBUser:               getEnv("DB_USER"),
-		DBPassword:           getEnv("DB_PASSWORD"),
-		DBAddress:            fmt.Sprintf("%s:%s", getEnv("DB_HOST"), getEnv("DB_PORT")),
-		DBName:               getEnv("DB_NAME"),
-		JWTAccessExpiration:  getEnvAsInt("JWT_ACCESS_EXPIRATION"),
-		JWTRefreshExpiration: getEnvAsInt("JWT_REFRESH_EXPIRATION"),
-		JWTSecret:            getEnv("JWT_SECRET"),
+		PublicHost:                      getEnv("PUBLIC_HOST"),
+		Port:                            getEnv("PORT"),
+		DBUser:                          getEnv("DB_USER"),
+		DBPassword:                      getEnv("DB_PASSWORD"),
+		DBAddress:                       fmt.Sprintf("%s:%s", getEnv("DB_HOST"), getEnv("DB_PORT")),
+		DBName:                          getEnv("DB_NAME"),
+		JWTAccessExpiration:             getEnvAsInt("JWT_ACCESS_EXPIRATION"),
+		JWTRefreshExpiration:            getEnvAsInt("JWT_REFRESH_EXPIRATION"),
+		JWTSecret:                       getEnv("JWT_SECRET"),
+		JWTIssuer:                       getEnvOrDefault("JWT_ISSUER", "gotask"),
+		JWTAudience:                     getEnvOrDefault("JWT_AUDIENCE", "gotask-api"),
+		JWTClockSkew:                    getEnvAsIntOrDefault("JWT_CLOCK_SKEW", 30),
+		JWTKeysDir:                      getEnvOrDefault("JWT_KEYS_DIR", ""),
+		JWTDenylist:                     getEnvOrDefault("JWT_DENYLIST", "database"),
+		AdminEmails:                     getEnvOrDefault("ADMIN_EMAILS", ""),
+		TOTPIssuer:                      getEnvOrDefault("TOTP_ISSUER", "GoTask"),
+		MailDriver:                      getEnvOrDefault("MAIL_DRIVER", "file"),
+		MailFrom:                        getEnvOrDefault("MAIL_FROM", "gotask@localhost"),
+		MailDir:                         getEnvOrDefault("MAIL_DIR", "mail"),
+		SMTPHost:                        getEnvOrDefault("SMTP_HOST", ""),
+		SMTPPort:                        getEnvAsIntOrDefault("SMTP_PORT", 587),
+		SMTPUsername:                    getEnvOrDefault("SMTP_USERNAME", ""),
+		SMTPPassword:                    getEnvOrDefault("SMTP_PASSWORD", ""),
+		PasswordResetURL:                getEnvOrDefault("PASSWORD_RESET_URL", ""),
+		PasswordResetExpiration:         getEnvAsIntOrDefault("PASSWORD_RESET_EXPIRATION", 3600),
+		EmailVerificationRequired:       getEnvAsBoolOrDefault("EMAIL_VERIFICATION_REQUIRED", false),
+		EmailVerificationURL:            getEnvOrDefault("EMAIL_VERIFICATION_URL", ""),
+		EmailVerificationExpiration:     getEnvAsIntOrDefault("EMAIL_VERIFICATION_EXPIRATION", 86400),
+		EmailVerificationResendInterval: getEnvAsIntOrDefault("EMAIL_VERIFICATION_RESEND_INTERVAL", 60),
+		OIDCIssuer:                      getEnvOrDefault("OIDC_ISSUER", ""),
+		OIDCClientID:                    getEnvOrDefault("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:                getEnvOrDefault("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:                 getEnvOrDefault("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:                      getEnvOrDefault("OIDC_SCOPES", "openid email profile"),
+		LoginMaxFailures:                getEnvAsIntOrDefault("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:              getEnvAsIntOrDefault("LOGIN_IP_MAX_FAILURES", 50),
+		LoginLockoutDuration:            getEnvAsIntOrDefault("LOGIN_LOCKOUT_DURATION", 900),
+		LoginBackoffBase:                getEnvAsIntOrDefault("LOGIN_BACKOFF_BASE", 1),
+		PasswordHasher:                  getEnvOrDefault("PASSWORD_HASHER", "argon2id"),
+		Argon2Memory:                    getEnvAsIntOrDefault("ARGON2_MEMORY", 19456),
+		Argon2Time:                      getEnvAsIntOrDefault("ARGON2_TIME", 2),
+		Argon2Parallelism:               getEnvAsIntOrDefault("ARGON2_PARALLELISM", 1),
+		BcryptCost:                      getEnvAsIntOrDefault("BCRYPT_COST", 10),
+		PasswordMinLength:               getEnvAsIntOrDefault("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:               getEnvAsIntOrDefault("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequireLowercase:        getEnvAsBoolOrDefault("PASSWORD_REQUIRE_LOWERCASE", false),
+		PasswordRequireUppercase:        getEnvAsBoolOrDefault("PASSWORD_REQUIRE_UPPERCASE", false),
+		PasswordRequireDigit:            getEnvAsBoolOrDefault("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:           getEnvAsBoolOrDefault("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordDisallowEmail:           getEnvAsBoolOrDefault("PASSWORD_DISALLOW_EMAIL", true),
+		PasswordMinStrength:             getEnvAsIntOrDefault("PASSWORD_MIN_STRENGTH", 2),
+		PasswordBreachList:              getEnvOrDefault("PASSWORD_BREACH_LIST", ""),
 	}
 }
+
+// getEnvOrDefault treats an empty variable as unset, so that compose files
+// can pass optional variables through.
+func getEnvOrDefault(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvAsIntOrDefault(key string, fallback int64) int64 {
+	if os.Getenv(key) == "" {
+		return fallback
+	}
+	return getEnvAsInt(key)
+}
+
+func getEnvAsBoolOrDefault(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("environment variable %s is not a valid boolean", key)
+	}
+	return b
+}
+
 func getEnv(key string) string {
 	value, ok := os.LookupEnv(key)
 	if !ok {
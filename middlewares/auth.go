@@ -2,55 +2,261 @@ package middlewares
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"strconv"
+	"sync"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/trsnaqe/gotask/config"
 	"github.com/trsnaqe/gotask/services/auth"
 	"github.com/trsnaqe/gotask/types"
 	"github.com/trsnaqe/gotask/utils"
 )
 
+var (
+	denylistMu    sync.RWMutex
+	tokenDenylist types.TokenDenylist
+)
+
+var (
+	personalAccessTokensMu sync.RWMutex
+	personalAccessTokens   types.PersonalAccessTokenStore
+)
+
+// UsePersonalAccessTokens makes the auth middlewares accept personal access
+// tokens from the store next to access JWTs.
+func UsePersonalAccessTokens(store types.PersonalAccessTokenStore) {
+	personalAccessTokensMu.Lock()
+	defer personalAccessTokensMu.Unlock()
+	personalAccessTokens = store
+}
+
+func currentPersonalAccessTokens() types.PersonalAccessTokenStore {
+	personalAccessTokensMu.RLock()
+	defer personalAccessTokensMu.RUnlock()
+	return personalAccessTokens
+}
+
+var (
+	sessionsMu sync.RWMutex
+	sessions   types.SessionStore
+)
+
+// UseSessions makes the auth middlewares reject access tokens of sessions
+// that were revoked or have expired, so revoking a session logs its device
+// out right away instead of when its access token expires.
+func UseSessions(store types.SessionStore) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	sessions = store
+}
+
+func currentSessions() types.SessionStore {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	return sessions
+}
+
+// UseDenylist makes the auth middlewares reject access tokens on the
+// denylist, which is filled on logout.
+func UseDenylist(d types.TokenDenylist) {
+	denylistMu.Lock()
+	defer denylistMu.Unlock()
+	tokenDenylist = d
+}
+
+func currentDenylist() types.TokenDenylist {
+	denylistMu.RLock()
+	defer denylistMu.RUnlock()
+	return tokenDenylist
+}
+
+// AuthMiddleware requires a valid access token. Requests without one get a
+// 401; refusing an authenticated user is left to the handlers, which answer
+// with a 403.
 func AuthMiddleware(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := utils.GetTokenFromRequest(r)
+	return requireToken(handlerFunc, store, auth.TokenTypeAccess)
+}
 
-		token, err := auth.ValidateJWT(tokenString)
+// RefreshTokenMiddleware requires a valid refresh token, for the endpoint
+// that exchanges it for new tokens.
+func RefreshTokenMiddleware(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return requireToken(handlerFunc, store, auth.TokenTypeRefresh)
+}
+
+func requireToken(handlerFunc http.HandlerFunc, store types.UserStore, tokenType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := authenticatedContext(r, store, tokenType)
 		if err != nil {
-			log.Printf("failed to validate token: %v", err)
-			utils.PermissionDenied(w)
+			log.Println(err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			utils.Unauthorized(w)
 			return
 		}
+		handlerFunc(w, r.WithContext(ctx))
+	}
+}
 
-		if !token.Valid {
-			log.Println("invalid token")
-			utils.PermissionDenied(w)
+// OptionalAuthMiddleware adds the user to the context when the request
+// carries a valid access token and lets anonymous requests through otherwise.
+func OptionalAuthMiddleware(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if utils.GetTokenFromRequest(r) != "" {
+			if ctx, err := authenticatedContext(r, store, auth.TokenTypeAccess); err == nil {
+				r = r.WithContext(ctx)
+			}
+		}
+		handlerFunc(w, r)
+	}
+}
+
+// authenticatedContext adds the user, role and session or token scopes of
+// the request to its context.
+func authenticatedContext(r *http.Request, store types.UserStore, tokenType string) (context.Context, error) {
+	ctx := r.Context()
+	if tokenType == auth.TokenTypeAccess && auth.IsPersonalAccessToken(utils.GetTokenFromRequest(r)) {
+		u, scopes, err := authenticatePersonalAccessToken(r, store)
+		if err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, types.UserKey, u.ID)
+		ctx = context.WithValue(ctx, types.RoleKey, u.Role)
+		ctx = context.WithValue(ctx, types.EmailVerifiedKey, u.VerifiedAt != nil)
+		return context.WithValue(ctx, types.ScopesKey, scopes), nil
+	}
+
+	u, sessionID, err := authenticate(r, store, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, types.UserKey, u.ID)
+	ctx = context.WithValue(ctx, types.RoleKey, u.Role)
+	ctx = context.WithValue(ctx, types.EmailVerifiedKey, u.VerifiedAt != nil)
+	if sessionID > 0 {
+		ctx = context.WithValue(ctx, types.SessionKey, sessionID)
+	}
+	return ctx, nil
+}
+
+// RequirePermission lets the request through when the role of the user
+// grants the permission. It goes inside AuthMiddleware, which puts the role
+// in the context:
+//
+//	middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionLogRead, handler), store)
+//
+// The role is the one stored on the user, not the one in the token, so a
+// changed role takes effect immediately. Personal access tokens also need
+// the permission among their scopes.
+func RequirePermission(permission types.Permission, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.GetUserIDFromContext(r.Context()) < 0 {
+			utils.Unauthorized(w)
 			return
 		}
+		if !auth.Can(r.Context(), permission) {
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("missing permission %s", permission))
+			return
+		}
+		handlerFunc(w, r)
+	}
+}
 
-		claims := token.Claims.(jwt.MapClaims)
-		str := claims["userID"].(string)
+// RequireVerifiedEmail refuses authenticated users that have not verified
+// their email address when EMAIL_VERIFICATION_REQUIRED is set. It goes inside
+// AuthMiddleware or OptionalAuthMiddleware; anonymous requests are left to
+// the handler, so routes that must not be used unverified need
+// AuthMiddleware.
+func RequireVerifiedEmail(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if config.Envs.EmailVerificationRequired && auth.GetUserIDFromContext(r.Context()) >= 0 {
+			if verified, _ := r.Context().Value(types.EmailVerifiedKey).(bool); !verified {
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("verify your email address first"))
+				return
+			}
+		}
+		handlerFunc(w, r)
+	}
+}
 
-		userID, err := strconv.Atoi(str)
+// authenticate returns the user of the token in the request and the session
+// the token belongs to, or 0 for tokens that are not bound to a session.
+func authenticate(r *http.Request, store types.UserStore, tokenType string) (*types.User, int, error) {
+	tokenString := utils.GetTokenFromRequest(r)
+	if tokenString == "" {
+		return nil, 0, errors.New("missing token")
+	}
+
+	claims, err := auth.ValidateJWT(tokenString, tokenType)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to validate token: %v", err)
+	}
+
+	if d := currentDenylist(); d != nil && tokenType == auth.TokenTypeAccess {
+		denied, err := d.IsDenied(claims.ID, time.Now())
 		if err != nil {
-			log.Printf("failed to convert userID to int: %v", err)
-			utils.PermissionDenied(w)
-			return
+			return nil, 0, fmt.Errorf("failed to check the token denylist: %v", err)
 		}
+		if denied {
+			return nil, 0, errors.New("token has been revoked")
+		}
+	}
 
-		u, err := store.GetUserByID(userID)
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to convert subject to a user ID: %v", err)
+	}
+
+	u, err := store.GetUserByID(userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get user by id: %v", err)
+	}
+	if u == nil {
+		return nil, 0, fmt.Errorf("user %d not found", userID)
+	}
+
+	sessionID, err := claims.SessionIDValue()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to convert sessionID to int: %v", err)
+	}
+
+	if s := currentSessions(); s != nil && tokenType == auth.TokenTypeAccess && sessionID > 0 {
+		session, err := s.GetActiveSession(sessionID, time.Now())
 		if err != nil {
-			log.Printf("failed to get user by id: %v", err)
-			utils.PermissionDenied(w)
-			return
+			return nil, 0, fmt.Errorf("session %d is not active: %v", sessionID, err)
 		}
+		if session.UserID != u.ID {
+			return nil, 0, fmt.Errorf("session %d does not belong to user %d", sessionID, u.ID)
+		}
+	}
+	return u, sessionID, nil
+}
 
-		// Add the user to the context
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, types.UserKey, u.ID)
-		r = r.WithContext(ctx)
+// authenticatePersonalAccessToken returns the user of the personal access
+// token in the request and its scopes, and records that it was used.
+func authenticatePersonalAccessToken(r *http.Request, store types.UserStore) (*types.User, []types.Permission, error) {
+	tokens := currentPersonalAccessTokens()
+	if tokens == nil {
+		return nil, nil, errors.New("personal access tokens are not enabled")
+	}
 
-		handlerFunc(w, r)
+	now := time.Now()
+	token, err := tokens.GetActivePersonalAccessToken(auth.HashPersonalAccessToken(utils.GetTokenFromRequest(r)), now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid personal access token: %v", err)
+	}
+
+	u, err := store.GetUserByID(token.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user by id: %v", err)
+	}
+	if u == nil {
+		return nil, nil, fmt.Errorf("user %d not found", token.UserID)
+	}
+
+	if err := tokens.TouchPersonalAccessToken(token.ID, now); err != nil {
+		log.Printf("failed to record the use of personal access token %d: %v", token.ID, err)
 	}
+	return u, token.Scopes, nil
 }
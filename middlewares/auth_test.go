@@ -0,0 +1,254 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/services/denylist"
+	"github.com/trsnaqe/gotask/types"
+)
+
+type mockUserStore struct {
+	types.UserStore
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	switch id {
+	case 1:
+		return &types.User{ID: 1, Role: types.RoleMember}, nil
+	case 3:
+		verifiedAt := "2026-10-19T12:00:00Z"
+		return &types.User{ID: 3, Role: types.RoleAdmin, VerifiedAt: &verifiedAt}, nil
+	}
+	return nil, nil
+}
+
+type mockSessionStore struct {
+	types.SessionStore
+	active map[int]int
+}
+
+func (m *mockSessionStore) GetActiveSession(sessionID int, now time.Time) (*types.Session, error) {
+	userID, ok := m.active[sessionID]
+	if !ok {
+		return nil, errors.New("session not found or expired")
+	}
+	return &types.Session{ID: sessionID, UserID: userID}, nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tokens, err := auth.CreateTokens(1, 7, types.RoleMember)
+	assert.NoError(t, err)
+	orphan, err := auth.CreateAccessToken(2, 8, types.RoleMember)
+	assert.NoError(t, err)
+
+	var gotUser, gotSession int
+	next := func(w http.ResponseWriter, r *http.Request) {
+		gotUser = auth.GetUserIDFromContext(r.Context())
+		gotSession = auth.GetSessionIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}
+	serve := func(handler http.HandlerFunc, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	access := AuthMiddleware(next, &mockUserStore{})
+	refresh := RefreshTokenMiddleware(next, &mockUserStore{})
+
+	t.Run("should accept an access token", func(t *testing.T) {
+		rr := serve(access, tokens.AccessToken)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 1, gotUser)
+		assert.Equal(t, 7, gotSession)
+	})
+
+	t.Run("should answer 401 without a valid token", func(t *testing.T) {
+		for _, token := range []string{"", "garbage", orphan} {
+			rr := serve(access, token)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer")
+		}
+	})
+
+	t.Run("should reject a refresh token as an access token", func(t *testing.T) {
+		rr := serve(access, tokens.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should reject a denied access token", func(t *testing.T) {
+		tokens, err := auth.CreateTokens(1, 9, types.RoleMember)
+		assert.NoError(t, err)
+		claims, err := auth.ValidateJWT(tokens.AccessToken, auth.TokenTypeAccess)
+		assert.NoError(t, err)
+
+		deny := denylist.NewMemory()
+		UseDenylist(deny)
+		defer UseDenylist(nil)
+
+		assert.Equal(t, http.StatusOK, serve(access, tokens.AccessToken).Code)
+		assert.NoError(t, deny.Deny(claims.ID, claims.ExpiresAt.Time))
+		assert.Equal(t, http.StatusUnauthorized, serve(access, tokens.AccessToken).Code)
+		assert.Equal(t, http.StatusOK, serve(refresh, tokens.RefreshToken).Code, "only access tokens are denied")
+	})
+
+	t.Run("should reject access tokens of revoked sessions", func(t *testing.T) {
+		store := &mockSessionStore{active: map[int]int{7: 1}}
+		UseSessions(store)
+		defer UseSessions(nil)
+
+		assert.Equal(t, http.StatusOK, serve(access, tokens.AccessToken).Code)
+		delete(store.active, 7)
+		assert.Equal(t, http.StatusUnauthorized, serve(access, tokens.AccessToken).Code)
+
+		store.active[7] = 2
+		assert.Equal(t, http.StatusUnauthorized, serve(access, tokens.AccessToken).Code, "the session belongs to someone else")
+	})
+
+	t.Run("should only accept refresh tokens on the refresh endpoint", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(refresh, tokens.RefreshToken).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(refresh, tokens.AccessToken).Code)
+	})
+}
+
+func TestRequirePermission(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := AuthMiddleware(RequirePermission(types.PermissionLogRead, next), &mockUserStore{})
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/log", nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	member, err := auth.CreateAccessToken(1, 1, types.RoleMember)
+	assert.NoError(t, err)
+	admin, err := auth.CreateAccessToken(3, 1, types.RoleAdmin)
+	assert.NoError(t, err)
+	// the role in the claims is not trusted, the stored one is
+	forged, err := auth.CreateAccessToken(1, 1, types.RoleAdmin)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(""))
+	assert.Equal(t, http.StatusForbidden, serve(member))
+	assert.Equal(t, http.StatusForbidden, serve(forged))
+	assert.Equal(t, http.StatusOK, serve(admin))
+}
+
+func TestPersonalAccessTokenAuth(t *testing.T) {
+	token, _, hash, err := auth.NewPersonalAccessToken()
+	assert.NoError(t, err)
+	tokens := &mockTokenStore{tokens: map[string]types.PersonalAccessToken{
+		hash: {ID: 5, UserID: 3, Scopes: []types.Permission{types.PermissionTaskRead}},
+	}}
+
+	next := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	serve := func(handler http.HandlerFunc, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", token)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	t.Run("should reject tokens when they are not enabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(AuthMiddleware(next, &mockUserStore{}), token))
+	})
+
+	UsePersonalAccessTokens(tokens)
+	defer UsePersonalAccessTokens(nil)
+
+	t.Run("should allow the scopes of the token", func(t *testing.T) {
+		handler := AuthMiddleware(RequirePermission(types.PermissionTaskRead, next), &mockUserStore{})
+		assert.Equal(t, http.StatusOK, serve(handler, token))
+		assert.Equal(t, []int{5}, tokens.touched)
+	})
+
+	t.Run("should forbid permissions outside of the scopes", func(t *testing.T) {
+		handler := AuthMiddleware(RequirePermission(types.PermissionUserAdmin, next), &mockUserStore{})
+		assert.Equal(t, http.StatusForbidden, serve(handler, token), "the admin role alone is not enough")
+	})
+
+	t.Run("should reject unknown tokens and the refresh endpoint", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(AuthMiddleware(next, &mockUserStore{}), auth.PersonalAccessTokenPrefix+"unknown"))
+		assert.Equal(t, http.StatusUnauthorized, serve(RefreshTokenMiddleware(next, &mockUserStore{}), token))
+	})
+}
+
+type mockTokenStore struct {
+	types.PersonalAccessTokenStore
+	tokens  map[string]types.PersonalAccessToken
+	touched []int
+}
+
+func (m *mockTokenStore) GetActivePersonalAccessToken(tokenHash string, now time.Time) (*types.PersonalAccessToken, error) {
+	t, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, errors.New("token not found, revoked or expired")
+	}
+	return &t, nil
+}
+
+func (m *mockTokenStore) TouchPersonalAccessToken(id int, now time.Time) error {
+	m.touched = append(m.touched, id)
+	return nil
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	previous := config.Envs.EmailVerificationRequired
+	defer func() { config.Envs.EmailVerificationRequired = previous }()
+
+	next := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := OptionalAuthMiddleware(RequireVerifiedEmail(next), &mockUserStore{})
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/task", nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	unverified, err := auth.CreateAccessToken(1, 1, types.RoleMember)
+	assert.NoError(t, err)
+	verified, err := auth.CreateAccessToken(3, 1, types.RoleAdmin)
+	assert.NoError(t, err)
+
+	config.Envs.EmailVerificationRequired = false
+	assert.Equal(t, http.StatusOK, serve(unverified))
+
+	config.Envs.EmailVerificationRequired = true
+	assert.Equal(t, http.StatusForbidden, serve(unverified))
+	assert.Equal(t, http.StatusOK, serve(verified))
+	assert.Equal(t, http.StatusOK, serve(""), "anonymous requests are left to the handler")
+}
+
+func TestHasPermission(t *testing.T) {
+	assert.True(t, auth.HasPermission(types.RoleAdmin, types.PermissionUserAdmin))
+	assert.True(t, auth.HasPermission(types.RoleMember, types.PermissionTaskWrite))
+	assert.False(t, auth.HasPermission(types.RoleMember, types.PermissionTaskOverrideWIP))
+	assert.True(t, auth.HasPermission(types.RoleViewer, types.PermissionTaskRead))
+	assert.False(t, auth.HasPermission(types.RoleViewer, types.PermissionTaskWrite))
+	assert.False(t, auth.HasPermission("", types.PermissionTaskRead))
+}
@@ -2,7 +2,10 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -13,33 +16,85 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateAccessToken(userID int) (string, error) {
-	expiration := time.Second * time.Duration(config.Envs.JWTAccessExpiration)
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeMFA     = "mfa"
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":    strconv.Itoa(userID),
-		"expiresAt": time.Now().Add(expiration).Unix(),
-		"type":      "access",
-	})
+	// MFAChallengeExpiration is how long a user has to enter their second
+	// factor after the password was accepted.
+	MFAChallengeExpiration = 5 * time.Minute
+)
 
-	tokenString, err := token.SignedString([]byte(config.Envs.JWTSecret))
-	if err != nil {
-		return "", err
+// ErrWrongTokenType is returned when a valid token is used for something it
+// was not issued for, like a refresh token sent as an access token.
+var ErrWrongTokenType = errors.New("wrong token type")
+
+// Claims are the claims of the tokens issued by the API. The user is the
+// subject, the jti identifies the token and Type tells access and refresh
+// tokens apart. Access tokens carry the role the user had when the token was
+// issued, for clients and other services.
+type Claims struct {
+	jwt.RegisteredClaims
+	Type      string     `json:"type"`
+	SessionID string     `json:"sessionID,omitempty"`
+	Role      types.Role `json:"role,omitempty"`
+}
+
+// UserID returns the user the token was issued to.
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// SessionIDValue returns the session the token belongs to, or 0 for tokens
+// that are not bound to a session.
+func (c *Claims) SessionIDValue() (int, error) {
+	if c.SessionID == "" {
+		return 0, nil
 	}
+	return strconv.Atoi(c.SessionID)
+}
 
-	return tokenString, nil
+func CreateAccessToken(userID int, sessionID int, role types.Role) (string, error) {
+	return createToken(userID, sessionID, role, TokenTypeAccess, time.Second*time.Duration(config.Envs.JWTAccessExpiration))
 }
 
-func CreateRefreshToken(userID int) (string, error) {
-	expiration := time.Second * time.Duration(config.Envs.JWTRefreshExpiration)
+func CreateRefreshToken(userID int, sessionID int) (string, error) {
+	return createToken(userID, sessionID, "", TokenTypeRefresh, time.Second*time.Duration(config.Envs.JWTRefreshExpiration))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":    strconv.Itoa(userID),
-		"expiresAt": time.Now().Add(expiration).Unix(),
-		"type":      "refresh",
-	})
+// CreateMFAToken creates the challenge token returned by a login that still
+// needs a second factor. It is only accepted by POST /login/mfa.
+func CreateMFAToken(userID int) (string, error) {
+	return createToken(userID, 0, "", TokenTypeMFA, MFAChallengeExpiration)
+}
 
-	tokenString, err := token.SignedString([]byte(config.Envs.JWTSecret))
+func createToken(userID int, sessionID int, role types.Role, tokenType string, expiration time.Duration) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    config.Envs.JWTIssuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{config.Envs.JWTAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        tokenID,
+		},
+		Type:      tokenType,
+		SessionID: strconv.Itoa(sessionID),
+		Role:      role,
+	}
+
+	key := currentKeySet().signing
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	tokenString, err := token.SignedString(key.private)
 	if err != nil {
 		return "", err
 	}
@@ -47,13 +102,24 @@ func CreateRefreshToken(userID int) (string, error) {
 	return tokenString, nil
 }
 
-func CreateTokens(userID int) (tokens types.Tokens, err error) {
-	accessToken, err := CreateAccessToken(userID)
+// newTokenID returns a random ID so that tokens issued for the same session
+// within the same second still differ.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateTokens creates an access and a refresh token bound to a session.
+func CreateTokens(userID int, sessionID int, role types.Role) (tokens types.Tokens, err error) {
+	accessToken, err := CreateAccessToken(userID, sessionID, role)
 	if err != nil {
 		return types.Tokens{AccessToken: "", RefreshToken: ""}, err
 	}
 
-	refreshToken, err := CreateRefreshToken(userID)
+	refreshToken, err := CreateRefreshToken(userID, sessionID)
 	if err != nil {
 		return types.Tokens{AccessToken: "", RefreshToken: ""}, err
 	}
@@ -61,14 +127,41 @@ func CreateTokens(userID int) (tokens types.Tokens, err error) {
 	return types.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
-func ValidateJWT(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+// ValidateJWT parses a token and checks its signature against the key named in
+// its kid header, its registered claims and that it is of the expected type.
+// Expiry, not-before and issued-at are checked with the configured clock skew
+// as leeway.
+func ValidateJWT(tokenString string, tokenType string) (*Claims, error) {
+	ks := currentKeySet()
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, ks.verificationKey,
+		jwt.WithValidMethods(ks.methods()),
+		jwt.WithIssuer(config.Envs.JWTIssuer),
+		jwt.WithAudience(config.Envs.JWTAudience),
+		jwt.WithLeeway(time.Second*time.Duration(config.Envs.JWTClockSkew)),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
 
-		return []byte(config.Envs.JWTSecret), nil
-	})
+	if claims.Subject == "" || claims.ID == "" {
+		return nil, errors.New("token is missing the subject or ID")
+	}
+	if claims.Type != tokenType {
+		return nil, fmt.Errorf("%w: expected %s token, got %q", ErrWrongTokenType, tokenType, claims.Type)
+	}
+	return claims, nil
+}
+
+// RefreshTokenID validates a refresh token and returns its jti.
+func RefreshTokenID(tokenString string) (string, error) {
+	claims, err := ValidateJWT(tokenString, TokenTypeRefresh)
+	if err != nil {
+		return "", err
+	}
+	return claims.ID, nil
 }
 
 func GetUserIDFromContext(ctx context.Context) int {
@@ -80,6 +173,21 @@ func GetUserIDFromContext(ctx context.Context) int {
 	return userID
 }
 
+// GetSessionIDFromContext returns the session of the authenticated request or
+// -1 for tokens that are not bound to a session.
+func GetSessionIDFromContext(ctx context.Context) int {
+	sessionID, ok := ctx.Value(types.SessionKey).(int)
+	if !ok {
+		return -1
+	}
+	return sessionID
+}
+
+// RefreshExpiration returns when a refresh token created now expires.
+func RefreshExpiration(now time.Time) time.Time {
+	return now.Add(time.Second * time.Duration(config.Envs.JWTRefreshExpiration))
+}
+
 func HashRefreshToken(value string) (string, error) {
 	shaHash := sha256.New()
 	shaHash.Write([]byte(value))
@@ -1,14 +1,17 @@
 package auth
 
 import (
-	"strconv"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/types"
 )
 
 func TestCreateJWT(t *testing.T) {
-	tokens, err := CreateTokens(123)
+	tokens, err := CreateTokens(123, 1, types.RoleMember)
 	if err != nil {
 		t.Errorf("error creating JWT: %v", err)
 	}
@@ -24,30 +27,17 @@ func TestCreateJWT(t *testing.T) {
 
 func TestValidateJWT(t *testing.T) {
 	userID := 123
-	tokenString, err := CreateAccessToken(userID)
+	tokenString, err := CreateAccessToken(userID, 1, types.RoleMember)
 	if err != nil {
 		t.Fatalf("error creating access token: %v", err)
 	}
 
-	token, err := ValidateJWT(tokenString)
+	claims, err := ValidateJWT(tokenString, TokenTypeAccess)
 	if err != nil {
-		t.Errorf("error validating JWT: %v", err)
+		t.Fatalf("error validating JWT: %v", err)
 	}
 
-	if !token.Valid {
-		t.Error("expected token to be valid")
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		t.Fatal("claims are not of type MapClaims")
-	}
-	claimUserID, ok := claims["userID"].(string)
-	if !ok {
-		t.Fatal("userID claim is not a string")
-	}
-
-	parsedUserID, err := strconv.Atoi(claimUserID)
+	parsedUserID, err := claims.UserID()
 	if err != nil {
 		t.Fatalf("error parsing userID from claim: %v", err)
 	}
@@ -55,6 +45,108 @@ func TestValidateJWT(t *testing.T) {
 	if parsedUserID != userID {
 		t.Errorf("expected userID to be %d, got %d", userID, parsedUserID)
 	}
+	if claims.Issuer != config.Envs.JWTIssuer || claims.ID == "" || claims.IssuedAt == nil || claims.NotBefore == nil {
+		t.Errorf("expected registered claims to be set, got %+v", claims.RegisteredClaims)
+	}
+}
+
+func TestValidateJWTRejects(t *testing.T) {
+	sign := func(t *testing.T, claims Claims, method jwt.SigningMethod) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = currentKeySet().SigningKeyID()
+		tokenString, err := token.SignedString([]byte(config.Envs.JWTSecret))
+		if err != nil {
+			t.Fatalf("error signing token: %v", err)
+		}
+		return tokenString
+	}
+	valid := func() Claims {
+		now := time.Now()
+		return Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    config.Envs.JWTIssuer,
+				Subject:   "123",
+				Audience:  jwt.ClaimStrings{config.Envs.JWTAudience},
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				NotBefore: jwt.NewNumericDate(now),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ID:        "abc",
+			},
+			Type: TokenTypeAccess,
+		}
+	}
+
+	t.Run("should reject a refresh token used as an access token", func(t *testing.T) {
+		tokenString, err := CreateRefreshToken(123, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ValidateJWT(tokenString, TokenTypeAccess); !errors.Is(err, ErrWrongTokenType) {
+			t.Errorf("expected a wrong token type error, got %v", err)
+		}
+	})
+
+	t.Run("should reject an access token used as a refresh token", func(t *testing.T) {
+		tokenString, err := CreateAccessToken(123, 1, types.RoleMember)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RefreshTokenID(tokenString); !errors.Is(err, ErrWrongTokenType) {
+			t.Errorf("expected a wrong token type error, got %v", err)
+		}
+	})
+
+	t.Run("should reject an expired token", func(t *testing.T) {
+		claims := valid()
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		if _, err := ValidateJWT(sign(t, claims, jwt.SigningMethodHS256), TokenTypeAccess); !errors.Is(err, jwt.ErrTokenExpired) {
+			t.Errorf("expected an expired token error, got %v", err)
+		}
+	})
+
+	t.Run("should accept a token expired within the clock skew", func(t *testing.T) {
+		claims := valid()
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Second))
+		if _, err := ValidateJWT(sign(t, claims, jwt.SigningMethodHS256), TokenTypeAccess); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should reject a token without expiry", func(t *testing.T) {
+		claims := valid()
+		claims.ExpiresAt = nil
+		if _, err := ValidateJWT(sign(t, claims, jwt.SigningMethodHS256), TokenTypeAccess); err == nil {
+			t.Error("expected a token without expiry to be rejected")
+		}
+	})
+
+	t.Run("should reject a token that is not valid yet", func(t *testing.T) {
+		claims := valid()
+		claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		if _, err := ValidateJWT(sign(t, claims, jwt.SigningMethodHS256), TokenTypeAccess); !errors.Is(err, jwt.ErrTokenNotValidYet) {
+			t.Errorf("expected a not valid yet error, got %v", err)
+		}
+	})
+
+	t.Run("should reject another issuer or audience", func(t *testing.T) {
+		claims := valid()
+		claims.Issuer = "someone-else"
+		if _, err := ValidateJWT(sign(t, claims, jwt.SigningMethodHS256), TokenTypeAccess); !errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+			t.Errorf("expected an invalid issuer error, got %v", err)
+		}
+
+		claims = valid()
+		claims.Audience = jwt.ClaimStrings{"another-api"}
+		if _, err := ValidateJWT(sign(t, claims, jwt.SigningMethodHS256), TokenTypeAccess); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+			t.Errorf("expected an invalid audience error, got %v", err)
+		}
+	})
+
+	t.Run("should reject another signing method", func(t *testing.T) {
+		if _, err := ValidateJWT(sign(t, valid(), jwt.SigningMethodHS512), TokenTypeAccess); err == nil {
+			t.Error("expected a HS512 token to be rejected")
+		}
+	})
 }
 
 func TestHashRefreshToken(t *testing.T) {
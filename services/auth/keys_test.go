@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func writeKey(t *testing.T, dir string, algorithm string, now time.Time) string {
+	kid, data, err := GenerateKey(algorithm, now)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600))
+	return kid
+}
+
+func TestKeySet(t *testing.T) {
+	previous := currentKeySet()
+	defer UseKeySet(previous)
+
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	oldKID := writeKey(t, dir, AlgorithmRS256, now)
+
+	ks, err := LoadKeySet(dir)
+	assert.NoError(t, err)
+	UseKeySet(ks)
+	assert.Equal(t, oldKID, ks.SigningKeyID())
+
+	oldToken, err := CreateAccessToken(1, 1, types.RoleMember)
+	assert.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &Claims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+	assert.Equal(t, oldKID, parsed.Header["kid"])
+
+	t.Run("should sign with the newest key and still accept the old one", func(t *testing.T) {
+		newKID := writeKey(t, dir, AlgorithmEdDSA, now.Add(time.Hour))
+		ks, err := LoadKeySet(dir)
+		assert.NoError(t, err)
+		UseKeySet(ks)
+		assert.Equal(t, newKID, ks.SigningKeyID())
+
+		newToken, err := CreateAccessToken(1, 1, types.RoleMember)
+		assert.NoError(t, err)
+		parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+		assert.NoError(t, err)
+		assert.Equal(t, "EdDSA", parsed.Method.Alg())
+
+		_, err = ValidateJWT(newToken, TokenTypeAccess)
+		assert.NoError(t, err)
+		_, err = ValidateJWT(oldToken, TokenTypeAccess)
+		assert.NoError(t, err, "tokens of the previous key stay valid after a rotation")
+	})
+
+	t.Run("should keep verifying with a retired public key", func(t *testing.T) {
+		private, err := os.ReadFile(filepath.Join(dir, oldKID+".pem"))
+		assert.NoError(t, err)
+		public, err := EncodePublicKey(private)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, oldKID+".pub.pem"), public, 0644))
+		assert.NoError(t, os.Remove(filepath.Join(dir, oldKID+".pem")))
+
+		ks, err := LoadKeySet(dir)
+		assert.NoError(t, err)
+		UseKeySet(ks)
+		_, err = ValidateJWT(oldToken, TokenTypeAccess)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject tokens of removed keys and HMAC tokens", func(t *testing.T) {
+		assert.NoError(t, os.Remove(filepath.Join(dir, oldKID+".pub.pem")))
+		ks, err := LoadKeySet(dir)
+		assert.NoError(t, err)
+		UseKeySet(ks)
+		_, err = ValidateJWT(oldToken, TokenTypeAccess)
+		assert.Error(t, err)
+
+		UseKeySet(previous)
+		hmacToken, err := CreateAccessToken(1, 1, types.RoleMember)
+		assert.NoError(t, err)
+		UseKeySet(ks)
+		_, err = ValidateJWT(hmacToken, TokenTypeAccess)
+		assert.Error(t, err)
+	})
+
+	t.Run("should publish the public keys", func(t *testing.T) {
+		writeKey(t, dir, AlgorithmRS256, now.Add(2*time.Hour))
+		ks, err := LoadKeySet(dir)
+		assert.NoError(t, err)
+
+		jwks := ks.JWKS()
+		if assert.Len(t, jwks.Keys, 2) {
+			assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+			assert.Equal(t, "Ed25519", jwks.Keys[0].Curve)
+			assert.NotEmpty(t, jwks.Keys[0].X)
+			assert.Equal(t, "RSA", jwks.Keys[1].KeyType)
+			assert.Equal(t, "AQAB", jwks.Keys[1].E)
+			assert.NotEmpty(t, jwks.Keys[1].N)
+		}
+		assert.Empty(t, NewHMACKeySet([]byte("secret")).JWKS().Keys, "shared secrets are never published")
+	})
+
+	t.Run("should fail without a private key", func(t *testing.T) {
+		_, err := LoadKeySet(t.TempDir())
+		assert.Error(t, err)
+	})
+}
@@ -1,15 +1,226 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/trsnaqe/gotask/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HasherArgon2id = "argon2id"
+	HasherBcrypt   = "bcrypt"
+
+	argon2idPrefix    = "$argon2id$"
+	argon2SaltLength  = 16
+	argon2KeyLength   = 32
+	argon2MaxMemory   = 1024 * 1024
+	argon2MaxTime     = 100
+	argon2MaxParallel = 255
+)
+
+// PasswordHasher hashes new passwords. Hashes of every supported algorithm
+// are checked with CompareValue, so the hasher can change without locking
+// out users whose passwords were hashed before.
+type PasswordHasher interface {
+	Hash(value string) (string, error)
+	// NeedsRehash reports whether a hash was made with another algorithm or
+	// other parameters than the hasher uses for new hashes.
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idHasher hashes with argon2id and encodes the hashes in the PHC
+// string format, `$argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>`.
+// Memory is in KiB.
+type Argon2idHasher struct {
+	Memory      uint32
+	Time        uint32
+	Parallelism uint8
+}
+
+// BcryptHasher hashes with bcrypt. bcrypt only uses the first 72 bytes of a
+// password, so longer passwords are refused.
+type BcryptHasher struct {
+	Cost int
+}
+
+var (
+	passwordHasherMu sync.RWMutex
+	passwordHasher   = defaultPasswordHasher()
+)
+
+// UsePasswordHasher replaces the hasher new passwords are hashed with.
+func UsePasswordHasher(h PasswordHasher) {
+	passwordHasherMu.Lock()
+	defer passwordHasherMu.Unlock()
+	passwordHasher = h
+}
+
+func currentPasswordHasher() PasswordHasher {
+	passwordHasherMu.RLock()
+	defer passwordHasherMu.RUnlock()
+	return passwordHasher
+}
+
+// defaultPasswordHasher returns the configured hasher, or argon2id with the
+// default parameters when the configuration is invalid. The server checks
+// the configuration on startup and refuses to run with an invalid one.
+func defaultPasswordHasher() PasswordHasher {
+	if h, err := NewPasswordHasher(config.Envs.PasswordHasher); err == nil {
+		return h
+	}
+	return &Argon2idHasher{Memory: 19456, Time: 2, Parallelism: 1}
+}
+
+// NewPasswordHasher returns the hasher with the name, configured by the
+// ARGON2_* or BCRYPT_COST variables.
+func NewPasswordHasher(name string) (PasswordHasher, error) {
+	switch name {
+	case HasherArgon2id:
+		if p := config.Envs.Argon2Parallelism; p < 1 || p > argon2MaxParallel {
+			return nil, fmt.Errorf("ARGON2_PARALLELISM should be between 1 and %d", argon2MaxParallel)
+		}
+		if m := config.Envs.Argon2Memory; m < 0 || m > argon2MaxMemory {
+			return nil, fmt.Errorf("ARGON2_MEMORY should be between 8 KiB per thread and %d KiB", argon2MaxMemory)
+		}
+		if t := config.Envs.Argon2Time; t < 1 || t > argon2MaxTime {
+			return nil, fmt.Errorf("ARGON2_TIME should be between 1 and %d", argon2MaxTime)
+		}
+		h := &Argon2idHasher{
+			Memory:      uint32(config.Envs.Argon2Memory),
+			Time:        uint32(config.Envs.Argon2Time),
+			Parallelism: uint8(config.Envs.Argon2Parallelism),
+		}
+		if err := h.validate(); err != nil {
+			return nil, err
+		}
+		return h, nil
+	case HasherBcrypt:
+		cost := int(config.Envs.BcryptCost)
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("BCRYPT_COST should be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return &BcryptHasher{Cost: cost}, nil
+	default:
+		return nil, fmt.Errorf("unsupported password hasher %s, should be one of %s, %s", name, HasherArgon2id, HasherBcrypt)
+	}
+}
+
+// HashValue hashes a password with the current hasher.
 func HashValue(value string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(value), bcrypt.DefaultCost)
+	return currentPasswordHasher().Hash(value)
+}
+
+// CompareValue checks a value against a hash made by any supported
+// algorithm, taken from the prefix of the hash.
+func CompareValue(hashedValue, value string) bool {
+	if strings.HasPrefix(hashedValue, argon2idPrefix) {
+		return compareArgon2id(hashedValue, value)
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashedValue), []byte(value)) == nil
+}
+
+// NeedsRehash reports whether a password hash should be replaced by a hash
+// of the current hasher, which is done when the user next logs in.
+func NeedsRehash(hashedValue string) bool {
+	return currentPasswordHasher().NeedsRehash(hashedValue)
+}
+
+func (h *Argon2idHasher) Hash(value string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(value), salt, h.Time, h.Memory, h.Parallelism, argon2KeyLength)
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.Memory, h.Time, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, key, err := parseArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return *params != *h || len(key) != argon2KeyLength
+}
+
+func (h *Argon2idHasher) validate() error {
+	if h.Memory < 8*uint32(h.Parallelism) || h.Memory > argon2MaxMemory {
+		return fmt.Errorf("ARGON2_MEMORY should be between 8 KiB per thread and %d KiB", argon2MaxMemory)
+	}
+	if h.Time < 1 || h.Time > argon2MaxTime {
+		return fmt.Errorf("ARGON2_TIME should be between 1 and %d", argon2MaxTime)
+	}
+	if h.Parallelism < 1 {
+		return fmt.Errorf("ARGON2_PARALLELISM should be between 1 and %d", argon2MaxParallel)
+	}
+	return nil
+}
+
+func (h *BcryptHasher) Hash(value string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(value), h.Cost)
 	if err != nil {
 		return "", err
 	}
 	return string(hash), nil
 }
 
-func CompareValue(hashedValue, value string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hashedValue), []byte(value)) == nil
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+func compareArgon2id(hash string, value string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	computed := argon2.IDKey([]byte(value), salt, params.Time, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
+
+// parseArgon2id decodes a PHC string. The parameters are bounded, so a hash
+// planted in the database cannot make a login exhaust the memory.
+func parseArgon2id(hash string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HasherArgon2id {
+		return nil, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	params := new(Argon2idHasher)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2 parameters: %v", err)
+	}
+	if err := params.validate(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(key) < 16 {
+		return nil, nil, nil, errors.New("argon2 hash is too short")
+	}
+	return params, salt, key, nil
 }
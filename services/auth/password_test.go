@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"strings"
 	"testing"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -32,3 +35,94 @@ func TestComparePasswords(t *testing.T) {
 		t.Errorf("expected value to not match hash")
 	}
 }
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := &Argon2idHasher{Memory: 1024, Time: 1, Parallelism: 1}
+	hash, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("expected a PHC string with the parameters, got %s", hash)
+	}
+	if !CompareValue(hash, "password") {
+		t.Error("expected value to match hash")
+	}
+	if CompareValue(hash, "notpassword") {
+		t.Error("expected value to not match hash")
+	}
+
+	other, _ := hasher.Hash("password")
+	if other == hash {
+		t.Error("expected hashes to be salted")
+	}
+
+	long := strings.Repeat("a", 72)
+	longHash, _ := hasher.Hash(long + "1")
+	if CompareValue(longHash, long+"2") {
+		t.Error("expected passwords longer than 72 bytes to be compared in full")
+	}
+}
+
+func TestBcryptHashesStillWork(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CompareValue(string(legacy), "password") {
+		t.Error("expected bcrypt hashes to be accepted")
+	}
+	if CompareValue(string(legacy), "notpassword") {
+		t.Error("expected value to not match hash")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	current := &Argon2idHasher{Memory: 1024, Time: 1, Parallelism: 1}
+	hash, _ := current.Hash("password")
+	weaker, _ := (&Argon2idHasher{Memory: 512, Time: 1, Parallelism: 1}).Hash("password")
+	legacy, _ := (&BcryptHasher{Cost: bcrypt.MinCost}).Hash("password")
+
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{"same argon2id parameters", current, hash, false},
+		{"other argon2id parameters", current, weaker, true},
+		{"bcrypt hash with argon2id", current, legacy, true},
+		{"same bcrypt cost", &BcryptHasher{Cost: bcrypt.MinCost}, legacy, false},
+		{"other bcrypt cost", &BcryptHasher{Cost: bcrypt.MinCost + 1}, legacy, true},
+		{"argon2id hash with bcrypt", &BcryptHasher{Cost: bcrypt.MinCost}, hash, true},
+	}
+	for _, test := range tests {
+		if got := test.hasher.NeedsRehash(test.hash); got != test.want {
+			t.Errorf("%s: expected NeedsRehash to be %v, got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestArgon2idRejectsUnboundedParameters(t *testing.T) {
+	hash := "$argon2id$v=19$m=99999999,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+	if CompareValue(hash, "password") {
+		t.Error("expected hashes with too much memory to be rejected")
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	if _, err := NewPasswordHasher("md5"); err == nil {
+		t.Error("expected unknown hashers to be rejected")
+	}
+	if _, err := NewPasswordHasher(HasherBcrypt); err != nil {
+		t.Errorf("expected the default bcrypt cost to be valid: %v", err)
+	}
+	h, err := NewPasswordHasher(HasherArgon2id)
+	if err != nil {
+		t.Fatalf("expected the default argon2id parameters to be valid: %v", err)
+	}
+	if _, ok := h.(*Argon2idHasher); !ok {
+		t.Errorf("expected an argon2id hasher, got %T", h)
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, which tells
+// them apart from JWTs and makes leaked tokens easy to search for.
+const PersonalAccessTokenPrefix = "gtp_"
+
+// NewPersonalAccessToken returns a random token, the prefix shown in token
+// listings and the hash to store.
+func NewPersonalAccessToken() (token string, prefix string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	token = PersonalAccessTokenPrefix + hex.EncodeToString(b)
+	return token, token[:len(PersonalAccessTokenPrefix)+8], HashPersonalAccessToken(token), nil
+}
+
+// HashPersonalAccessToken hashes a token for storage and lookup. The tokens
+// are random, so a plain SHA-256 is enough and lets them be looked up by hash.
+func HashPersonalAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
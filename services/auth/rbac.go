@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// rolePermissions lists what each role may do. Admins may do everything.
+var rolePermissions = map[types.Role][]types.Permission{
+	types.RoleAdmin: {
+		types.PermissionTaskRead,
+		types.PermissionTaskWrite,
+		types.PermissionTaskOverrideWIP,
+		types.PermissionUserAdmin,
+		types.PermissionLogRead,
+		types.PermissionLogWrite,
+		types.PermissionMetricsRead,
+		types.PermissionSystemAdmin,
+		types.PermissionNotificationRead,
+		types.PermissionAutomationManage,
+		types.PermissionWorkflowManage,
+		types.PermissionAccountManage,
+	},
+	types.RoleMember: {
+		types.PermissionTaskRead,
+		types.PermissionTaskWrite,
+		types.PermissionNotificationRead,
+		types.PermissionAutomationManage,
+		types.PermissionAccountManage,
+	},
+	types.RoleViewer: {
+		types.PermissionTaskRead,
+		types.PermissionNotificationRead,
+		types.PermissionAccountManage,
+	},
+}
+
+// HasPermission reports whether the role grants the permission. Unknown roles
+// grant nothing.
+func HasPermission(role types.Role, permission types.Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the authenticated request may use the permission. The
+// role of the user has to grant it, and requests made with a personal access
+// token are further limited to the scopes of the token.
+func Can(ctx context.Context, permission types.Permission) bool {
+	if !HasPermission(GetRoleFromContext(ctx), permission) {
+		return false
+	}
+	scopes, limited := ctx.Value(types.ScopesKey).([]types.Permission)
+	if !limited {
+		return true
+	}
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRoleFromContext returns the role of the authenticated user, or an empty
+// role for anonymous requests.
+func GetRoleFromContext(ctx context.Context) types.Role {
+	role, _ := ctx.Value(types.RoleKey).(types.Role)
+	return role
+}
+
+// CanManageProject reports whether the authenticated request may change the
+// settings of the project. Its owner may, and so may users with the
+// workflow:manage permission.
+func CanManageProject(ctx context.Context, project *types.Project) bool {
+	return project.OwnerID == GetUserIDFromContext(ctx) || Can(ctx, types.PermissionWorkflowManage)
+}
@@ -0,0 +1,17 @@
+package automation
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/automation/rules", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAutomationManage, h.handleGetRules), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/automation/rules", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAutomationManage, h.handleCreateRule), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/automation/rules/{id}", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAutomationManage, h.handleUpdateRule), h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/automation/rules/{id}", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAutomationManage, h.handleDeleteRule), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/automation/rules/{id}/runs", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAutomationManage, h.handleGetRuns), h.userStore)).Methods(http.MethodGet)
+}
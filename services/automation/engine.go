@@ -0,0 +1,394 @@
+package automation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// maxChainDepth is how many times actions may trigger further rules before
+// the engine stops following the chain.
+const maxChainDepth = 5
+
+// maxRunMessage is the size of the message column of automation_runs.
+const maxRunMessage = 255
+
+// Engine evaluates automation rules against task events. Actions are applied
+// to the task store directly, so they never emit events themselves; the
+// events they cause are followed explicitly as part of the same chain.
+type Engine struct {
+	store     types.AutomationStore
+	taskStore types.TaskStore
+	projects  types.ProjectStore
+	client    *http.Client
+	wg        sync.WaitGroup
+}
+
+func NewEngine(store types.AutomationStore, taskStore types.TaskStore, projects types.ProjectStore) *Engine {
+	return &Engine{
+		store:     store,
+		taskStore: taskStore,
+		projects:  projects,
+		client:    newWebhookClient(),
+	}
+}
+
+// newWebhookClient returns the client webhooks are sent with. Webhook URLs
+// come from users, so it refuses to connect to loopback, private, link-local
+// and other non-public addresses. The check runs on the address being
+// dialed, after DNS resolution and for every redirect, so hostnames that
+// resolve to internal addresses are refused as well.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not public", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+}
+
+// isPublicIP reports whether the address is reachable on the internet, as
+// opposed to loopback, private, link-local, multicast or unspecified ones.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP does not
+// count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// chain tracks the rules fired while handling one event and the events its
+// actions caused. A rule runs at most once per task within a chain, which
+// stops rules from triggering each other forever.
+type chain struct {
+	depth int
+	fired map[string]bool
+}
+
+// Enqueue handles an event in the background.
+func (e *Engine) Enqueue(event types.AutomationEvent) {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.Dispatch(event)
+	}()
+}
+
+// Wait blocks until all enqueued events are handled.
+func (e *Engine) Wait() {
+	e.wg.Wait()
+}
+
+// Dispatch runs every enabled rule matching the event and logs the outcome of
+// each run. Failures are logged and never returned, so a broken rule cannot
+// fail the change that triggered it.
+func (e *Engine) Dispatch(event types.AutomationEvent) {
+	e.run(event, &chain{fired: make(map[string]bool)})
+}
+
+// run evaluates the rules of the users the task belongs to, its creator, its
+// assignee and the owner of its project, so that rules only ever touch tasks
+// their owner has a stake in.
+func (e *Engine) run(event types.AutomationEvent, c *chain) {
+	task, err := e.eventTask(event)
+	if err != nil {
+		log.Printf("failed to load task %d for automation: %v", event.TaskID, err)
+		return
+	}
+
+	users, err := e.stakeholders(*task)
+	if err != nil {
+		log.Printf("failed to load stakeholders of task %d for automation: %v", task.ID, err)
+		return
+	}
+	rules, err := e.store.GetEnabledRulesByTrigger(event.Trigger, users)
+	if err != nil {
+		log.Printf("failed to load automation rules for %s: %v", event.Trigger, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	for _, rule := range rules {
+		if !triggerMatches(rule, event) || !conditionsHold(rule.Conditions, *task) {
+			continue
+		}
+
+		key := fmt.Sprintf("%d:%d", rule.ID, task.ID)
+		switch {
+		case c.depth >= maxChainDepth:
+			e.logRun(rule, event, types.OutcomeSkipped, fmt.Sprintf("chain of %d rules reached the limit", c.depth))
+			continue
+		case c.fired[key]:
+			e.logRun(rule, event, types.OutcomeSkipped, "rule already ran for this task in the same chain")
+			continue
+		}
+		c.fired[key] = true
+
+		followUps, err := e.execute(rule, task, event)
+		if err != nil {
+			e.logRun(rule, event, types.OutcomeFailed, err.Error())
+		} else {
+			e.logRun(rule, event, types.OutcomeSuccess, fmt.Sprintf("ran %d actions", len(rule.Actions)))
+		}
+
+		for _, followUp := range followUps {
+			e.run(followUp, &chain{depth: c.depth + 1, fired: c.fired})
+		}
+	}
+}
+
+func (e *Engine) logRun(rule types.AutomationRule, event types.AutomationEvent, outcome types.AutomationOutcome, message string) {
+	if len(message) > maxRunMessage {
+		message = message[:maxRunMessage]
+	}
+	err := e.store.LogRun(types.AutomationRun{
+		RuleID:  rule.ID,
+		TaskID:  event.TaskID,
+		Trigger: event.Trigger,
+		Outcome: outcome,
+		Message: message,
+	})
+	if err != nil {
+		log.Printf("failed to log run of automation rule %d: %v", rule.ID, err)
+	}
+}
+
+// eventTask returns the task of the event with its labels. Deleted tasks are
+// no longer in the store, so their events carry the task as it was.
+func (e *Engine) eventTask(event types.AutomationEvent) (*types.Task, error) {
+	if event.Task != nil {
+		task := *event.Task
+		return &task, nil
+	}
+
+	task, err := e.taskStore.GetTaskByID(event.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := e.taskStore.GetTaskLabels([]int{task.ID})
+	if err != nil {
+		return nil, err
+	}
+	task.Labels = labels[task.ID]
+	return task, nil
+}
+
+// stakeholders returns the users whose rules run on the task.
+func (e *Engine) stakeholders(task types.Task) ([]int, error) {
+	var users []int
+	add := func(id *int) {
+		if id == nil {
+			return
+		}
+		for _, user := range users {
+			if user == *id {
+				return
+			}
+		}
+		users = append(users, *id)
+	}
+
+	add(task.CreatedBy)
+	add(task.AssigneeID)
+	if task.ProjectID != nil {
+		project, err := e.projects.GetProjectByID(*task.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		add(&project.OwnerID)
+	}
+	return users, nil
+}
+
+func triggerMatches(rule types.AutomationRule, event types.AutomationEvent) bool {
+	if rule.Trigger != event.Trigger {
+		return false
+	}
+	if rule.TriggerStatus != nil && *rule.TriggerStatus != event.Status {
+		return false
+	}
+	if rule.TriggerLabel != nil && *rule.TriggerLabel != event.Label {
+		return false
+	}
+	if rule.TriggerField != nil && *rule.TriggerField != event.Field {
+		return false
+	}
+	return true
+}
+
+// conditionsHold reports whether the task has all labels, the priority, the
+// assignee and the project required by the conditions.
+func conditionsHold(conditions types.AutomationConditions, task types.Task) bool {
+	if conditions.Priority != nil && *conditions.Priority != task.Priority {
+		return false
+	}
+	if conditions.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *conditions.ProjectID) {
+		return false
+	}
+	if conditions.AssigneeID != nil && (task.AssigneeID == nil || *task.AssigneeID != *conditions.AssigneeID) {
+		return false
+	}
+	for _, want := range conditions.Labels {
+		found := false
+		for _, label := range task.Labels {
+			if label == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// execute applies the actions of a rule in order and stops at the first one
+// that fails. It returns the events caused by the actions that succeeded.
+func (e *Engine) execute(rule types.AutomationRule, task *types.Task, event types.AutomationEvent) ([]types.AutomationEvent, error) {
+	var followUps []types.AutomationEvent
+	for i, action := range rule.Actions {
+		caused, err := e.apply(rule, action, task, event)
+		followUps = append(followUps, caused...)
+		if err != nil {
+			return followUps, fmt.Errorf("action %d (%s) failed: %v", i+1, action.Type, err)
+		}
+	}
+	return followUps, nil
+}
+
+func (e *Engine) apply(rule types.AutomationRule, action types.AutomationAction, task *types.Task, event types.AutomationEvent) ([]types.AutomationEvent, error) {
+	switch action.Type {
+	case types.ActionSetStatus:
+		if task.Status == action.Status {
+			return nil, nil
+		}
+		status := action.Status
+		if err := e.taskStore.UpdateTask(task.ID, types.UpdateTaskPayload{Status: &status}, false); err != nil {
+			return nil, err
+		}
+		task.Status = status
+		return []types.AutomationEvent{{Trigger: types.TriggerStatusChanged, TaskID: task.ID, Status: status}}, nil
+
+	case types.ActionAddLabel:
+		added, err := e.taskStore.AddTaskLabel(task.ID, action.Label)
+		if err != nil || !added {
+			return nil, err
+		}
+		task.Labels = append(task.Labels, action.Label)
+		return []types.AutomationEvent{{Trigger: types.TriggerLabelAdded, TaskID: task.ID, Label: action.Label}}, nil
+
+	case types.ActionAssign:
+		if err := e.taskStore.UpdateTask(task.ID, types.UpdateTaskPayload{AssigneeID: action.AssigneeID}, false); err != nil {
+			return nil, err
+		}
+		task.AssigneeID = action.AssigneeID
+		return nil, nil
+
+	case types.ActionCreateTask:
+		description := fmt.Sprintf("Follow-up of task #%d created by automation rule %q", task.ID, rule.Name)
+		if event.Trigger == types.TriggerTaskDeleted {
+			description = fmt.Sprintf("Follow-up of deleted task #%d %q created by automation rule %q", task.ID, task.Title, rule.Name)
+		}
+		followUpID, err := e.taskStore.CreateTask(types.Task{
+			Title:       action.Title,
+			Description: description,
+			Status:      types.StatusPending,
+			AssigneeID:  task.AssigneeID,
+			CreatedBy:   &rule.OwnerID,
+			ProjectID:   task.ProjectID,
+		}, false)
+		if err != nil {
+			return nil, err
+		}
+		// a deleted task has nothing left to link to
+		if event.Trigger != types.TriggerTaskDeleted {
+			if err := e.taskStore.CreateTaskLink(followUpID, task.ID, types.LinkRelatesTo); err != nil {
+				return nil, err
+			}
+		}
+		return []types.AutomationEvent{{Trigger: types.TriggerTaskCreated, TaskID: followUpID}}, nil
+
+	case types.ActionWebhook:
+		return nil, e.sendWebhook(action.URL, rule, task, event)
+	}
+	return nil, fmt.Errorf("unknown action type %s", action.Type)
+}
+
+type webhookPayload struct {
+	RuleID int                   `json:"rule_id"`
+	Rule   string                `json:"rule"`
+	Event  types.AutomationEvent `json:"event"`
+	Task   types.Task            `json:"task"`
+}
+
+func (e *Engine) sendWebhook(url string, rule types.AutomationRule, task *types.Task, event types.AutomationEvent) error {
+	body, err := json.Marshal(webhookPayload{RuleID: rule.ID, Rule: rule.Name, Event: event, Task: *task})
+	if err != nil {
+		return err
+	}
+
+	res, err := e.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (e *Engine) dispatchOverdueTasks(now time.Time) error {
+	due, err := e.store.GetOverdueTasks(now)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range due {
+		// mark first so a slow or failing rule is not run again on the next tick
+		if err := e.store.MarkDueDateHandled(d.TaskID, d.DueDate); err != nil {
+			return err
+		}
+		e.Dispatch(types.AutomationEvent{Trigger: types.TriggerDueDatePassed, TaskID: d.TaskID})
+	}
+	return nil
+}
+
+// StartDueDateWatcher fires the due_date_passed trigger for overdue tasks
+// every interval in the background.
+func (e *Engine) StartDueDateWatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := e.dispatchOverdueTasks(now); err != nil {
+				log.Printf("failed to run automation for overdue tasks: %v", err)
+			}
+		}
+	}()
+}
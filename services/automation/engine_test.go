@@ -0,0 +1,482 @@
+package automation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestEngine(t *testing.T) {
+	owner := 1
+	completed := types.StatusCompleted
+	pending := types.StatusPending
+
+	t.Run("should run the actions of a matching rule", func(t *testing.T) {
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:            1,
+			OwnerID:       owner,
+			Name:          "follow up on backend work",
+			Enabled:       true,
+			Trigger:       types.TriggerStatusChanged,
+			TriggerStatus: &completed,
+			Conditions:    types.AutomationConditions{Labels: []string{"backend"}},
+			Actions: []types.AutomationAction{
+				{Type: types.ActionAddLabel, Label: "done"},
+				{Type: types.ActionCreateTask, Title: "Deploy it"},
+			},
+		}}}
+		taskStore := newMockTaskStore(types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusCompleted})
+		taskStore.labels[1] = []string{"backend"}
+
+		NewEngine(store, taskStore, &mockProjectStore{}).Dispatch(types.AutomationEvent{Trigger: types.TriggerStatusChanged, TaskID: 1, Status: types.StatusCompleted})
+
+		assert.Equal(t, []string{"backend", "done"}, taskStore.labels[1])
+		assert.Equal(t, "Deploy it", taskStore.tasks[2].Title)
+		assert.Equal(t, &owner, taskStore.tasks[2].CreatedBy, "follow-ups belong to the owner of the rule")
+		assert.Equal(t, []types.TaskLink{{TaskID: 2, LinkedTaskID: 1, Type: types.LinkRelatesTo}}, taskStore.links)
+		assert.Equal(t, []types.AutomationOutcome{types.OutcomeSuccess}, store.outcomes())
+	})
+
+	t.Run("should not run rules whose conditions do not hold", func(t *testing.T) {
+		high := types.PriorityHigh
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:         1,
+			OwnerID:    owner,
+			Enabled:    true,
+			Trigger:    types.TriggerTaskCreated,
+			Conditions: types.AutomationConditions{Priority: &high},
+			Actions:    []types.AutomationAction{{Type: types.ActionAddLabel, Label: "urgent"}},
+		}}}
+		taskStore := newMockTaskStore(types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusPending, Priority: types.PriorityLow})
+
+		NewEngine(store, taskStore, &mockProjectStore{}).Dispatch(types.AutomationEvent{Trigger: types.TriggerTaskCreated, TaskID: 1})
+
+		assert.Empty(t, taskStore.labels[1])
+		assert.Empty(t, store.runs)
+	})
+
+	t.Run("should only run rules on tasks of their owner", func(t *testing.T) {
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:      1,
+			OwnerID: 2,
+			Enabled: true,
+			Trigger: types.TriggerTaskCreated,
+			Actions: []types.AutomationAction{{Type: types.ActionAddLabel, Label: "mine"}},
+		}}}
+		other := 2
+		taskStore := newMockTaskStore(
+			types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusPending},
+			types.Task{ID: 2, CreatedBy: &owner, AssigneeID: &other, Status: types.StatusPending},
+			types.Task{ID: 3, CreatedBy: &other, Status: types.StatusPending},
+		)
+		engine := NewEngine(store, taskStore, &mockProjectStore{})
+
+		for id := 1; id <= 3; id++ {
+			engine.Dispatch(types.AutomationEvent{Trigger: types.TriggerTaskCreated, TaskID: id})
+		}
+
+		assert.Empty(t, taskStore.labels[1], "the owner has no stake in the task")
+		assert.Equal(t, []string{"mine"}, taskStore.labels[2])
+		assert.Equal(t, []string{"mine"}, taskStore.labels[3])
+	})
+
+	t.Run("should stop rules that trigger each other", func(t *testing.T) {
+		store := &mockAutomationStore{rules: []types.AutomationRule{
+			{ID: 1, OwnerID: owner, Enabled: true, Trigger: types.TriggerStatusChanged, TriggerStatus: &completed, Actions: []types.AutomationAction{{Type: types.ActionSetStatus, Status: types.StatusPending}}},
+			{ID: 2, OwnerID: owner, Enabled: true, Trigger: types.TriggerStatusChanged, TriggerStatus: &pending, Actions: []types.AutomationAction{{Type: types.ActionSetStatus, Status: types.StatusCompleted}}},
+		}}
+		taskStore := newMockTaskStore(types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusCompleted})
+
+		NewEngine(store, taskStore, &mockProjectStore{}).Dispatch(types.AutomationEvent{Trigger: types.TriggerStatusChanged, TaskID: 1, Status: types.StatusCompleted})
+
+		assert.Equal(t, []types.AutomationOutcome{types.OutcomeSuccess, types.OutcomeSuccess, types.OutcomeSkipped}, store.outcomes())
+		assert.Equal(t, 1, store.runs[2].RuleID)
+		assert.Equal(t, types.StatusCompleted, taskStore.tasks[1].Status)
+	})
+
+	t.Run("should log a failed webhook", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:      1,
+			OwnerID: owner,
+			Enabled: true,
+			Trigger: types.TriggerTaskCreated,
+			Actions: []types.AutomationAction{{Type: types.ActionWebhook, URL: server.URL}},
+		}}}
+		taskStore := newMockTaskStore(types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusPending})
+
+		engine := NewEngine(store, taskStore, &mockProjectStore{})
+		engine.client = server.Client()
+		engine.Dispatch(types.AutomationEvent{Trigger: types.TriggerTaskCreated, TaskID: 1})
+
+		assert.Equal(t, []types.AutomationOutcome{types.OutcomeFailed}, store.outcomes())
+		assert.Contains(t, store.runs[0].Message, "status 500")
+	})
+
+	t.Run("should refuse webhooks to internal addresses", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:      1,
+			OwnerID: owner,
+			Enabled: true,
+			Trigger: types.TriggerTaskCreated,
+			Actions: []types.AutomationAction{{Type: types.ActionWebhook, URL: server.URL}},
+		}}}
+		taskStore := newMockTaskStore(types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusPending})
+
+		NewEngine(store, taskStore, &mockProjectStore{}).Dispatch(types.AutomationEvent{Trigger: types.TriggerTaskCreated, TaskID: 1})
+
+		assert.False(t, called)
+		assert.Equal(t, []types.AutomationOutcome{types.OutcomeFailed}, store.outcomes())
+		assert.Contains(t, store.runs[0].Message, "is not public")
+	})
+
+	t.Run("should fire once per overdue task", func(t *testing.T) {
+		due := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+		store := &mockAutomationStore{
+			overdue: []types.TaskDueDate{{TaskID: 1, DueDate: due}},
+			rules: []types.AutomationRule{{
+				ID:      1,
+				OwnerID: owner,
+				Enabled: true,
+				Trigger: types.TriggerDueDatePassed,
+				Actions: []types.AutomationAction{{Type: types.ActionAddLabel, Label: "overdue"}},
+			}},
+		}
+		taskStore := newMockTaskStore(types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusInProgress})
+
+		assert.NoError(t, NewEngine(store, taskStore, &mockProjectStore{}).dispatchOverdueTasks(due.Add(time.Hour)))
+
+		assert.Equal(t, []types.TaskDueDate{{TaskID: 1, DueDate: due}}, store.handled)
+		assert.Equal(t, []string{"overdue"}, taskStore.labels[1])
+	})
+
+	t.Run("should emit events for changes made through the task store", func(t *testing.T) {
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:      1,
+			OwnerID: owner,
+			Enabled: true,
+			Trigger: types.TriggerTaskCreated,
+			Actions: []types.AutomationAction{{Type: types.ActionSetStatus, Status: types.StatusInProgress}},
+		}}}
+		taskStore := newMockTaskStore()
+		engine := NewEngine(store, taskStore, &mockProjectStore{})
+
+		id, err := NewTaskStore(taskStore, engine).CreateTask(types.Task{Title: "New task", Status: types.StatusPending, CreatedBy: &owner}, false)
+		assert.NoError(t, err)
+		engine.Wait()
+
+		assert.Equal(t, types.StatusInProgress, taskStore.tasks[id].Status)
+		assert.Equal(t, []types.AutomationOutcome{types.OutcomeSuccess}, store.outcomes())
+	})
+
+	t.Run("should run rules of the project owner on tasks of the project", func(t *testing.T) {
+		projectID, other, projectOwner := 7, 8, 3
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:         1,
+			OwnerID:    projectOwner,
+			Enabled:    true,
+			Trigger:    types.TriggerTaskCreated,
+			Conditions: types.AutomationConditions{ProjectID: &projectID},
+			Actions:    []types.AutomationAction{{Type: types.ActionAddLabel, Label: "triage"}},
+		}}}
+		taskStore := newMockTaskStore(
+			types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusPending, ProjectID: &projectID},
+			types.Task{ID: 2, CreatedBy: &owner, Status: types.StatusPending, ProjectID: &other},
+			types.Task{ID: 3, CreatedBy: &owner, Status: types.StatusPending},
+		)
+		projects := &mockProjectStore{projects: []types.Project{
+			{ID: projectID, Name: "Backend", OwnerID: projectOwner},
+			{ID: other, Name: "Frontend", OwnerID: projectOwner},
+		}}
+		engine := NewEngine(store, taskStore, projects)
+
+		for id := 1; id <= 3; id++ {
+			engine.Dispatch(types.AutomationEvent{Trigger: types.TriggerTaskCreated, TaskID: id})
+		}
+
+		assert.Equal(t, []string{"triage"}, taskStore.labels[1])
+		assert.Empty(t, taskStore.labels[2], "the task is in another project")
+		assert.Empty(t, taskStore.labels[3], "the project owner has no stake in the task")
+	})
+
+	t.Run("should run rules on deleted tasks", func(t *testing.T) {
+		projectID := 7
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:         1,
+			OwnerID:    owner,
+			Name:       "replace deleted bugs",
+			Enabled:    true,
+			Trigger:    types.TriggerTaskDeleted,
+			Conditions: types.AutomationConditions{Labels: []string{"bug"}},
+			Actions:    []types.AutomationAction{{Type: types.ActionCreateTask, Title: "Check why the bug was dropped"}},
+		}}}
+		taskStore := newMockTaskStore(types.Task{ID: 1, Title: "Crash on login", CreatedBy: &owner, Status: types.StatusPending, ProjectID: &projectID})
+		taskStore.labels[1] = []string{"bug"}
+		engine := NewEngine(store, taskStore, &mockProjectStore{projects: []types.Project{{ID: projectID, OwnerID: owner}}})
+
+		assert.NoError(t, NewTaskStore(taskStore, engine).DeleteTask(1))
+		engine.Wait()
+
+		assert.Equal(t, []types.AutomationOutcome{types.OutcomeSuccess}, store.outcomes())
+		assert.Equal(t, "Check why the bug was dropped", taskStore.tasks[2].Title)
+		assert.Contains(t, taskStore.tasks[2].Description, `deleted task #1 "Crash on login"`)
+		assert.Equal(t, &projectID, taskStore.tasks[2].ProjectID, "follow-ups stay in the project")
+		assert.Empty(t, taskStore.links)
+	})
+
+	t.Run("should run rules when a custom field changes", func(t *testing.T) {
+		field := "severity"
+		store := &mockAutomationStore{rules: []types.AutomationRule{{
+			ID:           1,
+			OwnerID:      owner,
+			Enabled:      true,
+			Trigger:      types.TriggerCustomFieldChanged,
+			TriggerField: &field,
+			Actions:      []types.AutomationAction{{Type: types.ActionAddLabel, Label: "reviewed"}},
+		}}}
+		taskStore := newMockTaskStore(types.Task{ID: 1, CreatedBy: &owner, Status: types.StatusPending})
+		taskStore.fields = []types.CustomField{{ID: 1, Name: "severity"}, {ID: 2, Name: "estimate"}}
+		taskStore.values[1] = map[int]string{1: `"low"`}
+		engine := NewEngine(store, taskStore, &mockProjectStore{})
+		tasks := NewTaskStore(taskStore, engine)
+
+		low, high, estimate := `"low"`, `"high"`, "3"
+		assert.NoError(t, tasks.SetCustomFieldValues(1, map[int]*string{1: &low, 2: &estimate}))
+		engine.Wait()
+		assert.Empty(t, store.runs, "the severity did not change")
+
+		assert.NoError(t, tasks.SetCustomFieldValues(1, map[int]*string{1: &high}))
+		engine.Wait()
+		assert.Equal(t, []types.AutomationOutcome{types.OutcomeSuccess}, store.outcomes())
+		assert.Equal(t, []string{"reviewed"}, taskStore.labels[1])
+	})
+}
+
+var errNotFound = errors.New("not found")
+
+type mockAutomationStore struct {
+	rules   []types.AutomationRule
+	runs    []types.AutomationRun
+	overdue []types.TaskDueDate
+	handled []types.TaskDueDate
+}
+
+func (m *mockAutomationStore) outcomes() []types.AutomationOutcome {
+	outcomes := make([]types.AutomationOutcome, 0, len(m.runs))
+	for _, run := range m.runs {
+		outcomes = append(outcomes, run.Outcome)
+	}
+	return outcomes
+}
+
+func (m *mockAutomationStore) GetRulesByOwner(ownerID int) ([]types.AutomationRule, error) {
+	rules := make([]types.AutomationRule, 0)
+	for _, rule := range m.rules {
+		if rule.OwnerID == ownerID {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (m *mockAutomationStore) GetRuleByID(ruleID int) (*types.AutomationRule, error) {
+	for _, rule := range m.rules {
+		if rule.ID == ruleID {
+			return &rule, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (m *mockAutomationStore) GetEnabledRulesByTrigger(trigger types.AutomationTrigger, ownerIDs []int) ([]types.AutomationRule, error) {
+	rules := make([]types.AutomationRule, 0)
+	for _, rule := range m.rules {
+		for _, ownerID := range ownerIDs {
+			if rule.Enabled && rule.Trigger == trigger && rule.OwnerID == ownerID {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules, nil
+}
+
+func (m *mockAutomationStore) CreateRule(rule types.AutomationRule) (int, error) {
+	rule.ID = len(m.rules) + 1
+	m.rules = append(m.rules, rule)
+	return rule.ID, nil
+}
+
+func (m *mockAutomationStore) UpdateRule(rule types.AutomationRule) error {
+	for i := range m.rules {
+		if m.rules[i].ID == rule.ID {
+			m.rules[i] = rule
+		}
+	}
+	return nil
+}
+
+func (m *mockAutomationStore) DeleteRule(ruleID int) error {
+	return nil
+}
+
+func (m *mockAutomationStore) LogRun(run types.AutomationRun) error {
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *mockAutomationStore) GetRuns(ruleID int) ([]types.AutomationRun, error) {
+	return m.runs, nil
+}
+
+func (m *mockAutomationStore) GetOverdueTasks(now time.Time) ([]types.TaskDueDate, error) {
+	return m.overdue, nil
+}
+
+func (m *mockAutomationStore) MarkDueDateHandled(taskID int, dueDate time.Time) error {
+	m.handled = append(m.handled, types.TaskDueDate{TaskID: taskID, DueDate: dueDate})
+	return nil
+}
+
+// mockTaskStore implements the task store methods the engine uses; calling
+// any other method panics.
+type mockTaskStore struct {
+	types.TaskStore
+	tasks  map[int]*types.Task
+	labels map[int][]string
+	links  []types.TaskLink
+	fields []types.CustomField
+	values map[int]map[int]string
+	lastID int
+}
+
+func newMockTaskStore(tasks ...types.Task) *mockTaskStore {
+	m := &mockTaskStore{tasks: make(map[int]*types.Task), labels: make(map[int][]string), values: make(map[int]map[int]string)}
+	for i := range tasks {
+		m.tasks[tasks[i].ID] = &tasks[i]
+		if tasks[i].ID > m.lastID {
+			m.lastID = tasks[i].ID
+		}
+	}
+	return m
+}
+
+func (m *mockTaskStore) GetTaskByID(taskID int) (*types.Task, error) {
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return nil, errNotFound
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (m *mockTaskStore) GetTaskLabels(taskIDs []int) (map[int][]string, error) {
+	labels := make(map[int][]string)
+	for _, id := range taskIDs {
+		labels[id] = append([]string(nil), m.labels[id]...)
+	}
+	return labels, nil
+}
+
+func (m *mockTaskStore) CreateTask(t types.Task, overrideWIP bool) (int, error) {
+	m.lastID++
+	t.ID = m.lastID
+	m.tasks[t.ID] = &t
+	return t.ID, nil
+}
+
+func (m *mockTaskStore) UpdateTask(taskID int, updates types.UpdateTaskPayload, overrideWIP bool) error {
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return errNotFound
+	}
+	if updates.Status != nil {
+		t.Status = *updates.Status
+	}
+	if updates.AssigneeID != nil {
+		t.AssigneeID = updates.AssigneeID
+	}
+	return nil
+}
+
+func (m *mockTaskStore) AddTaskLabel(taskID int, label string) (bool, error) {
+	for _, existing := range m.labels[taskID] {
+		if existing == label {
+			return false, nil
+		}
+	}
+	m.labels[taskID] = append(m.labels[taskID], label)
+	return true, nil
+}
+
+func (m *mockTaskStore) CreateTaskLink(taskID int, linkedTaskID int, linkType types.TaskLinkType) error {
+	m.links = append(m.links, types.TaskLink{TaskID: taskID, LinkedTaskID: linkedTaskID, Type: linkType})
+	return nil
+}
+
+func (m *mockTaskStore) DeleteTask(taskID int) error {
+	if _, ok := m.tasks[taskID]; !ok {
+		return errNotFound
+	}
+	delete(m.tasks, taskID)
+	delete(m.labels, taskID)
+	return nil
+}
+
+func (m *mockTaskStore) GetCustomFields() ([]types.CustomField, error) {
+	return m.fields, nil
+}
+
+func (m *mockTaskStore) GetCustomFieldValues(taskIDs []int) ([]types.CustomFieldValue, error) {
+	var values []types.CustomFieldValue
+	for _, id := range taskIDs {
+		for fieldID, value := range m.values[id] {
+			values = append(values, types.CustomFieldValue{TaskID: id, FieldID: fieldID, Value: value})
+		}
+	}
+	return values, nil
+}
+
+func (m *mockTaskStore) SetCustomFieldValues(taskID int, values map[int]*string) error {
+	if m.values[taskID] == nil {
+		m.values[taskID] = make(map[int]string)
+	}
+	for fieldID, value := range values {
+		if value == nil {
+			delete(m.values[taskID], fieldID)
+		} else {
+			m.values[taskID][fieldID] = *value
+		}
+	}
+	return nil
+}
+
+// mockProjectStore implements the project store methods the engine and the
+// handler use.
+type mockProjectStore struct {
+	types.ProjectStore
+	projects []types.Project
+}
+
+func (m *mockProjectStore) GetProjectByID(projectID int) (*types.Project, error) {
+	for _, p := range m.projects {
+		if p.ID == projectID {
+			return &p, nil
+		}
+	}
+	return nil, errNotFound
+}
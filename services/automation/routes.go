@@ -0,0 +1,313 @@
+package automation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.AutomationStore
+	projects  types.ProjectStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.AutomationStore, projects types.ProjectStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, projects: projects, userStore: userStore}
+}
+
+// HandleGetRules   get-automation-rules
+//
+// @Summary     Get Automation Rules
+// @Description Get the automation rules of the authenticated user
+// @Tags        Automation
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.AutomationRule
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /automation/rules [get]
+func (h *Handler) handleGetRules(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	rules, err := h.store.GetRulesByOwner(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, rules)
+}
+
+// HandleCreateRule   create-automation-rule
+//
+// @Summary     Create Automation Rule
+// @Description Create an automation rule owned by the authenticated user. A rule runs its actions in order when its trigger fires for a task the user created, is assigned to or whose project the user owns and all of its conditions hold.
+// @Tags        Automation
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       AutomationRulePayload body     types.AutomationRulePayload true "create rule"
+// @Success     201                   {object} types.AutomationRule
+// @Failure     400                   {object} types.ErrorResponse
+// @Failure     401                   {object} types.ErrorResponse
+// @Failure     403                   {object} types.ErrorResponse
+// @Failure     500                   {object} types.ErrorResponse
+// @Router      /automation/rules [post]
+func (h *Handler) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.parseRule(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	rule.OwnerID = auth.GetUserIDFromContext(r.Context())
+
+	id, err := h.store.CreateRule(rule)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rule.ID = id
+	utils.WriteJSON(w, http.StatusCreated, rule)
+}
+
+// HandleUpdateRule   update-automation-rule
+//
+// @Summary     Update Automation Rule
+// @Description Replace an automation rule of the authenticated user
+// @Tags        Automation
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                    path     int                         true "Rule ID"
+// @Param       AutomationRulePayload body     types.AutomationRulePayload true "update rule"
+// @Success     200                   {object} types.AutomationRule
+// @Failure     400                   {object} types.ErrorResponse
+// @Failure     401                   {object} types.ErrorResponse
+// @Failure     403                   {object} types.ErrorResponse
+// @Failure     404                   {object} types.ErrorResponse
+// @Failure     500                   {object} types.ErrorResponse
+// @Router      /automation/rules/{id} [put]
+func (h *Handler) handleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	existing, status, err := h.ownedRule(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	rule, err := h.parseRule(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	rule.ID = existing.ID
+	rule.OwnerID = existing.OwnerID
+	rule.CreatedAt = existing.CreatedAt
+
+	if err := h.store.UpdateRule(rule); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, rule)
+}
+
+// HandleDeleteRule   delete-automation-rule
+//
+// @Summary     Delete Automation Rule
+// @Description Delete an automation rule of the authenticated user together with its run log
+// @Tags        Automation
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Rule ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     404 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /automation/rules/{id} [delete]
+func (h *Handler) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	rule, status, err := h.ownedRule(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	if err := h.store.DeleteRule(rule.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Automation rule deleted"})
+}
+
+// HandleGetRuns   get-automation-runs
+//
+// @Summary     Get Automation Runs
+// @Description Get the latest runs of an automation rule of the authenticated user, newest first
+// @Tags        Automation
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Rule ID"
+// @Success     200 {array}  types.AutomationRun
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     404 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /automation/rules/{id}/runs [get]
+func (h *Handler) handleGetRuns(w http.ResponseWriter, r *http.Request) {
+	rule, status, err := h.ownedRule(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	runs, err := h.store.GetRuns(rule.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, runs)
+}
+
+// ownedRule loads the rule in the URL and checks that it belongs to the
+// authenticated user. On failure it also returns the status to respond with.
+func (h *Handler) ownedRule(r *http.Request) (*types.AutomationRule, int, error) {
+	ruleID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid rule ID")
+	}
+
+	rule, err := h.store.GetRuleByID(ruleID)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if rule.OwnerID != auth.GetUserIDFromContext(r.Context()) {
+		return nil, http.StatusForbidden, errors.New("the automation rule belongs to another user")
+	}
+	return rule, 0, nil
+}
+
+// parseRule reads and validates a rule payload. Struct tags cover the shape
+// of the payload; the fields each trigger and action type needs are checked
+// here.
+func (h *Handler) parseRule(r *http.Request) (types.AutomationRule, error) {
+	var payload types.AutomationRulePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		return types.AutomationRule{}, err
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		return types.AutomationRule{}, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors))
+	}
+
+	if payload.TriggerStatus != nil && payload.Trigger != types.TriggerStatusChanged {
+		return types.AutomationRule{}, fmt.Errorf("trigger_status can only be used with the %s trigger", types.TriggerStatusChanged)
+	}
+	if payload.TriggerLabel != nil {
+		if payload.Trigger != types.TriggerLabelAdded {
+			return types.AutomationRule{}, fmt.Errorf("trigger_label can only be used with the %s trigger", types.TriggerLabelAdded)
+		}
+		label := normalizeLabel(*payload.TriggerLabel)
+		if label == "" {
+			return types.AutomationRule{}, fmt.Errorf("trigger_label must not be empty")
+		}
+		payload.TriggerLabel = &label
+	}
+	if payload.TriggerField != nil && payload.Trigger != types.TriggerCustomFieldChanged {
+		return types.AutomationRule{}, fmt.Errorf("trigger_field can only be used with the %s trigger", types.TriggerCustomFieldChanged)
+	}
+
+	conditions := payload.Conditions
+	conditions.Labels = make([]string, 0, len(payload.Conditions.Labels))
+	for _, label := range payload.Conditions.Labels {
+		if label = normalizeLabel(label); label != "" {
+			conditions.Labels = append(conditions.Labels, label)
+		}
+	}
+	if conditions.AssigneeID != nil {
+		if _, err := h.userStore.GetUserByID(*conditions.AssigneeID); err != nil {
+			return types.AutomationRule{}, fmt.Errorf("assignee in conditions not found")
+		}
+	}
+	if conditions.ProjectID != nil {
+		if _, err := h.projects.GetProjectByID(*conditions.ProjectID); err != nil {
+			return types.AutomationRule{}, fmt.Errorf("project in conditions not found")
+		}
+	}
+
+	actions := make([]types.AutomationAction, 0, len(payload.Actions))
+	for i, action := range payload.Actions {
+		if err := h.validateAction(&action); err != nil {
+			return types.AutomationRule{}, fmt.Errorf("action %d: %v", i+1, err)
+		}
+		// a deleted task can no longer be changed
+		if payload.Trigger == types.TriggerTaskDeleted && action.Type != types.ActionCreateTask && action.Type != types.ActionWebhook {
+			return types.AutomationRule{}, fmt.Errorf("action %d: %s rules can only create tasks and send webhooks", i+1, types.TriggerTaskDeleted)
+		}
+		actions = append(actions, action)
+	}
+
+	enabled := true
+	if payload.Enabled != nil {
+		enabled = *payload.Enabled
+	}
+
+	return types.AutomationRule{
+		Name:          payload.Name,
+		Enabled:       enabled,
+		Trigger:       payload.Trigger,
+		TriggerStatus: payload.TriggerStatus,
+		TriggerLabel:  payload.TriggerLabel,
+		TriggerField:  payload.TriggerField,
+		Conditions:    conditions,
+		Actions:       actions,
+	}, nil
+}
+
+func (h *Handler) validateAction(action *types.AutomationAction) error {
+	switch action.Type {
+	case types.ActionSetStatus:
+		if action.Status == "" {
+			return errors.New("set_status needs a status")
+		}
+	case types.ActionAddLabel:
+		action.Label = normalizeLabel(action.Label)
+		if action.Label == "" {
+			return errors.New("add_label needs a label")
+		}
+	case types.ActionAssign:
+		if action.AssigneeID == nil {
+			return errors.New("assign needs an assignee_id")
+		}
+		if _, err := h.userStore.GetUserByID(*action.AssigneeID); err != nil {
+			return errors.New("assignee not found")
+		}
+	case types.ActionCreateTask:
+		if action.Title == "" {
+			return errors.New("create_task needs a title")
+		}
+	case types.ActionWebhook:
+		u, err := url.Parse(action.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook needs an http or https url")
+		}
+		if ip := net.ParseIP(u.Hostname()); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(u.Hostname(), "localhost") {
+			return errors.New("webhook url has to point to a public address")
+		}
+	}
+	return nil
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
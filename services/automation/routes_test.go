@@ -0,0 +1,110 @@
+package automation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestAutomationRules(t *testing.T) {
+	store := &mockAutomationStore{rules: []types.AutomationRule{
+		{ID: 1, OwnerID: 2, Name: "someone else's rule", Enabled: true, Trigger: types.TriggerTaskCreated},
+	}}
+	handler := NewHandler(store, &mockProjectStore{projects: []types.Project{{ID: 1, Name: "Backend", OwnerID: 3}}}, &mockUserStore{})
+	router := mux.NewRouter()
+	router.HandleFunc("/automation/rules", handler.handleCreateRule).Methods(http.MethodPost)
+	router.HandleFunc("/automation/rules/{id}", handler.handleUpdateRule).Methods(http.MethodPut)
+	router.HandleFunc("/automation/rules/{id}/runs", handler.handleGetRuns).Methods(http.MethodGet)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		assert.NoError(t, err)
+		req = req.WithContext(context.WithValue(req.Context(), types.UserKey, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should create a rule owned by the user", func(t *testing.T) {
+		rr := request("POST", "/automation/rules", `{
+			"name": "label finished work",
+			"trigger": "status_changed",
+			"trigger_status": "completed",
+			"conditions": {"labels": ["Backend"]},
+			"actions": [{"type": "add_label", "label": " Done "}]
+		}`)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		var rule types.AutomationRule
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rule))
+		assert.Equal(t, 1, rule.OwnerID)
+		assert.True(t, rule.Enabled)
+		assert.Equal(t, []string{"backend"}, rule.Conditions.Labels)
+		assert.Equal(t, "done", rule.Actions[0].Label)
+	})
+
+	t.Run("should reject an action without its parameters", func(t *testing.T) {
+		rr := request("POST", "/automation/rules", `{"name": "broken", "trigger": "task_created", "actions": [{"type": "create_task"}]}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject webhooks to internal addresses", func(t *testing.T) {
+		for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://169.254.169.254/latest", "http://[::1]/hook", "http://10.0.0.5/hook"} {
+			rr := request("POST", "/automation/rules", `{"name": "internal", "trigger": "task_created", "actions": [{"type": "webhook", "url": "`+url+`"}]}`)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, url)
+		}
+	})
+
+	t.Run("should reject a trigger status on another trigger", func(t *testing.T) {
+		rr := request("POST", "/automation/rules", `{"name": "broken", "trigger": "task_created", "trigger_status": "completed", "actions": [{"type": "add_label", "label": "new"}]}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should only create tasks and send webhooks for deleted tasks", func(t *testing.T) {
+		rr := request("POST", "/automation/rules", `{"name": "broken", "trigger": "task_deleted", "actions": [{"type": "add_label", "label": "gone"}]}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject a trigger field on another trigger", func(t *testing.T) {
+		rr := request("POST", "/automation/rules", `{"name": "broken", "trigger": "task_created", "trigger_field": "severity", "actions": [{"type": "add_label", "label": "new"}]}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject a condition on an unknown project", func(t *testing.T) {
+		rr := request("POST", "/automation/rules", `{"name": "broken", "trigger": "task_created", "conditions": {"project_id": 2}, "actions": [{"type": "add_label", "label": "new"}]}`)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should not let users change rules of others", func(t *testing.T) {
+		rr := request("PUT", "/automation/rules/1", `{"name": "taken over", "trigger": "task_created", "actions": [{"type": "add_label", "label": "new"}]}`)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should not show runs of rules of others", func(t *testing.T) {
+		rr := request("GET", "/automation/rules/1/runs", "")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+type mockUserStore struct {
+	types.UserStore
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	return &types.User{ID: id}, nil
+}
@@ -0,0 +1,209 @@
+package automation
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetRulesByOwner(ownerID int) ([]types.AutomationRule, error) {
+	return s.queryRules("SELECT * FROM automation_rules WHERE owner_id = ? ORDER BY id", ownerID)
+}
+
+func (s *Store) GetRuleByID(ruleID int) (*types.AutomationRule, error) {
+	rules, err := s.queryRules("SELECT * FROM automation_rules WHERE id = ?", ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("no automation rule found with the given ID")
+	}
+	return &rules[0], nil
+}
+
+func (s *Store) GetEnabledRulesByTrigger(trigger types.AutomationTrigger, ownerIDs []int) ([]types.AutomationRule, error) {
+	if len(ownerIDs) == 0 {
+		return []types.AutomationRule{}, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ownerIDs)), ", ")
+	args := []interface{}{trigger}
+	for _, id := range ownerIDs {
+		args = append(args, id)
+	}
+	return s.queryRules("SELECT * FROM automation_rules WHERE `trigger` = ? AND enabled = TRUE AND owner_id IN ("+placeholders+") ORDER BY id", args...)
+}
+
+func (s *Store) queryRules(query string, args ...interface{}) ([]types.AutomationRule, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]types.AutomationRule, 0)
+	for rows.Next() {
+		rule, err := scanRowIntoRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+func scanRowIntoRule(rows *sql.Rows) (*types.AutomationRule, error) {
+	rule := new(types.AutomationRule)
+	var conditions, actions []byte
+	err := rows.Scan(
+		&rule.ID,
+		&rule.OwnerID,
+		&rule.Name,
+		&rule.Enabled,
+		&rule.Trigger,
+		&rule.TriggerStatus,
+		&rule.TriggerLabel,
+		&rule.TriggerField,
+		&conditions,
+		&actions,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(conditions, &rule.Conditions); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(actions, &rule.Actions); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *Store) CreateRule(rule types.AutomationRule) (int, error) {
+	conditions, actions, err := marshalRule(rule)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.Exec(
+		"INSERT INTO automation_rules (owner_id, name, enabled, `trigger`, trigger_status, trigger_label, trigger_field, conditions, actions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		rule.OwnerID, rule.Name, rule.Enabled, rule.Trigger, rule.TriggerStatus, rule.TriggerLabel, rule.TriggerField, conditions, actions,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *Store) UpdateRule(rule types.AutomationRule) error {
+	conditions, actions, err := marshalRule(rule)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE automation_rules SET name = ?, enabled = ?, `trigger` = ?, trigger_status = ?, trigger_label = ?, trigger_field = ?, conditions = ?, actions = ?, updated_at = ? WHERE id = ?",
+		rule.Name, rule.Enabled, rule.Trigger, rule.TriggerStatus, rule.TriggerLabel, rule.TriggerField, conditions, actions, time.Now(), rule.ID,
+	)
+	return err
+}
+
+func marshalRule(rule types.AutomationRule) ([]byte, []byte, error) {
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conditions, actions, nil
+}
+
+func (s *Store) DeleteRule(ruleID int) error {
+	res, err := s.db.Exec("DELETE FROM automation_rules WHERE id = ?", ruleID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no automation rule found with the given ID")
+	}
+	return nil
+}
+
+func (s *Store) LogRun(run types.AutomationRun) error {
+	_, err := s.db.Exec(
+		"INSERT INTO automation_runs (rule_id, task_id, `trigger`, outcome, message) VALUES (?, ?, ?, ?, ?)",
+		run.RuleID, run.TaskID, run.Trigger, run.Outcome, run.Message,
+	)
+	return err
+}
+
+// GetRuns returns the latest runs of a rule, newest first.
+func (s *Store) GetRuns(ruleID int) ([]types.AutomationRun, error) {
+	rows, err := s.db.Query("SELECT * FROM automation_runs WHERE rule_id = ? ORDER BY created_at DESC, id DESC LIMIT 100", ruleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]types.AutomationRun, 0)
+	for rows.Next() {
+		run := types.AutomationRun{}
+		if err := rows.Scan(&run.ID, &run.RuleID, &run.TaskID, &run.Trigger, &run.Outcome, &run.Message, &run.CreatedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// GetOverdueTasks returns the open tasks whose due date has passed and was
+// not handled yet. A task whose due date is moved becomes due again.
+func (s *Store) GetOverdueTasks(now time.Time) ([]types.TaskDueDate, error) {
+	rows, err := s.db.Query(
+		`SELECT t.id, t.due_date FROM tasks t
+		LEFT JOIN automation_due_events e ON e.task_id = t.id AND e.due_date = t.due_date
+		WHERE t.due_date <= ? AND t.status != ? AND e.task_id IS NULL
+		ORDER BY t.due_date`,
+		now.UTC(), types.StatusCompleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := make([]types.TaskDueDate, 0)
+	for rows.Next() {
+		d := types.TaskDueDate{}
+		if err := rows.Scan(&d.TaskID, &d.DueDate); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, nil
+}
+
+func (s *Store) MarkDueDateHandled(taskID int, dueDate time.Time) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO automation_due_events (task_id, due_date) VALUES (?, ?)", taskID, dueDate.UTC())
+	return err
+}
@@ -0,0 +1,115 @@
+package automation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/trsnaqe/gotask/types"
+)
+
+var ruleColumns = []string{"id", "owner_id", "name", "enabled", "trigger", "trigger_status", "trigger_label", "trigger_field", "conditions", "actions", "created_at", "updated_at"}
+
+func TestGetEnabledRulesByTrigger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	rows := sqlmock.NewRows(ruleColumns).
+		AddRow(1, 1, "label finished work", true, types.TriggerStatusChanged, types.StatusCompleted, nil, nil,
+			[]byte(`{"labels":["backend"]}`), []byte(`[{"type":"add_label","label":"done"}]`), "2026-10-19", "2026-10-19")
+	mock.ExpectQuery("SELECT \\* FROM automation_rules WHERE `trigger` = \\? AND enabled = TRUE AND owner_id IN \\(\\?, \\?\\)").
+		WithArgs(types.TriggerStatusChanged, 1, 2).
+		WillReturnRows(rows)
+
+	rules, err := store.GetEnabledRulesByTrigger(types.TriggerStatusChanged, []int{1, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("expected one rule, got %d", len(rules))
+	}
+	rule := rules[0]
+	if rule.TriggerStatus == nil || *rule.TriggerStatus != types.StatusCompleted || rule.TriggerLabel != nil {
+		t.Errorf("unexpected trigger parameters: %+v", rule)
+	}
+	if len(rule.Conditions.Labels) != 1 || rule.Conditions.Labels[0] != "backend" {
+		t.Errorf("unexpected conditions: %+v", rule.Conditions)
+	}
+	if len(rule.Actions) != 1 || rule.Actions[0].Type != types.ActionAddLabel || rule.Actions[0].Label != "done" {
+		t.Errorf("unexpected actions: %+v", rule.Actions)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateRule(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	label := "urgent"
+	rule := types.AutomationRule{
+		OwnerID:      1,
+		Name:         "escalate urgent work",
+		Enabled:      true,
+		Trigger:      types.TriggerLabelAdded,
+		TriggerLabel: &label,
+		Actions:      []types.AutomationAction{{Type: types.ActionWebhook, URL: "https://example.com/hook"}},
+	}
+	mock.ExpectExec("INSERT INTO automation_rules").
+		WithArgs(1, rule.Name, true, types.TriggerLabelAdded, rule.TriggerStatus, rule.TriggerLabel, rule.TriggerField,
+			[]byte(`{}`), []byte(`[{"type":"webhook","url":"https://example.com/hook"}]`)).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+
+	id, err := store.CreateRule(rule)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != 3 {
+		t.Errorf("expected id 3, got %d", id)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetOverdueTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Hour)
+	mock.ExpectQuery("SELECT t.id, t.due_date FROM tasks t\\s+LEFT JOIN automation_due_events").
+		WithArgs(now, types.StatusCompleted).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "due_date"}).AddRow(4, due))
+	mock.ExpectExec("INSERT IGNORE INTO automation_due_events").WithArgs(4, due).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	overdue, err := store.GetOverdueTasks(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(overdue) != 1 || overdue[0].TaskID != 4 || !overdue[0].DueDate.Equal(due) {
+		t.Errorf("unexpected overdue tasks: %+v", overdue)
+	}
+	if err := store.MarkDueDateHandled(4, due); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,169 @@
+package automation
+
+import (
+	"log"
+	"sort"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// TaskStore wraps a task store and hands every successful change that rules
+// can react to over to the engine.
+type TaskStore struct {
+	types.TaskStore
+	engine *Engine
+}
+
+func NewTaskStore(inner types.TaskStore, engine *Engine) *TaskStore {
+	return &TaskStore{TaskStore: inner, engine: engine}
+}
+
+func (s *TaskStore) CreateTask(t types.Task, overrideWIP bool) (int, error) {
+	id, err := s.TaskStore.CreateTask(t, overrideWIP)
+	if err != nil {
+		return id, err
+	}
+	s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerTaskCreated, TaskID: id})
+	for _, label := range t.Labels {
+		s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerLabelAdded, TaskID: id, Label: label})
+	}
+	return id, nil
+}
+
+func (s *TaskStore) UpdateTask(taskID int, updates types.UpdateTaskPayload, overrideWIP bool) error {
+	var previous types.TaskStatus
+	if updates.Status != nil {
+		if t, err := s.TaskStore.GetTaskByID(taskID); err == nil {
+			previous = t.Status
+		}
+	}
+
+	if err := s.TaskStore.UpdateTask(taskID, updates, overrideWIP); err != nil {
+		return err
+	}
+	if updates.Status != nil && *updates.Status != previous {
+		s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerStatusChanged, TaskID: taskID, Status: *updates.Status})
+	}
+	return nil
+}
+
+func (s *TaskStore) ProgressTask(taskID int, overrideWIP bool) error {
+	if err := s.TaskStore.ProgressTask(taskID, overrideWIP); err != nil {
+		return err
+	}
+	s.statusChanged(taskID)
+	return nil
+}
+
+func (s *TaskStore) RegressTask(taskID int, reason *string, overrideWIP bool) error {
+	if err := s.TaskStore.RegressTask(taskID, reason, overrideWIP); err != nil {
+		return err
+	}
+	s.statusChanged(taskID)
+	return nil
+}
+
+func (s *TaskStore) ReopenTask(taskID int, reason *string, overrideWIP bool) error {
+	if err := s.TaskStore.ReopenTask(taskID, reason, overrideWIP); err != nil {
+		return err
+	}
+	s.statusChanged(taskID)
+	return nil
+}
+
+func (s *TaskStore) MergeTask(duplicateID int, originalID int) error {
+	if err := s.TaskStore.MergeTask(duplicateID, originalID); err != nil {
+		return err
+	}
+	s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerStatusChanged, TaskID: duplicateID, Status: types.StatusCompleted})
+	return nil
+}
+
+func (s *TaskStore) CloneTask(taskID int, options types.CloneTaskPayload, userID int) (int, error) {
+	id, err := s.TaskStore.CloneTask(taskID, options, userID)
+	if err != nil {
+		return id, err
+	}
+	s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerTaskCreated, TaskID: id})
+	return id, nil
+}
+
+func (s *TaskStore) AddTaskLabel(taskID int, label string) (bool, error) {
+	added, err := s.TaskStore.AddTaskLabel(taskID, label)
+	if err != nil || !added {
+		return added, err
+	}
+	s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerLabelAdded, TaskID: taskID, Label: label})
+	return true, nil
+}
+
+func (s *TaskStore) DeleteTask(taskID int) error {
+	task, err := s.TaskStore.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+	labels, err := s.TaskStore.GetTaskLabels([]int{taskID})
+	if err != nil {
+		return err
+	}
+	task.Labels = labels[taskID]
+
+	if err := s.TaskStore.DeleteTask(taskID); err != nil {
+		return err
+	}
+	s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerTaskDeleted, TaskID: taskID, Task: task})
+	return nil
+}
+
+// SetCustomFieldValues emits a change for every field whose value is
+// different afterwards, in field order.
+func (s *TaskStore) SetCustomFieldValues(taskID int, values map[int]*string) error {
+	current, err := s.TaskStore.GetCustomFieldValues([]int{taskID})
+	if err != nil {
+		return err
+	}
+	fields, err := s.TaskStore.GetCustomFields()
+	if err != nil {
+		return err
+	}
+
+	if err := s.TaskStore.SetCustomFieldValues(taskID, values); err != nil {
+		return err
+	}
+
+	previous := make(map[int]string, len(current))
+	for _, v := range current {
+		previous[v.FieldID] = v.Value
+	}
+	names := make(map[int]string, len(fields))
+	for _, f := range fields {
+		names[f.ID] = f.Name
+	}
+
+	fieldIDs := make([]int, 0, len(values))
+	for fieldID := range values {
+		fieldIDs = append(fieldIDs, fieldID)
+	}
+	sort.Ints(fieldIDs)
+
+	for _, fieldID := range fieldIDs {
+		old, had := previous[fieldID]
+		value := values[fieldID]
+		if (value == nil && !had) || (value != nil && had && *value == old) {
+			continue
+		}
+		s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerCustomFieldChanged, TaskID: taskID, Field: names[fieldID]})
+	}
+	return nil
+}
+
+// statusChanged emits a status change for transitions whose target status is
+// decided by the store.
+func (s *TaskStore) statusChanged(taskID int) {
+	t, err := s.TaskStore.GetTaskByID(taskID)
+	if err != nil {
+		log.Printf("failed to load task %d for automation: %v", taskID, err)
+		return
+	}
+	s.engine.Enqueue(types.AutomationEvent{Trigger: types.TriggerStatusChanged, TaskID: taskID, Status: t.Status})
+}
@@ -0,0 +1,48 @@
+package denylist
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory keeps the denylist in the process. Entries are lost on restart and
+// not shared between instances, so it only suits a single instance.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]time.Time)}
+}
+
+func (m *Memory) Deny(tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[tokenID] = expiresAt
+	return nil
+}
+
+func (m *Memory) IsDenied(tokenID string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiresAt, ok := m.entries[tokenID]
+	if ok && !expiresAt.After(now) {
+		delete(m.entries, tokenID)
+		return false, nil
+	}
+	return ok, nil
+}
+
+func (m *Memory) Purge(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	purged := 0
+	for tokenID, expiresAt := range m.entries {
+		if !expiresAt.After(now) {
+			delete(m.entries, tokenID)
+			purged++
+		}
+	}
+	return purged, nil
+}
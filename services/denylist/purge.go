@@ -0,0 +1,21 @@
+package denylist
+
+import (
+	"log"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// StartPurger periodically removes the entries of expired tokens.
+func StartPurger(denylist types.TokenDenylist, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if _, err := denylist.Purge(now); err != nil {
+				log.Printf("failed to purge the token denylist: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,40 @@
+package denylist
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Store keeps the denylist in the database, so that it survives restarts and
+// is shared by every instance of the API.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Deny(tokenID string, expiresAt time.Time) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)", tokenID, expiresAt.UTC())
+	return err
+}
+
+func (s *Store) IsDenied(tokenID string, now time.Time) (bool, error) {
+	var denied bool
+	err := s.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE token_id = ? AND expires_at > ?)",
+		tokenID, now.UTC(),
+	).Scan(&denied)
+	return denied, err
+}
+
+// Purge deletes the entries of tokens that have expired by now.
+func (s *Store) Purge(now time.Time) (int, error) {
+	res, err := s.db.Exec("DELETE FROM revoked_tokens WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := res.RowsAffected()
+	return int(purged), err
+}
@@ -0,0 +1,63 @@
+package denylist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT IGNORE INTO revoked_tokens \\(token_id, expires_at\\) VALUES \\(\\?, \\?\\)").
+		WithArgs("jti", now.Add(time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM revoked_tokens WHERE token_id = \\? AND expires_at > \\?\\)").
+		WithArgs("jti", now).
+		WillReturnRows(sqlmock.NewRows([]string{"denied"}).AddRow(true))
+	mock.ExpectExec("DELETE FROM revoked_tokens WHERE expires_at <= \\?").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	assert.NoError(t, store.Deny("jti", now.Add(time.Hour)))
+	denied, err := store.IsDenied("jti", now)
+	assert.NoError(t, err)
+	assert.True(t, denied)
+	purged, err := store.Purge(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	memory := NewMemory()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, memory.Deny("a", now.Add(time.Minute)))
+	assert.NoError(t, memory.Deny("b", now.Add(time.Hour)))
+
+	denied, _ := memory.IsDenied("a", now)
+	assert.True(t, denied)
+	denied, _ = memory.IsDenied("c", now)
+	assert.False(t, denied)
+
+	denied, _ = memory.IsDenied("a", now.Add(time.Minute))
+	assert.False(t, denied, "entries expire with the token")
+
+	purged, err := memory.Purge(now.Add(2 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Empty(t, memory.entries)
+}
@@ -0,0 +1,13 @@
+package lockout
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/users/{id}/unlock", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionUserAdmin, h.handleUnlockUser), h.userStore)).Methods(http.MethodPost)
+}
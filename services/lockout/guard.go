@@ -0,0 +1,164 @@
+package lockout
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/types"
+)
+
+// Guard slows down failed logins per account with an exponential backoff
+// and locks the account for LOGIN_LOCKOUT_DURATION after LOGIN_MAX_FAILURES
+// failures in a row. Addresses are locked out after LOGIN_IP_MAX_FAILURES
+// failures, across all accounts, without a backoff as many users may share
+// one address.
+//
+// Accounts are tracked by the email that was entered, whether or not a user
+// has it, so the responses do not reveal which accounts exist.
+type Guard struct {
+	store     types.LoginFailureStore
+	userStore types.UserStore
+	mailer    types.Mailer
+}
+
+func NewGuard(store types.LoginFailureStore, userStore types.UserStore, mailer types.Mailer) *Guard {
+	return &Guard{store: store, userStore: userStore, mailer: mailer}
+}
+
+// CheckLogin returns how long the login has to wait for the lock or the
+// backoff of the account or the IP, or zero when it may go ahead.
+func (g *Guard) CheckLogin(email string, ip string, now time.Time) (time.Duration, error) {
+	account, err := g.store.GetLoginFailures(types.LoginFailureScopeAccount, normalizeEmail(email))
+	if err != nil {
+		return 0, err
+	}
+	wait := waitFor(account, now, true)
+
+	if ip != "" {
+		address, err := g.store.GetLoginFailures(types.LoginFailureScopeIP, ip)
+		if err != nil {
+			return 0, err
+		}
+		if ipWait := waitFor(address, now, false); ipWait > wait {
+			wait = ipWait
+		}
+	}
+	return wait, nil
+}
+
+// LoginFailed records a failed login for the account and the IP and locks
+// them once they reach their limit. The owner of a locked account is told by
+// email.
+func (g *Guard) LoginFailed(email string, ip string, now time.Time) (bool, error) {
+	email = normalizeEmail(email)
+	window := lockoutDuration()
+
+	if ip != "" {
+		address, err := g.store.RecordLoginFailure(types.LoginFailureScopeIP, ip, now, window)
+		if err != nil {
+			return false, err
+		}
+		if address.Failures >= int(config.Envs.LoginIPMaxFailures) && !isLocked(address, now) {
+			if err := g.store.LockLogin(types.LoginFailureScopeIP, ip, now.Add(window)); err != nil {
+				return false, err
+			}
+			log.Printf("locked out logins from %s after %d failures", ip, address.Failures)
+		}
+	}
+
+	account, err := g.store.RecordLoginFailure(types.LoginFailureScopeAccount, email, now, window)
+	if err != nil {
+		return false, err
+	}
+	if account.Failures < int(config.Envs.LoginMaxFailures) || isLocked(account, now) {
+		return false, nil
+	}
+
+	until := now.Add(window)
+	if err := g.store.LockLogin(types.LoginFailureScopeAccount, email, until); err != nil {
+		return false, err
+	}
+	// the email is sent in the background so that the response time does
+	// not tell whether the account exists
+	go g.notifyLocked(email, account.Failures, until)
+	return true, nil
+}
+
+// LoginSucceeded forgets the failed logins of the account. Failures of the
+// IP are kept, so logging in to one account does not reset the limit for
+// guessing others.
+func (g *Guard) LoginSucceeded(email string) error {
+	return g.store.ClearLoginFailures(types.LoginFailureScopeAccount, normalizeEmail(email))
+}
+
+// Unlock lifts the lock of an account and forgets its failed logins.
+func (g *Guard) Unlock(email string) error {
+	return g.LoginSucceeded(email)
+}
+
+func (g *Guard) notifyLocked(email string, failures int, until time.Time) {
+	u, err := g.userStore.GetUserByEmail(email)
+	if err != nil || u == nil {
+		return
+	}
+	err = g.mailer.Send(types.Email{
+		To:      u.Email,
+		Subject: "Your account was locked",
+		Body: fmt.Sprintf(
+			"Logging in to your account failed %d times in a row, so logins are blocked until %s.\n\nIf this was not you, someone may be guessing your password. Consider resetting it once the lock is lifted, or ask an administrator to unlock the account.\n",
+			failures, until.UTC().Format(time.RFC1123),
+		),
+	})
+	if err != nil {
+		log.Printf("failed to send lockout email to user %d: %v", u.ID, err)
+	}
+}
+
+// waitFor returns how long a login has to wait for a lock and, for
+// accounts, for the backoff after the last failure, which doubles with each
+// failure from LOGIN_BACKOFF_BASE seconds up to the lockout duration.
+func waitFor(f *types.LoginFailures, now time.Time, backoff bool) time.Duration {
+	if f == nil {
+		return 0
+	}
+	var wait time.Duration
+	if f.LockedUntil != nil {
+		wait = f.LockedUntil.Sub(now)
+	}
+	if backoff && f.Failures > 0 {
+		if retry := f.LastFailedAt.Add(backoffDelay(f.Failures)).Sub(now); retry > wait {
+			wait = retry
+		}
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+func backoffDelay(failures int) time.Duration {
+	delay := time.Second * time.Duration(config.Envs.LoginBackoffBase)
+	limit := lockoutDuration()
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+func isLocked(f *types.LoginFailures, now time.Time) bool {
+	return f.LockedUntil != nil && f.LockedUntil.After(now)
+}
+
+func lockoutDuration() time.Duration {
+	return time.Second * time.Duration(config.Envs.LoginLockoutDuration)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
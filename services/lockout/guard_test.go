@@ -0,0 +1,169 @@
+package lockout
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/services/mailer"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestGuard(t *testing.T) {
+	previous := config.Envs
+	defer func() { config.Envs = previous }()
+	config.Envs.LoginMaxFailures = 3
+	config.Envs.LoginIPMaxFailures = 5
+	config.Envs.LoginLockoutDuration = 900
+	config.Envs.LoginBackoffBase = 1
+
+	newGuard := func() (*Guard, *mailer.Memory) {
+		mail := mailer.NewMemory()
+		userStore := &mockUserStore{users: map[int]*types.User{1: {ID: 1, Email: "bob@example.com"}}}
+		return NewGuard(&mockLoginFailureStore{failures: make(map[string]*types.LoginFailures)}, userStore, mail), mail
+	}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should back off exponentially and then lock the account", func(t *testing.T) {
+		guard, mail := newGuard()
+
+		locked, err := guard.LoginFailed("bob@example.com", "10.0.0.1", now)
+		assert.NoError(t, err)
+		assert.False(t, locked)
+		wait, _ := guard.CheckLogin("BOB@example.com ", "10.0.0.1", now)
+		assert.Equal(t, time.Second, wait, "emails are matched case-insensitively")
+
+		guard.LoginFailed("bob@example.com", "10.0.0.1", now)
+		wait, _ = guard.CheckLogin("bob@example.com", "10.0.0.1", now)
+		assert.Equal(t, 2*time.Second, wait)
+		wait, _ = guard.CheckLogin("alice@example.com", "10.0.0.1", now)
+		assert.Zero(t, wait, "other accounts are not slowed down")
+
+		locked, err = guard.LoginFailed("bob@example.com", "10.0.0.1", now)
+		assert.NoError(t, err)
+		assert.True(t, locked)
+		wait, _ = guard.CheckLogin("bob@example.com", "10.0.0.1", now.Add(time.Minute))
+		assert.Equal(t, 14*time.Minute, wait)
+		wait, _ = guard.CheckLogin("bob@example.com", "10.0.0.1", now.Add(15*time.Minute))
+		assert.Zero(t, wait, "the lock expires")
+
+		assert.Eventually(t, func() bool { return len(mail.Sent()) == 1 }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, "bob@example.com", mail.Sent()[0].To)
+	})
+
+	t.Run("should not email for unknown accounts", func(t *testing.T) {
+		guard, mail := newGuard()
+		for i := 0; i < 3; i++ {
+			guard.LoginFailed("nobody@example.com", "", now)
+		}
+		wait, _ := guard.CheckLogin("nobody@example.com", "", now)
+		assert.Equal(t, 15*time.Minute, wait, "unknown accounts lock like known ones")
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, mail.Sent())
+	})
+
+	t.Run("should forget failures after a login or an unlock", func(t *testing.T) {
+		guard, _ := newGuard()
+		for i := 0; i < 3; i++ {
+			guard.LoginFailed("bob@example.com", "", now)
+		}
+		assert.NoError(t, guard.Unlock("bob@example.com"))
+		wait, _ := guard.CheckLogin("bob@example.com", "", now)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("should lock an address guessing many accounts", func(t *testing.T) {
+		guard, _ := newGuard()
+		for i, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+			wait, _ := guard.CheckLogin("f@example.com", "10.0.0.2", now)
+			assert.Zero(t, wait, "failure %d does not slow down the address", i)
+			guard.LoginFailed(email, "10.0.0.2", now)
+		}
+		wait, _ := guard.CheckLogin("f@example.com", "10.0.0.2", now)
+		assert.Equal(t, 15*time.Minute, wait)
+		wait, _ = guard.CheckLogin("f@example.com", "10.0.0.3", now)
+		assert.Zero(t, wait)
+	})
+}
+
+func TestBackoffDelay(t *testing.T) {
+	previous := config.Envs
+	defer func() { config.Envs = previous }()
+	config.Envs.LoginLockoutDuration = 60
+	config.Envs.LoginBackoffBase = 1
+
+	assert.Equal(t, time.Second, backoffDelay(1))
+	assert.Equal(t, 8*time.Second, backoffDelay(4))
+	assert.Equal(t, time.Minute, backoffDelay(7), "the delay is capped at the lockout duration")
+	assert.Equal(t, time.Minute, backoffDelay(100))
+}
+
+// mockLoginFailureStore counts failures like the SQL store does.
+type mockLoginFailureStore struct {
+	failures map[string]*types.LoginFailures
+}
+
+func (m *mockLoginFailureStore) GetLoginFailures(scope types.LoginFailureScope, subject string) (*types.LoginFailures, error) {
+	f, ok := m.failures[string(scope)+"|"+subject]
+	if !ok {
+		return nil, nil
+	}
+	copied := *f
+	return &copied, nil
+}
+
+func (m *mockLoginFailureStore) RecordLoginFailure(scope types.LoginFailureScope, subject string, now time.Time, window time.Duration) (*types.LoginFailures, error) {
+	key := string(scope) + "|" + subject
+	f, ok := m.failures[key]
+	switch {
+	case !ok:
+		f = &types.LoginFailures{Scope: scope, Subject: subject}
+		m.failures[key] = f
+		fallthrough
+	case !f.LastFailedAt.After(now.Add(-window)):
+		f.Failures = 1
+		f.LockedUntil = nil
+	default:
+		f.Failures++
+	}
+	f.LastFailedAt = now
+	return m.GetLoginFailures(scope, subject)
+}
+
+func (m *mockLoginFailureStore) LockLogin(scope types.LoginFailureScope, subject string, until time.Time) error {
+	f, ok := m.failures[string(scope)+"|"+subject]
+	if !ok {
+		return errors.New("no failures recorded")
+	}
+	f.LockedUntil = &until
+	return nil
+}
+
+func (m *mockLoginFailureStore) ClearLoginFailures(scope types.LoginFailureScope, subject string) error {
+	delete(m.failures, string(scope)+"|"+subject)
+	return nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	users map[int]*types.User
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return u, nil
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
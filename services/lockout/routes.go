@@ -0,0 +1,69 @@
+package lockout
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	guard     *Guard
+	userStore types.UserStore
+	sessions  types.SessionStore
+}
+
+func NewHandler(guard *Guard, userStore types.UserStore, sessions types.SessionStore) *Handler {
+	return &Handler{guard: guard, userStore: userStore, sessions: sessions}
+}
+
+// HandleUnlockUser   unlock-user
+//
+// @Summary     Unlock User
+// @Description Lift the login lock of a user and forget their failed logins. Locks of IP addresses expire on their own.
+// @Tags        User
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "User ID"
+// @Success     200 {object} nil
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     404 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /users/{id}/unlock [post]
+func (h *Handler) handleUnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid user ID"))
+		return
+	}
+
+	u, err := h.userStore.GetUserByID(userID)
+	if err != nil || u == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user not found"))
+		return
+	}
+
+	if err := h.guard.Unlock(u.Email); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.sessions.RecordSecurityEvent(types.SecurityEvent{
+		UserID:    u.ID,
+		Type:      types.SecurityEventAccountUnlocked,
+		IP:        utils.GetClientIP(r),
+		UserAgent: r.UserAgent(),
+		Message:   fmt.Sprintf("unlocked by user %d", auth.GetUserIDFromContext(r.Context())),
+	})
+	if err != nil {
+		log.Printf("failed to record security event: %v", err)
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
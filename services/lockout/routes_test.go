@@ -0,0 +1,54 @@
+package lockout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/services/mailer"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestUnlockUser(t *testing.T) {
+	store := &mockLoginFailureStore{failures: make(map[string]*types.LoginFailures)}
+	userStore := &mockUserStore{users: map[int]*types.User{1: {ID: 1, Email: "bob@example.com"}}}
+	sessions := &mockSessionStore{}
+	handler := NewHandler(NewGuard(store, userStore, mailer.NewMemory()), userStore, sessions)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}/unlock", handler.handleUnlockUser).Methods(http.MethodPost)
+	unlock := func(id string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/users/"+id+"/unlock", nil)
+		assert.NoError(t, err)
+		req = req.WithContext(context.WithValue(req.Context(), types.UserKey, 2))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	now := time.Now()
+	store.RecordLoginFailure(types.LoginFailureScopeAccount, "bob@example.com", now, time.Hour)
+	store.LockLogin(types.LoginFailureScopeAccount, "bob@example.com", now.Add(time.Hour))
+
+	assert.Equal(t, http.StatusNotFound, unlock("9").Code)
+	assert.Equal(t, http.StatusOK, unlock("1").Code)
+	f, _ := store.GetLoginFailures(types.LoginFailureScopeAccount, "bob@example.com")
+	assert.Nil(t, f)
+	if assert.Len(t, sessions.events, 1) {
+		assert.Equal(t, types.SecurityEventAccountUnlocked, sessions.events[0].Type)
+	}
+}
+
+type mockSessionStore struct {
+	types.SessionStore
+	events []types.SecurityEvent
+}
+
+func (m *mockSessionStore) RecordSecurityEvent(e types.SecurityEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
@@ -0,0 +1,64 @@
+package lockout
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetLoginFailures returns the failed logins of an account or IP, or nil
+// when there are none.
+func (s *Store) GetLoginFailures(scope types.LoginFailureScope, subject string) (*types.LoginFailures, error) {
+	f := new(types.LoginFailures)
+	err := s.db.QueryRow(
+		"SELECT scope, subject, failures, last_failed_at, locked_until FROM login_failures WHERE scope = ? AND subject = ?",
+		scope, subject,
+	).Scan(&f.Scope, &f.Subject, &f.Failures, &f.LastFailedAt, &f.LockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// RecordLoginFailure counts a failed login in a single statement, so that
+// concurrent attempts are all counted. When the last failure is older than
+// window the count starts over and an expired lock is lifted.
+func (s *Store) RecordLoginFailure(scope types.LoginFailureScope, subject string, now time.Time, window time.Duration) (*types.LoginFailures, error) {
+	since := now.Add(-window).UTC()
+	// the assignments read last_failed_at before it is updated
+	_, err := s.db.Exec(
+		`INSERT INTO login_failures (scope, subject, failures, last_failed_at) VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(last_failed_at > ?, failures + 1, 1),
+			locked_until = IF(last_failed_at > ?, locked_until, NULL),
+			last_failed_at = VALUES(last_failed_at)`,
+		scope, subject, now.UTC(), since, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetLoginFailures(scope, subject)
+}
+
+func (s *Store) LockLogin(scope types.LoginFailureScope, subject string, until time.Time) error {
+	_, err := s.db.Exec("UPDATE login_failures SET locked_until = ? WHERE scope = ? AND subject = ?", until.UTC(), scope, subject)
+	return err
+}
+
+func (s *Store) ClearLoginFailures(scope types.LoginFailureScope, subject string) error {
+	_, err := s.db.Exec("DELETE FROM login_failures WHERE scope = ? AND subject = ?", scope, subject)
+	return err
+}
@@ -0,0 +1,39 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-15 * time.Minute)
+
+	mock.ExpectExec("INSERT INTO login_failures \\(scope, subject, failures, last_failed_at\\) VALUES \\(\\?, \\?, 1, \\?\\) ON DUPLICATE KEY UPDATE").
+		WithArgs(types.LoginFailureScopeAccount, "bob@example.com", now, since, since).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT scope, subject, failures, last_failed_at, locked_until FROM login_failures WHERE scope = \\? AND subject = \\?").
+		WithArgs(types.LoginFailureScopeAccount, "bob@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"scope", "subject", "failures", "last_failed_at", "locked_until"}).
+			AddRow("account", "bob@example.com", 3, now, nil))
+
+	f, err := store.RecordLoginFailure(types.LoginFailureScopeAccount, "bob@example.com", now, 15*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, f.Failures)
+	assert.Nil(t, f.LockedUntil)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// File writes every email to its own .eml file in a directory instead of
+// sending it, for local development.
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir string, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (f *File) Send(e types.Email) error {
+	if err := os.MkdirAll(f.dir, 0o700); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := now.UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(f.dir, name), buildMessage(f.from, e, now), 0o600)
+}
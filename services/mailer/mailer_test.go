@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg := string(buildMessage("gotask@example.com", types.Email{
+		To:      "bob@example.com",
+		Subject: "Hello\r\nBcc: eve@example.com",
+		Body:    "line one\nline two",
+	}, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)))
+
+	assert.Contains(t, msg, "To: bob@example.com\r\n")
+	assert.Contains(t, msg, "Subject: HelloBcc: eve@example.com\r\n", "line breaks cannot inject headers")
+	assert.True(t, strings.HasSuffix(msg, "\r\n\r\nline one\r\nline two"))
+}
+
+func TestSMTP(t *testing.T) {
+	m := NewSMTP("smtp.example.com", 587, "user", "secret", "gotask@example.com")
+	var gotAddr string
+	var gotTo []string
+	m.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo = addr, to
+		assert.NotNil(t, a)
+		assert.Equal(t, "gotask@example.com", from)
+		return nil
+	}
+
+	assert.NoError(t, m.Send(types.Email{To: "bob@example.com", Subject: "Hi", Body: "Hi"}))
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.Equal(t, []string{"bob@example.com"}, gotTo)
+}
+
+func TestFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFile(dir, "gotask@example.com")
+
+	assert.NoError(t, m.Send(types.Email{To: "bob@example.com", Subject: "Hi", Body: "first"}))
+	assert.NoError(t, m.Send(types.Email{To: "bob@example.com", Subject: "Hi", Body: "second"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	if assert.Len(t, files, 2) {
+		data, err := os.ReadFile(files[0])
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "To: bob@example.com")
+	}
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"sync"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// Memory keeps sent emails in the process, for tests.
+type Memory struct {
+	mu   sync.Mutex
+	sent []types.Email
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(e types.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, e)
+	return nil
+}
+
+// Sent returns the emails sent so far.
+func (m *Memory) Sent() []types.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]types.Email(nil), m.sent...)
+}
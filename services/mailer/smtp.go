@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// SMTP sends emails through an SMTP server. The connection is upgraded with
+// STARTTLS when the server offers it.
+type SMTP struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP returns a mailer for the server at host:port. Without a username
+// emails are sent unauthenticated.
+func NewSMTP(host string, port int64, username string, password string, from string) *SMTP {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTP{
+		addr:     net.JoinHostPort(host, fmt.Sprint(port)),
+		auth:     auth,
+		from:     from,
+		sendMail: smtp.SendMail,
+	}
+}
+
+func (s *SMTP) Send(e types.Email) error {
+	return s.sendMail(s.addr, s.auth, s.from, []string{e.To}, buildMessage(s.from, e, time.Now()))
+}
+
+// buildMessage formats an email as a plain text RFC 5322 message. Line
+// breaks are stripped from header values so they cannot inject headers.
+func buildMessage(from string, e types.Email, now time.Time) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(e.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(e.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(e.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,17 @@
+package mfa
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/mfa", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleGetMFA), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/mfa/enroll", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleEnrollMFA), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/mfa/confirm", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleConfirmMFA), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/mfa/recovery-codes", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleRegenerateRecoveryCodes), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/mfa/disable", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleDisableMFA), h.userStore)).Methods(http.MethodPost)
+}
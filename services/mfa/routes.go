@@ -0,0 +1,277 @@
+package mfa
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.MFAStore
+	userStore types.UserStore
+	guard     types.LoginGuard
+}
+
+func NewHandler(store types.MFAStore, userStore types.UserStore, guard types.LoginGuard) *Handler {
+	return &Handler{store: store, userStore: userStore, guard: guard}
+}
+
+// HandleGetMFA   get-mfa
+//
+// @Summary     Get Two-Factor Authentication
+// @Description Get whether two-factor authentication is enabled for the authenticated user and how many recovery codes are left
+// @Tags        MFA
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {object} types.MFA
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /mfa [get]
+func (h *Handler) handleGetMFA(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	m, err := h.store.GetMFA(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if m == nil {
+		m = &types.MFA{UserID: userID}
+	}
+	utils.WriteJSON(w, http.StatusOK, m)
+}
+
+// HandleEnrollMFA   enroll-mfa
+//
+// @Summary     Enroll in Two-Factor Authentication
+// @Description Create a TOTP secret for an authenticator app. Two-factor authentication is only enabled once a code is confirmed with POST /mfa/confirm; enrolling again before that replaces the secret.
+// @Tags        MFA
+// @Produce     json
+// @Security    jwtKey
+// @Success     201 {object} types.MFAEnrollment
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /mfa/enroll [post]
+func (h *Handler) handleEnrollMFA(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	m, err := h.store.GetMFA(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if m != nil && m.Enabled {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("two-factor authentication is already enabled, disable it first"))
+		return
+	}
+
+	u, err := h.userStore.GetUserByID(userID)
+	if err != nil || u == nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to get user: %v", err))
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.store.SaveMFASecret(userID, secret); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, types.MFAEnrollment{Secret: secret, URI: auth.TOTPURI(secret, u.Email)})
+}
+
+// HandleConfirmMFA   confirm-mfa
+//
+// @Summary     Confirm Two-Factor Authentication
+// @Description Enable two-factor authentication with the first code from the authenticator app. Returns one-time recovery codes, which are not shown again.
+// @Tags        MFA
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       MFACodePayload body     types.MFACodePayload true "Code from the authenticator app"
+// @Success     200            {object} types.MFARecoveryCodes
+// @Failure     400            {object} types.ErrorResponse
+// @Failure     401            {object} types.ErrorResponse
+// @Failure     403            {object} types.ErrorResponse
+// @Failure     500            {object} types.ErrorResponse
+// @Router      /mfa/confirm [post]
+func (h *Handler) handleConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	payload, ok := parseCode(w, r)
+	if !ok {
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	m, err := h.store.GetMFA(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if m == nil || m.Enabled {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("no pending enrollment, enroll first"))
+		return
+	}
+
+	step, valid := auth.ValidateTOTP(m.Secret, payload.Code, time.Now())
+	if !valid {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid code"))
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.store.EnableMFA(userID, step, hashes); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, types.MFARecoveryCodes{RecoveryCodes: codes})
+}
+
+// HandleRegenerateRecoveryCodes   regenerate-recovery-codes
+//
+// @Summary     Regenerate Recovery Codes
+// @Description Replace all recovery codes with new ones. Requires a code from the authenticator app or a recovery code. Wrong codes count as failed logins and lock the account like them.
+// @Tags        MFA
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       MFACodePayload body     types.MFACodePayload true "Code from the authenticator app or a recovery code"
+// @Success     200            {object} types.MFARecoveryCodes
+// @Failure     400            {object} types.ErrorResponse
+// @Failure     401            {object} types.ErrorResponse
+// @Failure     403            {object} types.ErrorResponse
+// @Failure     429            {object} types.ErrorResponse
+// @Failure     500            {object} types.ErrorResponse
+// @Router      /mfa/recovery-codes [post]
+func (h *Handler) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.verifyCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.store.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, types.MFARecoveryCodes{RecoveryCodes: codes})
+}
+
+// HandleDisableMFA   disable-mfa
+//
+// @Summary     Disable Two-Factor Authentication
+// @Description Disable two-factor authentication and delete the recovery codes. Requires a code from the authenticator app or a recovery code. Wrong codes count as failed logins and lock the account like them.
+// @Tags        MFA
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       MFACodePayload body     types.MFACodePayload true "Code from the authenticator app or a recovery code"
+// @Success     200            {object} nil
+// @Failure     400            {object} types.ErrorResponse
+// @Failure     401            {object} types.ErrorResponse
+// @Failure     403            {object} types.ErrorResponse
+// @Failure     429            {object} types.ErrorResponse
+// @Failure     500            {object} types.ErrorResponse
+// @Router      /mfa/disable [post]
+func (h *Handler) handleDisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.verifyCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DisableMFA(userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// verifyCode checks the code in the request against the enabled two-factor
+// authentication of the user and writes an error when it does not match.
+// Wrong codes count as failed logins of the user, so a stolen session cannot
+// be used to guess codes either.
+func (h *Handler) verifyCode(w http.ResponseWriter, r *http.Request) (int, bool) {
+	payload, ok := parseCode(w, r)
+	if !ok {
+		return 0, false
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	u, err := h.userStore.GetUserByID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return 0, false
+	}
+
+	now := time.Now()
+	wait, err := h.guard.CheckLogin(u.Email, utils.GetClientIP(r), now)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return 0, false
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("too many failed attempts, try again later"))
+		return 0, false
+	}
+
+	m, err := h.store.GetMFA(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return 0, false
+	}
+	if m == nil || !m.Enabled {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("two-factor authentication is not enabled"))
+		return 0, false
+	}
+
+	valid, _, err := Verify(h.store, m, payload.Code, now)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return 0, false
+	}
+	if !valid {
+		if _, err := h.guard.LoginFailed(u.Email, utils.GetClientIP(r), now); err != nil {
+			log.Printf("failed to record failed code of user %d: %v", userID, err)
+		}
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid code"))
+		return 0, false
+	}
+	if err := h.guard.LoginSucceeded(u.Email); err != nil {
+		log.Printf("failed to reset failed logins of user %d: %v", userID, err)
+	}
+	return userID, true
+}
+
+func parseCode(w http.ResponseWriter, r *http.Request) (types.MFACodePayload, bool) {
+	var payload types.MFACodePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return payload, false
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return payload, false
+	}
+	return payload, true
+}
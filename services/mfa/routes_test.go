@@ -0,0 +1,162 @@
+package mfa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestMFAEnrollment(t *testing.T) {
+	store := &mockMFAStore{recoveryCodes: make(map[string]bool)}
+	guard := &mockLoginGuard{}
+	handler := NewHandler(store, &mockUserStore{}, guard)
+
+	serve := func(handlerFunc http.HandlerFunc, payload interface{}) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		req = req.WithContext(context.WithValue(req.Context(), types.UserKey, 1))
+		rr := httptest.NewRecorder()
+		handlerFunc(rr, req)
+		return rr
+	}
+
+	rr := serve(handler.handleEnrollMFA, nil)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var enrollment types.MFAEnrollment
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &enrollment))
+	assert.Equal(t, enrollment.Secret, store.mfa.Secret)
+	assert.Contains(t, enrollment.URI, "bob@example.com")
+
+	t.Run("should not enable without a valid code", func(t *testing.T) {
+		rr := serve(handler.handleConfirmMFA, types.MFACodePayload{Code: "12345"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.False(t, store.mfa.Enabled)
+	})
+
+	var recoveryCodes types.MFARecoveryCodes
+	t.Run("should enable with a valid code and return recovery codes", func(t *testing.T) {
+		code, err := auth.TOTPCode(enrollment.Secret, auth.TOTPStep(time.Now()))
+		assert.NoError(t, err)
+
+		rr := serve(handler.handleConfirmMFA, types.MFACodePayload{Code: code})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &recoveryCodes))
+		assert.Len(t, recoveryCodes.RecoveryCodes, auth.RecoveryCodeCount)
+		assert.True(t, store.mfa.Enabled)
+		assert.True(t, store.recoveryCodes[auth.HashRecoveryCode(recoveryCodes.RecoveryCodes[0])], "only hashes are stored")
+
+		assert.Equal(t, http.StatusBadRequest, serve(handler.handleEnrollMFA, nil).Code, "cannot enroll twice")
+	})
+
+	t.Run("should count wrong codes as failed logins", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(handler.handleRegenerateRecoveryCodes, types.MFACodePayload{Code: "aaaaa-bbbbb"}).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(handler.handleDisableMFA, types.MFACodePayload{Code: "aaaaa-bbbbb"}).Code)
+		assert.Equal(t, []string{"bob@example.com", "bob@example.com"}, guard.failures)
+	})
+
+	t.Run("should refuse codes while locked, even valid ones", func(t *testing.T) {
+		guard.wait = 90 * time.Second
+		defer func() { guard.wait = 0 }()
+		rr := serve(handler.handleDisableMFA, types.MFACodePayload{Code: recoveryCodes.RecoveryCodes[0]})
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+		assert.True(t, store.mfa.Enabled)
+	})
+
+	t.Run("should disable with a recovery code", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(handler.handleDisableMFA, types.MFACodePayload{Code: recoveryCodes.RecoveryCodes[0]}).Code)
+		assert.Nil(t, store.mfa)
+		assert.Equal(t, []string{"bob@example.com"}, guard.succeeded)
+	})
+}
+
+type mockUserStore struct {
+	types.UserStore
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	return &types.User{ID: id, Email: "bob@example.com"}, nil
+}
+
+type mockLoginGuard struct {
+	wait      time.Duration
+	failures  []string
+	succeeded []string
+}
+
+func (m *mockLoginGuard) CheckLogin(email string, ip string, now time.Time) (time.Duration, error) {
+	return m.wait, nil
+}
+
+func (m *mockLoginGuard) LoginFailed(email string, ip string, now time.Time) (bool, error) {
+	m.failures = append(m.failures, email)
+	return false, nil
+}
+
+func (m *mockLoginGuard) LoginSucceeded(email string) error {
+	m.succeeded = append(m.succeeded, email)
+	return nil
+}
+
+type mockMFAStore struct {
+	mfa           *types.MFA
+	recoveryCodes map[string]bool
+}
+
+func (m *mockMFAStore) GetMFA(userID int) (*types.MFA, error) {
+	if m.mfa == nil {
+		return nil, nil
+	}
+	mfa := *m.mfa
+	return &mfa, nil
+}
+
+func (m *mockMFAStore) SaveMFASecret(userID int, secret string) error {
+	m.mfa = &types.MFA{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (m *mockMFAStore) EnableMFA(userID int, step int64, recoveryCodeHashes []string) error {
+	m.mfa.Enabled = true
+	m.mfa.LastUsedStep = step
+	return m.ReplaceRecoveryCodes(userID, recoveryCodeHashes)
+}
+
+func (m *mockMFAStore) DisableMFA(userID int) error {
+	m.mfa = nil
+	m.recoveryCodes = make(map[string]bool)
+	return nil
+}
+
+func (m *mockMFAStore) UseTOTPStep(userID int, step int64) (bool, error) {
+	if step <= m.mfa.LastUsedStep {
+		return false, nil
+	}
+	m.mfa.LastUsedStep = step
+	return true, nil
+}
+
+func (m *mockMFAStore) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	if !m.recoveryCodes[codeHash] {
+		return false, nil
+	}
+	delete(m.recoveryCodes, codeHash)
+	return true, nil
+}
+
+func (m *mockMFAStore) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	m.recoveryCodes = make(map[string]bool)
+	for _, hash := range codeHashes {
+		m.recoveryCodes[hash] = true
+	}
+	return nil
+}
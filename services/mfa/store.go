@@ -0,0 +1,163 @@
+package mfa
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetMFA returns the two-factor authentication of a user, or nil when the
+// user never started enrolling.
+func (s *Store) GetMFA(userID int) (*types.MFA, error) {
+	rows, err := s.db.Query(
+		"SELECT m.user_id, m.secret, m.enabled_at, m.last_used_step, (SELECT COUNT(*) FROM mfa_recovery_codes c WHERE c.user_id = m.user_id AND c.used_at IS NULL) FROM user_mfa m WHERE m.user_id = ?",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	m := new(types.MFA)
+	if err := rows.Scan(&m.UserID, &m.Secret, &m.EnabledAt, &m.LastUsedStep, &m.RecoveryCodesLeft); err != nil {
+		return nil, err
+	}
+	m.Enabled = m.EnabledAt != nil
+	return m, nil
+}
+
+// SaveMFASecret starts enrolling with a new secret, replacing the secret of
+// an enrollment that was never confirmed.
+func (s *Store) SaveMFASecret(userID int, secret string) error {
+	_, err := s.db.Exec(
+		"INSERT INTO user_mfa (user_id, secret) VALUES (?, ?) ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled_at = NULL, last_used_step = 0",
+		userID, secret,
+	)
+	return err
+}
+
+// EnableMFA confirms an enrollment with the step of the first code and stores
+// the recovery codes.
+func (s *Store) EnableMFA(userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"UPDATE user_mfa SET enabled_at = ?, last_used_step = ? WHERE user_id = ? AND enabled_at IS NULL",
+		time.Now().UTC(), step, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no pending enrollment found")
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DisableMFA removes the secret and the recovery codes of a user.
+func (s *Store) DisableMFA(userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_mfa WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records that a code of the step was used. It reports false
+// when the step or a later one was used before, which stops a code from
+// being replayed.
+func (s *Store) UseTOTPStep(userID int, step int64) (bool, error) {
+	res, err := s.db.Exec(
+		"UPDATE user_mfa SET last_used_step = ? WHERE user_id = ? AND enabled_at IS NOT NULL AND last_used_step < ?",
+		step, userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// UseRecoveryCode marks a recovery code as used. It reports false when the
+// code does not exist or was already used.
+func (s *Store) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	res, err := s.db.Exec(
+		"UPDATE mfa_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now().UTC(), userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user, used or not.
+func (s *Store) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(codeHashes))
+	args := make([]interface{}, 0, 2*len(codeHashes))
+	for i, hash := range codeHashes {
+		placeholders[i] = "(?, ?)"
+		args = append(args, userID, hash)
+	}
+	_, err := tx.Exec("INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES "+strings.Join(placeholders, ", "), args...)
+	return err
+}
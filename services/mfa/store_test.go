@@ -0,0 +1,91 @@
+package mfa
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMFA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	query := "SELECT m.user_id, m.secret, m.enabled_at, m.last_used_step, \\(SELECT COUNT\\(\\*\\) FROM mfa_recovery_codes c WHERE c.user_id = m.user_id AND c.used_at IS NULL\\) FROM user_mfa m WHERE m.user_id = \\?"
+	columns := []string{"user_id", "secret", "enabled_at", "last_used_step", "recovery_codes_left"}
+
+	mock.ExpectQuery(query).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "SECRET", "2026-10-19 12:00:00", 42, 9))
+	mock.ExpectQuery(query).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	m, err := store.GetMFA(1)
+	assert.NoError(t, err)
+	assert.True(t, m.Enabled)
+	assert.Equal(t, int64(42), m.LastUsedStep)
+	assert.Equal(t, 9, m.RecoveryCodesLeft)
+
+	m, err = store.GetMFA(2)
+	assert.NoError(t, err)
+	assert.Nil(t, m, "users that never enrolled have no MFA")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEnableMFA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE user_mfa SET enabled_at = \\?, last_used_step = \\? WHERE user_id = \\? AND enabled_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), 42, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM mfa_recovery_codes WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO mfa_recovery_codes \\(user_id, code_hash\\) VALUES \\(\\?, \\?\\), \\(\\?, \\?\\)").
+		WithArgs(1, "hash1", 1, "hash2").
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.EnableMFA(1, 42, []string{"hash1", "hash2"}))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	query := "UPDATE user_mfa SET last_used_step = \\? WHERE user_id = \\? AND enabled_at IS NOT NULL AND last_used_step < \\?"
+	mock.ExpectExec(query).WithArgs(43, 1, 43).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(43, 1, 43).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	used, err := store.UseTOTPStep(1, 43)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = store.UseTOTPStep(1, 43)
+	assert.NoError(t, err)
+	assert.False(t, used, "a step cannot be used twice")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,45 @@
+package mfa
+
+import (
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+)
+
+// Verify checks a code of an enabled two-factor authentication and uses it
+// up. The code is either the current code of the authenticator app, which is
+// accepted once, or one of the recovery codes, which usedRecoveryCode
+// reports.
+func Verify(store types.MFAStore, m *types.MFA, code string, now time.Time) (ok bool, usedRecoveryCode bool, err error) {
+	if m == nil || !m.Enabled {
+		return false, false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if auth.IsTOTPCode(code) {
+		step, valid := auth.ValidateTOTP(m.Secret, code, now)
+		if !valid {
+			return false, false, nil
+		}
+		ok, err := store.UseTOTPStep(m.UserID, step)
+		return ok, false, err
+	}
+
+	ok, err = store.UseRecoveryCode(m.UserID, auth.HashRecoveryCode(code))
+	return ok, ok, err
+}
+
+// newRecoveryCodes returns fresh recovery codes and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,20 @@
+package milestone
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/milestone", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetMilestones)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/milestone", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCreateMilestone)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/milestone/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetMilestone)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/milestone/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleDeleteMilestone)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/milestone/{id}/tasks", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetMilestoneTasks)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/milestone/{id}/tasks", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleAddMilestoneTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/milestone/{id}/tasks/{taskID}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleRemoveMilestoneTask)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/milestone/{id}/schedule", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetSchedule)), h.userStore)).Methods(http.MethodGet)
+}
@@ -0,0 +1,329 @@
+package milestone
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.MilestoneStore
+	taskStore types.TaskStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.MilestoneStore, taskStore types.TaskStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, taskStore: taskStore, userStore: userStore}
+}
+
+// HandleGetMilestones   get-milestones
+//
+// @Summary     Get Milestones
+// @Description Get all milestones ordered by target date
+// @Tags        Milestone
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.Milestone
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /milestone [get]
+func (h *Handler) handleGetMilestones(w http.ResponseWriter, r *http.Request) {
+	milestones, err := h.store.GetMilestones()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, milestones)
+}
+
+// HandleGetMilestone   get-milestone
+//
+// @Summary     Get Milestone by ID
+// @Description Get Milestone by ID
+// @Tags        Milestone
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Milestone ID"
+// @Success     200 {object} types.Milestone
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Router      /milestone/{id} [get]
+func (h *Handler) handleGetMilestone(w http.ResponseWriter, r *http.Request) {
+	milestoneID, err := milestoneIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	milestone, err := h.store.GetMilestoneByID(milestoneID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, milestone)
+}
+
+// HandleCreateMilestone   create-milestone
+//
+// @Summary     Create Milestone
+// @Description Create a milestone, the target date uses the YYYY-MM-DD format
+// @Tags        Milestone
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       CreateMilestonePayload body     types.CreateMilestonePayload true "create milestone"
+// @Success     201                    {object} types.Milestone
+// @Failure     400                    {object} types.ErrorResponse
+// @Failure     401                    {object} types.ErrorResponse
+// @Failure     403                    {object} types.ErrorResponse
+// @Failure     500                    {object} types.ErrorResponse
+// @Router      /milestone [post]
+func (h *Handler) handleCreateMilestone(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateMilestonePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if _, err := time.Parse(types.DateLayout, payload.TargetDate); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid target_date, expected YYYY-MM-DD"))
+		return
+	}
+
+	milestone := types.Milestone{Name: payload.Name, TargetDate: payload.TargetDate}
+	id, err := h.store.CreateMilestone(milestone)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	milestone.ID = id
+	utils.WriteJSON(w, http.StatusCreated, milestone)
+}
+
+// HandleDeleteMilestone   delete-milestone
+//
+// @Summary     Delete Milestone
+// @Description Delete a milestone, its tasks are kept without a milestone
+// @Tags        Milestone
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Milestone ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Router      /milestone/{id} [delete]
+func (h *Handler) handleDeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	milestoneID, err := milestoneIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.DeleteMilestone(milestoneID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Milestone deleted successfully"})
+}
+
+// HandleGetMilestoneTasks   get-milestone-tasks
+//
+// @Summary     Get Milestone Tasks
+// @Description Get the tasks belonging to a milestone
+// @Tags        Milestone
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Milestone ID"
+// @Success     200 {array}  types.Task
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /milestone/{id}/tasks [get]
+func (h *Handler) handleGetMilestoneTasks(w http.ResponseWriter, r *http.Request) {
+	milestoneID, err := milestoneIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tasks, err := h.taskStore.GetTasksByMilestone(milestoneID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+// HandleAddMilestoneTask   add-milestone-task
+//
+// @Summary     Add Task to Milestone
+// @Description Add a task to a milestone, moving it out of any other milestone
+// @Tags        Milestone
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                   path     int                        true "Milestone ID"
+// @Param       MilestoneTaskPayload body     types.MilestoneTaskPayload true "Task to add"
+// @Success     200                  {object} string
+// @Failure     400                  {object} types.ErrorResponse
+// @Failure     401                  {object} types.ErrorResponse
+// @Failure     403                  {object} types.ErrorResponse
+// @Failure     500                  {object} types.ErrorResponse
+// @Router      /milestone/{id}/tasks [post]
+func (h *Handler) handleAddMilestoneTask(w http.ResponseWriter, r *http.Request) {
+	milestoneID, err := milestoneIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.MilestoneTaskPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if _, err := h.store.GetMilestoneByID(milestoneID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := h.taskStore.GetTaskByID(payload.TaskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.taskStore.SetTaskMilestone(payload.TaskID, &milestoneID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d added to milestone %d", payload.TaskID, milestoneID)})
+}
+
+// HandleRemoveMilestoneTask   remove-milestone-task
+//
+// @Summary     Remove Task from Milestone
+// @Description Take a task out of a milestone
+// @Tags        Milestone
+// @Produce     json
+// @Security    jwtKey
+// @Param       id     path     int true "Milestone ID"
+// @Param       taskID path     int true "Task ID"
+// @Success     200    {object} string
+// @Failure     400    {object} types.ErrorResponse
+// @Failure     401    {object} types.ErrorResponse
+// @Failure     403    {object} types.ErrorResponse
+// @Failure     500    {object} types.ErrorResponse
+// @Router      /milestone/{id}/tasks/{taskID} [delete]
+func (h *Handler) handleRemoveMilestoneTask(w http.ResponseWriter, r *http.Request) {
+	milestoneID, err := milestoneIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	taskID, err := strconv.Atoi(mux.Vars(r)["taskID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid task ID"))
+		return
+	}
+
+	task, err := h.taskStore.GetTaskByID(taskID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if task.MilestoneID == nil || *task.MilestoneID != milestoneID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("task %d is not part of milestone %d", taskID, milestoneID))
+		return
+	}
+
+	if err := h.taskStore.SetTaskMilestone(taskID, nil); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d removed from milestone %d", taskID, milestoneID)})
+}
+
+// HandleGetSchedule   get-milestone-schedule
+//
+// @Summary     Get Milestone Schedule
+// @Description Schedule the tasks of a milestone from their estimates, start dates and blocks/blocked_by links. Returns earliest and latest start and finish dates, slack in days, the critical path and the tasks that put the target date at risk.
+// @Tags        Milestone
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Milestone ID"
+// @Success     200 {object} types.MilestoneSchedule
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     409 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /milestone/{id}/schedule [get]
+func (h *Handler) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	milestoneID, err := milestoneIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	milestone, err := h.store.GetMilestoneByID(milestoneID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tasks, err := h.taskStore.GetTasksByMilestone(milestoneID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	dependsOn := make(map[int][]int)
+	for _, t := range tasks {
+		links, err := h.taskStore.GetTaskLinks(t.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, l := range links {
+			if l.Type == types.LinkBlockedBy {
+				dependsOn[t.ID] = append(dependsOn[t.ID], l.LinkedTaskID)
+			}
+		}
+	}
+
+	schedule, err := ComputeSchedule(*milestone, tasks, dependsOn, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, schedule)
+}
+
+func milestoneIDFromRequest(r *http.Request) (int, error) {
+	milestoneID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, fmt.Errorf("invalid milestone ID")
+	}
+	return milestoneID, nil
+}
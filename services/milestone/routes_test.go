@@ -0,0 +1,181 @@
+package milestone
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestMilestone(t *testing.T) {
+	estimate := 2
+	taskStore := &mockTaskStore{
+		tasks: []types.Task{
+			{ID: 1, Status: types.StatusPending, EstimateDays: &estimate},
+			{ID: 2, Status: types.StatusPending, EstimateDays: &estimate},
+		},
+		links: map[int][]types.TaskLink{
+			1: {{TaskID: 1, LinkedTaskID: 2, Type: types.LinkBlocks}},
+			2: {{TaskID: 2, LinkedTaskID: 1, Type: types.LinkBlockedBy}},
+		},
+	}
+	handler := NewHandler(&mockMilestoneStore{}, taskStore, &mockUserStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	t.Run("should create a milestone with valid payload", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateMilestonePayload{Name: "v1.0", TargetDate: "2099-01-31"})
+		req, err := http.NewRequest("POST", "/milestone", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("should fail with an invalid target date", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateMilestonePayload{Name: "v1.0", TargetDate: "31.01.2099"})
+		req, err := http.NewRequest("POST", "/milestone", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should schedule tasks along their dependencies", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/milestone/1/schedule", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var schedule types.MilestoneSchedule
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &schedule))
+		assert.Equal(t, []int{1, 2}, schedule.CriticalPath)
+		assert.Equal(t, []int{1}, schedule.Tasks[1].DependsOn)
+	})
+
+	t.Run("should fail to remove a task that is not in the milestone", func(t *testing.T) {
+		req, err := http.NewRequest("DELETE", "/milestone/1/tasks/1", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestMilestonePermissions(t *testing.T) {
+	handler := NewHandler(&mockMilestoneStore{}, nil, &mockUserStore{roles: map[int]types.Role{2: types.RoleViewer}})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("should require a token", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/milestone", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should let viewers read but not write", func(t *testing.T) {
+		server := withAccessToken(t, router, 2)
+		req, err := http.NewRequest("GET", "/milestone", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		payloadJSON, _ := json.Marshal(types.CreateMilestonePayload{Name: "v1.0", TargetDate: "2099-01-31"})
+		req, err = http.NewRequest("POST", "/milestone", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr = httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+// withAccessToken sends requests without an Authorization header with an
+// access token of the user, as the milestone routes require one.
+func withAccessToken(t *testing.T, router *mux.Router, userID int) http.Handler {
+	token, err := auth.CreateAccessToken(userID, 0, types.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", token)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+type mockMilestoneStore struct{}
+
+func (m *mockMilestoneStore) GetMilestones() ([]types.Milestone, error) {
+	return nil, nil
+}
+
+func (m *mockMilestoneStore) GetMilestoneByID(milestoneID int) (*types.Milestone, error) {
+	return &types.Milestone{ID: milestoneID, Name: "v1.0", TargetDate: "2099-01-31"}, nil
+}
+
+func (m *mockMilestoneStore) CreateMilestone(types.Milestone) (int, error) {
+	return 1, nil
+}
+
+func (m *mockMilestoneStore) DeleteMilestone(milestoneID int) error {
+	return nil
+}
+
+// mockTaskStore implements the task store methods used by milestones; calling
+// any other method panics.
+type mockTaskStore struct {
+	types.TaskStore
+	tasks []types.Task
+	links map[int][]types.TaskLink
+}
+
+func (m *mockTaskStore) GetTaskByID(taskID int) (*types.Task, error) {
+	for _, t := range m.tasks {
+		if t.ID == taskID {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("no task found with the given ID")
+}
+
+func (m *mockTaskStore) GetTasksByMilestone(milestoneID int) ([]types.Task, error) {
+	return m.tasks, nil
+}
+
+func (m *mockTaskStore) GetTaskLinks(taskID int) ([]types.TaskLink, error) {
+	return m.links[taskID], nil
+}
+
+func (m *mockTaskStore) SetTaskMilestone(taskID int, milestoneID *int) error {
+	return nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	roles map[int]types.Role
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	role, ok := m.roles[id]
+	if !ok {
+		role = types.RoleMember
+	}
+	return &types.User{ID: id, Role: role}, nil
+}
@@ -0,0 +1,217 @@
+package milestone
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// ComputeSchedule places the tasks of a milestone on a day grid starting today
+// using the critical path method. dependsOn maps a task to the tasks that
+// block it; dependencies on tasks outside the milestone are ignored.
+//
+// Open tasks take their estimate in days, completed tasks take none and tasks
+// without an estimate are scheduled with zero days and flagged. A start date
+// on a task is the earliest day it can begin. Latest dates are computed back
+// from the target date, so slack is the number of days a task can slip
+// without delaying the milestone and negative slack means the target is
+// already out of reach. The critical path is the chain of open tasks with the
+// least slack. A task is at risk when it has negative slack or cannot finish
+// by its own due date.
+func ComputeSchedule(m types.Milestone, tasks []types.Task, dependsOn map[int][]int, now time.Time) (*types.MilestoneSchedule, error) {
+	target, err := time.ParseInLocation(types.DateLayout, m.TargetDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	// offsets are whole days from today; finishes are exclusive
+	deadline := daysBetween(today, target) + 1
+
+	n := len(tasks)
+	index := make(map[int]int, n)
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+
+	preds := make([][]int, n)
+	succs := make([][]int, n)
+	for i, t := range tasks {
+		for _, depID := range dependsOn[t.ID] {
+			j, ok := index[depID]
+			if !ok || j == i {
+				continue
+			}
+			preds[i] = append(preds[i], j)
+			succs[j] = append(succs[j], i)
+		}
+	}
+
+	order, err := topologicalOrder(tasks, preds, succs)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := make([]int, n)
+	unestimated := make([]bool, n)
+	for i, t := range tasks {
+		switch {
+		case t.Status == types.StatusCompleted:
+		case t.EstimateDays == nil:
+			unestimated[i] = true
+		default:
+			duration[i] = *t.EstimateDays
+		}
+	}
+
+	earliestStart := make([]int, n)
+	earliestFinish := make([]int, n)
+	finish := 0
+	for _, i := range order {
+		if tasks[i].Status != types.StatusCompleted {
+			start := 0
+			if tasks[i].StartDate != nil {
+				if day, err := time.ParseInLocation(types.DateLayout, *tasks[i].StartDate, time.UTC); err == nil && daysBetween(today, day) > start {
+					start = daysBetween(today, day)
+				}
+			}
+			for _, p := range preds[i] {
+				if earliestFinish[p] > start {
+					start = earliestFinish[p]
+				}
+			}
+			earliestStart[i] = start
+		}
+		earliestFinish[i] = earliestStart[i] + duration[i]
+		if earliestFinish[i] > finish {
+			finish = earliestFinish[i]
+		}
+	}
+
+	latestStart := make([]int, n)
+	latestFinish := make([]int, n)
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		latestFinish[i] = deadline
+		for _, s := range succs[i] {
+			if latestStart[s] < latestFinish[i] {
+				latestFinish[i] = latestStart[s]
+			}
+		}
+		latestStart[i] = latestFinish[i] - duration[i]
+	}
+
+	minSlack, hasOpen := 0, false
+	for i, t := range tasks {
+		if t.Status == types.StatusCompleted {
+			continue
+		}
+		if slack := latestStart[i] - earliestStart[i]; !hasOpen || slack < minSlack {
+			minSlack, hasOpen = slack, true
+		}
+	}
+
+	dateAt := func(offset int) string {
+		return today.AddDate(0, 0, offset).Format(types.DateLayout)
+	}
+	lastDay := func(start, end int) string {
+		if end-1 < start {
+			return dateAt(start)
+		}
+		return dateAt(end - 1)
+	}
+
+	schedule := &types.MilestoneSchedule{
+		MilestoneID:     m.ID,
+		TargetDate:      m.TargetDate,
+		ProjectedFinish: lastDay(0, finish),
+		AtRisk:          finish > deadline,
+		CriticalPath:    make([]int, 0),
+		Tasks:           make([]types.ScheduledTask, 0, n),
+	}
+
+	critical := make([]bool, n)
+	for _, i := range order {
+		t := tasks[i]
+		slack := latestStart[i] - earliestStart[i]
+		critical[i] = hasOpen && t.Status != types.StatusCompleted && slack == minSlack
+		if critical[i] {
+			schedule.CriticalPath = append(schedule.CriticalPath, t.ID)
+		}
+	}
+
+	for i, t := range tasks {
+		slack := latestStart[i] - earliestStart[i]
+		atRisk := t.Status != types.StatusCompleted && slack < 0
+		if t.Status != types.StatusCompleted && t.DueDate != nil {
+			if due, err := time.Parse(time.RFC3339Nano, *t.DueDate); err == nil && earliestFinish[i] > daysBetween(today, due)+1 {
+				atRisk = true
+			}
+		}
+
+		dependencies := make([]int, 0, len(preds[i]))
+		for _, p := range preds[i] {
+			dependencies = append(dependencies, tasks[p].ID)
+		}
+
+		schedule.Tasks = append(schedule.Tasks, types.ScheduledTask{
+			TaskID:         t.ID,
+			Title:          t.Title,
+			Status:         t.Status,
+			EstimateDays:   duration[i],
+			Unestimated:    unestimated[i],
+			DependsOn:      dependencies,
+			EarliestStart:  dateAt(earliestStart[i]),
+			EarliestFinish: lastDay(earliestStart[i], earliestFinish[i]),
+			LatestStart:    dateAt(latestStart[i]),
+			LatestFinish:   lastDay(latestStart[i], latestFinish[i]),
+			SlackDays:      slack,
+			Critical:       critical[i],
+			AtRisk:         atRisk,
+		})
+	}
+	return schedule, nil
+}
+
+// topologicalOrder orders the tasks so that every task comes after the tasks
+// it depends on, or fails when the dependencies contain a cycle.
+func topologicalOrder(tasks []types.Task, preds, succs [][]int) ([]int, error) {
+	remaining := make([]int, len(tasks))
+	queue := make([]int, 0, len(tasks))
+	for i := range tasks {
+		remaining[i] = len(preds[i])
+		if remaining[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+
+	order := make([]int, 0, len(tasks))
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		order = append(order, i)
+		for _, s := range succs[i] {
+			remaining[s]--
+			if remaining[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+
+	if len(order) < len(tasks) {
+		cycle := make([]int, 0)
+		for i, left := range remaining {
+			if left > 0 {
+				cycle = append(cycle, tasks[i].ID)
+			}
+		}
+		return nil, fmt.Errorf("task dependencies contain a cycle involving tasks %v", cycle)
+	}
+	return order, nil
+}
+
+func daysBetween(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
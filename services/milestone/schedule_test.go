@@ -0,0 +1,95 @@
+package milestone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func scheduleTasks() ([]types.Task, map[int][]int) {
+	estimate := func(days int) *int { return &days }
+	due := "2026-03-02T18:00:00Z"
+
+	tasks := []types.Task{
+		{ID: 1, Title: "Design", Status: types.StatusInProgress, EstimateDays: estimate(3)},
+		{ID: 2, Title: "Backend", Status: types.StatusPending, EstimateDays: estimate(2)},
+		{ID: 3, Title: "Frontend", Status: types.StatusPending, EstimateDays: estimate(4)},
+		{ID: 4, Title: "Release", Status: types.StatusPending, EstimateDays: estimate(1)},
+		{ID: 5, Title: "Announce", Status: types.StatusPending, EstimateDays: estimate(1), DueDate: &due},
+		{ID: 6, Title: "Kickoff", Status: types.StatusCompleted, EstimateDays: estimate(5)},
+	}
+	dependsOn := map[int][]int{
+		2: {1},
+		3: {1, 6},
+		4: {2, 3},
+	}
+	return tasks, dependsOn
+}
+
+func TestComputeSchedule(t *testing.T) {
+	tasks, dependsOn := scheduleTasks()
+	milestone := types.Milestone{ID: 1, TargetDate: "2026-03-10"}
+
+	schedule, err := ComputeSchedule(milestone, tasks, dependsOn, time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "2026-03-09", schedule.ProjectedFinish)
+	assert.False(t, schedule.AtRisk)
+	assert.Equal(t, []int{1, 3, 4}, schedule.CriticalPath)
+
+	frontend := schedule.Tasks[2]
+	assert.Equal(t, "2026-03-05", frontend.EarliestStart)
+	assert.Equal(t, "2026-03-08", frontend.EarliestFinish)
+	assert.Equal(t, "2026-03-06", frontend.LatestStart)
+	assert.Equal(t, "2026-03-09", frontend.LatestFinish)
+	assert.Equal(t, 1, frontend.SlackDays)
+	assert.Equal(t, []int{1, 6}, frontend.DependsOn)
+
+	assert.Equal(t, 3, schedule.Tasks[1].SlackDays)
+	assert.False(t, schedule.Tasks[1].Critical)
+	assert.Equal(t, 8, schedule.Tasks[4].SlackDays)
+	assert.False(t, schedule.Tasks[4].AtRisk)
+	assert.Equal(t, 0, schedule.Tasks[5].EstimateDays)
+}
+
+func TestComputeScheduleFlagsRisk(t *testing.T) {
+	tasks, dependsOn := scheduleTasks()
+	milestone := types.Milestone{ID: 1, TargetDate: "2026-03-05"}
+
+	schedule, err := ComputeSchedule(milestone, tasks, dependsOn, time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	assert.True(t, schedule.AtRisk)
+	assert.Equal(t, []int{1, 3, 4}, schedule.CriticalPath)
+	assert.Equal(t, -4, schedule.Tasks[0].SlackDays)
+	for _, task := range schedule.Tasks[:4] {
+		assert.True(t, task.AtRisk, "task %d", task.TaskID)
+	}
+	assert.False(t, schedule.Tasks[4].AtRisk)
+
+	// starting a day later makes the announcement miss its due date
+	later, err := ComputeSchedule(types.Milestone{ID: 1, TargetDate: "2026-03-31"}, tasks, dependsOn, time.Date(2026, time.March, 3, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.True(t, later.Tasks[4].AtRisk)
+}
+
+func TestComputeScheduleRespectsStartDates(t *testing.T) {
+	start := "2026-03-04"
+	tasks := []types.Task{{ID: 1, Status: types.StatusPending, StartDate: &start}}
+
+	schedule, err := ComputeSchedule(types.Milestone{ID: 1, TargetDate: "2026-03-10"}, tasks, nil, time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "2026-03-04", schedule.Tasks[0].EarliestStart)
+	assert.True(t, schedule.Tasks[0].Unestimated)
+}
+
+func TestComputeScheduleRejectsCycles(t *testing.T) {
+	tasks := []types.Task{{ID: 1}, {ID: 2}, {ID: 3}}
+	dependsOn := map[int][]int{1: {2}, 2: {1}}
+
+	_, err := ComputeSchedule(types.Milestone{ID: 1, TargetDate: "2026-03-10"}, tasks, dependsOn, time.Now())
+	assert.ErrorContains(t, err, "cycle")
+}
@@ -0,0 +1,83 @@
+package milestone
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetMilestones() ([]types.Milestone, error) {
+	rows, err := s.db.Query("SELECT * FROM milestones ORDER BY target_date")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	milestones := make([]types.Milestone, 0)
+	for rows.Next() {
+		m, err := scanRowIntoMilestone(rows)
+		if err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, *m)
+	}
+	return milestones, nil
+}
+
+func (s *Store) GetMilestoneByID(milestoneID int) (*types.Milestone, error) {
+	rows, err := s.db.Query("SELECT * FROM milestones WHERE id = ?", milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return scanRowIntoMilestone(rows)
+	}
+	return nil, errors.New("no milestone found with the given ID")
+}
+
+func scanRowIntoMilestone(rows *sql.Rows) (*types.Milestone, error) {
+	m := new(types.Milestone)
+	var target time.Time
+	if err := rows.Scan(&m.ID, &m.Name, &target, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	m.TargetDate = target.Format(types.DateLayout)
+	return m, nil
+}
+
+func (s *Store) CreateMilestone(m types.Milestone) (int, error) {
+	res, err := s.db.Exec("INSERT INTO milestones (name, target_date) VALUES (?, ?)", m.Name, m.TargetDate)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *Store) DeleteMilestone(milestoneID int) error {
+	res, err := s.db.Exec("DELETE FROM milestones WHERE id = ?", milestoneID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no milestone found with the given ID")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package milestone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetMilestoneByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now().Format(time.RFC3339)
+
+	mock.ExpectQuery("SELECT \\* FROM milestones WHERE id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "target_date", "created_at", "updated_at"}).
+			AddRow(1, "v1.0", time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), now, now))
+
+	milestone, err := store.GetMilestoneByID(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if milestone.TargetDate != "2026-03-31" {
+		t.Errorf("expected target date 2026-03-31, got %s", milestone.TargetDate)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteMissingMilestone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectExec("DELETE FROM milestones WHERE id = ?").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.DeleteMilestone(9); err == nil {
+		t.Error("expected an error for a missing milestone")
+	}
+}
@@ -0,0 +1,14 @@
+package notification
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/notifications", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionNotificationRead, h.handleGetNotifications), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/notifications/{id}/read", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionNotificationRead, h.handleMarkRead), h.userStore)).Methods(http.MethodPost)
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.NotificationStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.NotificationStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// HandleGetNotifications   get-notifications
+//
+// @Summary     Get Notifications
+// @Description Get notifications of the authenticated user, newest first
+// @Tags        Notification
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.Notification
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /notifications [get]
+func (h *Handler) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	notifications, err := h.store.GetNotificationsByUserID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, notifications)
+}
+
+// HandleMarkRead   mark-notification-read
+//
+// @Summary     Mark Notification Read
+// @Description Mark a notification of the authenticated user as read
+// @Tags        Notification
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Notification ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     404 {object} types.ErrorResponse
+// @Router      /notifications/{id}/read [post]
+func (h *Handler) handleMarkRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid notification ID"))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.MarkNotificationRead(notificationID, userID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Notification marked as read"})
+}
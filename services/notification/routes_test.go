@@ -0,0 +1,60 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestNotifications(t *testing.T) {
+	store := &mockNotificationStore{
+		notifications: []types.Notification{
+			{ID: 1, UserID: 1, Type: types.NotificationMention, Message: "You were mentioned in task #3"},
+			{ID: 2, UserID: 2, Type: types.NotificationMention, Message: "You were mentioned in task #4"},
+		},
+	}
+	handler := NewHandler(store, nil)
+
+	t.Run("should only return notifications of the authenticated user", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/notifications", nil)
+		assert.NoError(t, err)
+		req = req.WithContext(context.WithValue(req.Context(), types.UserKey, 1))
+		rr := httptest.NewRecorder()
+
+		handler.handleGetNotifications(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var got []types.Notification
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Len(t, got, 1)
+		assert.Equal(t, 1, got[0].ID)
+	})
+}
+
+type mockNotificationStore struct {
+	notifications []types.Notification
+}
+
+func (m *mockNotificationStore) CreateNotification(n types.Notification) error {
+	m.notifications = append(m.notifications, n)
+	return nil
+}
+
+func (m *mockNotificationStore) GetNotificationsByUserID(userID int) ([]types.Notification, error) {
+	result := make([]types.Notification, 0)
+	for _, n := range m.notifications {
+		if n.UserID == userID {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockNotificationStore) MarkNotificationRead(notificationID int, userID int) error {
+	return nil
+}
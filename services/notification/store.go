@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateNotification(n types.Notification) error {
+	_, err := s.db.Exec("INSERT INTO notifications (user_id, type, message, task_id) VALUES (?, ?, ?, ?)", n.UserID, n.Type, n.Message, n.TaskID)
+	return err
+}
+
+func (s *Store) GetNotificationsByUserID(userID int) ([]types.Notification, error) {
+	rows, err := s.db.Query("SELECT * FROM notifications WHERE user_id = ? ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]types.Notification, 0)
+	for rows.Next() {
+		n, err := scanRowIntoNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *n)
+	}
+	return notifications, nil
+}
+
+func (s *Store) MarkNotificationRead(notificationID int, userID int) error {
+	res, err := s.db.Exec("UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?", notificationID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("no notification found with the given ID")
+	}
+	return nil
+}
+
+func scanRowIntoNotification(rows *sql.Rows) (*types.Notification, error) {
+	n := new(types.Notification)
+	err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Message, &n.TaskID, &n.ReadAt, &n.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestCreateNotification(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	taskID := 3
+	n := types.Notification{UserID: 1, Type: types.NotificationMention, Message: "You were mentioned in task #3", TaskID: &taskID}
+
+	mock.ExpectExec("INSERT INTO notifications \\(user_id, type, message, task_id\\) VALUES \\(\\?, \\?, \\?, \\?\\)").
+		WithArgs(n.UserID, n.Type, n.Message, n.TaskID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := store.CreateNotification(n); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetNotificationsByUserID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	userID := 1
+
+	mock.ExpectQuery("SELECT \\* FROM notifications WHERE user_id = ?").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "type", "message", "task_id", "read_at", "created_at"}).
+			AddRow(1, userID, types.NotificationMention, "You were mentioned in task #3", 3, nil, time.Now().Format(time.RFC3339)))
+
+	notifications, err := store.GetNotificationsByUserID(userID)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if len(notifications) != 1 || notifications[0].TaskID == nil || *notifications[0].TaskID != 3 || notifications[0].ReadAt != nil {
+		t.Errorf("unexpected notifications %+v", notifications)
+	}
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectExec("UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE id = \\? AND user_id = \\?").
+		WithArgs(5, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.MarkNotificationRead(5, 1); err == nil {
+		t.Error("expected an error for a notification of another user")
+	}
+}
@@ -0,0 +1,12 @@
+package oidc
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/oidc/login", h.handleLogin).Methods(http.MethodGet)
+	router.HandleFunc("/auth/oidc/callback", h.handleCallback).Methods(http.MethodGet)
+}
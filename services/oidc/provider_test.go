@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testClientID     = "gotask"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "http://localhost:8080/api/v1/auth/oidc/callback"
+)
+
+// mockProvider is a minimal OpenID Connect provider. Authorize stands in for
+// the user logging in at the provider and returns the code the provider
+// would redirect back with.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	m := &mockProvider{t: t, key: key, kid: "key-1", codes: make(map[string]mockGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                           m.issuer(),
+			"authorization_endpoint":           m.issuer() + "/authorize",
+			"token_endpoint":                   m.issuer() + "/token",
+			"jwks_uri":                         m.issuer() + "/jwks",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": m.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", m.handleToken)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) issuer() string {
+	return m.server.URL
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(m.issuer(), testClientID, testClientSecret, testRedirectURL, []string{"openid", "email"}, m.server.Client())
+}
+
+// Authorize logs a user in at the provider for an authorization URL and
+// returns the state and the code of the redirect back.
+func (m *mockProvider) Authorize(authURL string, subject string, email string, emailVerified bool) (string, string) {
+	u, err := url.Parse(authURL)
+	assert.NoError(m.t, err)
+	query := u.Query()
+	assert.Equal(m.t, testClientID, query.Get("client_id"))
+	assert.Equal(m.t, "S256", query.Get("code_challenge_method"))
+
+	code, err := randomString()
+	assert.NoError(m.t, err)
+	claims := m.claims(subject, query.Get("nonce"))
+	claims["email"] = email
+	claims["email_verified"] = emailVerified
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codes[code] = mockGrant{challenge: query.Get("code_challenge"), claims: claims}
+	return query.Get("state"), code
+}
+
+func (m *mockProvider) claims(subject string, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   m.issuer(),
+		"sub":   subject,
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+}
+
+func (m *mockProvider) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	assert.NoError(m.t, err)
+	return signed
+}
+
+func (m *mockProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != testClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	grant, ok := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	m.mu.Unlock()
+
+	if !ok || codeChallenge(r.PostFormValue("code_verifier")) != grant.challenge || r.PostFormValue("redirect_uri") != testRedirectURL {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "code or verifier mismatch"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "provider-access-token",
+		"token_type":   "Bearer",
+		"id_token":     m.sign(grant.claims),
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+	ctx := context.Background()
+
+	t.Run("should accept a valid token", func(t *testing.T) {
+		claims := mock.claims("alice", "nonce")
+		claims["email"] = "alice@example.com"
+		claims["email_verified"] = "true"
+
+		verified, err := provider.VerifyIDToken(ctx, mock.sign(claims), "nonce", time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", verified.Subject)
+		assert.Equal(t, "alice@example.com", verified.Email)
+		assert.True(t, bool(verified.EmailVerified))
+	})
+
+	invalid := map[string]func(jwt.MapClaims){
+		"another nonce":    func(c jwt.MapClaims) { c["nonce"] = "other" },
+		"another audience": func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"another issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"an expired token": func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no expiration":    func(c jwt.MapClaims) { delete(c, "exp") },
+		"another azp":      func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "other"}; c["azp"] = "other" },
+	}
+	for name, change := range invalid {
+		t.Run("should reject "+name, func(t *testing.T) {
+			claims := mock.claims("alice", "nonce")
+			change(claims)
+			_, err := provider.VerifyIDToken(ctx, mock.sign(claims), "nonce", time.Now())
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("should reject tokens signed with another key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, mock.claims("alice", "nonce"))
+		token.Header["kid"] = mock.kid
+		signed, err := token.SignedString(other)
+		assert.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, signed, "nonce", time.Now())
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject tokens signed with the client secret", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, mock.claims("alice", "nonce"))
+		token.Header["kid"] = mock.kid
+		signed, err := token.SignedString([]byte(testClientSecret))
+		assert.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, signed, "nonce", time.Now())
+		assert.Error(t, err)
+	})
+}
+
+func TestDiscoveryChecksIssuer(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := NewProvider(mock.issuer()+"/other", testClientID, testClientSecret, testRedirectURL, nil, mock.server.Client())
+
+	_, err := provider.Discover(context.Background())
+	assert.Error(t, err)
+}
+
+func TestExchangeRequiresCodeVerifier(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := mock.provider()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", codeChallenge("verifier"))
+	assert.NoError(t, err)
+	_, code := mock.Authorize(authURL, "alice", "alice@example.com", true)
+
+	_, err = provider.Exchange(ctx, code, "another verifier")
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+const (
+	// stateCookie binds a login to the browser that started it.
+	stateCookie = "gotask_oidc_state"
+	// loginExpiration is how long the user has to log in at the provider.
+	loginExpiration = 10 * time.Minute
+	// deviceName names the sessions opened through single sign-on.
+	deviceName = "Single sign-on"
+)
+
+var (
+	errEmailNotVerified = errors.New("the identity provider did not confirm a verified email address")
+	errAccountNotLinked = errors.New("an account with this email address exists but its address is not verified, log in with your password and verify it first")
+)
+
+type Handler struct {
+	provider  *Provider
+	store     types.OIDCStore
+	userStore types.UserStore
+	logins    types.LoginFinisher
+}
+
+// NewHandler returns the single sign-on handler. A nil provider disables
+// single sign-on, and its routes answer 404.
+func NewHandler(provider *Provider, store types.OIDCStore, userStore types.UserStore, logins types.LoginFinisher) *Handler {
+	return &Handler{provider: provider, store: store, userStore: userStore, logins: logins}
+}
+
+// HandleOIDCLogin   oidc-login
+//
+// @Summary     Log in with Single Sign-On
+// @Description Redirect to the OpenID Connect provider configured by OIDC_ISSUER. The provider redirects back to /auth/oidc/callback.
+// @Tags        User
+// @Success     302
+// @Failure     404 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Failure     502 {object} types.ErrorResponse
+// @Router      /auth/oidc/login [get]
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("single sign-on is not configured"))
+		return
+	}
+
+	state, err := randomString()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	nonce, err := randomString()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	verifier, err := randomString()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	redirect, err := h.provider.AuthCodeURL(r.Context(), state, nonce, codeChallenge(verifier))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	expiresAt := time.Now().Add(loginExpiration)
+	err = h.store.SaveOIDCLoginState(types.OIDCLoginState{
+		StateHash:    auth.HashOneTimeToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	http.SetCookie(w, h.stateCookie(r, state, expiresAt))
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// HandleOIDCCallback   oidc-callback
+//
+// @Summary     Complete Single Sign-On
+// @Description The provider redirects here after the login. The provider account is linked to the user with the same verified email address, or a new user is created for it. The response is the same as for /login.
+// @Tags        User
+// @Produce     json
+// @Param       code  query    string true "Authorization code"
+// @Param       state query    string true "Login state"
+// @Success     200   {object} types.Tokens
+// @Failure     400   {object} types.ErrorResponse
+// @Failure     401   {object} types.ErrorResponse
+// @Failure     403   {object} types.ErrorResponse
+// @Failure     404   {object} types.ErrorResponse
+// @Failure     409   {object} types.ErrorResponse
+// @Failure     500   {object} types.ErrorResponse
+// @Failure     502   {object} types.ErrorResponse
+// @Router      /auth/oidc/callback [get]
+func (h *Handler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("single sign-on is not configured"))
+		return
+	}
+
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("identity provider returned %s: %s", providerError, query.Get("error_description")))
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("state and code are required"))
+		return
+	}
+
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("login state mismatch, start the login again"))
+		return
+	}
+	// the state works once, so the cookie is no longer needed
+	http.SetCookie(w, h.stateCookie(r, "", time.Unix(0, 0)))
+
+	now := time.Now()
+	login, err := h.store.UseOIDCLoginState(auth.HashOneTimeToken(state), now)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	idToken, err := h.provider.Exchange(r.Context(), code, login.CodeVerifier)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+	claims, err := h.provider.VerifyIDToken(r.Context(), idToken, login.Nonce, now)
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	userID, err := h.resolveUser(claims, now)
+	switch {
+	case errors.Is(err, errEmailNotVerified):
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	case errors.Is(err, errAccountNotLinked):
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.logins.FinishLogin(w, r, userID, deviceName)
+}
+
+// resolveUser returns the user a provider account belongs to. Accounts that
+// were linked before are found by issuer and subject. Otherwise the account
+// is linked to the user with the same email address, which both sides must
+// have verified, or a new user is created for it.
+func (h *Handler) resolveUser(claims *IDTokenClaims, now time.Time) (int, error) {
+	identity, err := h.store.GetUserIdentity(claims.Issuer, claims.Subject)
+	if err != nil {
+		return 0, err
+	}
+	if identity != nil {
+		return identity.UserID, nil
+	}
+
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return 0, errEmailNotVerified
+	}
+
+	u, err := h.userStore.GetUserByEmail(claims.Email)
+	if err != nil || u == nil {
+		u, err = h.createUser(claims.Email, now)
+		if err != nil {
+			return 0, err
+		}
+	} else if u.VerifiedAt == nil {
+		return 0, errAccountNotLinked
+	}
+
+	err = h.store.CreateUserIdentity(types.UserIdentity{
+		UserID:  u.ID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("linked %s account %s to user %d", claims.Issuer, claims.Subject, u.ID)
+	return u.ID, nil
+}
+
+// createUser creates a user for a provider account. The address was verified
+// by the provider, and the random password can only be replaced through a
+// password reset.
+func (h *Handler) createUser(email string, now time.Time) (*types.User, error) {
+	password, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := auth.HashValue(password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.userStore.CreateUser(types.User{
+		Email:    email,
+		Password: hashedPassword,
+		Role:     types.RoleMember,
+	})
+	if err != nil {
+		return nil, err
+	}
+	u, err := h.userStore.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.userStore.VerifyEmail(u.ID, email, now); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// stateCookie returns the cookie holding the state of a login, limited to
+// the single sign-on routes.
+func (h *Handler) stateCookie(r *http.Request, value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     stateCookie,
+		Value:    value,
+		Path:     path.Dir(r.URL.Path),
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(h.provider.redirectURL, "https://"),
+		// the provider redirects back with a top-level GET, which Lax allows
+		SameSite: http.SameSiteLaxMode,
+	}
+}
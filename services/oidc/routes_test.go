@@ -0,0 +1,189 @@
+package oidc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	mock := newMockProvider(t)
+	verifiedAt := time.Now().Format(time.RFC3339)
+	userStore := &mockUserStore{users: map[int]*types.User{
+		1: {ID: 1, Email: "bob@example.com", VerifiedAt: &verifiedAt},
+		2: {ID: 2, Email: "carol@example.com"},
+	}}
+	store := &mockOIDCStore{states: make(map[string]types.OIDCLoginState)}
+	logins := &mockLoginFinisher{}
+	handler := NewHandler(mock.provider(), store, userStore, logins)
+
+	// login starts a login and logs in at the provider, returning the
+	// callback request the provider redirects back with
+	login := func(subject string, email string, emailVerified bool) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil)
+		rr := httptest.NewRecorder()
+		handler.handleLogin(rr, req)
+		assert.Equal(t, http.StatusFound, rr.Code)
+
+		state, code := mock.Authorize(rr.Header().Get("Location"), subject, email, emailVerified)
+		callback := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?"+url.Values{"state": {state}, "code": {code}}.Encode(), nil)
+		for _, cookie := range rr.Result().Cookies() {
+			assert.True(t, cookie.HttpOnly)
+			assert.Equal(t, "/api/v1/auth/oidc", cookie.Path)
+			callback.AddCookie(cookie)
+		}
+		return callback
+	}
+	callback := func(req *http.Request) *httptest.ResponseRecorder {
+		logins.userID = 0
+		rr := httptest.NewRecorder()
+		handler.handleCallback(rr, req)
+		return rr
+	}
+
+	t.Run("should create a verified user for a new account", func(t *testing.T) {
+		rr := callback(login("alice-sub", "alice@example.com", true))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		u, err := userStore.GetUserByEmail("alice@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, u.ID, logins.userID)
+		assert.Equal(t, types.RoleMember, u.Role)
+		assert.NotNil(t, u.VerifiedAt)
+		assert.NotEmpty(t, u.Password)
+	})
+
+	t.Run("should find linked accounts by subject", func(t *testing.T) {
+		rr := callback(login("alice-sub", "alice@new.example.com", false))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		u, _ := userStore.GetUserByEmail("alice@example.com")
+		assert.Equal(t, u.ID, logins.userID)
+	})
+
+	t.Run("should link a user with the same verified address", func(t *testing.T) {
+		rr := callback(login("bob-sub", "bob@example.com", true))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 1, logins.userID)
+		assert.Equal(t, 1, store.identities[mock.issuer()+"|bob-sub"].UserID)
+	})
+
+	t.Run("should not link a user whose address is not verified", func(t *testing.T) {
+		rr := callback(login("carol-sub", "carol@example.com", true))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Zero(t, logins.userID)
+	})
+
+	t.Run("should require an address verified by the provider", func(t *testing.T) {
+		rr := callback(login("dave-sub", "dave@example.com", false))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		_, err := userStore.GetUserByEmail("dave@example.com")
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject a callback without the state cookie", func(t *testing.T) {
+		req := login("alice-sub", "alice@example.com", true)
+		req.Header.Del("Cookie")
+		assert.Equal(t, http.StatusBadRequest, callback(req).Code)
+	})
+
+	t.Run("should complete each login once", func(t *testing.T) {
+		req := login("alice-sub", "alice@example.com", true)
+		assert.Equal(t, http.StatusOK, callback(req).Code)
+		assert.Equal(t, http.StatusBadRequest, callback(req).Code)
+	})
+
+	t.Run("should report errors of the provider", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?error=access_denied", nil)
+		assert.Equal(t, http.StatusBadRequest, callback(req).Code)
+	})
+}
+
+func TestOIDCNotConfigured(t *testing.T) {
+	handler := NewHandler(nil, &mockOIDCStore{}, &mockUserStore{}, &mockLoginFinisher{})
+
+	rr := httptest.NewRecorder()
+	handler.handleLogin(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.handleCallback(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?state=a&code=b", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+type mockOIDCStore struct {
+	states     map[string]types.OIDCLoginState
+	identities map[string]types.UserIdentity
+}
+
+func (m *mockOIDCStore) SaveOIDCLoginState(s types.OIDCLoginState) error {
+	m.states[s.StateHash] = s
+	return nil
+}
+
+func (m *mockOIDCStore) UseOIDCLoginState(stateHash string, now time.Time) (*types.OIDCLoginState, error) {
+	s, ok := m.states[stateHash]
+	if !ok || !s.ExpiresAt.After(now) {
+		return nil, errors.New("invalid or expired login state, start the login again")
+	}
+	delete(m.states, stateHash)
+	return &s, nil
+}
+
+func (m *mockOIDCStore) GetUserIdentity(issuer string, subject string) (*types.UserIdentity, error) {
+	i, ok := m.identities[issuer+"|"+subject]
+	if !ok {
+		return nil, nil
+	}
+	return &i, nil
+}
+
+func (m *mockOIDCStore) CreateUserIdentity(i types.UserIdentity) error {
+	if m.identities == nil {
+		m.identities = make(map[string]types.UserIdentity)
+	}
+	m.identities[i.Issuer+"|"+i.Subject] = i
+	return nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	users map[int]*types.User
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (m *mockUserStore) CreateUser(u types.User) error {
+	u.ID = len(m.users) + 1
+	m.users[u.ID] = &u
+	return nil
+}
+
+func (m *mockUserStore) VerifyEmail(userID int, email string, now time.Time) error {
+	verifiedAt := now.Format(time.RFC3339)
+	m.users[userID].Email = email
+	m.users[userID].VerifiedAt = &verifiedAt
+	return nil
+}
+
+type mockLoginFinisher struct {
+	userID int
+}
+
+func (m *mockLoginFinisher) FinishLogin(w http.ResponseWriter, r *http.Request, userID int, deviceName string) {
+	m.userID = userID
+	utils.WriteJSON(w, http.StatusOK, types.Tokens{})
+}
@@ -0,0 +1,82 @@
+package oidc
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// SaveOIDCLoginState stores the state of a login that was sent to the
+// identity provider and drops the states of logins that were never finished.
+func (s *Store) SaveOIDCLoginState(state types.OIDCLoginState) error {
+	if _, err := s.db.Exec("DELETE FROM oidc_login_states WHERE expires_at <= ?", time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO oidc_login_states (state_hash, nonce, code_verifier, expires_at) VALUES (?, ?, ?, ?)",
+		state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+// UseOIDCLoginState returns an unexpired login state and deletes it, so that
+// each callback can only be completed once.
+func (s *Store) UseOIDCLoginState(stateHash string, now time.Time) (*types.OIDCLoginState, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	state := new(types.OIDCLoginState)
+	err = tx.QueryRow(
+		"SELECT state_hash, nonce, code_verifier, expires_at FROM oidc_login_states WHERE state_hash = ? AND expires_at > ? FOR UPDATE",
+		stateHash, now.UTC(),
+	).Scan(&state.StateHash, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("invalid or expired login state, start the login again")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM oidc_login_states WHERE state_hash = ?", stateHash); err != nil {
+		return nil, err
+	}
+	return state, tx.Commit()
+}
+
+// GetUserIdentity returns the identity of a provider account, or nil when it
+// is not linked to a user.
+func (s *Store) GetUserIdentity(issuer string, subject string) (*types.UserIdentity, error) {
+	i := new(types.UserIdentity)
+	err := s.db.QueryRow(
+		"SELECT id, user_id, issuer, subject, email, created_at FROM user_identities WHERE issuer = ? AND subject = ?",
+		issuer, subject,
+	).Scan(&i.ID, &i.UserID, &i.Issuer, &i.Subject, &i.Email, &i.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (s *Store) CreateUserIdentity(i types.UserIdentity) error {
+	_, err := s.db.Exec(
+		"INSERT INTO user_identities (user_id, issuer, subject, email) VALUES (?, ?, ?, ?)",
+		i.UserID, i.Issuer, i.Subject, i.Email,
+	)
+	return err
+}
@@ -0,0 +1,73 @@
+package oidc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseOIDCLoginState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	query := "SELECT state_hash, nonce, code_verifier, expires_at FROM oidc_login_states WHERE state_hash = \\? AND expires_at > \\? FOR UPDATE"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("hash", now).
+		WillReturnRows(sqlmock.NewRows([]string{"state_hash", "nonce", "code_verifier", "expires_at"}).AddRow("hash", "nonce", "verifier", now.Add(time.Minute)))
+	mock.ExpectExec("DELETE FROM oidc_login_states WHERE state_hash = \\?").
+		WithArgs("hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("hash", now).WillReturnRows(sqlmock.NewRows([]string{"state_hash", "nonce", "code_verifier", "expires_at"}))
+	mock.ExpectRollback()
+
+	state, err := store.UseOIDCLoginState("hash", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "nonce", state.Nonce)
+	assert.Equal(t, "verifier", state.CodeVerifier)
+
+	_, err = store.UseOIDCLoginState("hash", now)
+	assert.Error(t, err, "a used state does not work again")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetUserIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	query := "SELECT id, user_id, issuer, subject, email, created_at FROM user_identities WHERE issuer = \\? AND subject = \\?"
+	columns := []string{"id", "user_id", "issuer", "subject", "email", "created_at"}
+
+	mock.ExpectQuery(query).WithArgs("https://idp.example.com", "sub").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 7, "https://idp.example.com", "sub", "bob@example.com", "2026-10-19 12:00:00"))
+	mock.ExpectQuery(query).WithArgs("https://idp.example.com", "other").WillReturnRows(sqlmock.NewRows(columns))
+
+	identity, err := store.GetUserIdentity("https://idp.example.com", "sub")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, identity.UserID)
+
+	identity, err = store.GetUserIdentity("https://idp.example.com", "other")
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,12 @@
+package password
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/password/forgot", h.handleForgotPassword).Methods(http.MethodPost)
+	router.HandleFunc("/password/reset", h.handleResetPassword).Methods(http.MethodPost)
+}
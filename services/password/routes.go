@@ -0,0 +1,178 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.PasswordResetStore
+	userStore types.UserStore
+	sessions  types.SessionStore
+	mailer    types.Mailer
+	policy    types.PasswordPolicy
+}
+
+func NewHandler(store types.PasswordResetStore, userStore types.UserStore, sessions types.SessionStore, mailer types.Mailer, policy types.PasswordPolicy) *Handler {
+	return &Handler{store: store, userStore: userStore, sessions: sessions, mailer: mailer, policy: policy}
+}
+
+// HandleForgotPassword   forgot-password
+//
+// @Summary     Forgot Password
+// @Description Email a single-use password reset token to the address. The response is the same whether or not an account exists, so it cannot be used to find accounts.
+// @Tags        Password
+// @Accept      json
+// @Produce     json
+// @Param       ForgotPasswordPayload body     types.ForgotPasswordPayload true "Email of the account"
+// @Success     202                   {object} nil
+// @Failure     400                   {object} types.ErrorResponse
+// @Router      /password/forgot [post]
+func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ForgotPasswordPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	// the email is sent in the background so that the response time does
+	// not tell whether the account exists either
+	go h.sendResetToken(payload.Email)
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+// sendResetToken creates a reset token for the account with the email, if
+// there is one, and emails it.
+func (h *Handler) sendResetToken(email string) {
+	u, err := h.userStore.GetUserByEmail(email)
+	if err != nil || u == nil {
+		return
+	}
+
+	token, hash, err := auth.NewOneTimeToken()
+	if err != nil {
+		log.Printf("failed to create password reset token: %v", err)
+		return
+	}
+	expiration := time.Second * time.Duration(config.Envs.PasswordResetExpiration)
+	if err := h.store.CreatePasswordResetToken(u.ID, hash, time.Now().Add(expiration)); err != nil {
+		log.Printf("failed to store password reset token for user %d: %v", u.ID, err)
+		return
+	}
+
+	err = h.mailer.Send(types.Email{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body:    resetEmailBody(token, expiration),
+	})
+	if err != nil {
+		log.Printf("failed to send password reset email to user %d: %v", u.ID, err)
+	}
+}
+
+func resetEmailBody(token string, expiration time.Duration) string {
+	instructions := fmt.Sprintf("Reset it with this token:\n\n%s\n\nby sending it with your new password to POST /api/v1/password/reset.", token)
+	if base := config.Envs.PasswordResetURL; base != "" {
+		instructions = fmt.Sprintf("Reset it by opening this link:\n\n%s?token=%s", base, url.QueryEscape(token))
+	}
+	return fmt.Sprintf(
+		"Someone asked to reset the password of your account. %s\n\nThe token works once and expires in %s. If you did not ask for this, ignore this email; your password stays the same.\n",
+		instructions, expiration,
+	)
+}
+
+// HandleResetPassword   reset-password
+//
+// @Summary     Reset Password
+// @Description Set a new password with a token from the password reset email. The new password has to follow the password policy; a 400 lists every rule it breaks, and the token can be used again with another password. The token works once, and every session of the account is logged out.
+// @Tags        Password
+// @Accept      json
+// @Produce     json
+// @Param       ResetPasswordPayload body     types.ResetPasswordPayload true "Reset token and new password"
+// @Success     200                  {object} nil
+// @Failure     400                  {object} types.ErrorResponse
+// @Failure     500                  {object} types.ErrorResponse
+// @Router      /password/reset [post]
+func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ResetPasswordPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	tokenHash := auth.HashOneTimeToken(payload.Token)
+	now := time.Now()
+	// the password is checked before the token is used up, so that a
+	// password breaking the policy does not cost the user the email
+	userID, err := h.store.GetPasswordResetTokenUser(tokenHash, now)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired reset token"))
+		return
+	}
+	u, err := h.userStore.GetUserByID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.policy.Check(payload.NewPassword, u.Email); err != nil {
+		var policyErr *types.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := h.store.UsePasswordResetToken(tokenHash, now); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired reset token"))
+		return
+	}
+
+	hashedPassword, err := auth.HashValue(payload.NewPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.userStore.UpdateUser(userID, types.UpdateUserPayload{Password: &hashedPassword}); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// whoever knew the old password may still be logged in
+	revoked, err := h.sessions.RevokeOtherSessions(userID, 0)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.sessions.RecordSecurityEvent(types.SecurityEvent{
+		UserID:    userID,
+		Type:      types.SecurityEventPasswordReset,
+		IP:        utils.GetClientIP(r),
+		UserAgent: r.UserAgent(),
+		Message:   fmt.Sprintf("password was reset by email, %d sessions were revoked", revoked),
+	})
+	if err != nil {
+		log.Printf("failed to record security event: %v", err)
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,165 @@
+package password
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/services/mailer"
+	"github.com/trsnaqe/gotask/types"
+)
+
+var tokenPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+func TestPasswordReset(t *testing.T) {
+	userStore := &mockUserStore{user: types.User{ID: 1, Email: "bob@example.com", Password: "old"}}
+	sessions := &mockSessionStore{}
+	mail := mailer.NewMemory()
+	policy := &mockPasswordPolicy{}
+	handler := NewHandler(&mockResetStore{tokens: make(map[string]*mockResetToken)}, userStore, sessions, mail, policy)
+
+	serve := func(handlerFunc http.HandlerFunc, payload interface{}) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handlerFunc(rr, req)
+		return rr
+	}
+
+	t.Run("should answer the same for unknown accounts", func(t *testing.T) {
+		rr := serve(handler.handleForgotPassword, types.ForgotPasswordPayload{Email: "eve@example.com"})
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		rr = serve(handler.handleForgotPassword, types.ForgotPasswordPayload{Email: "bob@example.com"})
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+
+		assert.Eventually(t, func() bool { return len(mail.Sent()) == 1 }, time.Second, 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		assert.Len(t, mail.Sent(), 1, "only existing accounts get an email")
+		assert.Equal(t, "bob@example.com", mail.Sent()[0].To)
+	})
+
+	token := tokenPattern.FindString(mail.Sent()[0].Body)
+	assert.NotEmpty(t, token)
+
+	t.Run("should set the password once and revoke all sessions", func(t *testing.T) {
+		rr := serve(handler.handleResetPassword, types.ResetPasswordPayload{Token: "wrong", NewPassword: "newsecret"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		policy.rejected = "password1"
+		rr = serve(handler.handleResetPassword, types.ResetPasswordPayload{Token: token, NewPassword: "password1"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "is too easy to guess")
+		assert.Equal(t, []string{"bob@example.com"}, policy.emails, "the policy gets the email of the user")
+		assert.Equal(t, "old", userStore.user.Password)
+		assert.Empty(t, sessions.revokedUsers, "a refused password does not use up the token")
+
+		rr = serve(handler.handleResetPassword, types.ResetPasswordPayload{Token: token, NewPassword: "newsecret"})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.True(t, auth.CompareValue(userStore.user.Password, "newsecret"))
+		assert.Equal(t, []int{1}, sessions.revokedUsers)
+		if assert.Len(t, sessions.events, 1) {
+			assert.Equal(t, types.SecurityEventPasswordReset, sessions.events[0].Type)
+		}
+
+		rr = serve(handler.handleResetPassword, types.ResetPasswordPayload{Token: token, NewPassword: "another"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+type mockResetToken struct {
+	userID    int
+	expiresAt time.Time
+	used      bool
+}
+
+type mockResetStore struct {
+	tokens map[string]*mockResetToken
+}
+
+func (m *mockResetStore) CreatePasswordResetToken(userID int, tokenHash string, expiresAt time.Time) error {
+	m.tokens[tokenHash] = &mockResetToken{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *mockResetStore) GetPasswordResetTokenUser(tokenHash string, now time.Time) (int, error) {
+	t, ok := m.tokens[tokenHash]
+	if !ok || t.used || !t.expiresAt.After(now) {
+		return 0, errors.New("invalid or expired reset token")
+	}
+	return t.userID, nil
+}
+
+func (m *mockResetStore) UsePasswordResetToken(tokenHash string, now time.Time) (int, error) {
+	t, ok := m.tokens[tokenHash]
+	if !ok || t.used || !t.expiresAt.After(now) {
+		return 0, errors.New("invalid or expired reset token")
+	}
+	t.used = true
+	return t.userID, nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	user types.User
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	if email != m.user.Email {
+		return nil, errors.New("user not found")
+	}
+	u := m.user
+	return &u, nil
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	if id != m.user.ID {
+		return nil, errors.New("user not found")
+	}
+	u := m.user
+	return &u, nil
+}
+
+func (m *mockUserStore) UpdateUser(userID int, updates types.UpdateUserPayload) error {
+	if updates.Password != nil {
+		m.user.Password = *updates.Password
+	}
+	return nil
+}
+
+type mockSessionStore struct {
+	types.SessionStore
+	revokedUsers []int
+	events       []types.SecurityEvent
+}
+
+func (m *mockSessionStore) RevokeOtherSessions(userID int, keepSessionID int) (int, error) {
+	m.revokedUsers = append(m.revokedUsers, userID)
+	return 2, nil
+}
+
+func (m *mockSessionStore) RecordSecurityEvent(e types.SecurityEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+// mockPasswordPolicy refuses one password and accepts the others.
+type mockPasswordPolicy struct {
+	rejected string
+	emails   []string
+}
+
+func (m *mockPasswordPolicy) Check(password string, email string) error {
+	m.emails = append(m.emails, email)
+	if password == m.rejected {
+		return &types.PasswordPolicyError{Violations: []string{"is too easy to guess, avoid common passwords and words"}}
+	}
+	return nil
+}
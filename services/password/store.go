@@ -0,0 +1,63 @@
+package password
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreatePasswordResetToken(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := s.db.Exec(
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES (?, ?, ?)",
+		userID, tokenHash, expiresAt.UTC(),
+	)
+	return err
+}
+
+func (s *Store) GetPasswordResetTokenUser(tokenHash string, now time.Time) (int, error) {
+	var userID int
+	err := s.db.QueryRow(
+		"SELECT user_id FROM password_reset_tokens WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		tokenHash, now.UTC(),
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errors.New("invalid or expired reset token")
+	}
+	return userID, err
+}
+
+// UsePasswordResetToken returns the user of an unused, unexpired reset token
+// and uses up every open reset token of that user, so that older emails
+// stop working as well.
+func (s *Store) UsePasswordResetToken(tokenHash string, now time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(
+		"SELECT user_id FROM password_reset_tokens WHERE token_hash = ? AND used_at IS NULL AND expires_at > ? FOR UPDATE",
+		tokenHash, now.UTC(),
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errors.New("invalid or expired reset token")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL", now.UTC(), userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
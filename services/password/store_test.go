@@ -0,0 +1,69 @@
+package password
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsePasswordResetToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	query := "SELECT user_id FROM password_reset_tokens WHERE token_hash = \\? AND used_at IS NULL AND expires_at > \\? FOR UPDATE"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("hash", now).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(7))
+	mock.ExpectExec("UPDATE password_reset_tokens SET used_at = \\? WHERE user_id = \\? AND used_at IS NULL").
+		WithArgs(now, 7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("hash", now).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectRollback()
+
+	userID, err := store.UsePasswordResetToken("hash", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	_, err = store.UsePasswordResetToken("hash", now)
+	assert.Error(t, err, "a used token does not work again")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetPasswordResetTokenUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	query := "SELECT user_id FROM password_reset_tokens WHERE token_hash = \\? AND used_at IS NULL AND expires_at > \\?"
+
+	mock.ExpectQuery(query).WithArgs("hash", now).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(7))
+	mock.ExpectQuery(query).WithArgs("other", now).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	userID, err := store.GetPasswordResetTokenUser("hash", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	_, err = store.GetPasswordResetTokenUser("other", now)
+	assert.Error(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,142 @@
+package passwordpolicy
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxBreachLineLength bounds a line of the list, a SHA-1 hash in hex, a
+// colon and a count.
+const maxBreachLineLength = 64
+
+// BreachList looks passwords up in a local list of breached password hashes
+// in the format of the Have I Been Pwned downloads: one `<SHA-1>:<count>`
+// line per password, in upper case hex and sorted by hash. As the lines are
+// sorted, the hashes sharing a prefix form one bucket, which is found with a
+// binary search over the file without loading it.
+type BreachList struct {
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenBreachList opens a breach list file.
+func OpenBreachList(path string) (*BreachList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &BreachList{file: file, size: info.Size()}, nil
+}
+
+func (b *BreachList) Close() error {
+	return b.file.Close()
+}
+
+// Count returns how often a password appears in the list, zero when it does
+// not.
+func (b *BreachList) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// find the first line whose hash is not below the target; every line
+	// starting before lo is below it
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := b.lineFrom(mid)
+		if err != nil {
+			return 0, err
+		}
+		if line == nil || start >= hi {
+			hi = mid
+			continue
+		}
+		if hash, _ := splitBreachLine(line); hash < target {
+			lo = start + int64(len(line))
+		} else {
+			hi = mid
+		}
+	}
+
+	_, line, err := b.lineFrom(lo)
+	if err != nil || line == nil {
+		return 0, err
+	}
+	hash, count := splitBreachLine(line)
+	if hash != target {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		// lists without counts still mark the password as breached
+		return 1, nil
+	}
+	return n, nil
+}
+
+// lineFrom returns the first line starting at or after offset and where it
+// starts, with its line break, or nil at the end of the file.
+func (b *BreachList) lineFrom(offset int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		// a line starts at offset when the byte before it ends a line
+		buf, err := b.readAt(offset - 1)
+		if err != nil {
+			return 0, nil, err
+		}
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			if offset-1+int64(len(buf)) >= b.size {
+				return 0, nil, nil
+			}
+			return 0, nil, errors.New("breach list line is too long")
+		}
+		start = offset + int64(i)
+	}
+
+	buf, err := b.readAt(start)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(buf) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return start, buf[:i+1], nil
+	}
+	if start+int64(len(buf)) < b.size {
+		return 0, nil, errors.New("breach list line is too long")
+	}
+	return start, buf, nil
+}
+
+func (b *BreachList) readAt(offset int64) ([]byte, error) {
+	buf := make([]byte, maxBreachLineLength+1)
+	n, err := b.file.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read breach list: %v", err)
+	}
+	return buf[:n], nil
+}
+
+func splitBreachLine(line []byte) (string, string) {
+	hash, count, _ := strings.Cut(strings.TrimRight(string(line), "\r\n"), ":")
+	return strings.ToUpper(hash), count
+}
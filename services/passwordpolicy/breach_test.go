@@ -0,0 +1,80 @@
+package passwordpolicy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreachList(t *testing.T) {
+	passwords := make([]string, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		passwords = append(passwords, fmt.Sprintf("breached-%d", i))
+	}
+	list := openTestBreachList(t, passwords)
+
+	t.Run("should find every password of the list", func(t *testing.T) {
+		for i, password := range passwords {
+			count, err := list.Count(password)
+			assert.NoError(t, err)
+			assert.Equal(t, i+1, count, password)
+		}
+	})
+
+	t.Run("should not find other passwords", func(t *testing.T) {
+		for i := 0; i < 200; i++ {
+			count, err := list.Count(fmt.Sprintf("safe-%d", i))
+			assert.NoError(t, err)
+			assert.Zero(t, count)
+		}
+	})
+
+	t.Run("should handle tiny lists", func(t *testing.T) {
+		single := openTestBreachList(t, []string{"only"})
+		count, err := single.Count("only")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		count, err = single.Count("other")
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+
+		empty := openTestBreachList(t, nil)
+		count, err = empty.Count("only")
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("should fail on a missing file", func(t *testing.T) {
+		_, err := OpenBreachList(filepath.Join(t.TempDir(), "missing.txt"))
+		assert.Error(t, err)
+	})
+}
+
+// openTestBreachList writes a list of the passwords, the nth appearing n
+// times, sorted by hash like the downloaded lists are.
+func openTestBreachList(t *testing.T, passwords []string) *BreachList {
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d\r\n", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "breaches.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := OpenBreachList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { list.Close() })
+	return list
+}
@@ -0,0 +1,154 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/types"
+)
+
+// Policy holds the rules new passwords have to follow. Every rule is
+// checked, so a rejected password comes with all the reasons at once.
+type Policy struct {
+	MinLength        int
+	MaxLength        int
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// DisallowEmail refuses passwords containing the email of the user or
+	// the part of it before the @.
+	DisallowEmail bool
+	// MinStrength is the lowest EstimateStrength score accepted, from 0 to 4.
+	MinStrength int
+	// Breaches is the list of breached passwords, nil to not check one.
+	Breaches *BreachList
+}
+
+// NewPolicy returns the policy configured by the PASSWORD_* variables and
+// opens the breach list when PASSWORD_BREACH_LIST names one.
+func NewPolicy() (*Policy, error) {
+	p := &Policy{
+		MinLength:        int(config.Envs.PasswordMinLength),
+		MaxLength:        int(config.Envs.PasswordMaxLength),
+		RequireLowercase: config.Envs.PasswordRequireLowercase,
+		RequireUppercase: config.Envs.PasswordRequireUppercase,
+		RequireDigit:     config.Envs.PasswordRequireDigit,
+		RequireSymbol:    config.Envs.PasswordRequireSymbol,
+		DisallowEmail:    config.Envs.PasswordDisallowEmail,
+		MinStrength:      int(config.Envs.PasswordMinStrength),
+	}
+	if p.MinLength < 1 || p.MaxLength < p.MinLength {
+		return nil, fmt.Errorf("PASSWORD_MIN_LENGTH should be at least 1 and at most PASSWORD_MAX_LENGTH")
+	}
+	if p.MinStrength < 0 || p.MinStrength > 4 {
+		return nil, fmt.Errorf("PASSWORD_MIN_STRENGTH should be between 0 and 4")
+	}
+
+	if path := config.Envs.PasswordBreachList; path != "" {
+		breaches, err := OpenBreachList(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open breach list: %v", err)
+		}
+		p.Breaches = breaches
+	}
+	return p, nil
+}
+
+// Check returns a *types.PasswordPolicyError listing the rules the password
+// breaks, or an error when the breach list cannot be read.
+func (p *Policy) Check(password string, email string) error {
+	var violations []string
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+		// the other rules would only slow down rejecting it
+		return &types.PasswordPolicyError{Violations: violations}
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireUppercase && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.DisallowEmail && containsEmail(password, email) {
+		violations = append(violations, "must not contain your email address")
+	}
+
+	if strength := EstimateStrength(password, emailInputs(email)); strength.Score < p.MinStrength {
+		violations = append(violations, "is too easy to guess, "+strength.Feedback)
+	}
+
+	if p.Breaches != nil {
+		count, err := p.Breaches.Count(password)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			violations = append(violations, "has appeared in a data breach, choose another one")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &types.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// emailInputs returns the parts of an email an attacker would try: the
+// address, the part before the @ and the words of both.
+func emailInputs(email string) []string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+	inputs := []string{email}
+	local, domain, _ := strings.Cut(email, "@")
+	inputs = append(inputs, local)
+	split := func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }
+	inputs = append(inputs, strings.FieldsFunc(local, split)...)
+	if labels := strings.FieldsFunc(domain, split); len(labels) > 1 {
+		// the top level domain is too common to tell anything
+		inputs = append(inputs, labels[:len(labels)-1]...)
+	}
+	return inputs
+}
+
+// containsEmail reports whether the password contains the email or the part
+// of it before the @, ignoring case.
+func containsEmail(password string, email string) bool {
+	password = strings.ToLower(password)
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, _, _ := strings.Cut(email, "@")
+	if utf8.RuneCountInString(local) < minMatchLength {
+		return email != "" && strings.Contains(password, email)
+	}
+	return strings.Contains(password, local)
+}
@@ -0,0 +1,105 @@
+package passwordpolicy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestPolicy(t *testing.T) {
+	policy := &Policy{MinLength: 8, MaxLength: 64, DisallowEmail: true, MinStrength: 2}
+
+	violations := func(password string, email string) []string {
+		var policyErr *types.PasswordPolicyError
+		if err := policy.Check(password, email); !errors.As(err, &policyErr) {
+			assert.NoError(t, err)
+			return nil
+		}
+		return policyErr.Violations
+	}
+
+	t.Run("should accept a strong password", func(t *testing.T) {
+		assert.NoError(t, policy.Check("x7#kQ9!vLm2$", "bob@example.com"))
+		assert.NoError(t, policy.Check("correct horse battery staple", "bob@example.com"))
+	})
+
+	t.Run("should list every broken rule", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"must be at least 8 characters long",
+			"is too easy to guess, avoid common passwords and words",
+		}, violations("secret", "bob@example.com"))
+	})
+
+	t.Run("should refuse long passwords without checking the rest", func(t *testing.T) {
+		assert.Equal(t, []string{"must be at most 64 characters long"}, violations(strings.Repeat("a", 65), "bob@example.com"))
+	})
+
+	t.Run("should refuse the email of the user", func(t *testing.T) {
+		assert.Contains(t, violations("Bob@Example.com!92x", "bob@example.com"), "must not contain your email address")
+		assert.Contains(t, violations("xx-robert.smith-77", "Robert.Smith@example.com"), "must not contain your email address")
+		assert.Empty(t, violations("x7#kQ9!vLm2$", ""))
+	})
+
+	t.Run("should require the configured character classes", func(t *testing.T) {
+		strict := &Policy{MinLength: 8, MaxLength: 64, RequireLowercase: true, RequireUppercase: true, RequireDigit: true, RequireSymbol: true}
+		var policyErr *types.PasswordPolicyError
+		assert.ErrorAs(t, strict.Check("ÉTÉ ÉTÉ ÉTÉ", ""), &policyErr)
+		assert.Equal(t, []string{"must contain a lowercase letter", "must contain a digit", "must contain a symbol"}, policyErr.Violations)
+		assert.NoError(t, strict.Check("été-2024-Paris", ""))
+	})
+
+	t.Run("should refuse breached passwords", func(t *testing.T) {
+		breaches := openTestBreachList(t, []string{"hunter2-but-longer"})
+		breached := &Policy{MinLength: 8, MaxLength: 64, Breaches: breaches}
+		assert.Equal(t, []string{"has appeared in a data breach, choose another one"}, violationsOf(t, breached.Check("hunter2-but-longer", "")))
+		assert.NoError(t, breached.Check("hunter3-but-longer", ""))
+	})
+}
+
+func TestEstimateStrength(t *testing.T) {
+	inputs := emailInputs("bob.smith@example.com")
+
+	for _, tc := range []struct {
+		password string
+		maxScore int
+		feedback string
+	}{
+		{"password", 0, "avoid common passwords and words"},
+		{"P@ssw0rd", 0, "avoid common passwords and words"},
+		{"drowssap", 0, "avoid common passwords and words"},
+		{"abcdefgh", 0, "avoid sequences like abc or 123"},
+		{"aaaaaaaaaaaa", 0, "avoid repeated characters or words"},
+		{"sdfghjkl;'", 1, "avoid keyboard patterns like qwerty"},
+		{"smith1987", 1, ""},
+		{"examplebob", 2, "avoid your email address or name"},
+	} {
+		strength := EstimateStrength(tc.password, inputs)
+		assert.LessOrEqual(t, strength.Score, tc.maxScore, tc.password)
+		if tc.feedback != "" {
+			assert.Equal(t, tc.feedback, strength.Feedback, tc.password)
+		}
+	}
+
+	for _, password := range []string{"x7#kQ9!vLm2$", "correct horse battery staple", "Tr0ub4dour&3"} {
+		assert.Equal(t, 4, EstimateStrength(password, inputs).Score, password)
+	}
+
+	t.Run("should estimate long repetitive passwords quickly", func(t *testing.T) {
+		start := time.Now()
+		strength := EstimateStrength(strings.Repeat("ab1", 43), nil)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.LessOrEqual(t, strength.Score, 1)
+	})
+}
+
+func violationsOf(t *testing.T, err error) []string {
+	var policyErr *types.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected a policy error, got %v", err)
+	}
+	return policyErr.Violations
+}
@@ -0,0 +1,401 @@
+package passwordpolicy
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Strength is an estimate of how many guesses an attacker needs to find a
+// password, scored from 0 (guessed at once) to 4 (very hard to guess) like
+// zxcvbn does.
+type Strength struct {
+	Score    int
+	Guesses  float64
+	Feedback string
+}
+
+type matchKind int
+
+const (
+	matchBruteforce matchKind = iota
+	matchDictionary
+	matchUserInput
+	matchSequence
+	matchRepeat
+	matchSpatial
+	matchYear
+)
+
+// match is a part of the password, from i to j inclusive, that is cheaper
+// to guess than trying every character.
+type match struct {
+	i, j    int
+	kind    matchKind
+	guesses float64
+}
+
+const (
+	// bruteforceCardinality is the guesses per character that no pattern
+	// explains, as in zxcvbn.
+	bruteforceCardinality = 10
+	minMatchLength        = 3
+	minMatchGuesses       = 50
+)
+
+var (
+	// keyboardRows are the runs of neighbouring keys people type as
+	// passwords.
+	keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./", "qazwsxedcrfvtgbyhnujmikolp"}
+
+	// leet maps common character substitutions back to letters.
+	leet = map[rune]rune{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'}
+
+	commonRanks = rankWords(commonPasswords)
+)
+
+// EstimateStrength estimates the guesses needed for a password the way
+// zxcvbn does: it finds the common passwords and words, user inputs,
+// sequences, repeats, keyboard patterns and years in the password and picks
+// the cheapest way to cover it with them, guessing the rest character by
+// character. userInputs are words an attacker could know, like the email of
+// the user.
+func EstimateStrength(password string, userInputs []string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{Score: 0, Guesses: 1, Feedback: "enter a password"}
+	}
+
+	e := &estimator{userRanks: rankWords(userInputs), memo: make(map[string]float64)}
+	guesses, path := e.cover(runes)
+	strength := Strength{Score: score(guesses), Guesses: guesses}
+
+	// the feedback names the pattern that saves the attacker the most
+	weakest, saved := matchBruteforce, 0.0
+	for _, m := range path {
+		bruteforce := math.Pow(bruteforceCardinality, float64(m.j-m.i+1))
+		if s := bruteforce / m.guesses; m.kind != matchBruteforce && s > saved {
+			weakest, saved = m.kind, s
+		}
+	}
+	strength.Feedback = feedback(weakest)
+	return strength
+}
+
+type estimator struct {
+	userRanks map[string]int
+	// memo holds the guesses of the groups of repeats, which are estimated
+	// like passwords of their own
+	memo map[string]float64
+}
+
+// cover returns the fewest guesses for the password and the matches they
+// are made of.
+func (e *estimator) cover(runes []rune) (float64, []match) {
+	n := len(runes)
+	matches := e.findMatches(runes)
+
+	// best[k] are the fewest guesses for the first k characters, and last[k]
+	// the match that ends the cheapest cover of them
+	best := make([]float64, n+1)
+	last := make([]match, n+1)
+	best[0] = 1
+	for k := 1; k <= n; k++ {
+		best[k] = best[k-1] * bruteforceCardinality
+		last[k] = match{i: k - 1, j: k - 1, kind: matchBruteforce, guesses: bruteforceCardinality}
+		for _, m := range matches {
+			if m.j != k-1 {
+				continue
+			}
+			if guesses := best[m.i] * m.guesses; guesses < best[k] {
+				best[k] = guesses
+				last[k] = m
+			}
+		}
+	}
+
+	var path []match
+	for k := n; k > 0; k = last[k].i {
+		path = append(path, last[k])
+	}
+	return best[n], path
+}
+
+func (e *estimator) guesses(s string) float64 {
+	if guesses, ok := e.memo[s]; ok {
+		return guesses
+	}
+	guesses, _ := e.cover([]rune(s))
+	e.memo[s] = guesses
+	return guesses
+}
+
+func score(guesses float64) int {
+	switch {
+	case guesses < 1e3+5:
+		return 0
+	case guesses < 1e6+5:
+		return 1
+	case guesses < 1e8+5:
+		return 2
+	case guesses < 1e10+5:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func feedback(kind matchKind) string {
+	switch kind {
+	case matchDictionary:
+		return "avoid common passwords and words"
+	case matchUserInput:
+		return "avoid your email address or name"
+	case matchSequence:
+		return "avoid sequences like abc or 123"
+	case matchRepeat:
+		return "avoid repeated characters or words"
+	case matchSpatial:
+		return "avoid keyboard patterns like qwerty"
+	case matchYear:
+		return "avoid years"
+	default:
+		return "use a longer password or a few uncommon words"
+	}
+}
+
+func (e *estimator) findMatches(runes []rune) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, e.userRanks)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, e.repeatMatches(runes)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, yearMatches(runes)...)
+	// even the most obvious pattern takes a few guesses to try
+	for i := range matches {
+		matches[i].guesses = math.Max(matches[i].guesses, minMatchGuesses)
+	}
+	return matches
+}
+
+// dictionaryMatches finds common passwords and user inputs, also when they
+// are capitalized, spelled backwards or written with substitutions like
+// p4ssw0rd.
+func dictionaryMatches(runes []rune, userRanks map[string]int) []match {
+	var matches []match
+	for i := range runes {
+		for j := i + minMatchLength - 1; j < len(runes); j++ {
+			word := runes[i : j+1]
+			lower := strings.ToLower(string(word))
+			variations := uppercaseVariations(word)
+
+			candidates := []struct {
+				text       string
+				multiplier float64
+			}{
+				{lower, 1},
+				{reverse(lower), 2},
+			}
+			if unleeted, changed := unleet(lower); changed {
+				candidates = append(candidates, struct {
+					text       string
+					multiplier float64
+				}{unleeted, 2})
+			}
+
+			for _, c := range candidates {
+				if rank, ok := userRanks[c.text]; ok {
+					matches = append(matches, match{i: i, j: j, kind: matchUserInput, guesses: float64(rank) * variations * c.multiplier})
+				}
+				if rank, ok := commonRanks[c.text]; ok {
+					matches = append(matches, match{i: i, j: j, kind: matchDictionary, guesses: float64(rank) * variations * c.multiplier})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// sequenceMatches finds runs like abcd, 9876 or aceg, where each character
+// is the previous one moved by the same step.
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+minMatchLength <= len(runes); {
+		delta := runes[i+1] - runes[i]
+		if delta == 0 || delta > 5 || delta < -5 {
+			i++
+			continue
+		}
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta {
+			j++
+		}
+		if j-i+1 >= minMatchLength {
+			var base float64
+			switch first := unicode.ToLower(runes[i]); {
+			case strings.ContainsRune("az019", first):
+				base = 4
+			case unicode.IsDigit(first):
+				base = 10
+			default:
+				base = 26
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			matches = append(matches, match{i: i, j: j, kind: matchSequence, guesses: base * float64(j-i+1)})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+// repeatMatches finds characters or groups of characters that are repeated,
+// like aaaa or abcabc. Guessing them costs guessing the group once for each
+// possible number of repeats.
+func (e *estimator) repeatMatches(runes []rune) []match {
+	var matches []match
+	for i := range runes {
+		for size := 1; i+2*size <= len(runes); size++ {
+			count := 1
+			for end := i + (count+1)*size; end <= len(runes) && string(runes[i+count*size:end]) == string(runes[i:i+size]); end = i + (count+1)*size {
+				count++
+			}
+			if count < 2 || count*size < minMatchLength {
+				continue
+			}
+			base := e.guesses(string(runes[i : i+size]))
+			matches = append(matches, match{i: i, j: i + count*size - 1, kind: matchRepeat, guesses: base * float64(count)})
+		}
+	}
+	return matches
+}
+
+// spatialMatches finds runs of neighbouring keys, like qwerty or 7890, in
+// either direction.
+func spatialMatches(runes []rune) []match {
+	var matches []match
+	lower := []rune(strings.ToLower(string(runes)))
+	for i := range lower {
+		for j := i + minMatchLength - 1; j < len(lower); j++ {
+			run := string(lower[i : j+1])
+			for _, row := range keyboardRows {
+				if strings.Contains(row, run) || strings.Contains(reverse(row), run) {
+					matches = append(matches, match{i: i, j: j, kind: matchSpatial, guesses: 40 * float64(j-i+1)})
+					break
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// yearMatches finds years between 1900 and 2099, which are guessed around
+// the current year.
+func yearMatches(runes []rune) []match {
+	var matches []match
+	now := time.Now().Year()
+	for i := 0; i+4 <= len(runes); i++ {
+		year, err := strconv.Atoi(string(runes[i : i+4]))
+		if err != nil || year < 1900 || year > 2099 {
+			continue
+		}
+		distance := math.Abs(float64(year - now))
+		matches = append(matches, match{i: i, j: i + 3, kind: matchYear, guesses: math.Max(distance, 20)})
+	}
+	return matches
+}
+
+// uppercaseVariations is how many ways a word may be capitalized for the
+// capitalization it has. All lower, all upper and first upper are tried
+// first.
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0, upper == 1 && unicode.IsUpper(word[0]):
+		return 2
+	default:
+		variations := 0.0
+		for k := 1; k <= min(upper, lower); k++ {
+			variations += binomial(upper+lower, k)
+		}
+		return math.Max(variations, 2)
+	}
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+func unleet(s string) (string, bool) {
+	changed := false
+	unleeted := strings.Map(func(r rune) rune {
+		if letter, ok := leet[r]; ok {
+			changed = true
+			return letter
+		}
+		return r
+	}, s)
+	return unleeted, changed
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// rankWords ranks words by their position, the most common first. Words
+// shorter than a match are left out.
+func rankWords(words []string) map[string]int {
+	ranks := make(map[string]int, len(words))
+	for i, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if len([]rune(word)) < minMatchLength {
+			continue
+		}
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}
+
+// commonPasswords are the most common passwords and password words, most
+// common first.
+var commonPasswords = []string{
+	"password", "123456", "123456789", "qwerty", "12345678", "111111", "1234567890", "1234567", "12345",
+	"123123", "admin", "letmein", "welcome", "monkey", "dragon", "football", "iloveyou", "abc123",
+	"baseball", "master", "sunshine", "princess", "shadow", "login", "passw0rd", "trustno1", "superman",
+	"michael", "jennifer", "hunter", "ashley", "charlie", "jordan", "freedom", "whatever", "qazwsx",
+	"starwars", "mustang", "access", "batman", "secret", "summer", "winter", "spring", "autumn",
+	"flower", "hello", "computer", "pokemon", "killer", "soccer", "hockey", "ranger", "buster",
+	"thomas", "robert", "daniel", "andrew", "joshua", "matthew", "jessica", "pepper", "cheese",
+	"ginger", "orange", "banana", "cookie", "chocolate", "purple", "silver", "golden", "yellow",
+	"tigger", "maggie", "lovely", "angel", "family", "friends", "google", "internet", "samsung",
+	"apple", "america", "london", "qwertyuiop", "zaq12wsx", "asdfgh", "zxcvbn", "666666", "888888",
+	"000000", "121212", "654321", "7777777", "default", "changeme", "guest", "root", "test", "user",
+	"love", "blink182", "liverpool", "chelsea", "arsenal", "gotask", "task", "tasks", "todo",
+	"pass", "word", "letme", "monday", "friday", "january", "december", "dragons", "money", "music",
+	"nothing", "something", "forever", "hannah", "nicole", "jasmine", "justin", "diamond", "phoenix",
+}
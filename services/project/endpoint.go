@@ -0,0 +1,16 @@
+package project
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/project", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetProjects)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/project", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCreateProject)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/project/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetProject)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/project/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleDeleteProject)), h.userStore)).Methods(http.MethodDelete)
+}
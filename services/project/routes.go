@@ -0,0 +1,153 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.ProjectStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.ProjectStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// HandleGetProjects   get-projects
+//
+// @Summary     Get Projects
+// @Description Get all projects ordered by name
+// @Tags        Project
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.Project
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /project [get]
+func (h *Handler) handleGetProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := h.store.GetProjects()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, projects)
+}
+
+// HandleGetProject   get-project
+//
+// @Summary     Get Project by ID
+// @Description Get Project by ID
+// @Tags        Project
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Project ID"
+// @Success     200 {object} types.Project
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Router      /project/{id} [get]
+func (h *Handler) handleGetProject(w http.ResponseWriter, r *http.Request) {
+	projectID, err := projectIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	project, err := h.store.GetProjectByID(projectID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, project)
+}
+
+// HandleCreateProject   create-project
+//
+// @Summary     Create Project
+// @Description Create a project owned by the authenticated user
+// @Tags        Project
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       CreateProjectPayload body     types.CreateProjectPayload true "create project"
+// @Success     201                  {object} types.Project
+// @Failure     400                  {object} types.ErrorResponse
+// @Failure     401                  {object} types.ErrorResponse
+// @Failure     403                  {object} types.ErrorResponse
+// @Failure     500                  {object} types.ErrorResponse
+// @Router      /project [post]
+func (h *Handler) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateProjectPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	project := types.Project{Name: payload.Name, OwnerID: auth.GetUserIDFromContext(r.Context())}
+	id, err := h.store.CreateProject(project)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	project.ID = id
+	utils.WriteJSON(w, http.StatusCreated, project)
+}
+
+// HandleDeleteProject   delete-project
+//
+// @Summary     Delete Project
+// @Description Delete a project together with its custom fields, its tasks are kept without a project. Only the owner of the project or users with the workflow:manage permission may delete it.
+// @Tags        Project
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Project ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Router      /project/{id} [delete]
+func (h *Handler) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	projectID, err := projectIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	project, err := h.store.GetProjectByID(projectID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !auth.CanManageProject(r.Context(), project) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only the owner of the project can delete it"))
+		return
+	}
+
+	if err := h.store.DeleteProject(projectID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Project deleted successfully"})
+}
+
+func projectIDFromRequest(r *http.Request) (int, error) {
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, fmt.Errorf("invalid project ID")
+	}
+	return projectID, nil
+}
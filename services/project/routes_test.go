@@ -0,0 +1,108 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestProject(t *testing.T) {
+	store := &mockProjectStore{projects: map[int]types.Project{1: {ID: 1, Name: "Website", OwnerID: 1}}}
+	handler := NewHandler(store, &mockUserStore{roles: map[int]types.Role{3: types.RoleAdmin}})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("should create a project owned by the user", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateProjectPayload{Name: "Mobile app"})
+		req, err := http.NewRequest("POST", "/project", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		withAccessToken(t, router, 2).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 2, store.created.OwnerID)
+	})
+
+	t.Run("should fail without a name", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateProjectPayload{})
+		req, err := http.NewRequest("POST", "/project", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		withAccessToken(t, router, 2).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should only let the owner or an admin delete a project", func(t *testing.T) {
+		for userID, code := range map[int]int{2: http.StatusForbidden, 1: http.StatusOK, 3: http.StatusOK} {
+			req, err := http.NewRequest("DELETE", "/project/1", nil)
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			withAccessToken(t, router, userID).ServeHTTP(rr, req)
+
+			assert.Equal(t, code, rr.Code, userID)
+		}
+	})
+}
+
+// withAccessToken sends requests with an access token of the user, as the
+// project routes require one.
+func withAccessToken(t *testing.T, router *mux.Router, userID int) http.Handler {
+	token, err := auth.CreateAccessToken(userID, 0, types.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", token)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+type mockProjectStore struct {
+	projects map[int]types.Project
+	created  types.Project
+}
+
+func (m *mockProjectStore) GetProjects() ([]types.Project, error) {
+	return nil, nil
+}
+
+func (m *mockProjectStore) GetProjectByID(projectID int) (*types.Project, error) {
+	p, ok := m.projects[projectID]
+	if !ok {
+		return nil, errors.New("no project found with the given ID")
+	}
+	return &p, nil
+}
+
+func (m *mockProjectStore) CreateProject(p types.Project) (int, error) {
+	m.created = p
+	return 2, nil
+}
+
+func (m *mockProjectStore) DeleteProject(projectID int) error {
+	return nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	roles map[int]types.Role
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	role, ok := m.roles[id]
+	if !ok {
+		role = types.RoleMember
+	}
+	return &types.User{ID: id, Role: role}, nil
+}
@@ -0,0 +1,80 @@
+package project
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetProjects() ([]types.Project, error) {
+	rows, err := s.db.Query("SELECT * FROM projects ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]types.Project, 0)
+	for rows.Next() {
+		p, err := scanRowIntoProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, *p)
+	}
+	return projects, nil
+}
+
+func (s *Store) GetProjectByID(projectID int) (*types.Project, error) {
+	rows, err := s.db.Query("SELECT * FROM projects WHERE id = ?", projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return scanRowIntoProject(rows)
+	}
+	return nil, errors.New("no project found with the given ID")
+}
+
+func scanRowIntoProject(rows *sql.Rows) (*types.Project, error) {
+	p := new(types.Project)
+	if err := rows.Scan(&p.ID, &p.Name, &p.OwnerID, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Store) CreateProject(p types.Project) (int, error) {
+	res, err := s.db.Exec("INSERT INTO projects (name, owner_id) VALUES (?, ?)", p.Name, p.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *Store) DeleteProject(projectID int) error {
+	res, err := s.db.Exec("DELETE FROM projects WHERE id = ?", projectID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no project found with the given ID")
+	}
+	return nil
+}
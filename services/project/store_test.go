@@ -0,0 +1,52 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestCreateProject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectExec("INSERT INTO projects \\(name, owner_id\\) VALUES \\(\\?, \\?\\)").
+		WithArgs("Website", 3).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+
+	id, err := store.CreateProject(types.Project{Name: "Website", OwnerID: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != 4 {
+		t.Errorf("expected project ID 4, got %d", id)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetMissingProject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectQuery("SELECT \\* FROM projects WHERE id = ?").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id", "created_at"}))
+
+	if _, err := store.GetProjectByID(9); err == nil {
+		t.Error("expected an error for a missing project")
+	}
+}
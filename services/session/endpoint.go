@@ -0,0 +1,15 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/sessions", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleGetSessions), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/sessions/revoke-others", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleRevokeOtherSessions), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/sessions/{id}", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleRevokeSession), h.userStore)).Methods(http.MethodDelete)
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.SessionStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.SessionStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// HandleGetSessions   get-sessions
+//
+// @Summary     Get Sessions
+// @Description Get the active sessions of the authenticated user, most recently used first
+// @Tags        Session
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.Session
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /sessions [get]
+func (h *Handler) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	sessions, err := h.store.GetActiveSessions(userID, time.Now())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	current := auth.GetSessionIDFromContext(r.Context())
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	utils.WriteJSON(w, http.StatusOK, sessions)
+}
+
+// HandleRevokeSession   revoke-session
+//
+// @Summary     Revoke Session
+// @Description Revoke a session of the authenticated user, its refresh token stops working
+// @Tags        Session
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Session ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     404 {object} types.ErrorResponse
+// @Router      /sessions/{id} [delete]
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid session ID"))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.RevokeSession(userID, sessionID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Session revoked"})
+}
+
+// HandleRevokeOtherSessions   revoke-other-sessions
+//
+// @Summary     Log Out Everywhere Else
+// @Description Revoke every session of the authenticated user except the one making the request
+// @Tags        Session
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {object} map[string]int
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /sessions/revoke-others [post]
+func (h *Handler) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	current := auth.GetSessionIDFromContext(r.Context())
+	if current < 0 {
+		utils.WriteError(w, http.StatusBadRequest, errors.New("the token is not bound to a session, log in again"))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	revoked, err := h.store.RevokeOtherSessions(userID, current)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]int{"revoked": revoked})
+}
@@ -0,0 +1,76 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestSessions(t *testing.T) {
+	store := &mockSessionStore{sessions: []types.Session{
+		{ID: 1, UserID: 1, DeviceName: "laptop"},
+		{ID: 2, UserID: 1, DeviceName: "phone"},
+	}}
+	handler := NewHandler(store, nil)
+
+	withUser := func(req *http.Request, sessionID int) *http.Request {
+		ctx := context.WithValue(req.Context(), types.UserKey, 1)
+		if sessionID > 0 {
+			ctx = context.WithValue(ctx, types.SessionKey, sessionID)
+		}
+		return req.WithContext(ctx)
+	}
+
+	t.Run("should mark the current session", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/sessions", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleGetSessions(rr, withUser(req, 2))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var sessions []types.Session
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &sessions))
+		assert.False(t, sessions[0].Current)
+		assert.True(t, sessions[1].Current)
+	})
+
+	t.Run("should keep the current session when logging out everywhere else", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/sessions/revoke-others", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleRevokeOtherSessions(rr, withUser(req, 2))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 2, store.kept)
+	})
+
+	t.Run("should require a token bound to a session", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/sessions/revoke-others", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleRevokeOtherSessions(rr, withUser(req, 0))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+type mockSessionStore struct {
+	types.SessionStore
+	sessions []types.Session
+	kept     int
+}
+
+func (m *mockSessionStore) GetActiveSessions(userID int, now time.Time) ([]types.Session, error) {
+	return m.sessions, nil
+}
+
+func (m *mockSessionStore) RevokeOtherSessions(userID int, keepSessionID int) (int, error) {
+	m.kept = keepSessionID
+	return len(m.sessions) - 1, nil
+}
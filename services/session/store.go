@@ -0,0 +1,184 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateSession stores a new session without a usable refresh token. The
+// token carries the session ID, so it is only set afterwards through
+// RotateSession.
+func (s *Store) CreateSession(session types.Session) (int, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(
+		"INSERT INTO sessions (user_id, device_name, user_agent, ip, refresh_token, last_used_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		session.UserID, session.DeviceName, session.UserAgent, session.IP, "", now, now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetActiveSession returns a session that is neither revoked nor expired.
+func (s *Store) GetActiveSession(sessionID int, now time.Time) (*types.Session, error) {
+	sessions, err := s.querySessions(
+		"SELECT id, user_id, device_name, user_agent, ip, refresh_token, created_at, last_used_at, expires_at FROM sessions WHERE id = ? AND revoked_at IS NULL AND expires_at > ?",
+		sessionID, now.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, errors.New("session not found or expired")
+	}
+	return &sessions[0], nil
+}
+
+// GetActiveSessions returns the sessions of a user that are neither revoked
+// nor expired, most recently used first.
+func (s *Store) GetActiveSessions(userID int, now time.Time) ([]types.Session, error) {
+	return s.querySessions(
+		"SELECT id, user_id, device_name, user_agent, ip, refresh_token, created_at, last_used_at, expires_at FROM sessions WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ? ORDER BY last_used_at DESC",
+		userID, now.UTC(),
+	)
+}
+
+func (s *Store) querySessions(query string, args ...interface{}) ([]types.Session, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]types.Session, 0)
+	for rows.Next() {
+		session := types.Session{}
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.DeviceName,
+			&session.UserAgent,
+			&session.IP,
+			&session.RefreshToken,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// RotateSession makes tokenID the current refresh token of a session and
+// extends the session.
+func (s *Store) RotateSession(sessionID int, tokenID string, refreshTokenHash string, expiresAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"UPDATE sessions SET refresh_token = ?, last_used_at = ?, expires_at = ? WHERE id = ? AND revoked_at IS NULL",
+		refreshTokenHash, time.Now().UTC(), expiresAt.UTC(), sessionID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("session not found or revoked")
+	}
+
+	if _, err := tx.Exec("INSERT INTO refresh_tokens (id, session_id) VALUES (?, ?)", tokenID, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) RevokeSession(userID int, sessionID int) error {
+	res, err := s.db.Exec("UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL", time.Now().UTC(), sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no active session found with the given ID")
+	}
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of a user except the given one
+// and returns how many were revoked.
+func (s *Store) RevokeOtherSessions(userID int, keepSessionID int) (int, error) {
+	res, err := s.db.Exec("UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id != ? AND revoked_at IS NULL", time.Now().UTC(), userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(affected), nil
+}
+
+func (s *Store) GetRefreshToken(tokenID string) (*types.RefreshToken, error) {
+	rows, err := s.db.Query("SELECT id, session_id, created_at, rotated_at FROM refresh_tokens WHERE id = ?", tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, errors.New("unknown refresh token")
+	}
+	t := new(types.RefreshToken)
+	if err := rows.Scan(&t.ID, &t.SessionID, &t.CreatedAt, &t.RotatedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// MarkRefreshTokenRotated marks a refresh token as used. It reports false
+// when the token was already rotated, so of two concurrent uses of the same
+// token only one succeeds.
+func (s *Store) MarkRefreshTokenRotated(tokenID string) (bool, error) {
+	res, err := s.db.Exec("UPDATE refresh_tokens SET rotated_at = ? WHERE id = ? AND rotated_at IS NULL", time.Now().UTC(), tokenID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) RecordSecurityEvent(e types.SecurityEvent) error {
+	_, err := s.db.Exec(
+		"INSERT INTO security_events (user_id, type, session_id, ip, user_agent, message) VALUES (?, ?, ?, ?, ?, ?)",
+		e.UserID, e.Type, e.SessionID, e.IP, e.UserAgent, e.Message,
+	)
+	return err
+}
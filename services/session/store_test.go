@@ -0,0 +1,141 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var sessionColumns = []string{"id", "user_id", "device_name", "user_agent", "ip", "refresh_token", "created_at", "last_used_at", "expires_at"}
+
+func TestGetActiveSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(sessionColumns).
+		AddRow(3, 1, "laptop", "test-agent", "203.0.113.7", "hash", "2026-10-01", "2026-10-19", "2026-11-18")
+	mock.ExpectQuery("SELECT (.+) FROM sessions WHERE id = \\? AND revoked_at IS NULL AND expires_at > \\?").
+		WithArgs(3, now).
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.+) FROM sessions WHERE id = \\?").
+		WithArgs(4, now).
+		WillReturnRows(sqlmock.NewRows(sessionColumns))
+
+	session, err := store.GetActiveSession(3, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session.ID != 3 || session.DeviceName != "laptop" || session.RefreshToken != "hash" {
+		t.Errorf("unexpected session: %+v", session)
+	}
+
+	if _, err := store.GetActiveSession(4, now); err == nil {
+		t.Error("expected an error for a revoked or expired session")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRotateSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	expiresAt := time.Date(2026, 11, 18, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should record the new refresh token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE sessions SET refresh_token = \\?, last_used_at = \\?, expires_at = \\? WHERE id = \\? AND revoked_at IS NULL").
+			WithArgs("hash", sqlmock.AnyArg(), expiresAt, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO refresh_tokens \\(id, session_id\\) VALUES \\(\\?, \\?\\)").
+			WithArgs("jti", 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := store.RotateSession(3, "jti", "hash", expiresAt); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should fail if the session is revoked", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE sessions SET refresh_token = \\?, last_used_at = \\?, expires_at = \\? WHERE id = \\? AND revoked_at IS NULL").
+			WithArgs("hash", sqlmock.AnyArg(), expiresAt, 3).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		if err := store.RotateSession(3, "jti", "hash", expiresAt); err == nil {
+			t.Error("expected an error when rotating a revoked session")
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMarkRefreshTokenRotated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	mock.ExpectExec("UPDATE refresh_tokens SET rotated_at = \\? WHERE id = \\? AND rotated_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), "jti").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE refresh_tokens SET rotated_at = \\? WHERE id = \\? AND rotated_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), "jti").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rotated, err := store.MarkRefreshTokenRotated("jti")
+	if err != nil || !rotated {
+		t.Errorf("expected the token to be rotated, got %v, %v", rotated, err)
+	}
+	rotated, err = store.MarkRefreshTokenRotated("jti")
+	if err != nil || rotated {
+		t.Errorf("expected a second rotation to be refused, got %v, %v", rotated, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	mock.ExpectExec("UPDATE sessions SET revoked_at = \\? WHERE user_id = \\? AND id != \\? AND revoked_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), 1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	revoked, err := store.RevokeOtherSessions(1, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("expected 2 revoked sessions, got %d", revoked)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
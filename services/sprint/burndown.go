@@ -0,0 +1,67 @@
+package sprint
+
+import (
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// ComputeBurndown returns, for every day of the sprint up to now, the number of
+// tasks that were scheduled into the sprint and not yet completed at the end
+// of that day. Tasks carried over when the sprint closed still count for the
+// whole sprint; tasks removed earlier stop counting from their removal.
+func ComputeBurndown(sprint types.Sprint, memberships []types.SprintMembership, changes []types.TaskStatusChange, now time.Time) (*types.Burndown, error) {
+	start, err := time.ParseInLocation(types.SprintDateLayout, sprint.StartDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.ParseInLocation(types.SprintDateLayout, sprint.EndDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[int][]types.TaskStatusChange)
+	for _, c := range changes {
+		history[c.TaskID] = append(history[c.TaskID], c)
+	}
+
+	total := make(map[int]bool)
+	points := make([]types.BurndownPoint, 0)
+	for day := start; !day.After(end) && !day.After(now); day = day.AddDate(0, 0, 1) {
+		cutoff := day.AddDate(0, 0, 1)
+		scheduled := make(map[int]bool)
+		for _, m := range memberships {
+			if !m.AddedAt.Before(cutoff) {
+				continue
+			}
+			if m.RemovedAt != nil && m.RemovedAt.Before(cutoff) {
+				continue
+			}
+			scheduled[m.TaskID] = true
+		}
+
+		remaining := 0
+		for taskID := range scheduled {
+			total[taskID] = true
+			if statusAt(history[taskID], cutoff) != types.StatusCompleted {
+				remaining++
+			}
+		}
+		points = append(points, types.BurndownPoint{Date: day.Format(types.SprintDateLayout), Remaining: remaining})
+	}
+
+	return &types.Burndown{SprintID: sprint.ID, Total: len(total), Points: points}, nil
+}
+
+// statusAt returns the last status recorded before the cutoff, or an empty
+// status when the task had no recorded change by then.
+func statusAt(changes []types.TaskStatusChange, cutoff time.Time) types.TaskStatus {
+	var status types.TaskStatus
+	for _, c := range changes {
+		if !c.ChangedAt.Before(cutoff) {
+			break
+		}
+		status = c.Status
+	}
+	return status
+}
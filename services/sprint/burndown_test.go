@@ -0,0 +1,51 @@
+package sprint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestComputeBurndown(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2026, time.March, d, h, 0, 0, 0, time.UTC)
+	}
+	removed := day(3, 12)
+	carried := day(6, 9)
+
+	sprint := types.Sprint{ID: 1, StartDate: "2026-03-02", EndDate: "2026-03-05"}
+	memberships := []types.SprintMembership{
+		{TaskID: 1, AddedAt: day(1, 10)},
+		{TaskID: 2, AddedAt: day(1, 10), RemovedAt: &carried},
+		{TaskID: 3, AddedAt: day(1, 10), RemovedAt: &removed},
+		{TaskID: 4, AddedAt: day(3, 15)},
+	}
+	changes := []types.TaskStatusChange{
+		{TaskID: 1, Status: types.StatusPending, ChangedAt: day(1, 10)},
+		{TaskID: 1, Status: types.StatusInProgress, ChangedAt: day(2, 11)},
+		{TaskID: 1, Status: types.StatusCompleted, ChangedAt: day(4, 16)},
+		{TaskID: 4, Status: types.StatusCompleted, ChangedAt: day(5, 10)},
+	}
+
+	burndown, err := ComputeBurndown(sprint, memberships, changes, day(10, 0))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 4, burndown.Total)
+	assert.Equal(t, []types.BurndownPoint{
+		{Date: "2026-03-02", Remaining: 3},
+		{Date: "2026-03-03", Remaining: 3},
+		{Date: "2026-03-04", Remaining: 2},
+		{Date: "2026-03-05", Remaining: 1},
+	}, burndown.Points)
+}
+
+func TestComputeBurndownStopsAtNow(t *testing.T) {
+	sprint := types.Sprint{ID: 1, StartDate: "2026-03-02", EndDate: "2026-03-15"}
+
+	burndown, err := ComputeBurndown(sprint, nil, nil, time.Date(2026, time.March, 4, 8, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	assert.Len(t, burndown.Points, 3)
+}
@@ -0,0 +1,21 @@
+package sprint
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/sprint", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetSprints)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/sprint", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCreateSprint)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/sprint/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetSprint)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/sprint/{id}/start", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleStartSprint)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/sprint/{id}/close", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCloseSprint)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/sprint/{id}/tasks", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetSprintTasks)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/sprint/{id}/tasks", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleAddSprintTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/sprint/{id}/tasks/{taskID}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleRemoveSprintTask)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/sprint/{id}/burndown", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetBurndown)), h.userStore)).Methods(http.MethodGet)
+}
@@ -0,0 +1,423 @@
+package sprint
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.SprintStore
+	taskStore types.TaskStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.SprintStore, taskStore types.TaskStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, taskStore: taskStore, userStore: userStore}
+}
+
+// HandleGetSprints   get-sprints
+//
+// @Summary     Get Sprints
+// @Description Get all sprints ordered by start date
+// @Tags        Sprint
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.Sprint
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /sprint [get]
+func (h *Handler) handleGetSprints(w http.ResponseWriter, r *http.Request) {
+	sprints, err := h.store.GetSprints()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, sprints)
+}
+
+// HandleGetSprint   get-sprint
+//
+// @Summary     Get Sprint by ID
+// @Description Get Sprint by ID
+// @Tags        Sprint
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Sprint ID"
+// @Success     200 {object} types.Sprint
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Router      /sprint/{id} [get]
+func (h *Handler) handleGetSprint(w http.ResponseWriter, r *http.Request) {
+	sprintID, err := sprintIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sprint, err := h.store.GetSprintByID(sprintID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, sprint)
+}
+
+// HandleCreateSprint   create-sprint
+//
+// @Summary     Create Sprint
+// @Description Create a planned sprint, dates use the YYYY-MM-DD format
+// @Tags        Sprint
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       CreateSprintPayload body     types.CreateSprintPayload true "create sprint"
+// @Success     201                 {object} types.Sprint
+// @Failure     400                 {object} types.ErrorResponse
+// @Failure     401                 {object} types.ErrorResponse
+// @Failure     403                 {object} types.ErrorResponse
+// @Failure     500                 {object} types.ErrorResponse
+// @Router      /sprint [post]
+func (h *Handler) handleCreateSprint(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateSprintPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if err := validateSprintDates(payload.StartDate, payload.EndDate); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sprintID, err := h.store.CreateSprint(types.Sprint{
+		Name:      payload.Name,
+		Goal:      payload.Goal,
+		StartDate: payload.StartDate,
+		EndDate:   payload.EndDate,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sprint, err := h.store.GetSprintByID(sprintID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, sprint)
+}
+
+// HandleStartSprint   start-sprint
+//
+// @Summary     Start Sprint
+// @Description Activate a planned sprint, only one sprint can be active at a time
+// @Tags        Sprint
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Sprint ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     409 {object} types.ErrorResponse
+// @Router      /sprint/{id}/start [post]
+func (h *Handler) handleStartSprint(w http.ResponseWriter, r *http.Request) {
+	sprintID, err := sprintIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.store.GetSprintByID(sprintID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.StartSprint(sprintID); err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Sprint %d started", sprintID)})
+}
+
+// HandleCloseSprint   close-sprint
+//
+// @Summary     Close Sprint
+// @Description Close an active sprint and carry unfinished tasks to the given or the next planned sprint
+// @Tags        Sprint
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                 path     int                      true  "Sprint ID"
+// @Param       CloseSprintPayload body     types.CloseSprintPayload false "Sprint receiving unfinished tasks"
+// @Success     200                {object} types.CloseSprintResult
+// @Failure     400                {object} types.ErrorResponse
+// @Failure     401                {object} types.ErrorResponse
+// @Failure     403                {object} types.ErrorResponse
+// @Failure     409                {object} types.ErrorResponse
+// @Router      /sprint/{id}/close [post]
+func (h *Handler) handleCloseSprint(w http.ResponseWriter, r *http.Request) {
+	sprintID, err := sprintIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.CloseSprintPayload
+	if r.ContentLength > 0 {
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := utils.Validate.Struct(payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+			return
+		}
+	}
+
+	if _, err := h.store.GetSprintByID(sprintID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if payload.NextSprintID != nil {
+		if *payload.NextSprintID == sprintID {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unfinished tasks cannot be carried into the closed sprint"))
+			return
+		}
+		next, err := h.store.GetSprintByID(*payload.NextSprintID)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if next.Status == types.SprintClosed {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unfinished tasks cannot be carried into a closed sprint"))
+			return
+		}
+	}
+
+	result, err := h.store.CloseSprint(sprintID, payload.NextSprintID)
+	if err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, result)
+}
+
+// HandleGetSprintTasks   get-sprint-tasks
+//
+// @Summary     Get Sprint Tasks
+// @Description Get the tasks currently scheduled into a sprint
+// @Tags        Sprint
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Sprint ID"
+// @Success     200 {array}  types.Task
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /sprint/{id}/tasks [get]
+func (h *Handler) handleGetSprintTasks(w http.ResponseWriter, r *http.Request) {
+	sprintID, err := sprintIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	taskIDs, err := h.store.GetSprintTaskIDs(sprintID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tasks := make([]types.Task, 0, len(taskIDs))
+	for _, taskID := range taskIDs {
+		t, err := h.taskStore.GetTaskByID(taskID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		tasks = append(tasks, *t)
+	}
+	utils.WriteJSON(w, http.StatusOK, tasks)
+}
+
+// HandleAddSprintTask   add-sprint-task
+//
+// @Summary     Schedule Task into Sprint
+// @Description Schedule a task into a sprint, moving it out of any other sprint
+// @Tags        Sprint
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                path     int                     true "Sprint ID"
+// @Param       SprintTaskPayload body     types.SprintTaskPayload true "Task to schedule"
+// @Success     200               {object} string
+// @Failure     400               {object} types.ErrorResponse
+// @Failure     401               {object} types.ErrorResponse
+// @Failure     403               {object} types.ErrorResponse
+// @Failure     500               {object} types.ErrorResponse
+// @Router      /sprint/{id}/tasks [post]
+func (h *Handler) handleAddSprintTask(w http.ResponseWriter, r *http.Request) {
+	sprintID, err := sprintIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.SprintTaskPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	sprint, err := h.store.GetSprintByID(sprintID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if sprint.Status == types.SprintClosed {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("tasks cannot be scheduled into a closed sprint"))
+		return
+	}
+
+	if _, err := h.taskStore.GetTaskByID(payload.TaskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.AddTaskToSprint(sprintID, payload.TaskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d scheduled into sprint %d", payload.TaskID, sprintID)})
+}
+
+// HandleRemoveSprintTask   remove-sprint-task
+//
+// @Summary     Remove Task from Sprint
+// @Description Take a task out of a sprint
+// @Tags        Sprint
+// @Produce     json
+// @Security    jwtKey
+// @Param       id     path     int true "Sprint ID"
+// @Param       taskID path     int true "Task ID"
+// @Success     200    {object} string
+// @Failure     400    {object} types.ErrorResponse
+// @Failure     401    {object} types.ErrorResponse
+// @Failure     403    {object} types.ErrorResponse
+// @Router      /sprint/{id}/tasks/{taskID} [delete]
+func (h *Handler) handleRemoveSprintTask(w http.ResponseWriter, r *http.Request) {
+	sprintID, err := sprintIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	taskID, err := strconv.Atoi(mux.Vars(r)["taskID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid task ID"))
+		return
+	}
+
+	if err := h.store.RemoveTaskFromSprint(sprintID, taskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d removed from sprint %d", taskID, sprintID)})
+}
+
+// HandleGetBurndown   get-sprint-burndown
+//
+// @Summary     Get Sprint Burndown
+// @Description Daily count of open tasks in the sprint, computed from recorded status changes
+// @Tags        Sprint
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Sprint ID"
+// @Success     200 {object} types.Burndown
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /sprint/{id}/burndown [get]
+func (h *Handler) handleGetBurndown(w http.ResponseWriter, r *http.Request) {
+	sprintID, err := sprintIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sprint, err := h.store.GetSprintByID(sprintID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	memberships, err := h.store.GetSprintMemberships(sprintID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	taskIDs := make([]int, 0, len(memberships))
+	for _, m := range memberships {
+		taskIDs = append(taskIDs, m.TaskID)
+	}
+	changes, err := h.store.GetStatusChanges(taskIDs)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	burndown, err := ComputeBurndown(*sprint, memberships, changes, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, burndown)
+}
+
+func sprintIDFromRequest(r *http.Request) (int, error) {
+	sprintID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, fmt.Errorf("invalid sprint ID")
+	}
+	return sprintID, nil
+}
+
+func validateSprintDates(startDate, endDate string) error {
+	start, err := time.Parse(types.SprintDateLayout, startDate)
+	if err != nil {
+		return fmt.Errorf("invalid start_date, expected YYYY-MM-DD")
+	}
+	end, err := time.Parse(types.SprintDateLayout, endDate)
+	if err != nil {
+		return fmt.Errorf("invalid end_date, expected YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return fmt.Errorf("end_date must not be before start_date")
+	}
+	return nil
+}
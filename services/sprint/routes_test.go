@@ -0,0 +1,167 @@
+package sprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestSprint(t *testing.T) {
+	sprintStore := &mockSprintStore{}
+	handler := NewHandler(sprintStore, nil, &mockUserStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	t.Run("should create a sprint with valid payload", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateSprintPayload{Name: "Sprint 1", StartDate: "2026-03-02", EndDate: "2026-03-15"})
+		req, err := http.NewRequest("POST", "/sprint", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("should fail when the sprint ends before it starts", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateSprintPayload{Name: "Sprint 1", StartDate: "2026-03-15", EndDate: "2026-03-02"})
+		req, err := http.NewRequest("POST", "/sprint", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should fail to carry tasks into the sprint being closed", func(t *testing.T) {
+		next := 1
+		payloadJSON, _ := json.Marshal(types.CloseSprintPayload{NextSprintID: &next})
+		req, err := http.NewRequest("POST", "/sprint/1/close", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should close a sprint without payload", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/sprint/1/close", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []int{1}, sprintStore.closed)
+	})
+}
+
+func TestSprintPermissions(t *testing.T) {
+	handler := NewHandler(&mockSprintStore{}, nil, &mockUserStore{roles: map[int]types.Role{2: types.RoleViewer}})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("should require a token", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/sprint", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should let viewers read but not write", func(t *testing.T) {
+		server := withAccessToken(t, router, 2)
+		req, err := http.NewRequest("GET", "/sprint", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		payloadJSON, _ := json.Marshal(types.CreateSprintPayload{Name: "Sprint 1", StartDate: "2026-03-02", EndDate: "2026-03-15"})
+		req, err = http.NewRequest("POST", "/sprint", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr = httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+// withAccessToken sends requests without an Authorization header with an
+// access token of the user, as the sprint routes require one.
+func withAccessToken(t *testing.T, router *mux.Router, userID int) http.Handler {
+	token, err := auth.CreateAccessToken(userID, 0, types.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", token)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+type mockSprintStore struct {
+	closed []int
+}
+
+func (m *mockSprintStore) GetSprints() ([]types.Sprint, error) {
+	return nil, nil
+}
+
+func (m *mockSprintStore) GetSprintByID(sprintID int) (*types.Sprint, error) {
+	return &types.Sprint{ID: sprintID, StartDate: "2026-03-02", EndDate: "2026-03-15", Status: types.SprintActive}, nil
+}
+
+func (m *mockSprintStore) CreateSprint(types.Sprint) (int, error) {
+	return 1, nil
+}
+
+func (m *mockSprintStore) StartSprint(sprintID int) error {
+	return nil
+}
+
+func (m *mockSprintStore) CloseSprint(sprintID int, nextSprintID *int) (*types.CloseSprintResult, error) {
+	m.closed = append(m.closed, sprintID)
+	return &types.CloseSprintResult{CarriedTaskIDs: []int{}}, nil
+}
+
+func (m *mockSprintStore) GetSprintTaskIDs(sprintID int) ([]int, error) {
+	return nil, nil
+}
+
+func (m *mockSprintStore) AddTaskToSprint(sprintID int, taskID int) error {
+	return nil
+}
+
+func (m *mockSprintStore) RemoveTaskFromSprint(sprintID int, taskID int) error {
+	return nil
+}
+
+func (m *mockSprintStore) GetSprintMemberships(sprintID int) ([]types.SprintMembership, error) {
+	return nil, nil
+}
+
+func (m *mockSprintStore) GetStatusChanges(taskIDs []int) ([]types.TaskStatusChange, error) {
+	return nil, nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	roles map[int]types.Role
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	role, ok := m.roles[id]
+	if !ok {
+		role = types.RoleMember
+	}
+	return &types.User{ID: id, Role: role}, nil
+}
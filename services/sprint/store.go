@@ -0,0 +1,282 @@
+package sprint
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetSprints() ([]types.Sprint, error) {
+	rows, err := s.db.Query("SELECT * FROM sprints ORDER BY start_date")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sprints := make([]types.Sprint, 0)
+	for rows.Next() {
+		sp, err := scanRowIntoSprint(rows)
+		if err != nil {
+			return nil, err
+		}
+		sprints = append(sprints, *sp)
+	}
+	return sprints, nil
+}
+
+func (s *Store) GetSprintByID(sprintID int) (*types.Sprint, error) {
+	rows, err := s.db.Query("SELECT * FROM sprints WHERE id = ?", sprintID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		return scanRowIntoSprint(rows)
+	}
+	return nil, errors.New("no sprint found with the given ID")
+}
+
+func scanRowIntoSprint(rows *sql.Rows) (*types.Sprint, error) {
+	sp := new(types.Sprint)
+	var start, end time.Time
+	err := rows.Scan(&sp.ID, &sp.Name, &sp.Goal, &start, &end, &sp.Status, &sp.CreatedAt, &sp.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	sp.StartDate = start.Format(types.SprintDateLayout)
+	sp.EndDate = end.Format(types.SprintDateLayout)
+	return sp, nil
+}
+
+func (s *Store) CreateSprint(sp types.Sprint) (int, error) {
+	res, err := s.db.Exec("INSERT INTO sprints (name, goal, start_date, end_date) VALUES (?, ?, ?, ?)", sp.Name, sp.Goal, sp.StartDate, sp.EndDate)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// StartSprint activates a planned sprint. Only one sprint can be active at a time.
+func (s *Store) StartSprint(sprintID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var active int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM sprints WHERE status = ?", types.SprintActive).Scan(&active); err != nil {
+		return err
+	}
+	if active > 0 {
+		return errors.New("another sprint is already active")
+	}
+
+	res, err := tx.Exec("UPDATE sprints SET status = ?, updated_at = ? WHERE id = ? AND status = ?", types.SprintActive, time.Now(), sprintID, types.SprintPlanned)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("only planned sprints can be started")
+	}
+	return tx.Commit()
+}
+
+// CloseSprint closes an active sprint and carries its unfinished tasks over to
+// the next sprint. When nextSprintID is nil the earliest planned sprint is
+// used; if there is none the tasks are left unscheduled.
+func (s *Store) CloseSprint(sprintID int, nextSprintID *int) (*types.CloseSprintResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.Exec("UPDATE sprints SET status = ?, updated_at = ? WHERE id = ? AND status = ?", types.SprintClosed, now, sprintID, types.SprintActive)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, errors.New("only active sprints can be closed")
+	}
+
+	if nextSprintID == nil {
+		var id int
+		err := tx.QueryRow("SELECT id FROM sprints WHERE status = ? ORDER BY start_date LIMIT 1", types.SprintPlanned).Scan(&id)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == nil {
+			nextSprintID = &id
+		}
+	}
+
+	rows, err := tx.Query(`SELECT st.task_id FROM sprint_tasks st
+		JOIN tasks t ON t.id = st.task_id
+		WHERE st.sprint_id = ? AND st.removed_at IS NULL AND t.status <> ?`, sprintID, types.StatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+	carried := make([]int, 0)
+	for rows.Next() {
+		var taskID int
+		if err := rows.Scan(&taskID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		carried = append(carried, taskID)
+	}
+	rows.Close()
+
+	for _, taskID := range carried {
+		if _, err := tx.Exec("UPDATE sprint_tasks SET removed_at = ? WHERE sprint_id = ? AND task_id = ? AND removed_at IS NULL", now, sprintID, taskID); err != nil {
+			return nil, err
+		}
+		if nextSprintID != nil {
+			if _, err := tx.Exec("INSERT INTO sprint_tasks (sprint_id, task_id) VALUES (?, ?)", *nextSprintID, taskID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &types.CloseSprintResult{CarriedTaskIDs: carried, NextSprintID: nextSprintID}, nil
+}
+
+// GetSprintTaskIDs returns the tasks currently scheduled into a sprint.
+func (s *Store) GetSprintTaskIDs(sprintID int) ([]int, error) {
+	rows, err := s.db.Query("SELECT task_id FROM sprint_tasks WHERE sprint_id = ? AND removed_at IS NULL ORDER BY task_id", sprintID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// AddTaskToSprint schedules a task into a sprint, taking it out of any other
+// sprint it was scheduled into.
+func (s *Store) AddTaskToSprint(sprintID int, taskID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRow("SELECT COUNT(*) FROM sprint_tasks WHERE sprint_id = ? AND task_id = ? AND removed_at IS NULL", sprintID, taskID).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if current > 0 {
+		return errors.New("task is already scheduled into this sprint")
+	}
+
+	if _, err := tx.Exec("UPDATE sprint_tasks SET removed_at = ? WHERE task_id = ? AND removed_at IS NULL", time.Now(), taskID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO sprint_tasks (sprint_id, task_id) VALUES (?, ?)", sprintID, taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) RemoveTaskFromSprint(sprintID int, taskID int) error {
+	res, err := s.db.Exec("UPDATE sprint_tasks SET removed_at = ? WHERE sprint_id = ? AND task_id = ? AND removed_at IS NULL", time.Now(), sprintID, taskID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("task is not scheduled into this sprint")
+	}
+	return nil
+}
+
+// GetSprintMemberships returns every task that has ever been scheduled into a sprint.
+func (s *Store) GetSprintMemberships(sprintID int) ([]types.SprintMembership, error) {
+	rows, err := s.db.Query("SELECT task_id, added_at, removed_at FROM sprint_tasks WHERE sprint_id = ?", sprintID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := make([]types.SprintMembership, 0)
+	for rows.Next() {
+		m := types.SprintMembership{}
+		var removedAt sql.NullTime
+		if err := rows.Scan(&m.TaskID, &m.AddedAt, &removedAt); err != nil {
+			return nil, err
+		}
+		if removedAt.Valid {
+			m.RemovedAt = &removedAt.Time
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, nil
+}
+
+// GetStatusChanges returns the recorded status history of the given tasks,
+// oldest first.
+func (s *Store) GetStatusChanges(taskIDs []int) ([]types.TaskStatusChange, error) {
+	changes := make([]types.TaskStatusChange, 0)
+	if len(taskIDs) == 0 {
+		return changes, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(taskIDs)), ", ")
+	args := make([]interface{}, 0, len(taskIDs))
+	for _, id := range taskIDs {
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf("SELECT task_id, status, changed_at FROM task_status_history WHERE task_id IN (%s) ORDER BY changed_at, id", placeholders)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := types.TaskStatusChange{}
+		if err := rows.Scan(&c.TaskID, &c.Status, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
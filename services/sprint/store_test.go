@@ -0,0 +1,127 @@
+package sprint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestGetSprintByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now().Format(time.RFC3339)
+
+	mock.ExpectQuery("SELECT \\* FROM sprints WHERE id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "goal", "start_date", "end_date", "status", "created_at", "updated_at"}).
+			AddRow(1, "Sprint 1", "Ship it", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), types.SprintPlanned, now, now))
+
+	sprint, err := store.GetSprintByID(1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if sprint.StartDate != "2026-03-02" || sprint.EndDate != "2026-03-15" || sprint.Status != types.SprintPlanned {
+		t.Errorf("unexpected sprint %+v", sprint)
+	}
+}
+
+func TestStartSprintFailsWhenAnotherIsActive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM sprints WHERE status = ?").
+		WithArgs(types.SprintActive).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	if err := store.StartSprint(2); err == nil {
+		t.Error("expected an error when another sprint is active")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCloseSprintCarriesUnfinishedTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE sprints SET status = \\?, updated_at = \\? WHERE id = \\? AND status = \\?").
+		WithArgs(types.SprintClosed, sqlmock.AnyArg(), 1, types.SprintActive).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id FROM sprints WHERE status = \\? ORDER BY start_date LIMIT 1").
+		WithArgs(types.SprintPlanned).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("SELECT st.task_id FROM sprint_tasks st").
+		WithArgs(1, types.StatusCompleted).
+		WillReturnRows(sqlmock.NewRows([]string{"task_id"}).AddRow(7))
+	mock.ExpectExec("UPDATE sprint_tasks SET removed_at = \\?").
+		WithArgs(sqlmock.AnyArg(), 1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sprint_tasks \\(sprint_id, task_id\\) VALUES \\(\\?, \\?\\)").
+		WithArgs(2, 7).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	result, err := store.CloseSprint(1, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if len(result.CarriedTaskIDs) != 1 || result.CarriedTaskIDs[0] != 7 || result.NextSprintID == nil || *result.NextSprintID != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetStatusChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	changedAt := time.Now()
+
+	mock.ExpectQuery("SELECT task_id, status, changed_at FROM task_status_history WHERE task_id IN \\(\\?, \\?\\)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"task_id", "status", "changed_at"}).
+			AddRow(1, types.StatusCompleted, changedAt))
+
+	changes, err := store.GetStatusChanges([]int{1, 2})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if len(changes) != 1 || changes[0].Status != types.StatusCompleted {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+}
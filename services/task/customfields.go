@@ -0,0 +1,289 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+const (
+	customFieldParamPrefix = "cf."
+	maxCustomTextLength    = 1000
+)
+
+// normalizeCustomFieldValue validates a raw JSON value against the type of the
+// field and returns its canonical JSON encoding for storage.
+func (h *Handler) normalizeCustomFieldValue(field types.CustomField, raw json.RawMessage) (string, error) {
+	var value interface{}
+	switch field.Type {
+	case types.CustomFieldText:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", fmt.Errorf("%s must be a string", field.Name)
+		}
+		if len(s) > maxCustomTextLength {
+			return "", fmt.Errorf("%s must be at most %d characters", field.Name, maxCustomTextLength)
+		}
+		value = s
+	case types.CustomFieldNumber:
+		var n float64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return "", fmt.Errorf("%s must be a number", field.Name)
+		}
+		value = n
+	case types.CustomFieldDate:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", fmt.Errorf("%s must be a date formatted as YYYY-MM-DD", field.Name)
+		}
+		if _, err := time.Parse(types.CustomFieldDateLayout, s); err != nil {
+			return "", fmt.Errorf("%s must be a date formatted as YYYY-MM-DD", field.Name)
+		}
+		value = s
+	case types.CustomFieldSelect:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil || !hasOption(field, s) {
+			return "", fmt.Errorf("%s must be one of %s", field.Name, strings.Join(field.Options, ", "))
+		}
+		value = s
+	case types.CustomFieldMultiSelect:
+		var selected []string
+		if err := json.Unmarshal(raw, &selected); err != nil {
+			return "", fmt.Errorf("%s must be a list of options", field.Name)
+		}
+		seen := make(map[string]bool)
+		options := make([]string, 0, len(selected))
+		for _, s := range selected {
+			if !hasOption(field, s) {
+				return "", fmt.Errorf("%s must only contain %s", field.Name, strings.Join(field.Options, ", "))
+			}
+			if !seen[s] {
+				seen[s] = true
+				options = append(options, s)
+			}
+		}
+		value = options
+	case types.CustomFieldUser:
+		var userID int
+		if err := json.Unmarshal(raw, &userID); err != nil {
+			return "", fmt.Errorf("%s must be a user ID", field.Name)
+		}
+		if _, err := h.userStore.GetUserByID(userID); err != nil {
+			return "", fmt.Errorf("%s refers to an unknown user", field.Name)
+		}
+		value = userID
+	default:
+		return "", fmt.Errorf("%s has unsupported type %s", field.Name, field.Type)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func hasOption(field types.CustomField, option string) bool {
+	for _, o := range field.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeCustomFieldValue turns a stored value back into the Go value used in
+// the task JSON.
+func decodeCustomFieldValue(field types.CustomField, stored string) (interface{}, error) {
+	var err error
+	switch field.Type {
+	case types.CustomFieldNumber:
+		var n float64
+		err = json.Unmarshal([]byte(stored), &n)
+		return n, err
+	case types.CustomFieldMultiSelect:
+		var options []string
+		err = json.Unmarshal([]byte(stored), &options)
+		return options, err
+	case types.CustomFieldUser:
+		var userID int
+		err = json.Unmarshal([]byte(stored), &userID)
+		return userID, err
+	default:
+		var s string
+		err = json.Unmarshal([]byte(stored), &s)
+		return s, err
+	}
+}
+
+// withCustomFields fills the custom field values of the given tasks.
+func (h *Handler) withCustomFields(tasks []types.Task, fields []types.CustomField) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]types.CustomField, len(fields))
+	for _, f := range fields {
+		byID[f.ID] = f
+	}
+
+	taskIDs := make([]int, len(tasks))
+	index := make(map[int]int, len(tasks))
+	for i, t := range tasks {
+		taskIDs[i] = t.ID
+		index[t.ID] = i
+	}
+
+	values, err := h.store.GetCustomFieldValues(taskIDs)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		field, ok := byID[v.FieldID]
+		if !ok {
+			continue
+		}
+		decoded, err := decodeCustomFieldValue(field, v.Value)
+		if err != nil {
+			return err
+		}
+		t := &tasks[index[v.TaskID]]
+		if t.CustomFields == nil {
+			t.CustomFields = make(map[string]interface{})
+		}
+		t.CustomFields[field.Name] = decoded
+	}
+	return nil
+}
+
+func customFieldByName(fields []types.CustomField, name string) (types.CustomField, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return types.CustomField{}, false
+}
+
+func customFieldByID(fields []types.CustomField, fieldID int) (types.CustomField, bool) {
+	for _, f := range fields {
+		if f.ID == fieldID {
+			return f, true
+		}
+	}
+	return types.CustomField{}, false
+}
+
+// fieldsForProject keeps the fields that can be set on tasks of the project:
+// the fields without a project and, for tasks in a project, its own fields.
+func fieldsForProject(fields []types.CustomField, projectID *int) []types.CustomField {
+	scoped := make([]types.CustomField, 0, len(fields))
+	for _, f := range fields {
+		if f.ProjectID == nil || (projectID != nil && *f.ProjectID == *projectID) {
+			scoped = append(scoped, f)
+		}
+	}
+	return scoped
+}
+
+// sharesTasks reports whether fields of the two projects can be set on the
+// same task, in which case their names must differ. A nil project stands for
+// the fields that apply to every task.
+func sharesTasks(a, b *int) bool {
+	return a == nil || b == nil || *a == *b
+}
+
+// customFieldQuery adds the `cf.<name>=<value>` filters and the `sort=cf.<name>`
+// or `sort=-cf.<name>` order of the query parameters to q. Fields are looked up
+// in the project of q when it has one.
+func customFieldQuery(q *types.TaskQuery, fields []types.CustomField, params url.Values) error {
+	if q.ProjectID != nil {
+		fields = fieldsForProject(fields, q.ProjectID)
+	}
+
+	names := make([]string, 0)
+	for param := range params {
+		if strings.HasPrefix(param, customFieldParamPrefix) {
+			names = append(names, strings.TrimPrefix(param, customFieldParamPrefix))
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, err := resolveCustomField(fields, name)
+		if err != nil {
+			return err
+		}
+		value, err := encodeCustomFieldFilter(field, params.Get(customFieldParamPrefix+name))
+		if err != nil {
+			return err
+		}
+		q.CustomFields = append(q.CustomFields, types.CustomFieldFilter{Field: field, Value: value})
+	}
+
+	sortParam := params.Get("sort")
+	if sortParam == "" {
+		return nil
+	}
+	descending := strings.HasPrefix(sortParam, "-")
+	sortParam = strings.TrimPrefix(sortParam, "-")
+	if !strings.HasPrefix(sortParam, customFieldParamPrefix) {
+		return fmt.Errorf("invalid sort, should be cf.<name> or -cf.<name>")
+	}
+	field, err := resolveCustomField(fields, strings.TrimPrefix(sortParam, customFieldParamPrefix))
+	if err != nil {
+		return err
+	}
+	q.Sort = &types.CustomFieldSort{Field: field, Descending: descending}
+	return nil
+}
+
+// resolveCustomField finds the field by name. Fields of different projects
+// may share a name, in which case the project has to be given.
+func resolveCustomField(fields []types.CustomField, name string) (types.CustomField, error) {
+	found := make([]types.CustomField, 0, 1)
+	for _, f := range fields {
+		if f.Name == name {
+			found = append(found, f)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return types.CustomField{}, fmt.Errorf("unknown custom field %s", name)
+	case 1:
+		return found[0], nil
+	}
+	return types.CustomField{}, fmt.Errorf("custom field %s exists in several projects, filter on project_id", name)
+}
+
+// encodeCustomFieldFilter turns a filter value of the query string into the
+// JSON encoding used by stored values. Multi select filters hold one option.
+func encodeCustomFieldFilter(field types.CustomField, wanted string) (string, error) {
+	var value interface{} = wanted
+	switch field.Type {
+	case types.CustomFieldNumber:
+		n, err := strconv.ParseFloat(wanted, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return "", fmt.Errorf("%s must be a number", field.Name)
+		}
+		value = n
+	case types.CustomFieldUser:
+		userID, err := strconv.Atoi(wanted)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a user ID", field.Name)
+		}
+		value = userID
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -4,15 +4,42 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
 )
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/task", h.handleGetTasks).Methods(http.MethodGet)
-	router.HandleFunc("/task", h.handleCreateTask).Methods(http.MethodPost)
-	router.HandleFunc("/task/{id}", h.handleGetTask).Methods(http.MethodGet)
-	router.HandleFunc("/task/{id}", h.handleProgressTask).Methods(http.MethodPatch)
-	router.HandleFunc("/task/{id}", h.handleDeleteTask).Methods(http.MethodDelete)
-	router.HandleFunc("/task/{id}", h.handleUpdateTask).Methods(http.MethodPut)
-	router.HandleFunc("/task/concurrency", h.handleConcurrencyDemo).Methods(http.MethodPost)
-
+	router.HandleFunc("/task", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetTasks)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/task", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCreateTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/export", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleExportTasks)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/task/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetTask)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/task/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleProgressTask)), h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/task/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleDeleteTask)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/task/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleUpdateTask)), h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/task/concurrency", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionSystemAdmin, h.handleConcurrencyDemo)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/quick", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleQuickAdd)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/links", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetTaskLinks)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/task/{id}/links", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCreateTaskLink)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/links/{linkID}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleDeleteTaskLink)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/task/{id}/clone", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCloneTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/merge", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleMergeTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/regress", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleRegressTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/reopen", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleReopenTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/snooze", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleSnoozeTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/snooze", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleUnsnoozeTask)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/task/{id}/pin", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handlePinTask)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/pin", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleUnpinTask)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/task/{id}/labels", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleAddTaskLabel)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/task/{id}/labels/{label}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleRemoveTaskLabel)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/task/{id}/custom-fields", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleSetTaskCustomFields)), h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/task/{id}/escalations", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetTaskEscalations)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/custom-fields", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetCustomFields)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/custom-fields", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleCreateCustomField)), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/custom-fields/{id}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleDeleteCustomField)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/wip-limits", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetWIPLimits)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/wip-limits/{status}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionWorkflowManage, h.handleSetWIPLimit)), h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/wip-limits/{status}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionWorkflowManage, h.handleDeleteWIPLimit)), h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/stale-thresholds", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskRead, h.handleGetStaleThresholds)), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/stale-thresholds/{status}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleSetStaleThreshold)), h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/stale-thresholds/{status}", middlewares.AuthMiddleware(middlewares.RequireVerifiedEmail(middlewares.RequirePermission(types.PermissionTaskWrite, h.handleDeleteStaleThreshold)), h.userStore)).Methods(http.MethodDelete)
 }
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// exportColumns are the task columns of the CSV export, followed by one
+// cf.<name> column per custom field.
+var exportColumns = []string{"id", "title", "description", "status", "priority", "due_date", "assignee_id", "project_id", "parent_id", "created_by", "created_at", "updated_at", "labels"}
+
+// exportHeader returns the header row for the fields. Fields of different
+// projects sharing a name share a column, as a task only has one of them.
+func exportHeader(fields []types.CustomField) ([]string, []string) {
+	seen := make(map[string]bool, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !seen[f.Name] {
+			seen[f.Name] = true
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+
+	header := append([]string{}, exportColumns...)
+	for _, name := range names {
+		header = append(header, customFieldParamPrefix+name)
+	}
+	return header, names
+}
+
+// exportRow formats the task as a CSV row with the values of the named
+// custom fields. Lists are joined with semicolons.
+func exportRow(t types.Task, fieldNames []string) []string {
+	row := []string{
+		strconv.Itoa(t.ID),
+		exportText(t.Title),
+		exportText(t.Description),
+		string(t.Status),
+		string(t.Priority),
+		exportString(t.DueDate),
+		exportInt(t.AssigneeID),
+		exportInt(t.ProjectID),
+		exportInt(t.ParentID),
+		exportInt(t.CreatedBy),
+		t.CreatedAt,
+		t.UpdatedAt,
+		exportText(strings.Join(t.Labels, ";")),
+	}
+	for _, name := range fieldNames {
+		row = append(row, exportValue(t.CustomFields[name]))
+	}
+	return row
+}
+
+func exportValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case []string:
+		return exportText(strings.Join(v, ";"))
+	case string:
+		return exportText(v)
+	}
+	return ""
+}
+
+func exportString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func exportInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+// exportText keeps spreadsheets from evaluating user input as a formula by
+// prefixing text starting with a formula character with a quote.
+func exportText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
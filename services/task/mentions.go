@@ -0,0 +1,100 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+var (
+	mentionPattern   = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	referencePattern = regexp.MustCompile(`(?:^|[^\w&#])#(\d+)\b`)
+)
+
+// ParseMentions extracts `@user@example.com` mentions and `#123` task
+// references from free text. Results are deduplicated and keep the order in
+// which they first appear.
+func ParseMentions(text string) (emails []string, taskIDs []int) {
+	seenEmails := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		email := strings.ToLower(match[1])
+		if seenEmails[email] {
+			continue
+		}
+		seenEmails[email] = true
+		emails = append(emails, email)
+	}
+
+	seenTasks := make(map[int]bool)
+	for _, match := range referencePattern.FindAllStringSubmatch(text, -1) {
+		id, err := strconv.Atoi(match[1])
+		if err != nil || id <= 0 || seenTasks[id] {
+			continue
+		}
+		seenTasks[id] = true
+		taskIDs = append(taskIDs, id)
+	}
+	return emails, taskIDs
+}
+
+// syncMentions parses the description of a task, stores the resolved user and
+// task IDs and notifies users that were not mentioned before. It runs after
+// the task is saved, so callers log its errors instead of failing the request.
+func (h *Handler) syncMentions(taskID int, description string) error {
+	emails, refs := ParseMentions(description)
+
+	userIDs := make([]int, 0, len(emails))
+	for _, email := range emails {
+		u, err := h.userStore.GetUserByEmail(email)
+		if err != nil || u == nil {
+			continue
+		}
+		userIDs = append(userIDs, u.ID)
+	}
+
+	taskIDs := make([]int, 0, len(refs))
+	for _, refID := range refs {
+		if refID == taskID {
+			continue
+		}
+		if _, err := h.store.GetTaskByID(refID); err != nil {
+			continue
+		}
+		taskIDs = append(taskIDs, refID)
+	}
+
+	previous, err := h.store.GetTaskMentions([]int{taskID})
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.SetTaskMentions(taskID, userIDs, taskIDs); err != nil {
+		return err
+	}
+
+	alreadyMentioned := make(map[int]bool)
+	if m := previous[taskID]; m != nil {
+		for _, id := range m.Users {
+			alreadyMentioned[id] = true
+		}
+	}
+	for _, userID := range userIDs {
+		if alreadyMentioned[userID] {
+			continue
+		}
+		id := taskID
+		err := h.notifications.CreateNotification(types.Notification{
+			UserID:  userID,
+			Type:    types.NotificationMention,
+			Message: fmt.Sprintf("You were mentioned in task #%d", taskID),
+			TaskID:  &id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	emails, taskIDs := ParseMentions("Ask @Alice@Example.com about #12 (see #7), cc @bob@example.org. Again @alice@example.com #12")
+
+	assert.Equal(t, []string{"alice@example.com", "bob@example.org"}, emails)
+	assert.Equal(t, []int{12, 7}, taskIDs)
+}
+
+func TestParseMentionsIgnoresPlainEmailsAndAnchors(t *testing.T) {
+	emails, taskIDs := ParseMentions("Mail alice@example.com, see https://example.com/page#12 or issue#4 and &#123;")
+
+	assert.Empty(t, emails)
+	assert.Empty(t, taskIDs)
+}
@@ -0,0 +1,238 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+var (
+	clockPattern   = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+	clock24Pattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	digitsPattern  = regexp.MustCompile(`^\d+$`)
+
+	// datePrepositions are dropped from the title when they directly precede
+	// a date or time, so "Ship it by friday" becomes "Ship it".
+	datePrepositions = map[string]bool{"on": true, "by": true, "at": true, "due": true}
+)
+
+// QuickAdd is a task parsed from a single line of text.
+type QuickAdd struct {
+	Title    string
+	DueDate  *time.Time
+	Labels   []string
+	Priority types.TaskPriority
+	Assignee string
+}
+
+// ParseQuickAdd parses a line like `Fix login bug tomorrow 5pm #backend !high
+// @bob`. `#label` adds a label, `!priority` sets the priority, `@user` sets
+// the assignee and relative or absolute dates and times set the due date;
+// every other word is part of the title. Dates are resolved relative to now,
+// in its location. A date without a time is due at the end of that day and a
+// time without a date is due at its next occurrence.
+func ParseQuickAdd(text string, now time.Time) (QuickAdd, error) {
+	var result QuickAdd
+	var title []string
+	var date *time.Time
+	var hour, minute = -1, 0
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	setDate := func(d time.Time) error {
+		if date != nil {
+			return errors.New("only one due date can be given")
+		}
+		date = &d
+		if n := len(title); n > 0 && datePrepositions[strings.ToLower(title[n-1])] {
+			title = title[:n-1]
+		}
+		return nil
+	}
+
+	words := strings.Fields(text)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		lower := strings.ToLower(strings.TrimRight(word, ",;"))
+
+		switch {
+		case len(lower) > 1 && lower[0] == '#' && !digitsPattern.MatchString(lower[1:]):
+			result.Labels = append(result.Labels, lower[1:])
+			continue
+		case len(lower) > 1 && lower[0] == '!':
+			priority := types.TaskPriority(lower[1:])
+			switch priority {
+			case types.PriorityLow, types.PriorityMedium, types.PriorityHigh, types.PriorityUrgent:
+			default:
+				return result, fmt.Errorf("unknown priority %s, should be one of low, medium, high, urgent", lower[1:])
+			}
+			if result.Priority != "" {
+				return result, errors.New("only one priority can be given")
+			}
+			result.Priority = priority
+			continue
+		case len(lower) > 1 && lower[0] == '@':
+			if result.Assignee != "" {
+				return result, errors.New("only one assignee can be given")
+			}
+			result.Assignee = lower[1:]
+			continue
+		}
+
+		if h, m, ok := parseClock(lower); ok {
+			if hour >= 0 {
+				return result, errors.New("only one due time can be given")
+			}
+			hour, minute = h, m
+			if n := len(title); n > 0 && datePrepositions[strings.ToLower(title[n-1])] {
+				title = title[:n-1]
+			}
+			continue
+		}
+
+		if d, consumed, ok := parseDate(words[i:], today); ok {
+			if err := setDate(d); err != nil {
+				return result, err
+			}
+			i += consumed - 1
+			continue
+		}
+
+		title = append(title, word)
+	}
+
+	result.Title = strings.Join(title, " ")
+	if result.Title == "" {
+		return result, errors.New("a title is required")
+	}
+	result.Labels = normalizeLabels(result.Labels)
+
+	switch {
+	case date != nil && hour >= 0:
+		due := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location())
+		result.DueDate = &due
+	case date != nil:
+		due := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 0, 0, now.Location())
+		result.DueDate = &due
+	case hour >= 0:
+		due := time.Date(today.Year(), today.Month(), today.Day(), hour, minute, 0, 0, now.Location())
+		if !due.After(now) {
+			due = due.AddDate(0, 0, 1)
+		}
+		result.DueDate = &due
+	}
+	return result, nil
+}
+
+// parseClock parses times like 5pm, 5:30pm, noon and 17:00.
+func parseClock(word string) (int, int, bool) {
+	if word == "noon" {
+		return 12, 0, true
+	}
+	if m := clockPattern.FindStringSubmatch(word); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour < 1 || hour > 12 || minute > 59 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+		return hour, minute, true
+	}
+	if m := clock24Pattern.FindStringSubmatch(word); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour > 23 || minute > 59 {
+			return 0, 0, false
+		}
+		return hour, minute, true
+	}
+	return 0, 0, false
+}
+
+// parseDate parses a date at the start of words and returns it together with
+// the number of words it used. Supported are today, tomorrow, weekdays
+// (optionally prefixed with next), next week, in N days/weeks and
+// YYYY-MM-DD.
+func parseDate(words []string, today time.Time) (time.Time, int, bool) {
+	word := func(i int) string {
+		if i >= len(words) {
+			return ""
+		}
+		return strings.ToLower(strings.TrimRight(words[i], ",;"))
+	}
+
+	switch first := word(0); {
+	case first == "today":
+		return today, 1, true
+	case first == "tomorrow":
+		return today.AddDate(0, 0, 1), 1, true
+	case isoDatePattern.MatchString(first):
+		d, err := time.ParseInLocation("2006-01-02", first, today.Location())
+		return d, 1, err == nil
+	case first == "next" && word(1) == "week":
+		daysUntilMonday := (8 - int(today.Weekday())) % 7
+		if daysUntilMonday == 0 {
+			daysUntilMonday = 7
+		}
+		return today.AddDate(0, 0, daysUntilMonday), 2, true
+	case first == "next":
+		if d, ok := nextWeekday(word(1), today); ok {
+			return d, 2, true
+		}
+	case first == "in":
+		n, err := strconv.Atoi(word(1))
+		if err != nil || n <= 0 {
+			break
+		}
+		switch strings.TrimSuffix(word(2), "s") {
+		case "day":
+			return today.AddDate(0, 0, n), 3, true
+		case "week":
+			return today.AddDate(0, 0, 7*n), 3, true
+		}
+	default:
+		if d, ok := nextWeekday(first, today); ok {
+			return d, 1, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// nextWeekday returns the next day after today falling on the named weekday.
+func nextWeekday(name string, today time.Time) (time.Time, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if name != strings.ToLower(day.String()) {
+			continue
+		}
+		days := (int(day) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), true
+	}
+	return time.Time{}, false
+}
+
+// normalizeLabels lower-cases and trims labels and drops empty and duplicate
+// ones, keeping the order in which they first appear.
+func normalizeLabels(labels []string) []string {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(labels))
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	return normalized
+}
@@ -0,0 +1,71 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	// a Wednesday
+	now := time.Date(2026, 10, 21, 9, 30, 0, 0, berlin)
+
+	result, err := ParseQuickAdd("Fix login bug tomorrow 5pm #backend !high @bob", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fix login bug", result.Title)
+	assert.Equal(t, []string{"backend"}, result.Labels)
+	assert.Equal(t, types.PriorityHigh, result.Priority)
+	assert.Equal(t, "bob", result.Assignee)
+	assert.Equal(t, time.Date(2026, 10, 22, 17, 0, 0, 0, berlin), *result.DueDate)
+}
+
+func TestParseQuickAddDates(t *testing.T) {
+	now := time.Date(2026, 10, 21, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		text  string
+		title string
+		due   *time.Time
+	}{
+		{"Write notes", "Write notes", nil},
+		{"Ship release by friday", "Ship release", ptrTime(time.Date(2026, 10, 23, 23, 59, 0, 0, time.UTC))},
+		{"Standup wednesday at 9:00", "Standup", ptrTime(time.Date(2026, 10, 28, 9, 0, 0, 0, time.UTC))},
+		{"Plan next release next week", "Plan next release", ptrTime(time.Date(2026, 10, 26, 23, 59, 0, 0, time.UTC))},
+		{"Renew cert in 2 weeks", "Renew cert", ptrTime(time.Date(2026, 11, 4, 23, 59, 0, 0, time.UTC))},
+		{"Call back at 8am", "Call back", ptrTime(time.Date(2026, 10, 22, 8, 0, 0, 0, time.UTC))},
+		{"Lunch noon", "Lunch", ptrTime(time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC))},
+		{"Pay invoice on 2026-12-01 #billing #Billing", "Pay invoice", ptrTime(time.Date(2026, 12, 1, 23, 59, 0, 0, time.UTC))},
+		{"Follow up on #12", "Follow up on #12", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			result, err := ParseQuickAdd(tt.text, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.title, result.Title)
+			assert.Equal(t, tt.due, result.DueDate)
+		})
+	}
+}
+
+func TestParseQuickAddRejectsInvalidInput(t *testing.T) {
+	now := time.Now()
+
+	for _, text := range []string{
+		"#backend !high",
+		"Fix bug !critical",
+		"Fix bug @bob @alice",
+		"Fix bug today tomorrow",
+	} {
+		_, err := ParseQuickAdd(text, now)
+		assert.Error(t, err, text)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -1,30 +1,41 @@
 package task
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/services/auth"
 	"github.com/trsnaqe/gotask/types"
 	"github.com/trsnaqe/gotask/utils"
 )
 
 type Handler struct {
-	store types.TaskStore
-	mu    sync.Mutex
-	queue chan int
+	store         types.TaskStore
+	projects      types.ProjectStore
+	userStore     types.UserStore
+	notifications types.NotificationStore
+	mu            sync.Mutex
+	queue         chan int
 }
 
-func NewHandler(store types.TaskStore) *Handler {
+func NewHandler(store types.TaskStore, projects types.ProjectStore, userStore types.UserStore, notifications types.NotificationStore) *Handler {
 	handler := &Handler{
-		store: store,
-		queue: make(chan int, 2), // 2 workers
+		store:         store,
+		projects:      projects,
+		userStore:     userStore,
+		notifications: notifications,
+		queue:         make(chan int, 2), // 2 workers
 	}
 	handler.StartWorkers(2) // Start 2 worker goroutines
 	return handler
@@ -33,42 +44,129 @@ func NewHandler(store types.TaskStore) *Handler {
 // HandleGetTasks   get-tasks
 //
 // @Summary     Get Tasks
-// @Description Get Tasks
+// @Description Get Tasks. Snoozed tasks are hidden until they wake up and tasks pinned by the authenticated user come first.
 // @Tags        Task
 // @Produce     json
+// @Security    jwtKey
 // @Success     200    {object} types.Task
 // @Param       status query    string false "Task Status" Enums(pending, in_progress, completed)
+// @Param       project_id query int  false "Only tasks of the project"
+// @Param       cf.name query   string false "Filter on a custom field, e.g. cf.environment=prod"
+// @Param       sort   query    string false "Sort on a custom field, cf.<name> or -cf.<name>"
+// @Param       include_snoozed query bool false "Include snoozed tasks"
+// @Param       stale  query    bool   false "Only return tasks that exceeded the stale threshold of their status"
 // @Failure     400    {object} types.ErrorResponse
+// @Failure     401    {object} types.ErrorResponse
+// @Failure     403    {object} types.ErrorResponse
 // @Failure     500    {object} types.ErrorResponse
 // @Router      /task [get]
 func (h *Handler) handleGetTasks(w http.ResponseWriter, r *http.Request) {
-	statusStr := r.URL.Query().Get("status")
-
-	if statusStr != "" {
-		status := types.TaskStatus(statusStr)
-		switch status {
-		case types.StatusPending, types.StatusInProgress, types.StatusCompleted:
-			tasks, err := h.store.GetTasksByStatus(status)
-			if err != nil {
-				utils.WriteError(w, http.StatusInternalServerError, err)
-				return
-			}
-			utils.WriteJSON(w, http.StatusOK, tasks)
-			return
-		default:
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid task status, should be one of pending, in_progress, completed"))
-			return
-		}
+	fields, err := h.store.GetCustomFields()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	q, err := taskQueryFromRequest(r, fields)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
 
-	tasks, err := h.store.GetTasks()
+	tasks, err := h.store.FindTasks(q)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+
+	now := time.Now()
+	if includeSnoozed, _ := strconv.ParseBool(r.URL.Query().Get("include_snoozed")); !includeSnoozed {
+		tasks = withoutSnoozed(tasks, now)
+	}
+
+	if err := h.withStale(tasks, now); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if stale, _ := strconv.ParseBool(r.URL.Query().Get("stale")); stale {
+		tasks = onlyStale(tasks)
+	}
+
+	if err := h.enrichTasks(tasks); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.withPins(tasks, auth.GetUserIDFromContext(r.Context())); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	utils.WriteJSON(w, http.StatusOK, tasks)
 }
 
+// HandleExportTasks   export-tasks
+//
+// @Summary     Export Tasks
+// @Description Export the tasks matching the filters of GET /task as CSV, with one cf.<name> column per custom field. Snoozed tasks are included. Labels and multi select values are separated by semicolons.
+// @Tags        Task
+// @Produce     text/csv
+// @Security    jwtKey
+// @Param       status     query    string false "Task Status" Enums(pending, in_progress, completed)
+// @Param       project_id query    int    false "Only tasks of the project"
+// @Param       cf.name    query    string false "Filter on a custom field, e.g. cf.environment=prod"
+// @Param       sort       query    string false "Sort on a custom field, cf.<name> or -cf.<name>"
+// @Success     200        {string} string
+// @Failure     400        {object} types.ErrorResponse
+// @Failure     401        {object} types.ErrorResponse
+// @Failure     403        {object} types.ErrorResponse
+// @Failure     500        {object} types.ErrorResponse
+// @Router      /task/export [get]
+func (h *Handler) handleExportTasks(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.store.GetCustomFields()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	q, err := taskQueryFromRequest(r, fields)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tasks, err := h.store.FindTasks(q)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.enrichTasks(tasks); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if q.ProjectID != nil {
+		fields = fieldsForProject(fields, q.ProjectID)
+	}
+	header, fieldNames := exportHeader(fields)
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="tasks.csv"`)
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		log.Printf("error exporting tasks: %v", err)
+		return
+	}
+	for _, t := range tasks {
+		if err := writer.Write(exportRow(t, fieldNames)); err != nil {
+			log.Printf("error exporting tasks: %v", err)
+			return
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("error exporting tasks: %v", err)
+	}
+}
+
 // HandleGetTask   Get Task by ID
 //
 // @Summary     Get Task by ID
@@ -76,9 +174,12 @@ func (h *Handler) handleGetTasks(w http.ResponseWriter, r *http.Request) {
 // @Tags        Task
 // @Accept      json
 // @Produce     json
+// @Security    jwtKey
 // @Param       id  path     int true "Task ID"
 // @Success     200 {object} string
 // @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
 // @Failure     500 {object} types.ErrorResponse
 // @Router      /task/{id} [get]
 func (h *Handler) handleGetTask(w http.ResponseWriter, r *http.Request) {
@@ -101,7 +202,21 @@ func (h *Handler) handleGetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, task)
+	tasks := []types.Task{*task}
+	if err := h.enrichTasks(tasks); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.withStale(tasks, time.Now()); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.withPins(tasks, auth.GetUserIDFromContext(r.Context())); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tasks[0])
 }
 
 // HandleCreateTask   create-task
@@ -111,9 +226,14 @@ func (h *Handler) handleGetTask(w http.ResponseWriter, r *http.Request) {
 // @Tags        Task
 // @Accept      json
 // @Produce     json
-// @Param       CreateTaskPayload body     types.CreateTaskPayload true "create task"
+// @Security    jwtKey
+// @Param       CreateTaskPayload body     types.CreateTaskPayload true  "create task"
+// @Param       override_wip      query    bool                    false "Bypass WIP limits in emergencies, requires the task:override_wip permission"
 // @Success     201               {object} string
 // @Failure     400               {object} types.ErrorResponse
+// @Failure     401               {object} types.ErrorResponse
+// @Failure     403               {object} types.ErrorResponse
+// @Failure     409               {object} types.ErrorResponse
 // @Failure     500               {object} types.ErrorResponse
 // @Router      /task [post]
 func (h *Handler) handleCreateTask(w http.ResponseWriter, r *http.Request) {
@@ -135,17 +255,61 @@ func (h *Handler) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.store.CreateTask(types.Task{
+	task := types.Task{
 		Title:       payload.Title,
 		Description: payload.Description,
 		Status:      payload.Status,
-	})
+		Priority:    payload.Priority,
+		AssigneeID:  payload.AssigneeID,
+		Labels:      normalizeLabels(payload.Labels),
+		CreatedBy:   creatorOf(r),
+		ParentID:    payload.ParentID,
+		ProjectID:   payload.ProjectID,
+	}
+	if payload.DueDate != nil {
+		due := payload.DueDate.Format(time.RFC3339)
+		task.DueDate = &due
+	}
+	if payload.AssigneeID != nil {
+		if _, err := h.userStore.GetUserByID(*payload.AssigneeID); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("assignee not found"))
+			return
+		}
+	}
+	if payload.ParentID != nil {
+		parent, err := h.store.GetTaskByID(*payload.ParentID)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("parent task not found"))
+			return
+		}
+		// subtasks stay in the project of their parent unless told otherwise
+		if task.ProjectID == nil {
+			task.ProjectID = parent.ProjectID
+		}
+	}
+	if payload.ProjectID != nil {
+		if _, err := h.projects.GetProjectByID(*payload.ProjectID); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("project not found"))
+			return
+		}
+	}
+
+	override, ok := wipOverride(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := h.store.CreateTask(task, override)
 
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		writeTransitionError(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	if err := h.syncMentions(taskID, payload.Description); err != nil {
+		log.Printf("failed to sync the mentions of task %d: %v", taskID, err)
+	}
+
 	utils.WriteJSON(w, http.StatusCreated, map[string]string{"message": "Task created successfully"})
 }
 
@@ -156,10 +320,15 @@ func (h *Handler) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 // @Tags        Task
 // @Accept      json
 // @Produce     json
-// @Param       id                path     int                     true "Task ID"
-// @Param       UpdateTaskPayload body     types.UpdateTaskPayload true "Task updates"
+// @Security    jwtKey
+// @Param       id                path     int                     true  "Task ID"
+// @Param       UpdateTaskPayload body     types.UpdateTaskPayload true  "Task updates"
+// @Param       override_wip      query    bool                    false "Bypass WIP limits in emergencies, requires the task:override_wip permission"
 // @Success     200               {object} string
 // @Failure     400               {object} types.ErrorResponse
+// @Failure     401               {object} types.ErrorResponse
+// @Failure     403               {object} types.ErrorResponse
+// @Failure     409               {object} types.ErrorResponse
 // @Failure     500               {object} types.ErrorResponse
 // @Router      /task/{id} [put]
 func (h *Handler) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
@@ -190,11 +359,54 @@ func (h *Handler) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Println("Parsed JSON request body into updates struct")
 
-	err = h.store.UpdateTask(taskID, updates)
+	if updates.StartDate != nil {
+		if _, err := time.Parse(types.DateLayout, *updates.StartDate); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("start date must use the YYYY-MM-DD format"))
+			return
+		}
+	}
+	if updates.EstimateDays != nil && *updates.EstimateDays < 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("estimate must not be negative"))
+		return
+	}
+	if updates.Priority != nil {
+		switch *updates.Priority {
+		case types.PriorityLow, types.PriorityMedium, types.PriorityHigh, types.PriorityUrgent:
+		case "":
+			// an empty priority leaves the current one in place
+			updates.Priority = nil
+		default:
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("priority should be one of low, medium, high, urgent"))
+			return
+		}
+	}
+	if err := utils.Validate.Struct(updates); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+	if updates.AssigneeID != nil {
+		if _, err := h.userStore.GetUserByID(*updates.AssigneeID); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("assignee not found"))
+			return
+		}
+	}
+
+	override, ok := wipOverride(w, r)
+	if !ok {
+		return
+	}
+
+	err = h.store.UpdateTask(taskID, updates, override)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		writeTransitionError(w, err, http.StatusInternalServerError)
 		return
 	}
+
+	if updates.Description != nil {
+		if err := h.syncMentions(taskID, *updates.Description); err != nil {
+			log.Printf("failed to sync the mentions of task %d: %v", taskID, err)
+		}
+	}
 	log.Println("Task updated successfully")
 
 	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Task updated successfully"})
@@ -207,9 +419,12 @@ func (h *Handler) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 // @Tags        Task
 // @Accept      json
 // @Produce     json
+// @Security    jwtKey
 // @Param       id  path     int true "Task ID"
 // @Success     200 {object} string
 // @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
 // @Failure     500 {object} types.ErrorResponse
 // @Router      /task/{id} [delete]
 func (h *Handler) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
@@ -248,10 +463,15 @@ func (h *Handler) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 // @Tags        Task
 // @Accept      json
 // @Produce     json
-// @Param       id  path     int true "Task ID"
-// @Success     200 {object} string
-// @Failure     400 {object} types.ErrorResponse
-// @Failure     500 {object} types.ErrorResponse
+// @Security    jwtKey
+// @Param       id           path     int  true  "Task ID"
+// @Param       override_wip query    bool false "Bypass WIP limits in emergencies, requires the task:override_wip permission"
+// @Success     200          {object} string
+// @Failure     400          {object} types.ErrorResponse
+// @Failure     401          {object} types.ErrorResponse
+// @Failure     403          {object} types.ErrorResponse
+// @Failure     409          {object} types.ErrorResponse
+// @Failure     500          {object} types.ErrorResponse
 // @Router      /task/{id} [patch]
 func (h *Handler) handleProgressTask(w http.ResponseWriter, r *http.Request) {
 	locked := h.mu.TryLock()
@@ -280,53 +500,1352 @@ func (h *Handler) handleProgressTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.store.ProgressTask(taskID)
+	override, ok := wipOverride(w, r)
+	if !ok {
+		return
+	}
+
+	err = h.store.ProgressTask(taskID, override)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		writeTransitionError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d progressed successfully", taskID)})
 }
 
-// HandleConcurrency   concurrency-demo
+// HandleGetTaskLinks   get-task-links
 //
-// @Summary     Concurrency Demo
-// @Description Endpoint to demonstrate queued processing, Check logs for processing status and prometheus metrics in `api/v1/metrics` for queue length and tasks processed
+// @Summary     Get Task Links
+// @Description Get typed links (relates_to, duplicates, duplicated_by, cloned_from, cloned_by) of a task
 // @Tags        Task
-// @Accept      json
 // @Produce     json
-// @Success     200 {object} string
+// @Security    jwtKey
+// @Param       id  path     int true "Task ID"
+// @Success     200 {array}  types.TaskLink
 // @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
 // @Failure     500 {object} types.ErrorResponse
-// @Router      /task/concurrency [post]
-func (h *Handler) handleConcurrencyDemo(w http.ResponseWriter, r *http.Request) {
-	for i := 1; i <= 4; i++ {
-		// add queued task to prometheus
-		// I want to see how many tasks are in the queue
-		// and how many processed using total etc
-		h.queue <- i // Add task to the queue
-		utils.IncrementQueueLength()
-		log.Printf("Task %d added to the queue", i)
+// @Router      /task/{id}/links [get]
+func (h *Handler) handleGetTaskLinks(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Tasks added to the queue for concurrency demonstration, check logs for processing status and prometheus metrics in `api/v1/metrics` for queue length and tasks processed"})
+	if _, err := h.store.GetTaskByID(taskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	links, err := h.store.GetTaskLinks(taskID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, links)
 }
 
-func (h *Handler) worker() {
-	for range h.queue {
-		utils.IncrementQueueLength()
-		log.Println("Processing task")
-		// Simulate processing time
-		time.Sleep(time.Duration(rand.Intn(5)+1) * time.Second)
-		log.Println("Task processed")
-		utils.IncrementTasksProcessed()
-		utils.DecrementQueueLength()
+// HandleCreateTaskLink   create-task-link
+//
+// @Summary     Link Tasks
+// @Description Link a task to another one, the inverse link is stored on the other task
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                    path     int                         true "Task ID"
+// @Param       CreateTaskLinkPayload body     types.CreateTaskLinkPayload true "Linked task and link type"
+// @Success     201                   {object} string
+// @Failure     400                   {object} types.ErrorResponse
+// @Failure     401                   {object} types.ErrorResponse
+// @Failure     403                   {object} types.ErrorResponse
+// @Failure     500                   {object} types.ErrorResponse
+// @Router      /task/{id}/links [post]
+func (h *Handler) handleCreateTaskLink(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.CreateTaskLinkPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if payload.TaskID == taskID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a task cannot be linked to itself"))
+		return
+	}
+
+	for _, id := range []int{taskID, payload.TaskID} {
+		if _, err := h.store.GetTaskByID(id); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := h.store.CreateTaskLink(taskID, payload.TaskID, payload.Type); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
 	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]string{"message": "Tasks linked successfully"})
 }
 
-func (h *Handler) StartWorkers(numWorkers int) {
-	for i := 0; i < numWorkers; i++ {
-		go h.worker()
+// HandleDeleteTaskLink   delete-task-link
+//
+// @Summary     Unlink Tasks
+// @Description Remove a link of a task together with its inverse link
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       id     path     int true "Task ID"
+// @Param       linkID path     int true "Link ID"
+// @Success     200    {object} string
+// @Failure     400    {object} types.ErrorResponse
+// @Failure     401    {object} types.ErrorResponse
+// @Failure     403    {object} types.ErrorResponse
+// @Failure     500    {object} types.ErrorResponse
+// @Router      /task/{id}/links/{linkID} [delete]
+func (h *Handler) handleDeleteTaskLink(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	linkID, err := strconv.Atoi(mux.Vars(r)["linkID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid link ID"))
+		return
+	}
+
+	if err := h.store.DeleteTaskLink(taskID, linkID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Link deleted successfully"})
+}
+
+// HandleCloneTask   clone-task
+//
+// @Summary     Clone Task
+// @Description Copy a task, with its custom field values, into a new pending task created by the user and record a cloned_from link to the original. Labels and subtasks are copied when asked for.
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id               path     int                    true  "Task ID"
+// @Param       CloneTaskPayload body     types.CloneTaskPayload false "What to copy along"
+// @Success     201              {object} types.Task
+// @Failure     400              {object} types.ErrorResponse
+// @Failure     401              {object} types.ErrorResponse
+// @Failure     403              {object} types.ErrorResponse
+// @Failure     409              {object} types.ErrorResponse
+// @Failure     500              {object} types.ErrorResponse
+// @Router      /task/{id}/clone [post]
+func (h *Handler) handleCloneTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.CloneTaskPayload
+	if r.ContentLength > 0 {
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if _, err := h.store.GetTaskByID(taskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cloneID, err := h.store.CloneTask(taskID, payload, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		writeTransitionError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	clone, err := h.store.GetTaskByID(cloneID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.syncMentions(cloneID, clone.Description); err != nil {
+		log.Printf("failed to sync the mentions of task %d: %v", cloneID, err)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, clone)
+}
+
+// HandleMergeTask   merge-task
+//
+// @Summary     Merge Duplicate Task
+// @Description Close a duplicate task and link it to the original with duplicates/duplicated_by
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id               path     int                    true "Duplicate Task ID"
+// @Param       MergeTaskPayload body     types.MergeTaskPayload true "Original task"
+// @Success     200              {object} string
+// @Failure     400              {object} types.ErrorResponse
+// @Failure     401              {object} types.ErrorResponse
+// @Failure     403              {object} types.ErrorResponse
+// @Failure     409              {object} types.ErrorResponse
+// @Failure     500              {object} types.ErrorResponse
+// @Router      /task/{id}/merge [post]
+func (h *Handler) handleMergeTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.MergeTaskPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if payload.Into == taskID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a task cannot be merged into itself"))
+		return
+	}
+
+	for _, id := range []int{taskID, payload.Into} {
+		if _, err := h.store.GetTaskByID(id); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := h.store.MergeTask(taskID, payload.Into); err != nil {
+		writeTransitionError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d merged into task %d", taskID, payload.Into)})
+}
+
+// HandleGetWIPLimits   get-wip-limits
+//
+// @Summary     Get WIP Limits
+// @Description Get the configured work-in-progress limits with the current number of tasks per status
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.WIPLimit
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /wip-limits [get]
+func (h *Handler) handleGetWIPLimits(w http.ResponseWriter, r *http.Request) {
+	limits, err := h.store.GetWIPLimits()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, limits)
+}
+
+// HandleSetWIPLimit   set-wip-limit
+//
+// @Summary     Set WIP Limit
+// @Description Set the maximum number of tasks allowed in a status. Requires the workflow:manage permission
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       status             path     string                   true "Task Status" Enums(pending, in_progress, completed)
+// @Param       SetWIPLimitPayload body     types.SetWIPLimitPayload true "Limit"
+// @Success     200                {object} string
+// @Failure     400                {object} types.ErrorResponse
+// @Failure     401                {object} types.ErrorResponse
+// @Failure     403                {object} types.ErrorResponse
+// @Failure     500                {object} types.ErrorResponse
+// @Router      /wip-limits/{status} [put]
+func (h *Handler) handleSetWIPLimit(w http.ResponseWriter, r *http.Request) {
+	status, err := statusFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.SetWIPLimitPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.SetWIPLimit(status, payload.MaxTasks, userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("WIP limit for %s set to %d", status, payload.MaxTasks)})
+}
+
+// HandleDeleteWIPLimit   delete-wip-limit
+//
+// @Summary     Delete WIP Limit
+// @Description Remove the WIP limit of a status. Requires the workflow:manage permission
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       status path     string true "Task Status" Enums(pending, in_progress, completed)
+// @Success     200    {object} string
+// @Failure     400    {object} types.ErrorResponse
+// @Failure     401    {object} types.ErrorResponse
+// @Failure     403    {object} types.ErrorResponse
+// @Router      /wip-limits/{status} [delete]
+func (h *Handler) handleDeleteWIPLimit(w http.ResponseWriter, r *http.Request) {
+	status, err := statusFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.DeleteWIPLimit(status); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("WIP limit for %s removed", status)})
+}
+
+// HandleQuickAdd   quick-add-task
+//
+// @Summary     Quick Add Task
+// @Description Create a pending task from a single line like "Fix login bug tomorrow 5pm #backend !high @bob". Words starting with # are labels, ! sets the priority, @ the assignee (email or its local part) and dates such as today, tomorrow, friday, next week, in 3 days or 2026-01-31 with an optional time set the due date in the given IANA time zone (UTC by default). With dry_run the parsed task is returned without creating it.
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       QuickAddPayload body     types.QuickAddPayload true  "Quick add line"
+// @Param       dry_run         query    bool                  false "Only preview the parsed task"
+// @Param       override_wip    query    bool                  false "Bypass WIP limits in emergencies, requires the task:override_wip permission"
+// @Success     200             {object} types.QuickAddResult
+// @Success     201             {object} types.QuickAddResult
+// @Failure     400             {object} types.ErrorResponse
+// @Failure     401             {object} types.ErrorResponse
+// @Failure     403             {object} types.ErrorResponse
+// @Failure     409             {object} types.ErrorResponse
+// @Failure     500             {object} types.ErrorResponse
+// @Router      /task/quick [post]
+func (h *Handler) handleQuickAdd(w http.ResponseWriter, r *http.Request) {
+	var payload types.QuickAddPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	loc := time.UTC
+	if payload.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(payload.Timezone); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown time zone %s", payload.Timezone))
+			return
+		}
+	}
+
+	parsed, err := ParseQuickAdd(payload.Text, time.Now().In(loc))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result := types.QuickAddResult{
+		Title:    parsed.Title,
+		Labels:   parsed.Labels,
+		Priority: parsed.Priority,
+	}
+	if result.Priority == "" {
+		result.Priority = types.PriorityMedium
+	}
+	if parsed.DueDate != nil {
+		due := parsed.DueDate.Format(time.RFC3339)
+		result.DueDate = &due
+	}
+	if parsed.Assignee != "" {
+		assigneeID, err := h.resolveAssignee(parsed.Assignee)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		result.AssigneeID = &assigneeID
+	}
+
+	task := types.CreateTaskPayload{
+		Title:       result.Title,
+		Description: result.Title,
+		Status:      types.StatusPending,
+		Priority:    result.Priority,
+		Labels:      result.Labels,
+	}
+	if err := utils.Validate.Struct(task); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid task: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		result.DryRun = true
+		utils.WriteJSON(w, http.StatusOK, result)
+		return
+	}
+
+	override, ok := wipOverride(w, r)
+	if !ok {
+		return
+	}
+
+	result.ID, err = h.store.CreateTask(types.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		DueDate:     result.DueDate,
+		Priority:    result.Priority,
+		AssigneeID:  result.AssigneeID,
+		Labels:      result.Labels,
+		CreatedBy:   creatorOf(r),
+	}, override)
+	if err != nil {
+		writeTransitionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, result)
+}
+
+// resolveAssignee finds the user of a quick-add handle, either a full email
+// address or an unambiguous local part of one.
+func (h *Handler) resolveAssignee(handle string) (int, error) {
+	if strings.Contains(handle, "@") {
+		u, err := h.userStore.GetUserByEmail(handle)
+		if err != nil || u == nil {
+			return 0, fmt.Errorf("unknown assignee @%s", handle)
+		}
+		return u.ID, nil
+	}
+
+	users, err := h.userStore.GetUsersByEmailLocalPart(handle)
+	if err != nil {
+		return 0, err
+	}
+	switch len(users) {
+	case 0:
+		return 0, fmt.Errorf("unknown assignee @%s", handle)
+	case 1:
+		return users[0].ID, nil
+	}
+	return 0, fmt.Errorf("assignee @%s is ambiguous, use the full email address", handle)
+}
+
+// HandleSnoozeTask   snooze-task
+//
+// @Summary     Snooze Task
+// @Description Hide a task from the task list until the given time
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                path     int                     true "Task ID"
+// @Param       SnoozeTaskPayload body     types.SnoozeTaskPayload true "Wake time"
+// @Success     200               {object} string
+// @Failure     400               {object} types.ErrorResponse
+// @Failure     401               {object} types.ErrorResponse
+// @Failure     403               {object} types.ErrorResponse
+// @Router      /task/{id}/snooze [post]
+func (h *Handler) handleSnoozeTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.SnoozeTaskPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if !payload.Until.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("snooze time must be in the future"))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.SnoozeTask(taskID, payload.Until, userID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task snoozed until %s", payload.Until.Format(time.RFC3339))})
+}
+
+// HandleUnsnoozeTask   unsnooze-task
+//
+// @Summary     Unsnooze Task
+// @Description Bring a snoozed task back to the task list right away
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Task ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /task/{id}/snooze [delete]
+func (h *Handler) handleUnsnoozeTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.store.UnsnoozeTask(taskID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Task unsnoozed"})
+}
+
+// HandlePinTask   pin-task
+//
+// @Summary     Pin Task
+// @Description Pin a task to the top of the task list of the authenticated user
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Task ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /task/{id}/pin [post]
+func (h *Handler) handlePinTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.store.GetTaskByID(taskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.PinTask(userID, taskID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Task pinned"})
+}
+
+// HandleUnpinTask   unpin-task
+//
+// @Summary     Unpin Task
+// @Description Remove a task from the pinned tasks of the authenticated user
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Task ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /task/{id}/pin [delete]
+func (h *Handler) handleUnpinTask(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.UnpinTask(userID, taskID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Task unpinned"})
+}
+
+// HandleGetStaleThresholds   get-stale-thresholds
+//
+// @Summary     Get Stale Thresholds
+// @Description Get the number of days a task can stay in each status without updates before it is stale, first without a project and then for each project. Tasks use the thresholds of their project where it has one.
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.StaleThreshold
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /stale-thresholds [get]
+func (h *Handler) handleGetStaleThresholds(w http.ResponseWriter, r *http.Request) {
+	thresholds, err := h.store.GetStaleThresholds()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, thresholds)
+}
+
+// HandleSetStaleThreshold   set-stale-threshold
+//
+// @Summary     Set Stale Threshold
+// @Description Set the number of days a task can stay in a status without updates, for the tasks of a project or, without project_id, for all other tasks. Thresholds of a project are managed by the owner of the project, the others require the workflow:manage permission. Stale tasks are escalated to their assignee and, after twice the threshold, to the owner of their project, or to the user who set the threshold for tasks without a project.
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       status                   path     string                         true "Task Status" Enums(pending, in_progress)
+// @Param       project_id               query    int                            false "Project of the threshold"
+// @Param       SetStaleThresholdPayload body     types.SetStaleThresholdPayload true "Threshold"
+// @Success     200                      {object} string
+// @Failure     400                      {object} types.ErrorResponse
+// @Failure     401                      {object} types.ErrorResponse
+// @Failure     403                      {object} types.ErrorResponse
+// @Failure     500                      {object} types.ErrorResponse
+// @Router      /stale-thresholds/{status} [put]
+func (h *Handler) handleSetStaleThreshold(w http.ResponseWriter, r *http.Request) {
+	status, err := statusFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if status == types.StatusCompleted {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("completed tasks cannot go stale"))
+		return
+	}
+	projectID, err := projectIDFromQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !h.authorizeProjectSetting(w, r, projectID, "stale thresholds") {
+		return
+	}
+
+	var payload types.SetStaleThresholdPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.SetStaleThreshold(projectID, status, payload.Days, userID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Stale threshold for %s set to %d days", status, payload.Days)})
+}
+
+// HandleDeleteStaleThreshold   delete-stale-threshold
+//
+// @Summary     Delete Stale Threshold
+// @Description Stop detecting stale tasks in a status, for the tasks of a project or, without project_id, for all tasks without a threshold of their project. Requires the same permission as setting the threshold.
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       status     path     string true  "Task Status" Enums(pending, in_progress)
+// @Param       project_id query    int    false "Project of the threshold"
+// @Success     200    {object} string
+// @Failure     400    {object} types.ErrorResponse
+// @Failure     401    {object} types.ErrorResponse
+// @Failure     403    {object} types.ErrorResponse
+// @Router      /stale-thresholds/{status} [delete]
+func (h *Handler) handleDeleteStaleThreshold(w http.ResponseWriter, r *http.Request) {
+	status, err := statusFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	projectID, err := projectIDFromQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !h.authorizeProjectSetting(w, r, projectID, "stale thresholds") {
+		return
+	}
+
+	if err := h.store.DeleteStaleThreshold(projectID, status); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Stale threshold for %s removed", status)})
+}
+
+// HandleGetTaskEscalations   get-task-escalations
+//
+// @Summary     Get Task Escalations
+// @Description Get the escalation history of a task, oldest first
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Task ID"
+// @Success     200 {array}  types.TaskEscalation
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /task/{id}/escalations [get]
+func (h *Handler) handleGetTaskEscalations(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.store.GetTaskByID(taskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	escalations, err := h.store.GetTaskEscalations(taskID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, escalations)
+}
+
+// HandleGetCustomFields   get-custom-fields
+//
+// @Summary     Get Custom Fields
+// @Description Get the custom field definitions that can be set on tasks
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       project_id query    int false "Only the fields that can be set on tasks of the project"
+// @Success     200        {array}  types.CustomField
+// @Failure     400        {object} types.ErrorResponse
+// @Failure     401        {object} types.ErrorResponse
+// @Failure     403        {object} types.ErrorResponse
+// @Failure     500        {object} types.ErrorResponse
+// @Router      /custom-fields [get]
+func (h *Handler) handleGetCustomFields(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.store.GetCustomFields()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	projectID, err := projectIDFromQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if projectID != nil {
+		fields = fieldsForProject(fields, projectID)
+	}
+	utils.WriteJSON(w, http.StatusOK, fields)
+}
+
+// HandleCreateCustomField   create-custom-field
+//
+// @Summary     Create Custom Field
+// @Description Define a typed custom field. Select fields need a list of options. Fields of a project are managed by the owner of the project, fields without a project require the workflow:manage permission.
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       CreateCustomFieldPayload body     types.CreateCustomFieldPayload true "Custom field"
+// @Success     201                      {object} types.CustomField
+// @Failure     400                      {object} types.ErrorResponse
+// @Failure     401                      {object} types.ErrorResponse
+// @Failure     403                      {object} types.ErrorResponse
+// @Failure     500                      {object} types.ErrorResponse
+// @Router      /custom-fields [post]
+func (h *Handler) handleCreateCustomField(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateCustomFieldPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	isSelect := payload.Type == types.CustomFieldSelect || payload.Type == types.CustomFieldMultiSelect
+	if isSelect && len(payload.Options) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%s fields need at least one option", payload.Type))
+		return
+	}
+	if !isSelect && len(payload.Options) > 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("only select fields can have options"))
+		return
+	}
+
+	if !h.authorizeProjectSetting(w, r, payload.ProjectID, "custom fields") {
+		return
+	}
+
+	fields, err := h.store.GetCustomFields()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, f := range fields {
+		if f.Name == payload.Name && sharesTasks(f.ProjectID, payload.ProjectID) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("custom field %s already exists", payload.Name))
+			return
+		}
+	}
+
+	field := types.CustomField{Name: payload.Name, Type: payload.Type, Options: payload.Options, ProjectID: payload.ProjectID}
+	field.ID, err = h.store.CreateCustomField(field)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, field)
+}
+
+// HandleDeleteCustomField   delete-custom-field
+//
+// @Summary     Delete Custom Field
+// @Description Delete a custom field definition together with its values on all tasks. Requires the same permission as creating it.
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Custom Field ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Router      /custom-fields/{id} [delete]
+func (h *Handler) handleDeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	fieldID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid custom field ID"))
+		return
+	}
+
+	fields, err := h.store.GetCustomFields()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	field, ok := customFieldByID(fields, fieldID)
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("no custom field found with the given ID"))
+		return
+	}
+	if !h.authorizeProjectSetting(w, r, field.ProjectID, "custom fields") {
+		return
+	}
+
+	if err := h.store.DeleteCustomField(fieldID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Custom field deleted successfully"})
+}
+
+// HandleSetTaskCustomFields   set-task-custom-fields
+//
+// @Summary     Set Task Custom Fields
+// @Description Set custom field values of a task by field name. Only fields without a project and fields of the project of the task can be set. A null value removes the field from the task.
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id     path     int                    true "Task ID"
+// @Param       values body     map[string]interface{} true "Values by field name"
+// @Success     200    {object} types.Task
+// @Failure     400    {object} types.ErrorResponse
+// @Failure     401    {object} types.ErrorResponse
+// @Failure     403    {object} types.ErrorResponse
+// @Failure     500    {object} types.ErrorResponse
+// @Router      /task/{id}/custom-fields [put]
+func (h *Handler) handleSetTaskCustomFields(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload map[string]json.RawMessage
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := h.store.GetTaskByID(taskID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	fields, err := h.store.GetCustomFields()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	fields = fieldsForProject(fields, task.ProjectID)
+
+	values := make(map[int]*string, len(payload))
+	for name, raw := range payload {
+		field, ok := customFieldByName(fields, name)
+		if !ok {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown custom field %s", name))
+			return
+		}
+		if string(raw) == "null" {
+			values[field.ID] = nil
+			continue
+		}
+		value, err := h.normalizeCustomFieldValue(field, raw)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		values[field.ID] = &value
+	}
+
+	if err := h.store.SetCustomFieldValues(taskID, values); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tasks := []types.Task{*task}
+	if err := h.enrichTasks(tasks); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, tasks[0])
+}
+
+func statusFromRequest(r *http.Request) (types.TaskStatus, error) {
+	status := types.TaskStatus(mux.Vars(r)["status"])
+	switch status {
+	case types.StatusPending, types.StatusInProgress, types.StatusCompleted:
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid task status, should be one of pending, in_progress, completed")
+}
+
+// taskQueryFromRequest reads the status, project and custom field filters and
+// the custom field sort of GET /task from the query string.
+func taskQueryFromRequest(r *http.Request, fields []types.CustomField) (types.TaskQuery, error) {
+	params := r.URL.Query()
+	var q types.TaskQuery
+	if statusStr := params.Get("status"); statusStr != "" {
+		status := types.TaskStatus(statusStr)
+		switch status {
+		case types.StatusPending, types.StatusInProgress, types.StatusCompleted:
+			q.Status = &status
+		default:
+			return q, fmt.Errorf("invalid task status, should be one of pending, in_progress, completed")
+		}
+	}
+	if projectIDStr := params.Get("project_id"); projectIDStr != "" {
+		projectID, err := strconv.Atoi(projectIDStr)
+		if err != nil {
+			return q, fmt.Errorf("invalid project ID")
+		}
+		q.ProjectID = &projectID
+	}
+	return q, customFieldQuery(&q, fields, params)
+}
+
+// creatorOf returns the user creating a task with the request, or nil when
+// no user is authenticated.
+func creatorOf(r *http.Request) *int {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID < 0 {
+		return nil
+	}
+	return &userID
+}
+
+// projectIDFromQuery returns the project_id query parameter, or nil when it
+// is not set.
+func projectIDFromQuery(r *http.Request) (*int, error) {
+	projectIDStr := r.URL.Query().Get("project_id")
+	if projectIDStr == "" {
+		return nil, nil
+	}
+	projectID, err := strconv.Atoi(projectIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project ID")
+	}
+	return &projectID, nil
+}
+
+// authorizeProjectSetting reports whether the request may manage the named
+// settings, custom fields or stale thresholds, of the project, or the ones
+// without a project when projectID is nil. Otherwise it responds with an
+// error and returns false.
+func (h *Handler) authorizeProjectSetting(w http.ResponseWriter, r *http.Request, projectID *int, setting string) bool {
+	if projectID == nil {
+		if auth.Can(r.Context(), types.PermissionWorkflowManage) {
+			return true
+		}
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("%s without a project require the %s permission", setting, types.PermissionWorkflowManage))
+		return false
+	}
+
+	project, err := h.projects.GetProjectByID(*projectID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("project not found"))
+		return false
+	}
+	if !auth.CanManageProject(r.Context(), project) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only the owner of the project can manage its %s", setting))
+		return false
+	}
+	return true
+}
+
+// wipOverride reports whether the request asks to bypass WIP limits. Only
+// users with the task:override_wip permission may do so; for anyone else it
+// responds with 403 and returns false for ok. Uses are logged so emergency
+// overrides can be audited.
+func wipOverride(w http.ResponseWriter, r *http.Request) (override bool, ok bool) {
+	override, _ = strconv.ParseBool(r.URL.Query().Get("override_wip"))
+	if !override {
+		return false, true
+	}
+	if !auth.Can(r.Context(), types.PermissionTaskOverrideWIP) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("overriding WIP limits requires the %s permission", types.PermissionTaskOverrideWIP))
+		return false, false
+	}
+	log.Printf("WIP limit override by user %d: %s %s", auth.GetUserIDFromContext(r.Context()), r.Method, r.URL.Path)
+	return true, true
+}
+
+// writeTransitionError responds with 409 when a status transition was blocked
+// by a WIP limit, not allowed from the current status or lost a race, and with
+// the given status code otherwise.
+func writeTransitionError(w http.ResponseWriter, err error, status int) {
+	var wipErr *types.WIPLimitError
+	var transitionErr *types.InvalidTransitionError
+	if errors.As(err, &wipErr) || errors.As(err, &transitionErr) || errors.Is(err, types.ErrTaskStatusChanged) {
+		status = http.StatusConflict
+	}
+	utils.WriteError(w, status, err)
+}
+
+// enrichTasks fills mentions, labels and custom field values of the given
+// tasks.
+func (h *Handler) enrichTasks(tasks []types.Task) error {
+	taskIDs := make([]int, len(tasks))
+	for i := range tasks {
+		taskIDs[i] = tasks[i].ID
+	}
+
+	mentions, err := h.store.GetTaskMentions(taskIDs)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		if m := mentions[tasks[i].ID]; m != nil {
+			tasks[i].Mentions = m.Users
+			tasks[i].References = m.Tasks
+			tasks[i].ReferencedBy = m.ReferencedBy
+		}
+	}
+
+	labels, err := h.store.GetTaskLabels(taskIDs)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].Labels = labels[tasks[i].ID]
+	}
+
+	fields, err := h.store.GetCustomFields()
+	if err != nil {
+		return err
+	}
+	return h.withCustomFields(tasks, fields)
+}
+
+func taskIDFromRequest(r *http.Request) (int, error) {
+	taskIDStr, ok := mux.Vars(r)["id"]
+	if !ok {
+		return 0, fmt.Errorf("task ID is missing in URL")
+	}
+
+	taskID, err := strconv.Atoi(taskIDStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid task ID")
+	}
+	return taskID, nil
+}
+
+// HandleRegressTask   regress-task
+//
+// @Summary     Regress Task
+// @Description Move a task one stage back, completed to in_progress or in_progress to pending
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                    path     int                         true  "Task ID"
+// @Param       TransitionTaskPayload body     types.TransitionTaskPayload false "Reason"
+// @Param       override_wip          query    bool                        false "Bypass WIP limits in emergencies, requires the task:override_wip permission"
+// @Success     200                   {object} string
+// @Failure     400                   {object} types.ErrorResponse
+// @Failure     401                   {object} types.ErrorResponse
+// @Failure     403                   {object} types.ErrorResponse
+// @Failure     409                   {object} types.ErrorResponse
+// @Failure     500                   {object} types.ErrorResponse
+// @Router      /task/{id}/regress [post]
+func (h *Handler) handleRegressTask(w http.ResponseWriter, r *http.Request) {
+	taskID, payload, ok := h.parseTransitionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	override, ok := wipOverride(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.RegressTask(taskID, payload.Reason, override); err != nil {
+		writeTransitionError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d regressed successfully", taskID)})
+}
+
+// HandleReopenTask   reopen-task
+//
+// @Summary     Reopen Task
+// @Description Move a completed task back to pending and increase its reopened_count
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                    path     int                         true  "Task ID"
+// @Param       TransitionTaskPayload body     types.TransitionTaskPayload false "Reason"
+// @Param       override_wip          query    bool                        false "Bypass WIP limits in emergencies, requires the task:override_wip permission"
+// @Success     200                   {object} string
+// @Failure     400                   {object} types.ErrorResponse
+// @Failure     401                   {object} types.ErrorResponse
+// @Failure     403                   {object} types.ErrorResponse
+// @Failure     409                   {object} types.ErrorResponse
+// @Failure     500                   {object} types.ErrorResponse
+// @Router      /task/{id}/reopen [post]
+func (h *Handler) handleReopenTask(w http.ResponseWriter, r *http.Request) {
+	taskID, payload, ok := h.parseTransitionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	override, ok := wipOverride(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.ReopenTask(taskID, payload.Reason, override); err != nil {
+		writeTransitionError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Task %d reopened successfully", taskID)})
+}
+
+// parseTransitionRequest reads the task ID and the optional reason of a
+// regress or reopen request, writing the error response when they are invalid.
+func (h *Handler) parseTransitionRequest(w http.ResponseWriter, r *http.Request) (int, types.TransitionTaskPayload, bool) {
+	var payload types.TransitionTaskPayload
+
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return 0, payload, false
+	}
+
+	if r.ContentLength > 0 {
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return 0, payload, false
+		}
+		if err := utils.Validate.Struct(payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+			return 0, payload, false
+		}
+	}
+
+	if _, err := h.store.GetTaskByID(taskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return 0, payload, false
+	}
+	return taskID, payload, true
+}
+
+// HandleConcurrency   concurrency-demo
+//
+// @Summary     Concurrency Demo
+// @Description Endpoint to demonstrate queued processing, Check logs for processing status and prometheus metrics in `api/v1/metrics` for queue length and tasks processed. Requires the system:admin permission.
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /task/concurrency [post]
+func (h *Handler) handleConcurrencyDemo(w http.ResponseWriter, r *http.Request) {
+	for i := 1; i <= 4; i++ {
+		// add queued task to prometheus
+		// I want to see how many tasks are in the queue
+		// and how many processed using total etc
+		h.queue <- i // Add task to the queue
+		utils.IncrementQueueLength()
+		log.Printf("Task %d added to the queue", i)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Tasks added to the queue for concurrency demonstration, check logs for processing status and prometheus metrics in `api/v1/metrics` for queue length and tasks processed"})
+}
+
+func (h *Handler) worker() {
+	for range h.queue {
+		utils.IncrementQueueLength()
+		log.Println("Processing task")
+		// Simulate processing time
+		time.Sleep(time.Duration(rand.Intn(5)+1) * time.Second)
+		log.Println("Task processed")
+		utils.IncrementTasksProcessed()
+		utils.DecrementQueueLength()
+	}
+}
+
+func (h *Handler) StartWorkers(numWorkers int) {
+	for i := 0; i < numWorkers; i++ {
+		go h.worker()
+	}
+}
+
+// HandleAddTaskLabel   add-task-label
+//
+// @Summary     Add Task Label
+// @Description Add a label to a task
+// @Tags        Task
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id      path     int                       true "Task ID"
+// @Param       payload body     types.AddTaskLabelPayload true "Label"
+// @Success     200     {object} string
+// @Failure     400     {object} types.ErrorResponse
+// @Failure     401     {object} types.ErrorResponse
+// @Failure     403     {object} types.ErrorResponse
+// @Failure     500     {object} types.ErrorResponse
+// @Router      /task/{id}/labels [post]
+func (h *Handler) handleAddTaskLabel(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.AddTaskLabelPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+	labels := normalizeLabels([]string{payload.Label})
+	if len(labels) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("label must not be empty"))
+		return
+	}
+
+	if _, err := h.store.GetTaskByID(taskID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.store.AddTaskLabel(taskID, labels[0]); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Label added"})
+}
+
+// HandleRemoveTaskLabel   remove-task-label
+//
+// @Summary     Remove Task Label
+// @Description Remove a label from a task
+// @Tags        Task
+// @Produce     json
+// @Security    jwtKey
+// @Param       id    path     int    true "Task ID"
+// @Param       label path     string true "Label"
+// @Success     200   {object} string
+// @Failure     400   {object} types.ErrorResponse
+// @Failure     401   {object} types.ErrorResponse
+// @Failure     403   {object} types.ErrorResponse
+// @Failure     500   {object} types.ErrorResponse
+// @Router      /task/{id}/labels/{label} [delete]
+func (h *Handler) handleRemoveTaskLabel(w http.ResponseWriter, r *http.Request) {
+	taskID, err := taskIDFromRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	label := strings.ToLower(strings.TrimSpace(mux.Vars(r)["label"]))
+	if err := h.store.RemoveTaskLabel(taskID, label); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
 	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Label removed"})
 }
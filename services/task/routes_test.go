@@ -2,19 +2,28 @@ package task
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/services/auth"
 	"github.com/trsnaqe/gotask/types"
 )
 
 func TestTask(t *testing.T) {
 	taskStore := &mockTaskStore{}
-	handler := NewHandler(taskStore)
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{}, &mockNotificationStore{})
 
 	t.Run("should create a task with valid payload", func(t *testing.T) {
 		payload := types.CreateTaskPayload{
@@ -36,6 +45,22 @@ func TestTask(t *testing.T) {
 		assert.Equal(t, http.StatusCreated, rr.Code)
 	})
 
+	t.Run("should fail to create a task in an unknown project", func(t *testing.T) {
+		project := 9
+		payloadJSON, _ := json.Marshal(types.CreateTaskPayload{Title: "Task 1", Description: "Description of Task 1", Status: types.StatusPending, ProjectID: &project})
+		req, err := http.NewRequest("POST", "/task", bytes.NewBuffer(payloadJSON))
+		if err != nil {
+			t.Fatal("Error creating request")
+		}
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/task", handler.handleCreateTask).Methods("POST")
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
 	t.Run("should update a task with valid payload", func(t *testing.T) {
 		payload := types.Task{
 			Title:       "Updated Task 1",
@@ -56,6 +81,20 @@ func TestTask(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rr.Code)
 	})
 
+	t.Run("should fail to update a task with an invalid payload", func(t *testing.T) {
+		for _, body := range []string{`{"title": "ab"}`, `{"description": "ab"}`, `{"status": "archived"}`, `{"estimate_days": 400}`} {
+			req, err := http.NewRequest("PUT", "/task/1", strings.NewReader(body))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+
+			router.HandleFunc("/task/{id}", handler.handleUpdateTask).Methods("PUT")
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+		}
+	})
+
 	t.Run("should delete a task with valid ID", func(t *testing.T) {
 		req, err := http.NewRequest("DELETE", "/task/1", nil)
 		if err != nil {
@@ -120,21 +159,859 @@ func TestTask(t *testing.T) {
 
 }
 
-type mockTaskStore struct{}
+func TestTaskMentions(t *testing.T) {
+	taskStore := &mockTaskStore{}
+	userStore := &mockUserStore{users: map[string]int{"alice@example.com": 7}}
+	notificationStore := &mockNotificationStore{}
+	handler := NewHandler(taskStore, &mockProjectStore{}, userStore, notificationStore)
+
+	t.Run("should store mentions and notify mentioned users on create", func(t *testing.T) {
+		payload := types.CreateTaskPayload{
+			Title:       "Task 4",
+			Description: "Ping @alice@example.com and @ghost@example.com, see #3",
+			Status:      types.StatusPending,
+		}
+		payloadJSON, _ := json.Marshal(payload)
+		req, err := http.NewRequest("POST", "/task", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/task", handler.handleCreateTask).Methods("POST")
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, []int{7}, taskStore.mentionedUsers)
+		assert.Equal(t, []int{3}, taskStore.referencedTasks)
+		assert.Len(t, notificationStore.created, 1)
+		assert.Equal(t, 7, notificationStore.created[0].UserID)
+		assert.Equal(t, types.NotificationMention, notificationStore.created[0].Type)
+	})
+
+	t.Run("should not notify users that were already mentioned", func(t *testing.T) {
+		taskStore.mentions = &types.TaskMentions{Users: []int{7}}
+		notificationStore.created = nil
+
+		description := "Still waiting on @alice@example.com"
+		payloadJSON, _ := json.Marshal(types.UpdateTaskPayload{Description: &description})
+		req, err := http.NewRequest("PUT", "/task/1", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/task/{id}", handler.handleUpdateTask).Methods("PUT")
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []int{7}, taskStore.mentionedUsers)
+		assert.Empty(t, notificationStore.created)
+	})
+
+	t.Run("should create the task when storing its mentions fails", func(t *testing.T) {
+		taskStore.mentionsErr = errors.New("connection lost")
+		defer func() { taskStore.mentionsErr = nil }()
+
+		payloadJSON, _ := json.Marshal(types.CreateTaskPayload{Title: "Task 5", Description: "Ping @alice@example.com", Status: types.StatusPending})
+		req, err := http.NewRequest("POST", "/task", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/task", handler.handleCreateTask).Methods("POST")
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+}
+
+func TestTaskLinks(t *testing.T) {
+	taskStore := &mockTaskStore{}
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	t.Run("should link two tasks", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateTaskLinkPayload{TaskID: 2, Type: types.LinkRelatesTo})
+		req, err := http.NewRequest("POST", "/task/1/links", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, []types.TaskLink{{TaskID: 1, LinkedTaskID: 2, Type: types.LinkRelatesTo}}, taskStore.links)
+	})
+
+	t.Run("should fail to link a task to itself", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateTaskLinkPayload{TaskID: 1, Type: types.LinkRelatesTo})
+		req, err := http.NewRequest("POST", "/task/1/links", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should fail with an unknown link type", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateTaskLinkPayload{TaskID: 2, Type: "blocks_forever"})
+		req, err := http.NewRequest("POST", "/task/1/links", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should clone a task", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/task/1/clone", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, []int{1}, taskStore.cloned)
+		assert.Equal(t, 1, taskStore.clonedBy)
+		assert.Equal(t, types.CloneTaskPayload{}, taskStore.cloneOptions)
+	})
+
+	t.Run("should clone a task with its labels and subtasks", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CloneTaskPayload{Labels: true, Children: true})
+		req, err := http.NewRequest("POST", "/task/2/clone", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, types.CloneTaskPayload{Labels: true, Children: true}, taskStore.cloneOptions)
+	})
+
+	t.Run("should merge a duplicate into the original", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.MergeTaskPayload{Into: 1})
+		req, err := http.NewRequest("POST", "/task/3/merge", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, [][2]int{{3, 1}}, taskStore.merged)
+	})
+}
+
+func TestTaskWIPLimits(t *testing.T) {
+	taskStore := &mockTaskStore{wipLimit: &types.WIPLimitError{Status: types.StatusInProgress, Limit: 2, Current: 2}}
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{roles: map[int]types.Role{1: types.RoleAdmin}}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	t.Run("should reject a progress exceeding the WIP limit with 409", func(t *testing.T) {
+		req, err := http.NewRequest("PATCH", "/task/1", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(), "at most 2 tasks")
+	})
+
+	t.Run("should reject an update exceeding the WIP limit with 409", func(t *testing.T) {
+		status := types.StatusInProgress
+		payloadJSON, _ := json.Marshal(types.UpdateTaskPayload{Status: &status})
+		req, err := http.NewRequest("PUT", "/task/1", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should allow exceeding the WIP limit with the override flag", func(t *testing.T) {
+		token, err := auth.CreateAccessToken(1, 0, types.RoleAdmin)
+		assert.NoError(t, err)
+		payloadJSON, _ := json.Marshal(types.CreateTaskPayload{Title: "Hotfix", Description: "Production is down", Status: types.StatusInProgress})
+		req, err := http.NewRequest("POST", "/task?override_wip=true", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", token)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("should only let admins override the WIP limit", func(t *testing.T) {
+		token, err := auth.CreateAccessToken(2, 0, types.RoleMember)
+		assert.NoError(t, err)
+		payloadJSON, _ := json.Marshal(types.CreateTaskPayload{Title: "Hotfix", Description: "Production is down", Status: types.StatusInProgress})
+		req, err := http.NewRequest("POST", "/task?override_wip=true", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", token)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should only let admins change WIP limits", func(t *testing.T) {
+		member := withAccessToken(t, router, 2)
+		for _, method := range []string{"PUT", "DELETE"} {
+			payloadJSON, _ := json.Marshal(types.SetWIPLimitPayload{MaxTasks: 5})
+			req, err := http.NewRequest(method, "/wip-limits/in_progress", bytes.NewBuffer(payloadJSON))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			member.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusForbidden, rr.Code, method)
+
+			req, err = http.NewRequest(method, "/wip-limits/in_progress", bytes.NewBuffer(payloadJSON))
+			assert.NoError(t, err)
+			rr = httptest.NewRecorder()
+			server.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code, method)
+		}
+	})
+}
+
+func TestTaskPermissions(t *testing.T) {
+	handler := NewHandler(&mockTaskStore{}, &mockProjectStore{}, &mockUserStore{roles: map[int]types.Role{2: types.RoleViewer}}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("should require a token", func(t *testing.T) {
+		for _, method := range []string{"GET", "POST"} {
+			req, err := http.NewRequest(method, "/task", nil)
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, method)
+		}
+	})
+
+	t.Run("should let viewers read but not change tasks", func(t *testing.T) {
+		server := withAccessToken(t, router, 2)
+		req, err := http.NewRequest("GET", "/task", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		for _, route := range [][2]string{{"POST", "/task"}, {"PATCH", "/task/1"}, {"DELETE", "/task/1"}, {"POST", "/task/1/clone"}, {"PUT", "/task/1/custom-fields"}, {"POST", "/task/1/snooze"}, {"DELETE", "/task/1/snooze"}} {
+			req, err := http.NewRequest(route[0], route[1], nil)
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			server.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusForbidden, rr.Code, route[1])
+		}
+	})
+}
+
+func TestTaskTokenScopes(t *testing.T) {
+	token, _, hash, err := auth.NewPersonalAccessToken()
+	assert.NoError(t, err)
+	middlewares.UsePersonalAccessTokens(&mockTokenStore{tokens: map[string]types.PersonalAccessToken{
+		hash: {ID: 1, UserID: 1, Scopes: []types.Permission{types.PermissionTaskRead}},
+	}})
+	defer middlewares.UsePersonalAccessTokens(nil)
+
+	taskStore := &mockTaskStore{}
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	serve := func(method string, url string, payload interface{}) int {
+		payloadJSON, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("GET", "/task", nil))
+	assert.Equal(t, http.StatusForbidden, serve("POST", "/task", types.CreateTaskPayload{Title: "Task 1", Description: "Description", Status: types.StatusPending}), "a task:read token cannot create tasks")
+	assert.Equal(t, http.StatusForbidden, serve("DELETE", "/task/1", nil))
+	assert.Equal(t, http.StatusForbidden, serve("POST", "/task/2/merge", types.MergeTaskPayload{Into: 1}))
+	assert.Nil(t, taskStore.created)
+}
+
+func TestTaskEmailVerification(t *testing.T) {
+	previous := config.Envs.EmailVerificationRequired
+	defer func() { config.Envs.EmailVerificationRequired = previous }()
+	config.Envs.EmailVerificationRequired = true
+
+	handler := NewHandler(&mockTaskStore{}, &mockProjectStore{}, &mockUserStore{}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	for _, authorized := range []bool{true, false} {
+		req, err := http.NewRequest("GET", "/task", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		if authorized {
+			withAccessToken(t, router, 1).ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusForbidden, rr.Code, "the user has not verified the email")
+		} else {
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, "dropping the token does not get around the check")
+		}
+	}
+}
+
+func TestTaskTransitions(t *testing.T) {
+	taskStore := &mockTaskStore{}
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	t.Run("should reopen a task with a reason", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(map[string]string{"reason": "bug came back"})
+		req, err := http.NewRequest("POST", "/task/1/reopen", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should regress a task without a body", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/task/1/regress", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should answer 409 when the transition is not allowed", func(t *testing.T) {
+		taskStore.transitionErr = &types.InvalidTransitionError{Action: "regress", Status: types.StatusPending}
+		req, err := http.NewRequest("POST", "/task/1/regress", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should answer 409 when a concurrent transition won", func(t *testing.T) {
+		taskStore.transitionErr = types.ErrTaskStatusChanged
+		req, err := http.NewRequest("POST", "/task/1/reopen", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+}
+
+func TestTaskLabelsAndAssignment(t *testing.T) {
+	taskStore := &mockTaskStore{}
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	t.Run("should add a normalized label", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.AddTaskLabelPayload{Label: "  Backend "})
+		req, err := http.NewRequest("POST", "/task/1/labels", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{"backend"}, taskStore.labels)
+	})
+
+	t.Run("should update priority and assignee", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", "/task/1", strings.NewReader(`{"priority": "high", "assignee_id": 2}`))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, types.PriorityHigh, *taskStore.updates.Priority)
+		assert.Equal(t, 2, *taskStore.updates.AssigneeID)
+	})
+
+	t.Run("should reject an unknown priority", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", "/task/1", strings.NewReader(`{"priority": "someday"}`))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestTaskStaleness(t *testing.T) {
+	now := time.Now()
+	fresh := now.Add(-time.Hour).Format(time.RFC3339)
+	recent := now.AddDate(0, 0, -3).Format(time.RFC3339)
+	old := now.AddDate(0, 0, -6).Format(time.RFC3339)
+	owner, assignee, projectOwner, projectID := 7, 3, 2, 4
+	taskStore := &mockTaskStore{
+		tasks: []types.Task{
+			{ID: 1, Status: types.StatusInProgress, UpdatedAt: fresh},
+			{ID: 2, Status: types.StatusInProgress, UpdatedAt: old},
+			{ID: 3, Status: types.StatusCompleted, UpdatedAt: old},
+			{ID: 4, Status: types.StatusInProgress, UpdatedAt: old, ProjectID: &projectID},
+			{ID: 5, Status: types.StatusPending, UpdatedAt: recent, ProjectID: &projectID},
+		},
+		staleThresholds: []types.StaleThreshold{
+			{Status: types.StatusInProgress, Days: 5, UpdatedBy: &owner},
+			{ProjectID: &projectID, Status: types.StatusPending, Days: 2, UpdatedBy: &projectOwner},
+			{ProjectID: &projectID, Status: types.StatusInProgress, Days: 10, UpdatedBy: &projectOwner},
+		},
+	}
+	notificationStore := &mockNotificationStore{}
+	projects := &mockProjectStore{projects: []types.Project{{ID: projectID, Name: "Backend", OwnerID: projectOwner}}}
+	handler := NewHandler(taskStore, projects, &mockUserStore{roles: map[int]types.Role{1: types.RoleAdmin}}, notificationStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	t.Run("should only return stale tasks on request", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/task?stale=true", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var tasks []types.Task
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tasks))
+		assert.Len(t, tasks, 2, "the thresholds of the project replace the others for its tasks")
+		assert.Equal(t, 2, tasks[0].ID)
+		assert.Equal(t, 5, tasks[1].ID)
+		assert.True(t, tasks[0].Stale)
+	})
+
+	t.Run("should let project owners set the thresholds of their project", func(t *testing.T) {
+		for _, tc := range []struct {
+			user int
+			path string
+			code int
+		}{
+			{1, "/stale-thresholds/pending", http.StatusOK},
+			{projectOwner, "/stale-thresholds/pending", http.StatusForbidden},
+			{projectOwner, "/stale-thresholds/pending?project_id=4", http.StatusOK},
+			{assignee, "/stale-thresholds/pending?project_id=4", http.StatusForbidden},
+			{1, "/stale-thresholds/pending?project_id=4", http.StatusOK},
+			{1, "/stale-thresholds/pending?project_id=9", http.StatusBadRequest},
+		} {
+			req, err := http.NewRequest("PUT", tc.path, strings.NewReader(`{"days": 3}`))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			withAccessToken(t, router, tc.user).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.code, rr.Code, "user %d on %s", tc.user, tc.path)
+		}
+	})
+
+	t.Run("should not accept a threshold for completed tasks", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", "/stale-thresholds/completed", strings.NewReader(`{"days": 3}`))
+		assert.NoError(t, err)
+		req = mux.SetURLVars(req, map[string]string{"status": "completed"})
+		rr := httptest.NewRecorder()
+		handler.handleSetStaleThreshold(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should escalate to the assignee and then to the threshold owner", func(t *testing.T) {
+		taskStore.staleTasks = []types.StaleTask{{
+			TaskID:     2,
+			Title:      "Write docs",
+			AssigneeID: &assignee,
+			UpdatedAt:  now.AddDate(0, 0, -6),
+			Threshold:  taskStore.staleThresholds[0],
+		}}
+		assert.NoError(t, handler.escalateStaleTasks(now))
+		assert.Len(t, taskStore.escalations, 1)
+		assert.Equal(t, assignee, notificationStore.created[0].UserID)
+
+		// the store reports the recorded level on the next run
+		taskStore.staleTasks[0].Level = types.EscalationAssignee
+		assert.NoError(t, handler.escalateStaleTasks(now))
+		assert.Len(t, notificationStore.created, 1)
+
+		assert.NoError(t, handler.escalateStaleTasks(now.AddDate(0, 0, 5)))
+		assert.Len(t, taskStore.escalations, 2)
+		assert.Equal(t, types.EscalationOwner, taskStore.escalations[1].Level)
+		assert.Equal(t, owner, notificationStore.created[1].UserID)
+		assert.Equal(t, types.NotificationStaleTask, notificationStore.created[1].Type)
+	})
+
+	t.Run("should escalate tasks of a project to the project owner", func(t *testing.T) {
+		taskStore.escalations = nil
+		notificationStore.created = nil
+		taskStore.staleTasks = []types.StaleTask{{
+			TaskID:         5,
+			Title:          "Plan the release",
+			AssigneeID:     &assignee,
+			ProjectOwnerID: &projectOwner,
+			UpdatedAt:      now.AddDate(0, 0, -4),
+			Threshold:      types.StaleThreshold{ProjectID: &projectID, Status: types.StatusPending, Days: 2, UpdatedBy: &owner},
+		}}
+		assert.NoError(t, handler.escalateStaleTasks(now))
+
+		assert.Len(t, taskStore.escalations, 2)
+		assert.Equal(t, types.EscalationOwner, taskStore.escalations[1].Level)
+		assert.Equal(t, projectOwner, notificationStore.created[1].UserID)
+	})
+}
+
+func TestTaskCustomFields(t *testing.T) {
+	website, mobile := 5, 6
+	taskStore := &mockTaskStore{
+		tasks: []types.Task{{ID: 1}, {ID: 2}, {ID: 3, ProjectID: &website}},
+		customFields: []types.CustomField{
+			{ID: 1, Name: "points", Type: types.CustomFieldNumber},
+			{ID: 2, Name: "environment", Type: types.CustomFieldMultiSelect, Options: []string{"staging", "prod"}},
+			{ID: 3, Name: "store", Type: types.CustomFieldText, ProjectID: &mobile},
+		},
+		fieldValues: []types.CustomFieldValue{
+			{TaskID: 1, FieldID: 1, Value: "8"},
+			{TaskID: 2, FieldID: 1, Value: "3"},
+			{TaskID: 1, FieldID: 2, Value: `["prod"]`},
+			{TaskID: 3, FieldID: 2, Value: `["staging","prod"]`},
+		},
+	}
+	projectStore := &mockProjectStore{projects: []types.Project{{ID: website, OwnerID: 1}, {ID: mobile, OwnerID: 2}}}
+	handler := NewHandler(taskStore, projectStore, &mockUserStore{roles: map[int]types.Role{3: types.RoleAdmin}}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	getTasks := func(t *testing.T, url string) {
+		req, err := http.NewRequest("GET", url, nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	t.Run("should filter tasks on a multi select field", func(t *testing.T) {
+		getTasks(t, "/task?cf.environment=staging&status=pending")
+
+		status := types.StatusPending
+		assert.Equal(t, types.TaskQuery{
+			Status:       &status,
+			CustomFields: []types.CustomFieldFilter{{Field: taskStore.customFields[1], Value: `"staging"`}},
+		}, taskStore.query)
+	})
+
+	t.Run("should sort tasks on a number field", func(t *testing.T) {
+		getTasks(t, "/task?sort=-cf.points&cf.points=8.0")
+
+		assert.Equal(t, []types.CustomFieldFilter{{Field: taskStore.customFields[0], Value: "8"}}, taskStore.query.CustomFields)
+		assert.Equal(t, &types.CustomFieldSort{Field: taskStore.customFields[0], Descending: true}, taskStore.query.Sort)
+	})
+
+	t.Run("should look fields up in the project", func(t *testing.T) {
+		getTasks(t, "/task?project_id=6&cf.store=play")
+
+		assert.Equal(t, &mobile, taskStore.query.ProjectID)
+		assert.Equal(t, []types.CustomFieldFilter{{Field: taskStore.customFields[2], Value: `"play"`}}, taskStore.query.CustomFields)
+	})
+
+	t.Run("should reject filters on unknown fields and invalid values", func(t *testing.T) {
+		for _, url := range []string{"/task?cf.customer=acme", "/task?cf.store=play&project_id=5", "/task?cf.points=many", "/task?sort=title"} {
+			req, err := http.NewRequest("GET", url, nil)
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			server.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, url)
+		}
+	})
+
+	t.Run("should store validated values and remove null ones", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", "/task/1/custom-fields", bytes.NewBufferString(`{"points": 5, "environment": null}`))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "5", *taskStore.setValues[1])
+		assert.Nil(t, taskStore.setValues[2])
+	})
+
+	t.Run("should reject values that do not match the field type", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", "/task/1/custom-fields", bytes.NewBufferString(`{"environment": ["dev"]}`))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should require options for select fields", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.CreateCustomFieldPayload{Name: "customer", Type: types.CustomFieldSelect})
+		req, err := http.NewRequest("POST", "/custom-fields", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleCreateCustomField(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should only set fields of the project of the task", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", "/task/3/custom-fields", bytes.NewBufferString(`{"store": "play"}`))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "unknown custom field store")
+	})
+
+	t.Run("should list the fields of a project with the global ones", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/custom-fields?project_id=6", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		var fields []types.CustomField
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &fields))
+		assert.Len(t, fields, 3)
+	})
+
+	t.Run("should let project owners and admins manage custom fields", func(t *testing.T) {
+		create := func(userID int, payload types.CreateCustomFieldPayload) int {
+			payloadJSON, _ := json.Marshal(payload)
+			req, err := http.NewRequest("POST", "/custom-fields", bytes.NewBuffer(payloadJSON))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			withAccessToken(t, router, userID).ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		assert.Equal(t, http.StatusCreated, create(1, types.CreateCustomFieldPayload{Name: "customer", Type: types.CustomFieldText, ProjectID: &website}))
+		assert.Equal(t, http.StatusForbidden, create(1, types.CreateCustomFieldPayload{Name: "customer", Type: types.CustomFieldText, ProjectID: &mobile}), "only the owner manages the fields of a project")
+		assert.Equal(t, http.StatusForbidden, create(1, types.CreateCustomFieldPayload{Name: "severity", Type: types.CustomFieldText}), "fields without a project need an admin")
+		assert.Equal(t, http.StatusBadRequest, create(1, types.CreateCustomFieldPayload{Name: "points", Type: types.CustomFieldText, ProjectID: &website}), "names cannot shadow a global field")
+		assert.Equal(t, http.StatusCreated, create(2, types.CreateCustomFieldPayload{Name: "customer", Type: types.CustomFieldText, ProjectID: &mobile}))
+		assert.Equal(t, http.StatusCreated, create(3, types.CreateCustomFieldPayload{Name: "severity", Type: types.CustomFieldText}))
+
+		req, err := http.NewRequest("DELETE", "/custom-fields/1", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestTaskExport(t *testing.T) {
+	website := 5
+	taskStore := &mockTaskStore{
+		tasks: []types.Task{
+			{ID: 1, Title: "=HYPERLINK(\"http://example.com\")", Status: types.StatusPending, Priority: types.PriorityHigh, ProjectID: &website},
+			{ID: 2, Title: "Release", Status: types.StatusCompleted, Priority: types.PriorityLow},
+		},
+		customFields: []types.CustomField{
+			{ID: 1, Name: "points", Type: types.CustomFieldNumber},
+			{ID: 2, Name: "environment", Type: types.CustomFieldMultiSelect, Options: []string{"staging", "prod"}},
+		},
+		fieldValues: []types.CustomFieldValue{
+			{TaskID: 1, FieldID: 1, Value: "2.5"},
+			{TaskID: 1, FieldID: 2, Value: `["staging","prod"]`},
+		},
+	}
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{}, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	req, err := http.NewRequest("GET", "/task/export?status=pending", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "title", "description", "status", "priority", "due_date", "assignee_id", "project_id", "parent_id", "created_by", "created_at", "updated_at", "labels", "cf.environment", "cf.points"}, records[0])
+	assert.Equal(t, []string{"1", "'=HYPERLINK(\"http://example.com\")", "", "pending", "high", "", "", "5", "", "", "", "", "", "staging;prod", "2.5"}, records[1])
+	assert.Equal(t, []string{"2", "Release", "", "completed", "low", "", "", "", "", "", "", "", "", "", ""}, records[2])
+	assert.Equal(t, types.StatusPending, *taskStore.query.Status)
+}
+
+func TestTaskQuickAdd(t *testing.T) {
+	taskStore := &mockTaskStore{}
+	userStore := &mockUserStore{users: map[string]int{"bob@example.com": 4, "bobby@example.com": 5}}
+	handler := NewHandler(taskStore, &mockProjectStore{}, userStore, &mockNotificationStore{})
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	server := withAccessToken(t, router, 1)
+
+	quickAdd := func(url, text string) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(types.QuickAddPayload{Text: text, Timezone: "Europe/Berlin"})
+		req, err := http.NewRequest("POST", url, bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should preview the parsed task in dry run mode", func(t *testing.T) {
+		rr := quickAdd("/task/quick?dry_run=true", "Fix login bug tomorrow 5pm #backend !high @bob")
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var result types.QuickAddResult
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+		assert.True(t, result.DryRun)
+		assert.Equal(t, "Fix login bug", result.Title)
+		assert.Equal(t, 4, *result.AssigneeID)
+		assert.Contains(t, *result.DueDate, "T17:00:00+")
+		assert.Nil(t, taskStore.created)
+	})
+
+	t.Run("should create the parsed task", func(t *testing.T) {
+		rr := quickAdd("/task/quick", "Fix login bug #backend !high")
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, types.PriorityHigh, taskStore.created.Priority)
+		assert.Equal(t, []string{"backend"}, taskStore.created.Labels)
+	})
+
+	t.Run("should reject unknown assignees", func(t *testing.T) {
+		rr := quickAdd("/task/quick", "Fix login bug @bob@example.org")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("should not resolve assignees for anonymous callers", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.QuickAddPayload{Text: "Probe @bob"})
+		req, err := http.NewRequest("POST", "/task/quick?dry_run=true", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "assignee")
+	})
+}
+
+func TestTaskSnoozeAndPin(t *testing.T) {
+	later := time.Now().Add(time.Hour).Format(time.RFC3339)
+	earlier := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	taskStore := &mockTaskStore{
+		tasks:  []types.Task{{ID: 1}, {ID: 2, SnoozedUntil: &later}, {ID: 3, SnoozedUntil: &earlier}},
+		pinned: []int{3},
+	}
+	notificationStore := &mockNotificationStore{}
+	handler := NewHandler(taskStore, &mockProjectStore{}, &mockUserStore{}, notificationStore)
+
+	getTasks := func(t *testing.T, url string, userID int) []types.Task {
+		req, err := http.NewRequest("GET", url, nil)
+		assert.NoError(t, err)
+		if userID > 0 {
+			req = req.WithContext(context.WithValue(req.Context(), types.UserKey, userID))
+		}
+		rr := httptest.NewRecorder()
+		handler.handleGetTasks(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var tasks []types.Task
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tasks))
+		return tasks
+	}
+
+	t.Run("should hide tasks that are still snoozed", func(t *testing.T) {
+		tasks := getTasks(t, "/task", 0)
+		assert.Len(t, tasks, 2)
+		assert.Equal(t, 1, tasks[0].ID)
+		assert.Equal(t, 3, tasks[1].ID)
+	})
+
+	t.Run("should include snoozed tasks on request", func(t *testing.T) {
+		assert.Len(t, getTasks(t, "/task?include_snoozed=true", 0), 3)
+	})
+
+	t.Run("should put pinned tasks of the user first", func(t *testing.T) {
+		tasks := getTasks(t, "/task", 7)
+		assert.Equal(t, 3, tasks[0].ID)
+		assert.True(t, tasks[0].Pinned)
+		assert.False(t, tasks[1].Pinned)
+	})
+
+	t.Run("should reject snoozing into the past", func(t *testing.T) {
+		payloadJSON, _ := json.Marshal(types.SnoozeTaskPayload{Until: time.Now().Add(-time.Minute)})
+		req, err := http.NewRequest("POST", "/task/1/snooze", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		rr := httptest.NewRecorder()
+		handler.handleSnoozeTask(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should notify the snoozer and assignee when a task wakes up", func(t *testing.T) {
+		snoozer, assignee := 3, 4
+		taskStore.snoozed = []types.Task{
+			{ID: 2, Title: "Renew cert", SnoozedBy: &snoozer, AssigneeID: &assignee},
+			{ID: 5, Title: "Call back", SnoozedBy: &snoozer, AssigneeID: &snoozer},
+		}
+
+		assert.NoError(t, handler.wakeSnoozedTasks(time.Now()))
+		assert.Len(t, notificationStore.created, 3)
+		assert.Equal(t, types.NotificationSnoozeEnded, notificationStore.created[0].Type)
+		assert.Equal(t, 4, notificationStore.created[1].UserID)
+	})
+}
+
+type mockTaskStore struct {
+	mentions        *types.TaskMentions
+	mentionsErr     error
+	mentionedUsers  []int
+	referencedTasks []int
+	links           []types.TaskLink
+	cloned          []int
+	cloneOptions    types.CloneTaskPayload
+	clonedBy        int
+	merged          [][2]int
+	wipLimit        *types.WIPLimitError
+	transitionErr   error
+	tasks           []types.Task
+	customFields    []types.CustomField
+	query           types.TaskQuery
+	fieldValues     []types.CustomFieldValue
+	setValues       map[int]*string
+	created         *types.Task
+	pinned          []int
+	snoozed         []types.Task
+	labels          []string
+	updates         *types.UpdateTaskPayload
+	staleThresholds []types.StaleThreshold
+	staleTasks      []types.StaleTask
+	escalations     []types.TaskEscalation
+}
 
 func (m *mockTaskStore) GetTasks() ([]types.Task, error) {
-	return nil, nil
+	return m.tasks, nil
+}
+
+func (m *mockTaskStore) FindTasks(q types.TaskQuery) ([]types.Task, error) {
+	m.query = q
+	return m.tasks, nil
 }
 
 func (m *mockTaskStore) GetTaskByID(id int) (*types.Task, error) {
-	return nil, nil
+	return &types.Task{ID: id}, nil
 }
 
-func (m *mockTaskStore) CreateTask(task types.Task) error {
-	return nil
+func (m *mockTaskStore) CreateTask(task types.Task, overrideWIP bool) (int, error) {
+	if m.wipLimit != nil && !overrideWIP {
+		return 0, m.wipLimit
+	}
+	m.created = &task
+	return 1, nil
 }
 
-func (m *mockTaskStore) UpdateTask(taskID int, updates types.UpdateTaskPayload) error {
+func (m *mockTaskStore) UpdateTask(taskID int, updates types.UpdateTaskPayload, overrideWIP bool) error {
+	if m.wipLimit != nil && updates.Status != nil && !overrideWIP {
+		return m.wipLimit
+	}
+	m.updates = &updates
 	return nil
 }
 
@@ -142,9 +1019,300 @@ func (m *mockTaskStore) DeleteTask(taskID int) error {
 	return nil
 }
 
-func (m *mockTaskStore) ProgressTask(taskID int) error {
+func (m *mockTaskStore) ProgressTask(taskID int, overrideWIP bool) error {
+	if m.wipLimit != nil && !overrideWIP {
+		return m.wipLimit
+	}
 	return nil
 }
 func (m *mockTaskStore) GetTasksByStatus(status types.TaskStatus) ([]types.Task, error) {
 	return nil, nil
 }
+
+func (m *mockTaskStore) GetTaskMentions(taskIDs []int) (map[int]*types.TaskMentions, error) {
+	mentions := make(map[int]*types.TaskMentions)
+	if m.mentions != nil {
+		for _, id := range taskIDs {
+			mentions[id] = m.mentions
+		}
+	}
+	return mentions, nil
+}
+
+func (m *mockTaskStore) SetTaskMentions(taskID int, userIDs []int, taskIDs []int) error {
+	if m.mentionsErr != nil {
+		return m.mentionsErr
+	}
+	m.mentionedUsers = userIDs
+	m.referencedTasks = taskIDs
+	return nil
+}
+
+// withAccessToken sends requests without an Authorization header with an
+// access token of the user, as the task routes require one.
+func withAccessToken(t *testing.T, router *mux.Router, userID int) http.Handler {
+	token, err := auth.CreateAccessToken(userID, 0, types.RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", token)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+type mockProjectStore struct {
+	projects []types.Project
+}
+
+func (m *mockProjectStore) GetProjects() ([]types.Project, error) {
+	return m.projects, nil
+}
+
+func (m *mockProjectStore) GetProjectByID(projectID int) (*types.Project, error) {
+	for _, p := range m.projects {
+		if p.ID == projectID {
+			return &p, nil
+		}
+	}
+	return nil, errors.New("no project found with the given ID")
+}
+
+func (m *mockProjectStore) CreateProject(p types.Project) (int, error) {
+	m.projects = append(m.projects, p)
+	return len(m.projects), nil
+}
+
+func (m *mockProjectStore) DeleteProject(projectID int) error {
+	return nil
+}
+
+type mockUserStore struct {
+	users map[string]int
+	roles map[int]types.Role
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	id, ok := m.users[email]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &types.User{ID: id, Email: email}, nil
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	role, ok := m.roles[id]
+	if !ok {
+		role = types.RoleMember
+	}
+	return &types.User{ID: id, Role: role}, nil
+}
+
+func (m *mockUserStore) SetUserRole(userID int, role types.Role) error {
+	return nil
+}
+
+func (m *mockUserStore) VerifyEmail(userID int, email string, now time.Time) error {
+	return nil
+}
+
+func (m *mockUserStore) GetUsersByEmailLocalPart(localPart string) ([]types.User, error) {
+	users := make([]types.User, 0)
+	for email, id := range m.users {
+		if strings.HasPrefix(email, localPart+"@") {
+			users = append(users, types.User{ID: id, Email: email})
+		}
+	}
+	return users, nil
+}
+
+func (m *mockUserStore) CreateUser(types.User) error {
+	return nil
+}
+
+func (m *mockUserStore) UpdateUser(userID int, updates types.UpdateUserPayload) error {
+	return nil
+}
+
+func (m *mockUserStore) ChangePassword(userID int, oldPassword string, newPassword string) error {
+	return nil
+}
+
+type mockTokenStore struct {
+	types.PersonalAccessTokenStore
+	tokens map[string]types.PersonalAccessToken
+}
+
+func (m *mockTokenStore) GetActivePersonalAccessToken(tokenHash string, now time.Time) (*types.PersonalAccessToken, error) {
+	t, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, fmt.Errorf("token not found, revoked or expired")
+	}
+	return &t, nil
+}
+
+func (m *mockTokenStore) TouchPersonalAccessToken(id int, now time.Time) error {
+	return nil
+}
+
+type mockNotificationStore struct {
+	created []types.Notification
+}
+
+func (m *mockNotificationStore) CreateNotification(n types.Notification) error {
+	m.created = append(m.created, n)
+	return nil
+}
+
+func (m *mockNotificationStore) GetNotificationsByUserID(userID int) ([]types.Notification, error) {
+	return nil, nil
+}
+
+func (m *mockNotificationStore) MarkNotificationRead(notificationID int, userID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) GetTaskLinks(taskID int) ([]types.TaskLink, error) {
+	return m.links, nil
+}
+
+func (m *mockTaskStore) CreateTaskLink(taskID int, linkedTaskID int, linkType types.TaskLinkType) error {
+	m.links = append(m.links, types.TaskLink{TaskID: taskID, LinkedTaskID: linkedTaskID, Type: linkType})
+	return nil
+}
+
+func (m *mockTaskStore) DeleteTaskLink(taskID int, linkID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) CloneTask(taskID int, options types.CloneTaskPayload, userID int) (int, error) {
+	m.cloned = append(m.cloned, taskID)
+	m.cloneOptions = options
+	m.clonedBy = userID
+	return 100 + taskID, nil
+}
+
+func (m *mockTaskStore) MergeTask(duplicateID int, originalID int) error {
+	m.merged = append(m.merged, [2]int{duplicateID, originalID})
+	return nil
+}
+
+func (m *mockTaskStore) GetWIPLimits() ([]types.WIPLimit, error) {
+	return nil, nil
+}
+
+func (m *mockTaskStore) SetWIPLimit(status types.TaskStatus, maxTasks int, userID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) DeleteWIPLimit(status types.TaskStatus) error {
+	return nil
+}
+
+func (m *mockTaskStore) RegressTask(taskID int, reason *string, overrideWIP bool) error {
+	return m.transitionErr
+}
+
+func (m *mockTaskStore) ReopenTask(taskID int, reason *string, overrideWIP bool) error {
+	return m.transitionErr
+}
+
+func (m *mockTaskStore) GetTaskLabels(taskIDs []int) (map[int][]string, error) {
+	return map[int][]string{}, nil
+}
+
+func (m *mockTaskStore) GetStaleThresholds() ([]types.StaleThreshold, error) {
+	return m.staleThresholds, nil
+}
+
+func (m *mockTaskStore) SetStaleThreshold(projectID *int, status types.TaskStatus, days int, userID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) DeleteStaleThreshold(projectID *int, status types.TaskStatus) error {
+	return nil
+}
+
+func (m *mockTaskStore) GetStaleTasks(now time.Time) ([]types.StaleTask, error) {
+	return m.staleTasks, nil
+}
+
+func (m *mockTaskStore) RecordEscalation(e types.TaskEscalation) (bool, error) {
+	for _, existing := range m.escalations {
+		if existing.TaskID == e.TaskID && existing.Level == e.Level && existing.StaleSince == e.StaleSince {
+			return false, nil
+		}
+	}
+	m.escalations = append(m.escalations, e)
+	return true, nil
+}
+
+func (m *mockTaskStore) GetTaskEscalations(taskID int) ([]types.TaskEscalation, error) {
+	return m.escalations, nil
+}
+
+func (m *mockTaskStore) AddTaskLabel(taskID int, label string) (bool, error) {
+	m.labels = append(m.labels, label)
+	return true, nil
+}
+
+func (m *mockTaskStore) RemoveTaskLabel(taskID int, label string) error {
+	return nil
+}
+
+func (m *mockTaskStore) GetCustomFields() ([]types.CustomField, error) {
+	return m.customFields, nil
+}
+
+func (m *mockTaskStore) CreateCustomField(f types.CustomField) (int, error) {
+	m.customFields = append(m.customFields, f)
+	return len(m.customFields), nil
+}
+
+func (m *mockTaskStore) DeleteCustomField(fieldID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) GetCustomFieldValues(taskIDs []int) ([]types.CustomFieldValue, error) {
+	return m.fieldValues, nil
+}
+
+func (m *mockTaskStore) SetCustomFieldValues(taskID int, values map[int]*string) error {
+	m.setValues = values
+	return nil
+}
+
+func (m *mockTaskStore) SnoozeTask(taskID int, until time.Time, userID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) UnsnoozeTask(taskID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) WakeSnoozedTasks(now time.Time) ([]types.Task, error) {
+	return m.snoozed, nil
+}
+
+func (m *mockTaskStore) PinTask(userID int, taskID int) error {
+	m.pinned = append(m.pinned, taskID)
+	return nil
+}
+
+func (m *mockTaskStore) UnpinTask(userID int, taskID int) error {
+	return nil
+}
+
+func (m *mockTaskStore) GetPinnedTaskIDs(userID int) ([]int, error) {
+	return m.pinned, nil
+}
+
+func (m *mockTaskStore) GetTasksByMilestone(milestoneID int) ([]types.Task, error) {
+	return nil, nil
+}
+
+func (m *mockTaskStore) SetTaskMilestone(taskID int, milestoneID *int) error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package task
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// isSnoozed reports whether a task is hidden by a snooze at the given time.
+func isSnoozed(t types.Task, now time.Time) bool {
+	if t.SnoozedUntil == nil {
+		return false
+	}
+	until, err := time.Parse(time.RFC3339Nano, *t.SnoozedUntil)
+	if err != nil {
+		return false
+	}
+	return until.After(now)
+}
+
+// withoutSnoozed drops the tasks that are snoozed at the given time.
+func withoutSnoozed(tasks []types.Task, now time.Time) []types.Task {
+	visible := make([]types.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if !isSnoozed(t, now) {
+			visible = append(visible, t)
+		}
+	}
+	return visible
+}
+
+// withPins marks the tasks pinned by the user and moves them to the top,
+// keeping the existing order within pinned and unpinned tasks.
+func (h *Handler) withPins(tasks []types.Task, userID int) error {
+	if userID <= 0 {
+		return nil
+	}
+
+	pinnedIDs, err := h.store.GetPinnedTaskIDs(userID)
+	if err != nil {
+		return err
+	}
+	pinned := make(map[int]bool, len(pinnedIDs))
+	for _, id := range pinnedIDs {
+		pinned[id] = true
+	}
+
+	for i := range tasks {
+		tasks[i].Pinned = pinned[tasks[i].ID]
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Pinned && !tasks[j].Pinned
+	})
+	return nil
+}
+
+// wakeSnoozedTasks un-snoozes the tasks whose wake time has passed and lets
+// the user who snoozed them and their assignee know they are back.
+func (h *Handler) wakeSnoozedTasks(now time.Time) error {
+	tasks, err := h.store.WakeSnoozedTasks(now)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		recipients := make(map[int]bool)
+		for _, userID := range []*int{t.SnoozedBy, t.AssigneeID} {
+			if userID == nil || recipients[*userID] {
+				continue
+			}
+			recipients[*userID] = true
+
+			taskID := t.ID
+			err := h.notifications.CreateNotification(types.Notification{
+				UserID:  *userID,
+				Type:    types.NotificationSnoozeEnded,
+				Message: fmt.Sprintf("Task #%d %q is back from snooze", t.ID, t.Title),
+				TaskID:  &taskID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// StartSnoozeWatcher wakes snoozed tasks every interval in the background.
+func (h *Handler) StartSnoozeWatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := h.wakeSnoozedTasks(now); err != nil {
+				log.Printf("failed to wake snoozed tasks: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,143 @@
+package task
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+// markStale flags the tasks that were not updated for longer than the
+// threshold of their status, using the thresholds of their project where it
+// has one.
+func markStale(tasks []types.Task, thresholds []types.StaleThreshold, now time.Time) {
+	days := make(map[types.TaskStatus]int)
+	projectDays := make(map[int]map[types.TaskStatus]int)
+	for _, t := range thresholds {
+		if t.ProjectID == nil {
+			days[t.Status] = t.Days
+			continue
+		}
+		if projectDays[*t.ProjectID] == nil {
+			projectDays[*t.ProjectID] = make(map[types.TaskStatus]int)
+		}
+		projectDays[*t.ProjectID][t.Status] = t.Days
+	}
+
+	for i := range tasks {
+		limit, ok := days[tasks[i].Status]
+		if tasks[i].ProjectID != nil {
+			if projectLimit, found := projectDays[*tasks[i].ProjectID][tasks[i].Status]; found {
+				limit, ok = projectLimit, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		updatedAt, err := time.Parse(time.RFC3339Nano, tasks[i].UpdatedAt)
+		if err != nil {
+			continue
+		}
+		tasks[i].Stale = !isSnoozed(tasks[i], now) && !updatedAt.After(now.AddDate(0, 0, -limit))
+	}
+}
+
+// withStale loads the stale thresholds and flags stale tasks.
+func (h *Handler) withStale(tasks []types.Task, now time.Time) error {
+	thresholds, err := h.store.GetStaleThresholds()
+	if err != nil {
+		return err
+	}
+	markStale(tasks, thresholds, now)
+	return nil
+}
+
+func onlyStale(tasks []types.Task) []types.Task {
+	stale := make([]types.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if t.Stale {
+			stale = append(stale, t)
+		}
+	}
+	return stale
+}
+
+// escalationLevel returns how far a task should be escalated after being
+// stale for the given time: to the assignee once the threshold has passed
+// and to the owner of the project or the threshold once it has passed twice.
+func escalationLevel(idle time.Duration, days int) int {
+	threshold := time.Duration(days) * 24 * time.Hour
+	switch {
+	case idle >= 2*threshold:
+		return types.EscalationOwner
+	case idle >= threshold:
+		return types.EscalationAssignee
+	}
+	return 0
+}
+
+// escalateStaleTasks records the escalations that are due for stale tasks and
+// notifies the escalated user. Escalations are kept per stale period, so a
+// task that is updated and goes stale again starts over with its assignee.
+func (h *Handler) escalateStaleTasks(now time.Time) error {
+	stale, err := h.store.GetStaleTasks(now)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range stale {
+		due := escalationLevel(now.Sub(t.UpdatedAt), t.Threshold.Days)
+		for level := t.Level + 1; level <= due; level++ {
+			escalation := types.TaskEscalation{
+				TaskID:     t.TaskID,
+				Level:      level,
+				StaleSince: t.UpdatedAt.UTC().Format(time.RFC3339Nano),
+			}
+			switch level {
+			case types.EscalationAssignee:
+				escalation.UserID = t.AssigneeID
+				escalation.Message = fmt.Sprintf("Task #%d %q has not been updated for %d days in %s", t.TaskID, t.Title, t.Threshold.Days, t.Threshold.Status)
+			case types.EscalationOwner:
+				escalation.UserID = t.ProjectOwnerID
+				if escalation.UserID == nil {
+					escalation.UserID = t.Threshold.UpdatedBy
+				}
+				escalation.Message = fmt.Sprintf("Task #%d %q is still stale after %d days in %s", t.TaskID, t.Title, 2*t.Threshold.Days, t.Threshold.Status)
+			}
+
+			recorded, err := h.store.RecordEscalation(escalation)
+			if err != nil {
+				return err
+			}
+			if !recorded || escalation.UserID == nil {
+				continue
+			}
+
+			taskID := t.TaskID
+			err = h.notifications.CreateNotification(types.Notification{
+				UserID:  *escalation.UserID,
+				Type:    types.NotificationStaleTask,
+				Message: escalation.Message,
+				TaskID:  &taskID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// StartStaleWatcher escalates stale tasks every interval in the background.
+func (h *Handler) StartStaleWatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := h.escalateStaleTasks(now); err != nil {
+				log.Printf("failed to escalate stale tasks: %v", err)
+			}
+		}
+	}()
+}
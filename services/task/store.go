@@ -2,8 +2,10 @@ package task
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -52,6 +54,74 @@ func (s *Store) GetTasks() ([]types.Task, error) {
 	return tasks, nil
 }
 
+// FindTasks returns the tasks matching the query. Custom fields are filtered
+// and sorted by the database, using the type of the field.
+func (s *Store) FindTasks(q types.TaskQuery) ([]types.Task, error) {
+	query := "SELECT t.* FROM tasks t"
+	args := make([]interface{}, 0)
+	orderBy := "t.id"
+	if q.Sort != nil {
+		query += " LEFT JOIN task_custom_field_values sv ON sv.task_id = t.id AND sv.field_id = ?"
+		args = append(args, q.Sort.Field.ID)
+		direction := "ASC"
+		if q.Sort.Descending {
+			direction = "DESC"
+		}
+		orderBy = fmt.Sprintf("sv.value IS NULL, %s %s, t.id", customFieldSortKey(q.Sort.Field.Type), direction)
+	}
+
+	conditions := make([]string, 0)
+	if q.Status != nil {
+		conditions = append(conditions, "t.status = ?")
+		args = append(args, *q.Status)
+	}
+	if q.ProjectID != nil {
+		conditions = append(conditions, "t.project_id = ?")
+		args = append(args, *q.ProjectID)
+	}
+	for _, f := range q.CustomFields {
+		match := "v.value = CAST(? AS JSON)"
+		if f.Field.Type == types.CustomFieldMultiSelect {
+			match = "JSON_CONTAINS(v.value, ?)"
+		}
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM task_custom_field_values v WHERE v.task_id = t.id AND v.field_id = ? AND "+match+")")
+		args = append(args, f.Field.ID, f.Value)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + orderBy
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]types.Task, 0)
+	for rows.Next() {
+		t, err := scanRowIntoTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *t)
+	}
+	return tasks, nil
+}
+
+// customFieldSortKey returns the expression ordering stored values of the
+// type: numbers and users numerically, multi selects by their JSON text and
+// everything else by the unquoted string.
+func customFieldSortKey(fieldType types.CustomFieldType) string {
+	switch fieldType {
+	case types.CustomFieldNumber, types.CustomFieldUser:
+		return "CAST(sv.value AS DOUBLE)"
+	case types.CustomFieldMultiSelect:
+		return "CAST(sv.value AS CHAR)"
+	}
+	return "JSON_UNQUOTE(sv.value)"
+}
+
 // get task by status, enum
 func (s *Store) GetTasksByStatus(status types.TaskStatus) ([]types.Task, error) {
 	rows, err := s.db.Query("SELECT * FROM tasks WHERE status = ?", status)
@@ -72,18 +142,79 @@ func (s *Store) GetTasksByStatus(status types.TaskStatus) ([]types.Task, error)
 
 func scanRowIntoTask(rows *sql.Rows) (*types.Task, error) {
 	t := new(types.Task)
-	err := rows.Scan(&t.ID, &t.Title, &t.Description, &t.Status, &t.CreatedAt, &t.UpdatedAt)
+	var startDate sql.NullTime
+	err := rows.Scan(&t.ID, &t.Title, &t.Description, &t.Status, &t.CreatedAt, &t.UpdatedAt, &t.StartedAt, &t.CompletedAt, &t.ReopenedCount, &t.DueDate, &t.Priority, &t.AssigneeID, &t.SnoozedUntil, &t.SnoozedBy,
+		&startDate, &t.EstimateDays, &t.MilestoneID, &t.CreatedBy, &t.ParentID, &t.ProjectID)
 	if err != nil {
 		return nil, err
 	}
+	if startDate.Valid {
+		start := startDate.Time.Format(types.DateLayout)
+		t.StartDate = &start
+	}
 	return t, nil
 }
 
-func (s *Store) CreateTask(t types.Task) error {
+func (s *Store) CreateTask(t types.Task, overrideWIP bool) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if !overrideWIP {
+		if err := checkWIPLimit(tx, t.Status, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	var startedAt, completedAt *time.Time
+	now := time.Now()
+	switch t.Status {
+	case types.StatusInProgress:
+		startedAt = &now
+	case types.StatusCompleted:
+		completedAt = &now
+	}
 
-	_, err := s.db.Exec("INSERT INTO tasks (title, description, status) VALUES (?, ?, ?)", t.Title, t.Description, t.Status)
+	if t.Priority == "" {
+		t.Priority = types.PriorityMedium
+	}
 
-	return err
+	var dueDate *time.Time
+	if t.DueDate != nil {
+		due, err := time.Parse(time.RFC3339, *t.DueDate)
+		if err != nil {
+			return 0, fmt.Errorf("invalid due date: %w", err)
+		}
+		due = due.UTC()
+		dueDate = &due
+	}
+
+	res, err := tx.Exec("INSERT INTO tasks (title, description, status, started_at, completed_at, due_date, priority, assignee_id, created_by, parent_id, project_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		t.Title, t.Description, t.Status, startedAt, completedAt, dueDate, t.Priority, t.AssigneeID, t.CreatedBy, t.ParentID, t.ProjectID)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, label := range t.Labels {
+		if _, err := tx.Exec("INSERT IGNORE INTO task_labels (task_id, label) VALUES (?, ?)", id, label); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := recordStatusChange(tx, int(id), t.Status, nil); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(id), nil
 }
 
 func validateCreateTaskPayload(payload types.CreateTaskPayload) error {
@@ -95,7 +226,9 @@ func validateCreateTaskPayload(payload types.CreateTaskPayload) error {
 	return nil
 }
 
-func (s *Store) UpdateTask(taskID int, updates types.UpdateTaskPayload) error {
+// UpdateTask applies the given updates. Status changes are checked against the
+// WIP limit of the target status unless overrideWIP is set.
+func (s *Store) UpdateTask(taskID int, updates types.UpdateTaskPayload, overrideWIP bool) error {
 	var setValues []string
 	var args []interface{}
 
@@ -107,9 +240,33 @@ func (s *Store) UpdateTask(taskID int, updates types.UpdateTaskPayload) error {
 		setValues = append(setValues, "description = ?")
 		args = append(args, updates.Description)
 	}
+	if updates.DueDate != nil {
+		setValues = append(setValues, "due_date = ?")
+		args = append(args, updates.DueDate.UTC())
+	}
+	if updates.StartDate != nil {
+		setValues = append(setValues, "start_date = ?")
+		args = append(args, updates.StartDate)
+	}
+	if updates.EstimateDays != nil {
+		setValues = append(setValues, "estimate_days = ?")
+		args = append(args, updates.EstimateDays)
+	}
+	if updates.Priority != nil {
+		setValues = append(setValues, "priority = ?")
+		args = append(args, updates.Priority)
+	}
+	if updates.AssigneeID != nil {
+		setValues = append(setValues, "assignee_id = ?")
+		args = append(args, updates.AssigneeID)
+	}
 	if updates.Status != nil {
 		setValues = append(setValues, "status = ?")
 		args = append(args, updates.Status)
+
+		clauses, clauseArgs := statusTimestamps(*updates.Status, time.Now())
+		setValues = append(setValues, clauses...)
+		args = append(args, clauseArgs...)
 	}
 	setValues = append(setValues, "updated_at = ?")
 	args = append(args, time.Now()) // current timestamp
@@ -117,28 +274,932 @@ func (s *Store) UpdateTask(taskID int, updates types.UpdateTaskPayload) error {
 	query := fmt.Sprintf("UPDATE tasks SET %s WHERE id = ?", strings.Join(setValues, ", "))
 	args = append(args, taskID)
 
-	_, err := s.db.Exec(query, args...)
+	if updates.Status == nil {
+		_, err := s.db.Exec(query, args...)
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !overrideWIP {
+		if err := checkWIPLimit(tx, *updates.Status, taskID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	if err := recordStatusChange(tx, taskID, *updates.Status, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// checkWIPLimit fails with a WIPLimitError when moving a task into the given
+// status would exceed its configured limit. The limit row is locked so that
+// concurrent transitions into the same status are serialized.
+func checkWIPLimit(tx *sql.Tx, status types.TaskStatus, taskID int) error {
+	var limit int
+	err := tx.QueryRow("SELECT max_tasks FROM wip_limits WHERE status = ? FOR UPDATE", status).Scan(&limit)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM tasks WHERE status = ? AND id <> ?", status, taskID).Scan(&count); err != nil {
+		return err
+	}
+	if count >= limit {
+		return &types.WIPLimitError{Status: status, Limit: limit, Current: count}
+	}
+	return nil
+}
+
+// recordStatusChange appends an entry to the status history of a task, which
+// sprint burndowns are computed from.
+func recordStatusChange(db execer, taskID int, status types.TaskStatus, reason *string) error {
+	_, err := db.Exec("INSERT INTO task_status_history (task_id, status, reason) VALUES (?, ?, ?)", taskID, status, reason)
 	return err
 }
 
-func (s *Store) ProgressTask(taskID int) error {
+// statusTimestamps returns the SET clauses that keep started_at and
+// completed_at consistent with a task entering the given status.
+func statusTimestamps(status types.TaskStatus, now time.Time) ([]string, []interface{}) {
+	switch status {
+	case types.StatusPending:
+		return []string{"started_at = NULL", "completed_at = NULL"}, nil
+	case types.StatusInProgress:
+		return []string{"started_at = COALESCE(started_at, ?)", "completed_at = NULL"}, []interface{}{now}
+	case types.StatusCompleted:
+		return []string{"completed_at = ?"}, []interface{}{now}
+	}
+	return nil, nil
+}
+
+// transitionTask moves a task from one status to another with a single
+// compare-and-set update. If the task left the expected status in the
+// meantime, for example because a concurrent progress call won, nothing is
+// changed and ErrTaskStatusChanged is returned.
+func (s *Store) transitionTask(taskID int, from, to types.TaskStatus, reason *string, reopen bool, overrideWIP bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !overrideWIP {
+		if err := checkWIPLimit(tx, to, taskID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	setValues := []string{"status = ?"}
+	args := []interface{}{to}
+
+	clauses, clauseArgs := statusTimestamps(to, now)
+	setValues = append(setValues, clauses...)
+	args = append(args, clauseArgs...)
+
+	if reopen {
+		setValues = append(setValues, "reopened_count = reopened_count + 1")
+	}
+	setValues = append(setValues, "updated_at = ?")
+	args = append(args, now)
+
+	query := fmt.Sprintf("UPDATE tasks SET %s WHERE id = ? AND status = ?", strings.Join(setValues, ", "))
+	args = append(args, taskID, from)
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return types.ErrTaskStatusChanged
+	}
+
+	if err := recordStatusChange(tx, taskID, to, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) ProgressTask(taskID int, overrideWIP bool) error {
 	task, err := s.GetTaskByID(taskID)
 	if err != nil {
 		return err
 	}
+
+	var next types.TaskStatus
 	switch task.Status {
 	case types.StatusPending:
-		task.Status = types.StatusInProgress
+		next = types.StatusInProgress
 	case types.StatusInProgress:
-		task.Status = types.StatusCompleted
+		next = types.StatusCompleted
+	default:
+		return &types.InvalidTransitionError{Action: "progress", Status: task.Status}
+	}
+
+	return s.transitionTask(taskID, task.Status, next, nil, false, overrideWIP)
+}
+
+// RegressTask moves a task one stage back: completed tasks go back to
+// in_progress and in_progress tasks back to pending.
+func (s *Store) RegressTask(taskID int, reason *string, overrideWIP bool) error {
+	task, err := s.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+
+	var previous types.TaskStatus
+	switch task.Status {
 	case types.StatusCompleted:
-		return errors.New("task is already completed")
+		previous = types.StatusInProgress
+	case types.StatusInProgress:
+		previous = types.StatusPending
+	default:
+		return &types.InvalidTransitionError{Action: "regress", Status: task.Status}
+	}
+
+	return s.transitionTask(taskID, task.Status, previous, reason, false, overrideWIP)
+}
+
+// ReopenTask moves a completed task back to pending and counts the reopen.
+func (s *Store) ReopenTask(taskID int, reason *string, overrideWIP bool) error {
+	task, err := s.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+
+	if task.Status != types.StatusCompleted {
+		return &types.InvalidTransitionError{Action: "reopen", Status: task.Status}
 	}
 
-	return s.UpdateTask(taskID, types.UpdateTaskPayload{Status: &task.Status})
+	return s.transitionTask(taskID, task.Status, types.StatusPending, reason, true, overrideWIP)
 }
 
 func (s *Store) DeleteTask(taskID int) error {
 	_, err := s.db.Exec("DELETE FROM tasks WHERE id = ?", taskID)
 	return err
 }
+
+// GetTaskMentions returns, for each of the given tasks, the users mentioned
+// and the tasks referenced by it, along with the tasks that reference it.
+func (s *Store) GetTaskMentions(taskIDs []int) (map[int]*types.TaskMentions, error) {
+	mentions := make(map[int]*types.TaskMentions, len(taskIDs))
+	for _, id := range taskIDs {
+		mentions[id] = &types.TaskMentions{Users: []int{}, Tasks: []int{}, ReferencedBy: []int{}}
+	}
+	if len(taskIDs) == 0 {
+		return mentions, nil
+	}
+
+	placeholders, args := inClause(taskIDs)
+	err := s.queryIDPairs("SELECT task_id, user_id FROM task_mentions WHERE task_id IN ("+placeholders+") ORDER BY user_id", args, func(taskID, userID int) {
+		mentions[taskID].Users = append(mentions[taskID].Users, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.queryIDPairs("SELECT task_id, referenced_task_id FROM task_references WHERE task_id IN ("+placeholders+") ORDER BY referenced_task_id", args, func(taskID, refID int) {
+		mentions[taskID].Tasks = append(mentions[taskID].Tasks, refID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.queryIDPairs("SELECT referenced_task_id, task_id FROM task_references WHERE referenced_task_id IN ("+placeholders+") ORDER BY task_id", args, func(taskID, refID int) {
+		mentions[taskID].ReferencedBy = append(mentions[taskID].ReferencedBy, refID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mentions, nil
+}
+
+// queryIDPairs calls add with the two IDs of each row of the query.
+func (s *Store) queryIDPairs(query string, args []interface{}, add func(int, int)) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a, b int
+		if err := rows.Scan(&a, &b); err != nil {
+			return err
+		}
+		add(a, b)
+	}
+	return rows.Err()
+}
+
+// SetTaskMentions replaces the stored mentions and references of a task.
+func (s *Store) SetTaskMentions(taskID int, userIDs []int, taskIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM task_mentions WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM task_references WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if _, err := tx.Exec("INSERT INTO task_mentions (task_id, user_id) VALUES (?, ?)", taskID, userID); err != nil {
+			return err
+		}
+	}
+	for _, refID := range taskIDs {
+		if _, err := tx.Exec("INSERT INTO task_references (task_id, referenced_task_id) VALUES (?, ?)", taskID, refID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *Store) GetTaskLinks(taskID int) ([]types.TaskLink, error) {
+	rows, err := s.db.Query("SELECT * FROM task_links WHERE task_id = ? ORDER BY id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]types.TaskLink, 0)
+	for rows.Next() {
+		l := types.TaskLink{}
+		if err := rows.Scan(&l.ID, &l.TaskID, &l.LinkedTaskID, &l.Type, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
+
+// CreateTaskLink links two tasks and stores the inverse link on the other task.
+func (s *Store) CreateTaskLink(taskID int, linkedTaskID int, linkType types.TaskLinkType) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertLinkPair(tx, taskID, linkedTaskID, linkType); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteTaskLink removes a link of the given task together with its inverse.
+func (s *Store) DeleteTaskLink(taskID int, linkID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var linkedTaskID int
+	var linkType types.TaskLinkType
+	err = tx.QueryRow("SELECT linked_task_id, type FROM task_links WHERE id = ? AND task_id = ?", linkID, taskID).Scan(&linkedTaskID, &linkType)
+	if err == sql.ErrNoRows {
+		return errors.New("no link found with the given ID")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM task_links WHERE id = ?", linkID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM task_links WHERE task_id = ? AND linked_task_id = ? AND type = ?", linkedTaskID, taskID, linkType.Inverse()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CloneTask copies a task as a new pending task created by the user, with
+// its custom field values and, when asked for, its labels and subtasks. Each
+// copy records a clone link to the task it was copied from.
+func (s *Store) CloneTask(taskID int, options types.CloneTaskPayload, userID int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	cloneID, err := cloneTask(tx, taskID, nil, options, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return cloneID, nil
+}
+
+// cloneTask copies one task under the given parent, or under the parent of
+// the original when parentID is nil, and then its subtasks under the copy.
+func cloneTask(tx *sql.Tx, taskID int, parentID *int, options types.CloneTaskPayload, userID int) (int, error) {
+	if err := checkWIPLimit(tx, types.StatusPending, 0); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO tasks (title, description, status, due_date, priority, assignee_id, start_date, estimate_days, milestone_id, created_by, parent_id, project_id)
+		SELECT title, description, ?, due_date, priority, assignee_id, start_date, estimate_days, milestone_id, ?, COALESCE(?, parent_id), project_id FROM tasks WHERE id = ?`,
+		types.StatusPending, userID, parentID, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if affected == 0 {
+		return 0, errors.New("no task found with the given ID")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	cloneID := int(id)
+
+	if options.Labels {
+		if _, err := tx.Exec("INSERT INTO task_labels (task_id, label) SELECT ?, label FROM task_labels WHERE task_id = ?", cloneID, taskID); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO task_custom_field_values (task_id, field_id, value) SELECT ?, field_id, value FROM task_custom_field_values WHERE task_id = ?", cloneID, taskID); err != nil {
+		return 0, err
+	}
+
+	if err := recordStatusChange(tx, cloneID, types.StatusPending, nil); err != nil {
+		return 0, err
+	}
+	if err := insertLinkPair(tx, cloneID, taskID, types.LinkClonedFrom); err != nil {
+		return 0, err
+	}
+
+	if !options.Children {
+		return cloneID, nil
+	}
+	children, err := queryTxIDs(tx, "SELECT id FROM tasks WHERE parent_id = ? ORDER BY id", taskID)
+	if err != nil {
+		return 0, err
+	}
+	for _, childID := range children {
+		if _, err := cloneTask(tx, childID, &cloneID, options, userID); err != nil {
+			return 0, err
+		}
+	}
+	return cloneID, nil
+}
+
+// queryTxIDs reads a column of IDs inside a transaction. The rows are closed
+// before returning, so the transaction can run other statements.
+func queryTxIDs(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MergeTask closes a duplicate task and links it to the original one.
+func (s *Store) MergeTask(duplicateID int, originalID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkWIPLimit(tx, types.StatusCompleted, duplicateID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res, err := tx.Exec("UPDATE tasks SET status = ?, completed_at = ?, updated_at = ? WHERE id = ?", types.StatusCompleted, now, now, duplicateID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no task found with the given ID")
+	}
+
+	reason := fmt.Sprintf("duplicate of task #%d", originalID)
+	if err := recordStatusChange(tx, duplicateID, types.StatusCompleted, &reason); err != nil {
+		return err
+	}
+
+	if err := insertLinkPair(tx, duplicateID, originalID, types.LinkDuplicates); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertLinkPair(tx *sql.Tx, taskID int, linkedTaskID int, linkType types.TaskLinkType) error {
+	query := "INSERT IGNORE INTO task_links (task_id, linked_task_id, type) VALUES (?, ?, ?)"
+	if _, err := tx.Exec(query, taskID, linkedTaskID, linkType); err != nil {
+		return err
+	}
+	_, err := tx.Exec(query, linkedTaskID, taskID, linkType.Inverse())
+	return err
+}
+
+func (s *Store) GetWIPLimits() ([]types.WIPLimit, error) {
+	rows, err := s.db.Query(`SELECT w.status, w.max_tasks, COUNT(t.id), w.updated_at FROM wip_limits w
+		LEFT JOIN tasks t ON t.status = w.status
+		GROUP BY w.status, w.max_tasks, w.updated_at
+		ORDER BY w.status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make([]types.WIPLimit, 0)
+	for rows.Next() {
+		l := types.WIPLimit{}
+		if err := rows.Scan(&l.Status, &l.MaxTasks, &l.Current, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		limits = append(limits, l)
+	}
+	return limits, nil
+}
+
+func (s *Store) SetWIPLimit(status types.TaskStatus, maxTasks int, userID int) error {
+	_, err := s.db.Exec(`INSERT INTO wip_limits (status, max_tasks, updated_by) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE max_tasks = VALUES(max_tasks), updated_by = VALUES(updated_by), updated_at = CURRENT_TIMESTAMP`, status, maxTasks, userID)
+	return err
+}
+
+func (s *Store) DeleteWIPLimit(status types.TaskStatus) error {
+	res, err := s.db.Exec("DELETE FROM wip_limits WHERE status = ?", status)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no WIP limit configured for the given status")
+	}
+	return nil
+}
+
+// GetStaleThresholds returns the thresholds without a project followed by the
+// thresholds of each project.
+func (s *Store) GetStaleThresholds() ([]types.StaleThreshold, error) {
+	rows, err := s.db.Query(`SELECT NULL AS project_id, status, days, updated_by, updated_at FROM stale_thresholds
+		UNION ALL SELECT project_id, status, days, updated_by, updated_at FROM project_stale_thresholds
+		ORDER BY project_id, status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	thresholds := make([]types.StaleThreshold, 0)
+	for rows.Next() {
+		t := types.StaleThreshold{}
+		if err := rows.Scan(&t.ProjectID, &t.Status, &t.Days, &t.UpdatedBy, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, nil
+}
+
+// SetStaleThreshold sets the threshold of a status in the project, or the one
+// without a project when projectID is nil.
+func (s *Store) SetStaleThreshold(projectID *int, status types.TaskStatus, days int, userID int) error {
+	if projectID == nil {
+		_, err := s.db.Exec(`INSERT INTO stale_thresholds (status, days, updated_by) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE days = VALUES(days), updated_by = VALUES(updated_by), updated_at = CURRENT_TIMESTAMP`, status, days, userID)
+		return err
+	}
+	_, err := s.db.Exec(`INSERT INTO project_stale_thresholds (project_id, status, days, updated_by) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE days = VALUES(days), updated_by = VALUES(updated_by), updated_at = CURRENT_TIMESTAMP`, *projectID, status, days, userID)
+	return err
+}
+
+func (s *Store) DeleteStaleThreshold(projectID *int, status types.TaskStatus) error {
+	var res sql.Result
+	var err error
+	if projectID == nil {
+		res, err = s.db.Exec("DELETE FROM stale_thresholds WHERE status = ?", status)
+	} else {
+		res, err = s.db.Exec("DELETE FROM project_stale_thresholds WHERE project_id = ? AND status = ?", *projectID, status)
+	}
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no stale threshold configured for the given status")
+	}
+	return nil
+}
+
+// GetStaleTasks returns the tasks that were not updated for longer than the
+// threshold of their status, preferring the threshold of their project and
+// skipping snoozed tasks. Level is the highest escalation recorded since the
+// last update of the task.
+func (s *Store) GetStaleTasks(now time.Time) ([]types.StaleTask, error) {
+	rows, err := s.db.Query(`SELECT t.id, t.title, t.assignee_id, p.owner_id, t.updated_at, st.project_id, st.status, st.days, st.updated_by, st.updated_at,
+			(SELECT COALESCE(MAX(e.level), 0) FROM task_escalations e WHERE e.task_id = t.id AND e.stale_since = t.updated_at)
+		FROM tasks t
+		JOIN (
+			SELECT NULL AS project_id, status, days, updated_by, updated_at FROM stale_thresholds
+			UNION ALL SELECT project_id, status, days, updated_by, updated_at FROM project_stale_thresholds
+		) st ON st.status = t.status AND (st.project_id = t.project_id OR (st.project_id IS NULL AND NOT EXISTS (
+			SELECT 1 FROM project_stale_thresholds pst WHERE pst.project_id = t.project_id AND pst.status = t.status
+		)))
+		LEFT JOIN projects p ON p.id = t.project_id
+		WHERE t.updated_at <= DATE_SUB(?, INTERVAL st.days DAY)
+			AND (t.snoozed_until IS NULL OR t.snoozed_until <= ?)
+		ORDER BY t.updated_at`, now.UTC(), now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stale := make([]types.StaleTask, 0)
+	for rows.Next() {
+		t := types.StaleTask{}
+		err := rows.Scan(
+			&t.TaskID,
+			&t.Title,
+			&t.AssigneeID,
+			&t.ProjectOwnerID,
+			&t.UpdatedAt,
+			&t.Threshold.ProjectID,
+			&t.Threshold.Status,
+			&t.Threshold.Days,
+			&t.Threshold.UpdatedBy,
+			&t.Threshold.UpdatedAt,
+			&t.Level,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stale = append(stale, t)
+	}
+	return stale, nil
+}
+
+// RecordEscalation stores an escalation of a stale task and reports whether
+// it was new. StaleSince is the RFC 3339 time the task was last updated.
+func (s *Store) RecordEscalation(e types.TaskEscalation) (bool, error) {
+	staleSince, err := time.Parse(time.RFC3339Nano, e.StaleSince)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.db.Exec("INSERT IGNORE INTO task_escalations (task_id, level, user_id, stale_since, message) VALUES (?, ?, ?, ?, ?)",
+		e.TaskID, e.Level, e.UserID, staleSince.UTC(), e.Message)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) GetTaskEscalations(taskID int) ([]types.TaskEscalation, error) {
+	rows, err := s.db.Query("SELECT id, task_id, level, user_id, stale_since, message, created_at FROM task_escalations WHERE task_id = ? ORDER BY created_at, id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	escalations := make([]types.TaskEscalation, 0)
+	for rows.Next() {
+		e := types.TaskEscalation{}
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Level, &e.UserID, &e.StaleSince, &e.Message, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		escalations = append(escalations, e)
+	}
+	return escalations, nil
+}
+
+func (s *Store) GetCustomFields() ([]types.CustomField, error) {
+	rows, err := s.db.Query("SELECT * FROM custom_fields ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := make([]types.CustomField, 0)
+	for rows.Next() {
+		f := types.CustomField{}
+		var options []byte
+		if err := rows.Scan(&f.ID, &f.Name, &f.Type, &options, &f.CreatedAt, &f.ProjectID); err != nil {
+			return nil, err
+		}
+		if len(options) > 0 {
+			if err := json.Unmarshal(options, &f.Options); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func (s *Store) CreateCustomField(f types.CustomField) (int, error) {
+	var options []byte
+	if len(f.Options) > 0 {
+		var err error
+		if options, err = json.Marshal(f.Options); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := s.db.Exec("INSERT INTO custom_fields (name, type, options, project_id) VALUES (?, ?, ?, ?)", f.Name, f.Type, options, f.ProjectID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *Store) DeleteCustomField(fieldID int) error {
+	res, err := s.db.Exec("DELETE FROM custom_fields WHERE id = ?", fieldID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no custom field found with the given ID")
+	}
+	return nil
+}
+
+func (s *Store) GetCustomFieldValues(taskIDs []int) ([]types.CustomFieldValue, error) {
+	values := make([]types.CustomFieldValue, 0)
+	if len(taskIDs) == 0 {
+		return values, nil
+	}
+
+	placeholders, args := inClause(taskIDs)
+	rows, err := s.db.Query("SELECT task_id, field_id, value FROM task_custom_field_values WHERE task_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v := types.CustomFieldValue{}
+		if err := rows.Scan(&v.TaskID, &v.FieldID, &v.Value); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// SetCustomFieldValues stores the given values of a task. A nil value removes
+// the field from the task.
+func (s *Store) SetCustomFieldValues(taskID int, values map[int]*string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	fieldIDs := make([]int, 0, len(values))
+	for fieldID := range values {
+		fieldIDs = append(fieldIDs, fieldID)
+	}
+	sort.Ints(fieldIDs)
+
+	for _, fieldID := range fieldIDs {
+		value := values[fieldID]
+		if value == nil {
+			_, err = tx.Exec("DELETE FROM task_custom_field_values WHERE task_id = ? AND field_id = ?", taskID, fieldID)
+		} else {
+			_, err = tx.Exec(`INSERT INTO task_custom_field_values (task_id, field_id, value) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE value = VALUES(value)`, taskID, fieldID, *value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE tasks SET updated_at = ? WHERE id = ?", time.Now(), taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) GetTaskLabels(taskIDs []int) (map[int][]string, error) {
+	labels := make(map[int][]string)
+	if len(taskIDs) == 0 {
+		return labels, nil
+	}
+
+	placeholders, args := inClause(taskIDs)
+	rows, err := s.db.Query("SELECT task_id, label FROM task_labels WHERE task_id IN ("+placeholders+") ORDER BY label", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var label string
+		if err := rows.Scan(&taskID, &label); err != nil {
+			return nil, err
+		}
+		labels[taskID] = append(labels[taskID], label)
+	}
+	return labels, nil
+}
+
+// AddTaskLabel adds a label to a task and reports whether the task did not
+// have it yet.
+func (s *Store) AddTaskLabel(taskID int, label string) (bool, error) {
+	res, err := s.db.Exec("INSERT IGNORE INTO task_labels (task_id, label) VALUES (?, ?)", taskID, label)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (s *Store) RemoveTaskLabel(taskID int, label string) error {
+	_, err := s.db.Exec("DELETE FROM task_labels WHERE task_id = ? AND label = ?", taskID, label)
+	return err
+}
+
+// inClause returns the placeholders and arguments for an IN (...) condition.
+func inClause(ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+func (s *Store) SnoozeTask(taskID int, until time.Time, userID int) error {
+	res, err := s.db.Exec("UPDATE tasks SET snoozed_until = ?, snoozed_by = ? WHERE id = ?", until.UTC(), userID, taskID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	// MySQL does not count rows that already had the values, so snoozing
+	// again until the same time affects nothing either.
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM tasks WHERE id = ?)", taskID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("no task found with the given ID")
+	}
+	return nil
+}
+
+func (s *Store) UnsnoozeTask(taskID int) error {
+	_, err := s.db.Exec("UPDATE tasks SET snoozed_until = NULL, snoozed_by = NULL WHERE id = ?", taskID)
+	return err
+}
+
+// WakeSnoozedTasks clears the snooze of every task whose wake time has passed
+// and returns those tasks as they were before waking up.
+func (s *Store) WakeSnoozedTasks(now time.Time) ([]types.Task, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT * FROM tasks WHERE snoozed_until <= ? FOR UPDATE", now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]types.Task, 0)
+	for rows.Next() {
+		t, err := scanRowIntoTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tasks = append(tasks, *t)
+	}
+	rows.Close()
+	if len(tasks) == 0 {
+		return tasks, nil
+	}
+
+	ids := make([]int, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	placeholders, args := inClause(ids)
+	if _, err := tx.Exec("UPDATE tasks SET snoozed_until = NULL, snoozed_by = NULL WHERE id IN ("+placeholders+")", args...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *Store) PinTask(userID int, taskID int) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO task_pins (user_id, task_id) VALUES (?, ?)", userID, taskID)
+	return err
+}
+
+func (s *Store) UnpinTask(userID int, taskID int) error {
+	_, err := s.db.Exec("DELETE FROM task_pins WHERE user_id = ? AND task_id = ?", userID, taskID)
+	return err
+}
+
+func (s *Store) GetPinnedTaskIDs(userID int) ([]int, error) {
+	return s.queryIDs("SELECT task_id FROM task_pins WHERE user_id = ? ORDER BY pinned_at", userID)
+}
+
+func (s *Store) GetTasksByMilestone(milestoneID int) ([]types.Task, error) {
+	rows, err := s.db.Query("SELECT * FROM tasks WHERE milestone_id = ? ORDER BY id", milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]types.Task, 0)
+	for rows.Next() {
+		t, err := scanRowIntoTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *t)
+	}
+	return tasks, nil
+}
+
+func (s *Store) SetTaskMilestone(taskID int, milestoneID *int) error {
+	_, err := s.db.Exec("UPDATE tasks SET milestone_id = ?, updated_at = ? WHERE id = ?", milestoneID, time.Now(), taskID)
+	return err
+}
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -9,6 +10,8 @@ import (
 	"github.com/trsnaqe/gotask/types"
 )
 
+var taskColumns = []string{"id", "title", "description", "status", "created_at", "updated_at", "started_at", "completed_at", "reopened_count", "due_date", "priority", "assignee_id", "snoozed_until", "snoozed_by", "start_date", "estimate_days", "milestone_id", "created_by", "parent_id", "project_id"}
+
 func taskComparator(task1, task2 *types.Task) bool {
 	return task1.ID == task2.ID &&
 		task1.Title == task2.Title &&
@@ -31,13 +34,14 @@ func TestGetTaskByID(t *testing.T) {
 		Status:      types.StatusPending,
 		CreatedAt:   time.Now().Format(time.RFC3339),
 		UpdatedAt:   time.Now().Format(time.RFC3339),
+		Priority:    types.PriorityMedium,
 	}
 
 	// Mock the database query
 	mock.ExpectQuery("SELECT \\* FROM tasks WHERE id = ?").
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
-			AddRow(expectedTask.ID, expectedTask.Title, expectedTask.Description, expectedTask.Status, expectedTask.CreatedAt, expectedTask.UpdatedAt))
+		WillReturnRows(sqlmock.NewRows(taskColumns).
+			AddRow(expectedTask.ID, expectedTask.Title, expectedTask.Description, expectedTask.Status, expectedTask.CreatedAt, expectedTask.UpdatedAt, nil, nil, 0, nil, expectedTask.Priority, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 
 	// Call the GetTaskByID function
 	resultTask, err := store.GetTaskByID(1)
@@ -80,9 +84,9 @@ func TestGetTasks(t *testing.T) {
 	}
 
 	mock.ExpectQuery("SELECT \\* FROM tasks").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
-			AddRow(expectedTasks[0].ID, expectedTasks[0].Title, expectedTasks[0].Description, expectedTasks[0].Status, expectedTasks[0].CreatedAt, expectedTasks[0].UpdatedAt).
-			AddRow(expectedTasks[1].ID, expectedTasks[1].Title, expectedTasks[1].Description, expectedTasks[1].Status, expectedTasks[1].CreatedAt, expectedTasks[1].UpdatedAt))
+		WillReturnRows(sqlmock.NewRows(taskColumns).
+			AddRow(expectedTasks[0].ID, expectedTasks[0].Title, expectedTasks[0].Description, expectedTasks[0].Status, expectedTasks[0].CreatedAt, expectedTasks[0].UpdatedAt, nil, nil, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil).
+			AddRow(expectedTasks[1].ID, expectedTasks[1].Title, expectedTasks[1].Description, expectedTasks[1].Status, expectedTasks[1].CreatedAt, expectedTasks[1].UpdatedAt, nil, nil, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 
 	resultTasks, err := store.GetTasks()
 	if err != nil {
@@ -130,9 +134,9 @@ func TestGetTasksByStatus(t *testing.T) {
 
 	mock.ExpectQuery("SELECT \\* FROM tasks WHERE status = ?").
 		WithArgs(status).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
-			AddRow(expectedTasks[0].ID, expectedTasks[0].Title, expectedTasks[0].Description, expectedTasks[0].Status, expectedTasks[0].CreatedAt, expectedTasks[0].UpdatedAt).
-			AddRow(expectedTasks[1].ID, expectedTasks[1].Title, expectedTasks[1].Description, expectedTasks[1].Status, expectedTasks[1].CreatedAt, expectedTasks[1].UpdatedAt))
+		WillReturnRows(sqlmock.NewRows(taskColumns).
+			AddRow(expectedTasks[0].ID, expectedTasks[0].Title, expectedTasks[0].Description, expectedTasks[0].Status, expectedTasks[0].CreatedAt, expectedTasks[0].UpdatedAt, nil, nil, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil).
+			AddRow(expectedTasks[1].ID, expectedTasks[1].Title, expectedTasks[1].Description, expectedTasks[1].Status, expectedTasks[1].CreatedAt, expectedTasks[1].UpdatedAt, nil, nil, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 
 	resultTasks, err := store.GetTasksByStatus(status)
 	if err != nil {
@@ -163,15 +167,26 @@ func TestCreateTask(t *testing.T) {
 		Status:      types.StatusPending,
 	}
 
-	mock.ExpectExec("INSERT INTO tasks \\(title, description, status\\) VALUES \\(\\?, \\?, \\?\\)").
-		WithArgs(newTask.Title, newTask.Description, newTask.Status).
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT max_tasks FROM wip_limits WHERE status = \\? FOR UPDATE").
+		WithArgs(newTask.Status).
+		WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}))
+	mock.ExpectExec("INSERT INTO tasks \\(title, description, status, started_at, completed_at, due_date, priority, assignee_id, created_by, parent_id, project_id\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(newTask.Title, newTask.Description, newTask.Status, nil, nil, nil, types.PriorityMedium, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO task_status_history \\(task_id, status, reason\\) VALUES \\(\\?, \\?, \\?\\)").
+		WithArgs(1, newTask.Status, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	err = store.CreateTask(newTask)
+	id, err := store.CreateTask(newTask, false)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 		return
 	}
+	if id != 1 {
+		t.Errorf("expected created task ID 1, got %d", id)
+	}
 }
 
 func TestUpdateTask(t *testing.T) {
@@ -193,7 +208,7 @@ func TestUpdateTask(t *testing.T) {
 		WithArgs(updates.Title, sqlmock.AnyArg(), taskID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = store.UpdateTask(taskID, updates)
+	err = store.UpdateTask(taskID, updates, false)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 		return
@@ -220,17 +235,28 @@ func TestProgressTask(t *testing.T) {
 
 	mock.ExpectQuery("SELECT \\* FROM tasks WHERE id = ?").
 		WithArgs(taskID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at"}).
-			AddRow(expectedTask.ID, expectedTask.Title, expectedTask.Description, expectedTask.Status, expectedTask.CreatedAt, expectedTask.UpdatedAt))
+		WillReturnRows(sqlmock.NewRows(taskColumns).
+			AddRow(expectedTask.ID, expectedTask.Title, expectedTask.Description, expectedTask.Status, expectedTask.CreatedAt, expectedTask.UpdatedAt, nil, nil, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 
 	//it should insert one step further as the status is updated
 	expectedTask.Status = types.StatusInProgress
 
-	mock.ExpectExec("UPDATE tasks SET status = \\?, updated_at = \\? WHERE id = ?").
-		WithArgs(expectedTask.Status, sqlmock.AnyArg(), taskID).
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT max_tasks FROM wip_limits WHERE status = \\? FOR UPDATE").
+		WithArgs(expectedTask.Status).
+		WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}).AddRow(3))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE status = \\? AND id <> \\?").
+		WithArgs(expectedTask.Status, taskID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, started_at = COALESCE\\(started_at, \\?\\), completed_at = NULL, updated_at = \\? WHERE id = \\? AND status = \\?").
+		WithArgs(expectedTask.Status, sqlmock.AnyArg(), sqlmock.AnyArg(), taskID, types.StatusPending).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_status_history \\(task_id, status, reason\\) VALUES \\(\\?, \\?, \\?\\)").
+		WithArgs(taskID, expectedTask.Status, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	err = store.ProgressTask(taskID)
+	err = store.ProgressTask(taskID, false)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 		return
@@ -257,3 +283,729 @@ func TestDeleteTask(t *testing.T) {
 		return
 	}
 }
+
+func TestGetTaskMentions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectQuery("SELECT task_id, user_id FROM task_mentions WHERE task_id IN \\(\\?, \\?\\)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"task_id", "user_id"}).AddRow(1, 2).AddRow(1, 5))
+	mock.ExpectQuery("SELECT task_id, referenced_task_id FROM task_references WHERE task_id IN \\(\\?, \\?\\)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"task_id", "referenced_task_id"}).AddRow(1, 3))
+	mock.ExpectQuery("SELECT referenced_task_id, task_id FROM task_references WHERE referenced_task_id IN \\(\\?, \\?\\)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"referenced_task_id", "task_id"}).AddRow(1, 4))
+
+	mentions, err := store.GetTaskMentions([]int{1, 2})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := map[int]*types.TaskMentions{
+		1: {Users: []int{2, 5}, Tasks: []int{3}, ReferencedBy: []int{4}},
+		2: {Users: []int{}, Tasks: []int{}, ReferencedBy: []int{}},
+	}
+	if !reflect.DeepEqual(mentions, expected) {
+		t.Errorf("expected mentions %+v, got %+v", expected, mentions)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetTaskMentions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	taskID := 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM task_mentions WHERE task_id = ?").WithArgs(taskID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_references WHERE task_id = ?").WithArgs(taskID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO task_mentions \\(task_id, user_id\\) VALUES \\(\\?, \\?\\)").WithArgs(taskID, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_references \\(task_id, referenced_task_id\\) VALUES \\(\\?, \\?\\)").WithArgs(taskID, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = store.SetTaskMentions(taskID, []int{2}, []int{3})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateTaskWithLabels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	due := time.Date(2026, 10, 20, 17, 0, 0, 0, time.UTC)
+	assignee := 4
+	newTask := types.Task{
+		Title:       "Fix login bug",
+		Description: "Fix login bug",
+		Status:      types.StatusPending,
+		DueDate:     &[]string{due.Format(time.RFC3339)}[0],
+		Priority:    types.PriorityHigh,
+		AssigneeID:  &assignee,
+		Labels:      []string{"backend", "auth"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT max_tasks FROM wip_limits").WithArgs(newTask.Status).WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}))
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(newTask.Title, newTask.Description, newTask.Status, nil, nil, due, types.PriorityHigh, assignee, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_labels \\(task_id, label\\) VALUES \\(\\?, \\?\\)").WithArgs(7, "backend").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_labels \\(task_id, label\\) VALUES \\(\\?, \\?\\)").WithArgs(7, "auth").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_status_history").WithArgs(7, newTask.Status, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if _, err := store.CreateTask(newTask, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddTaskLabel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	mock.ExpectExec("INSERT IGNORE INTO task_labels").WithArgs(1, "backend").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_labels").WithArgs(1, "backend").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	added, err := store.AddTaskLabel(1, "backend")
+	if err != nil || !added {
+		t.Errorf("expected the label to be added, got %v, %v", added, err)
+	}
+	added, err = store.AddTaskLabel(1, "backend")
+	if err != nil || added {
+		t.Errorf("expected an existing label not to be added again, got %v, %v", added, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetStaleTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	updatedAt := now.AddDate(0, 0, -6)
+	owner, projectOwner, projectID := 7, 2, 4
+	rows := sqlmock.NewRows([]string{"id", "title", "assignee_id", "owner_id", "updated_at", "project_id", "status", "days", "updated_by", "threshold_updated_at", "level"}).
+		AddRow(2, "Write docs", nil, nil, updatedAt, nil, types.StatusInProgress, 5, owner, "2026-10-01", 1).
+		AddRow(3, "Plan the release", nil, projectOwner, updatedAt, projectID, types.StatusInProgress, 3, projectOwner, "2026-10-02", 0)
+	mock.ExpectQuery("SELECT t.id, t.title, t.assignee_id, p.owner_id, t.updated_at").WithArgs(now, now).WillReturnRows(rows)
+
+	stale, err := store.GetStaleTasks(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stale) != 2 {
+		t.Fatalf("expected two stale tasks, got %d", len(stale))
+	}
+	if stale[0].TaskID != 2 || stale[0].Level != 1 || stale[0].Threshold.Days != 5 || *stale[0].Threshold.UpdatedBy != owner || stale[0].ProjectOwnerID != nil {
+		t.Errorf("unexpected stale task: %+v", stale[0])
+	}
+	if stale[1].TaskID != 3 || *stale[1].Threshold.ProjectID != projectID || *stale[1].ProjectOwnerID != projectOwner {
+		t.Errorf("unexpected stale task of a project: %+v", stale[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRecordEscalation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	staleSince := time.Date(2026, 10, 13, 9, 30, 0, 0, time.UTC)
+	assignee := 3
+	escalation := types.TaskEscalation{TaskID: 2, Level: types.EscalationAssignee, UserID: &assignee, StaleSince: staleSince.Format(time.RFC3339Nano), Message: "stale"}
+	mock.ExpectExec("INSERT IGNORE INTO task_escalations").
+		WithArgs(2, types.EscalationAssignee, &assignee, staleSince, "stale").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	recorded, err := store.RecordEscalation(escalation)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recorded {
+		t.Error("expected an escalation that already exists not to be recorded again")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateTaskLink(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT IGNORE INTO task_links").WithArgs(1, 2, types.LinkDuplicates).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_links").WithArgs(2, 1, types.LinkDuplicatedBy).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	if err := store.CreateTaskLink(1, 2, types.LinkDuplicates); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteTaskLink(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT linked_task_id, type FROM task_links WHERE id = \\? AND task_id = \\?").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"linked_task_id", "type"}).AddRow(2, types.LinkClonedFrom))
+	mock.ExpectExec("DELETE FROM task_links WHERE id = ?").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_links WHERE task_id = \\? AND linked_task_id = \\? AND type = \\?").
+		WithArgs(2, 1, types.LinkClonedBy).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := store.DeleteTaskLink(1, 5); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCloneTask(t *testing.T) {
+	expectClone := func(mock sqlmock.Sqlmock, taskID int, parentID interface{}, cloneID int64, labels bool) {
+		mock.ExpectQuery("SELECT max_tasks FROM wip_limits").WithArgs(types.StatusPending).WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}))
+		mock.ExpectExec("INSERT INTO tasks \\(title, description, status, due_date, priority, assignee_id, start_date, estimate_days, milestone_id, created_by, parent_id, project_id\\)\\s+SELECT title, description, \\?, due_date, priority, assignee_id, start_date, estimate_days, milestone_id, \\?, COALESCE\\(\\?, parent_id\\), project_id FROM tasks WHERE id = \\?").
+			WithArgs(types.StatusPending, 4, parentID, taskID).
+			WillReturnResult(sqlmock.NewResult(cloneID, 1))
+		if labels {
+			mock.ExpectExec("INSERT INTO task_labels \\(task_id, label\\) SELECT \\?, label FROM task_labels WHERE task_id = \\?").
+				WithArgs(cloneID, taskID).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+		}
+		mock.ExpectExec("INSERT INTO task_custom_field_values \\(task_id, field_id, value\\) SELECT \\?, field_id, value FROM task_custom_field_values WHERE task_id = \\?").
+			WithArgs(cloneID, taskID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO task_status_history").WithArgs(cloneID, types.StatusPending, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT IGNORE INTO task_links").WithArgs(cloneID, taskID, types.LinkClonedFrom).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT IGNORE INTO task_links").WithArgs(taskID, cloneID, types.LinkClonedBy).WillReturnResult(sqlmock.NewResult(2, 1))
+	}
+
+	t.Run("should copy the task for the cloning user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectClone(mock, 1, nil, 9, false)
+		mock.ExpectCommit()
+
+		cloneID, err := NewStore(db).CloneTask(1, types.CloneTaskPayload{}, 4)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if cloneID != 9 {
+			t.Errorf("expected clone ID 9, got %d", cloneID)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("should copy labels and subtasks when asked", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectClone(mock, 1, nil, 9, true)
+		mock.ExpectQuery("SELECT id FROM tasks WHERE parent_id = \\? ORDER BY id").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		expectClone(mock, 5, 9, 10, true)
+		mock.ExpectQuery("SELECT id FROM tasks WHERE parent_id = \\? ORDER BY id").WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		cloneID, err := NewStore(db).CloneTask(1, types.CloneTaskPayload{Labels: true, Children: true}, 4)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if cloneID != 9 {
+			t.Errorf("expected clone ID 9, got %d", cloneID)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestMergeTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT max_tasks FROM wip_limits").WithArgs(types.StatusCompleted).WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, completed_at = \\?, updated_at = \\? WHERE id = ?").
+		WithArgs(types.StatusCompleted, sqlmock.AnyArg(), sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_status_history").WithArgs(3, types.StatusCompleted, "duplicate of task #1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_links").WithArgs(3, 1, types.LinkDuplicates).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT IGNORE INTO task_links").WithArgs(1, 3, types.LinkDuplicatedBy).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	if err := store.MergeTask(3, 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateTaskRejectsTransitionOverWIPLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	status := types.StatusInProgress
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT max_tasks FROM wip_limits WHERE status = \\? FOR UPDATE").
+		WithArgs(status).
+		WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}).AddRow(2))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tasks WHERE status = \\? AND id <> \\?").
+		WithArgs(status, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	err = store.UpdateTask(1, types.UpdateTaskPayload{Status: &status}, false)
+
+	var wipErr *types.WIPLimitError
+	if !errors.As(err, &wipErr) {
+		t.Fatalf("expected a WIP limit error, got %v", err)
+	}
+	if wipErr.Limit != 2 || wipErr.Status != status {
+		t.Errorf("unexpected WIP limit error %+v", wipErr)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateTaskOverridesWIPLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	status := types.StatusInProgress
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE tasks SET status = \\?, started_at = COALESCE\\(started_at, \\?\\), completed_at = NULL, updated_at = \\? WHERE id = ?").
+		WithArgs(status, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_status_history").WithArgs(1, status, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := store.UpdateTask(1, types.UpdateTaskPayload{Status: &status}, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReopenTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	taskID := 1
+	reason := "bug came back"
+	now := time.Now().Format(time.RFC3339)
+
+	mock.ExpectQuery("SELECT \\* FROM tasks WHERE id = ?").
+		WithArgs(taskID).
+		WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(taskID, "Task 1", "Description for task 1", types.StatusCompleted, now, now, now, now, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT max_tasks FROM wip_limits").WithArgs(types.StatusPending).WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}))
+	mock.ExpectExec("UPDATE tasks SET status = \\?, started_at = NULL, completed_at = NULL, reopened_count = reopened_count \\+ 1, updated_at = \\? WHERE id = \\? AND status = \\?").
+		WithArgs(types.StatusPending, sqlmock.AnyArg(), taskID, types.StatusCompleted).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO task_status_history").WithArgs(taskID, types.StatusPending, reason).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := store.ReopenTask(taskID, &reason, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRegressTaskLosesRaceWithProgress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	taskID := 1
+	now := time.Now().Format(time.RFC3339)
+
+	mock.ExpectQuery("SELECT \\* FROM tasks WHERE id = ?").
+		WithArgs(taskID).
+		WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(taskID, "Task 1", "Description for task 1", types.StatusInProgress, now, now, now, nil, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT max_tasks FROM wip_limits").WithArgs(types.StatusPending).WillReturnRows(sqlmock.NewRows([]string{"max_tasks"}))
+	// a concurrent progress call already completed the task, so the
+	// compare-and-set update matches no row
+	mock.ExpectExec("UPDATE tasks SET status = \\?, started_at = NULL, completed_at = NULL, updated_at = \\? WHERE id = \\? AND status = \\?").
+		WithArgs(types.StatusPending, sqlmock.AnyArg(), taskID, types.StatusInProgress).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = store.RegressTask(taskID, nil, false)
+	if !errors.Is(err, types.ErrTaskStatusChanged) {
+		t.Errorf("expected ErrTaskStatusChanged, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRegressPendingTaskFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now().Format(time.RFC3339)
+
+	mock.ExpectQuery("SELECT \\* FROM tasks WHERE id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, "Task 1", "Description for task 1", types.StatusPending, now, now, nil, nil, 0, nil, types.PriorityMedium, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+
+	var transitionErr *types.InvalidTransitionError
+	if err := store.RegressTask(1, nil, false); !errors.As(err, &transitionErr) {
+		t.Errorf("expected an invalid transition error, got %v", err)
+	}
+}
+
+func TestGetCustomFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now().Format(time.RFC3339)
+
+	mock.ExpectQuery("SELECT \\* FROM custom_fields ORDER BY name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "options", "created_at", "project_id"}).
+			AddRow(1, "environment", types.CustomFieldSelect, []byte(`["staging","prod"]`), now, nil).
+			AddRow(2, "points", types.CustomFieldNumber, nil, now, 3))
+
+	fields, err := store.GetCustomFields()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	project := 3
+	expected := []types.CustomField{
+		{ID: 1, Name: "environment", Type: types.CustomFieldSelect, Options: []string{"staging", "prod"}, CreatedAt: now},
+		{ID: 2, Name: "points", Type: types.CustomFieldNumber, CreatedAt: now, ProjectID: &project},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected %v, got %v", expected, fields)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	status := types.StatusInProgress
+	project := 3
+	points := types.CustomField{ID: 1, Name: "points", Type: types.CustomFieldNumber}
+	environment := types.CustomField{ID: 2, Name: "environment", Type: types.CustomFieldMultiSelect}
+
+	mock.ExpectQuery("SELECT t\\.\\* FROM tasks t LEFT JOIN task_custom_field_values sv ON sv\\.task_id = t\\.id AND sv\\.field_id = \\? "+
+		"WHERE t\\.status = \\? AND t\\.project_id = \\? "+
+		"AND EXISTS \\(SELECT 1 FROM task_custom_field_values v WHERE v\\.task_id = t\\.id AND v\\.field_id = \\? AND v\\.value = CAST\\(\\? AS JSON\\)\\) "+
+		"AND EXISTS \\(SELECT 1 FROM task_custom_field_values v WHERE v\\.task_id = t\\.id AND v\\.field_id = \\? AND JSON_CONTAINS\\(v\\.value, \\?\\)\\) "+
+		"ORDER BY sv\\.value IS NULL, CAST\\(sv\\.value AS DOUBLE\\) DESC, t\\.id").
+		WithArgs(1, status, project, 1, "8", 2, `"prod"`).
+		WillReturnRows(sqlmock.NewRows(taskColumns))
+
+	_, err = store.FindTasks(types.TaskQuery{
+		Status:    &status,
+		ProjectID: &project,
+		CustomFields: []types.CustomFieldFilter{
+			{Field: points, Value: "8"},
+			{Field: environment, Value: `"prod"`},
+		},
+		Sort: &types.CustomFieldSort{Field: points, Descending: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mock.ExpectQuery("SELECT t\\.\\* FROM tasks t ORDER BY t\\.id").
+		WillReturnRows(sqlmock.NewRows(taskColumns))
+	if _, err := store.FindTasks(types.TaskQuery{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetCustomFieldValues(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	points := "5"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO task_custom_field_values \\(task_id, field_id, value\\) VALUES \\(\\?, \\?, \\?\\)").
+		WithArgs(1, 1, points).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM task_custom_field_values WHERE task_id = \\? AND field_id = \\?").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE tasks SET updated_at = \\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := store.SetCustomFieldValues(1, map[int]*string{1: &points, 2: nil}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWakeSnoozedTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now()
+	created := now.Format(time.RFC3339)
+	snoozedUntil := now.Add(-time.Minute).Format(time.RFC3339)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM tasks WHERE snoozed_until <= \\? FOR UPDATE").
+		WithArgs(now.UTC()).
+		WillReturnRows(sqlmock.NewRows(taskColumns).
+			AddRow(1, "Task 1", "Description for task 1", types.StatusPending, created, created, nil, nil, 0, nil, types.PriorityMedium, nil, snoozedUntil, 3, nil, nil, nil, nil, nil, nil).
+			AddRow(2, "Task 2", "Description for task 2", types.StatusPending, created, created, nil, nil, 0, nil, types.PriorityMedium, nil, snoozedUntil, 3, nil, nil, nil, nil, nil, nil))
+	mock.ExpectExec("UPDATE tasks SET snoozed_until = NULL, snoozed_by = NULL WHERE id IN \\(\\?, \\?\\)").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	tasks, err := store.WakeSnoozedTasks(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 2 || *tasks[0].SnoozedBy != 3 {
+		t.Errorf("expected the two woken tasks, got %+v", tasks)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSnoozeUnknownTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	until := time.Now().Add(time.Hour)
+
+	mock.ExpectExec("UPDATE tasks SET snoozed_until = \\?, snoozed_by = \\? WHERE id = \\?").
+		WithArgs(until.UTC(), 3, 42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM tasks WHERE id = \\?\\)").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if err := store.SnoozeTask(42, until, 3); err == nil {
+		t.Error("expected an error for an unknown task")
+	}
+
+	mock.ExpectExec("UPDATE tasks SET snoozed_until = \\?, snoozed_by = \\? WHERE id = \\?").
+		WithArgs(until.UTC(), 3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM tasks WHERE id = \\?\\)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	if err := store.SnoozeTask(1, until, 3); err != nil {
+		t.Errorf("snoozing again until the same time should succeed, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetTasksByMilestone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Now().Format(time.RFC3339)
+	startDate := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT \\* FROM tasks WHERE milestone_id = \\? ORDER BY id").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(taskColumns).
+			AddRow(1, "Task 1", "Description for task 1", types.StatusPending, now, now, nil, nil, 0, nil, types.PriorityMedium, nil, nil, nil, startDate, 3, 2, nil, nil, nil))
+
+	tasks, err := store.GetTasksByMilestone(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 1 || *tasks[0].StartDate != "2026-03-04" || *tasks[0].EstimateDays != 3 || *tasks[0].MilestoneID != 2 {
+		t.Errorf("unexpected tasks %+v", tasks)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateTaskPlanningFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	startDate := "2026-03-04"
+	estimate := 3
+
+	mock.ExpectExec("UPDATE tasks SET start_date = \\?, estimate_days = \\?, updated_at = \\? WHERE id = \\?").
+		WithArgs(startDate, estimate, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.UpdateTask(1, types.UpdateTaskPayload{StartDate: &startDate, EstimateDays: &estimate}, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,15 @@
+package token
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/tokens", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleGetTokens), h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/tokens", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleCreateToken), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/tokens/{id}", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleRevokeToken), h.userStore)).Methods(http.MethodDelete)
+}
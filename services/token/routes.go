@@ -0,0 +1,144 @@
+package token
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.PersonalAccessTokenStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.PersonalAccessTokenStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// HandleGetTokens   get-tokens
+//
+// @Summary     Get Personal Access Tokens
+// @Description Get the personal access tokens of the authenticated user that were not revoked, newest first. The tokens themselves are never shown again.
+// @Tags        Token
+// @Produce     json
+// @Security    jwtKey
+// @Success     200 {array}  types.PersonalAccessToken
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /tokens [get]
+func (h *Handler) handleGetTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.store.GetPersonalAccessTokens(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+// HandleCreateToken   create-token
+//
+// @Summary     Create Personal Access Token
+// @Description Create a token for scripts. Send it in the Authorization header like a JWT. It only grants the selected scopes, which have to be permissions of your role; account:manage cannot be granted. The token is only returned in this response.
+// @Tags        Token
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       CreatePersonalAccessTokenPayload body     types.CreatePersonalAccessTokenPayload true "Name, scopes and optional expiry"
+// @Success     201                              {object} types.CreatedPersonalAccessToken
+// @Failure     400                              {object} types.ErrorResponse
+// @Failure     401                              {object} types.ErrorResponse
+// @Failure     403                              {object} types.ErrorResponse
+// @Failure     500                              {object} types.ErrorResponse
+// @Router      /tokens [post]
+func (h *Handler) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreatePersonalAccessTokenPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	role := auth.GetRoleFromContext(r.Context())
+	scopes := make([]types.Permission, 0, len(payload.Scopes))
+	seen := make(map[types.Permission]bool)
+	for _, scope := range payload.Scopes {
+		if scope == types.PermissionAccountManage {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%s cannot be granted to a token", scope))
+			return
+		}
+		if !auth.HasPermission(role, scope) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown scope %s or not granted by your role", scope))
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	value, prefix, hash, err := auth.NewPersonalAccessToken()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	token := types.PersonalAccessToken{
+		UserID:    auth.GetUserIDFromContext(r.Context()),
+		Name:      payload.Name,
+		Prefix:    prefix,
+		TokenHash: hash,
+		Scopes:    scopes,
+	}
+	if payload.ExpiresInDays != nil {
+		expiresAt := time.Now().UTC().AddDate(0, 0, *payload.ExpiresInDays).Format(time.RFC3339)
+		token.ExpiresAt = &expiresAt
+	}
+
+	token.ID, err = h.store.CreatePersonalAccessToken(token)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	token.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	utils.WriteJSON(w, http.StatusCreated, types.CreatedPersonalAccessToken{PersonalAccessToken: token, Token: value})
+}
+
+// HandleRevokeToken   revoke-token
+//
+// @Summary     Revoke Personal Access Token
+// @Description Revoke a personal access token of the authenticated user, it stops working immediately
+// @Tags        Token
+// @Produce     json
+// @Security    jwtKey
+// @Param       id  path     int true "Token ID"
+// @Success     200 {object} string
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     404 {object} types.ErrorResponse
+// @Router      /tokens/{id} [delete]
+func (h *Handler) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid token ID"))
+		return
+	}
+
+	if err := h.store.RevokePersonalAccessToken(auth.GetUserIDFromContext(r.Context()), tokenID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "Token revoked"})
+}
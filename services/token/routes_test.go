@@ -0,0 +1,97 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestPersonalAccessTokens(t *testing.T) {
+	store := &mockTokenStore{}
+	handler := NewHandler(store, nil)
+
+	withUser := func(req *http.Request, role types.Role) *http.Request {
+		ctx := context.WithValue(req.Context(), types.UserKey, 1)
+		return req.WithContext(context.WithValue(ctx, types.RoleKey, role))
+	}
+	create := func(t *testing.T, role types.Role, payload types.CreatePersonalAccessTokenPayload) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleCreateToken(rr, withUser(req, role))
+		return rr
+	}
+
+	t.Run("should show the token once and store its hash", func(t *testing.T) {
+		days := 30
+		rr := create(t, types.RoleMember, types.CreatePersonalAccessTokenPayload{
+			Name:          "ci",
+			Scopes:        []types.Permission{types.PermissionTaskRead, types.PermissionTaskRead, types.PermissionTaskWrite},
+			ExpiresInDays: &days,
+		})
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		var created types.CreatedPersonalAccessToken
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		assert.True(t, strings.HasPrefix(created.Token, auth.PersonalAccessTokenPrefix))
+		assert.True(t, strings.HasPrefix(created.Token, created.Prefix))
+		assert.Equal(t, []types.Permission{types.PermissionTaskRead, types.PermissionTaskWrite}, created.Scopes)
+		assert.NotNil(t, created.ExpiresAt)
+
+		if assert.Len(t, store.tokens, 1) {
+			assert.Equal(t, auth.HashPersonalAccessToken(created.Token), store.tokens[0].TokenHash)
+			assert.NotContains(t, rr.Body.String(), store.tokens[0].TokenHash)
+		}
+	})
+
+	t.Run("should reject scopes outside of the role", func(t *testing.T) {
+		rr := create(t, types.RoleViewer, types.CreatePersonalAccessTokenPayload{Name: "ci", Scopes: []types.Permission{types.PermissionTaskWrite}})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = create(t, types.RoleMember, types.CreatePersonalAccessTokenPayload{Name: "ci", Scopes: []types.Permission{"task:everything"}})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should never grant account management", func(t *testing.T) {
+		rr := create(t, types.RoleAdmin, types.CreatePersonalAccessTokenPayload{Name: "ci", Scopes: []types.Permission{types.PermissionAccountManage}})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should require a name and at least one scope", func(t *testing.T) {
+		rr := create(t, types.RoleMember, types.CreatePersonalAccessTokenPayload{Scopes: []types.Permission{types.PermissionTaskRead}})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = create(t, types.RoleMember, types.CreatePersonalAccessTokenPayload{Name: "ci"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+type mockTokenStore struct {
+	types.PersonalAccessTokenStore
+	tokens []types.PersonalAccessToken
+}
+
+func (m *mockTokenStore) CreatePersonalAccessToken(t types.PersonalAccessToken) (int, error) {
+	t.ID = len(m.tokens) + 1
+	m.tokens = append(m.tokens, t)
+	return t.ID, nil
+}
+
+func (m *mockTokenStore) GetActivePersonalAccessToken(tokenHash string, now time.Time) (*types.PersonalAccessToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("token not found, revoked or expired")
+}
@@ -0,0 +1,129 @@
+package token
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const tokenColumns = "id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at"
+
+func scanRowIntoToken(rows *sql.Rows) (*types.PersonalAccessToken, error) {
+	t := new(types.PersonalAccessToken)
+	var scopes []byte
+	err := rows.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.Prefix,
+		&t.TokenHash,
+		&scopes,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &t.Scopes); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *Store) CreatePersonalAccessToken(t types.PersonalAccessToken) (int, error) {
+	scopes, err := json.Marshal(t.Scopes)
+	if err != nil {
+		return 0, err
+	}
+
+	var expiresAt *time.Time
+	if t.ExpiresAt != nil {
+		expires, err := time.Parse(time.RFC3339, *t.ExpiresAt)
+		if err != nil {
+			return 0, err
+		}
+		expires = expires.UTC()
+		expiresAt = &expires
+	}
+
+	res, err := s.db.Exec(
+		"INSERT INTO personal_access_tokens (user_id, name, prefix, token_hash, scopes, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		t.UserID, t.Name, t.Prefix, t.TokenHash, scopes, expiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetPersonalAccessTokens returns the tokens of a user that were not revoked,
+// including expired ones, newest first.
+func (s *Store) GetPersonalAccessTokens(userID int) ([]types.PersonalAccessToken, error) {
+	rows, err := s.db.Query("SELECT "+tokenColumns+" FROM personal_access_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]types.PersonalAccessToken, 0)
+	for rows.Next() {
+		t, err := scanRowIntoToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, nil
+}
+
+// GetActivePersonalAccessToken looks a token up by its hash. Revoked and
+// expired tokens are not found.
+func (s *Store) GetActivePersonalAccessToken(tokenHash string, now time.Time) (*types.PersonalAccessToken, error) {
+	rows, err := s.db.Query(
+		"SELECT "+tokenColumns+" FROM personal_access_tokens WHERE token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)",
+		tokenHash, now.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, errors.New("token not found, revoked or expired")
+	}
+	return scanRowIntoToken(rows)
+}
+
+func (s *Store) RevokePersonalAccessToken(userID int, tokenID int) error {
+	res, err := s.db.Exec("UPDATE personal_access_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL", time.Now().UTC(), tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("no active token found with the given ID")
+	}
+	return nil
+}
+
+func (s *Store) TouchPersonalAccessToken(tokenID int, now time.Time) error {
+	_, err := s.db.Exec("UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?", now.UTC(), tokenID)
+	return err
+}
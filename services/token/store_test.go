@@ -0,0 +1,90 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	expiresAt := "2026-11-18T12:00:00Z"
+	mock.ExpectExec("INSERT INTO personal_access_tokens \\(user_id, name, prefix, token_hash, scopes, expires_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?\\)").
+		WithArgs(1, "ci", "gtp_abcdef12", "hash", []byte(`["task:read","task:write"]`), time.Date(2026, 11, 18, 12, 0, 0, 0, time.UTC)).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+
+	id, err := store.CreatePersonalAccessToken(types.PersonalAccessToken{
+		UserID:    1,
+		Name:      "ci",
+		Prefix:    "gtp_abcdef12",
+		TokenHash: "hash",
+		Scopes:    []types.Permission{types.PermissionTaskRead, types.PermissionTaskWrite},
+		ExpiresAt: &expiresAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, id)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetActivePersonalAccessToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "name", "prefix", "token_hash", "scopes", "expires_at", "last_used_at", "created_at"}
+	query := "SELECT id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at FROM personal_access_tokens WHERE token_hash = \\? AND revoked_at IS NULL AND \\(expires_at IS NULL OR expires_at > \\?\\)"
+
+	mock.ExpectQuery(query).
+		WithArgs("hash", now).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(4, 1, "ci", "gtp_abcdef12", "hash", []byte(`["task:read"]`), nil, nil, "2026-10-19 10:00:00"))
+	mock.ExpectQuery(query).
+		WithArgs("revoked", now).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	token, err := store.GetActivePersonalAccessToken("hash", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, token.ID)
+	assert.Equal(t, []types.Permission{types.PermissionTaskRead}, token.Scopes)
+
+	_, err = store.GetActivePersonalAccessToken("revoked", now)
+	assert.Error(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokePersonalAccessToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	mock.ExpectExec("UPDATE personal_access_tokens SET revoked_at = \\? WHERE id = \\? AND user_id = \\? AND revoked_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), 4, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Error(t, store.RevokePersonalAccessToken(2, 4), "tokens of other users cannot be revoked")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
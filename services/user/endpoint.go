@@ -5,12 +5,15 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
 )
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/login", h.handleLogin).Methods(http.MethodPost)
+	router.HandleFunc("/login/mfa", h.handleLoginMFA).Methods(http.MethodPost)
 	router.HandleFunc("/register", h.handleRegister).Methods(http.MethodPost)
-	router.HandleFunc("/logout", middlewares.AuthMiddleware(h.handleLogout, h.store)).Methods(http.MethodPost)
-	router.HandleFunc("/refresh", middlewares.AuthMiddleware(h.handleRefreshToken, h.store)).Methods(http.MethodPost)
-	router.HandleFunc("/change-password", middlewares.AuthMiddleware(h.ChangePassword, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/logout", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleLogout), h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/refresh", middlewares.RefreshTokenMiddleware(h.handleRefreshToken, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/change-password", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.ChangePassword), h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id}/role", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionUserAdmin, h.handleSetUserRole), h.store)).Methods(http.MethodPut)
 }
@@ -1,34 +1,69 @@
 package user
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator"
+	"github.com/gorilla/mux"
 	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/services/mfa"
 	"github.com/trsnaqe/gotask/types"
 	"github.com/trsnaqe/gotask/utils"
 )
 
 type Handler struct {
-	store types.UserStore
+	store    types.UserStore
+	sessions types.SessionStore
+	denylist types.TokenDenylist
+	mfa      types.MFAStore
+	verifier types.EmailVerifier
+	guard    types.LoginGuard
+	policy   types.PasswordPolicy
+
+	dummyPasswordHash string
 }
 
-func NewHandler(store types.UserStore) *Handler {
-	return &Handler{store: store}
+func NewHandler(store types.UserStore, sessions types.SessionStore, denylist types.TokenDenylist, mfaStore types.MFAStore, verifier types.EmailVerifier, guard types.LoginGuard, policy types.PasswordPolicy) *Handler {
+	return &Handler{
+		store:             store,
+		sessions:          sessions,
+		denylist:          denylist,
+		mfa:               mfaStore,
+		verifier:          verifier,
+		guard:             guard,
+		policy:            policy,
+		dummyPasswordHash: newDummyPasswordHash(),
+	}
+}
+
+// newDummyPasswordHash hashes a throwaway password with the current hasher.
+// It is compared against when no user has the email, so that unknown emails
+// take as long to reject as wrong passwords.
+func newDummyPasswordHash() string {
+	hash, err := auth.HashValue("gotask-dummy-password")
+	if err != nil {
+		log.Printf("failed to hash dummy password: %v", err)
+	}
+	return hash
 }
 
 // HandleLogin   login
 //
 // @Summary     Login to Account
-// @Description Login to Account using email and password
+// @Description Login to Account using email and password. When two-factor authentication is enabled the response is a types.MFAChallenge instead, to be completed at /login/mfa. Failed logins slow down further attempts for the account, and too many lock it for a while.
 // @Tags        User
 // @Accept      json
 // @Produce     json
 // @Param       RegisterPayload body     types.LoginUserPayload true "User email and password"
 // @Success     200             {object} types.Tokens
 // @Failure     400             {object} types.ErrorResponse
+// @Failure     429             {object} types.ErrorResponse
 // @Failure     500             {object} types.ErrorResponse
 // @Router      /login [post]
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -43,42 +78,212 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
 		return
 	}
+
+	now := time.Now()
+	if !h.checkLoginAttempt(w, r, payload.Email, now) {
+		return
+	}
+
 	u, err := h.store.GetUserByEmail(payload.Email)
-	if err != nil {
+	if err != nil || u == nil {
+		auth.CompareValue(h.dummyPasswordHash, payload.Password)
+		h.loginFailed(r, nil, payload.Email, now)
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid email or password"))
 		return
 	}
 
 	if !auth.CompareValue(u.Password, payload.Password) {
+		h.loginFailed(r, u, payload.Email, now)
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid email or password"))
 		return
 	}
 
-	tokens, err := auth.CreateTokens(u.ID)
+	if err := h.guard.LoginSucceeded(payload.Email); err != nil {
+		log.Printf("failed to reset failed logins of user %d: %v", u.ID, err)
+	}
+	h.rehashPassword(u, payload.Password)
+	h.FinishLogin(w, r, u.ID, payload.DeviceName)
+}
+
+// rehashPassword replaces a password hash made with an older algorithm or
+// older parameters while the password is at hand. The login goes ahead when
+// it fails, as the old hash still works.
+func (h *Handler) rehashPassword(u *types.User, password string) {
+	if !auth.NeedsRehash(u.Password) {
+		return
+	}
+	hashedPassword, err := auth.HashValue(password)
+	if err == nil {
+		err = h.store.UpdateUser(u.ID, types.UpdateUserPayload{Password: &hashedPassword})
+	}
+	if err != nil {
+		log.Printf("failed to rehash the password of user %d: %v", u.ID, err)
+	}
+}
+
+// checkLoginAttempt answers 429 when the account or the address is locked
+// or still backing off from earlier failures.
+func (h *Handler) checkLoginAttempt(w http.ResponseWriter, r *http.Request, email string, now time.Time) bool {
+	wait, err := h.guard.CheckLogin(email, utils.GetClientIP(r), now)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("too many failed login attempts, try again later"))
+		return false
+	}
+	return true
+}
+
+// loginFailed records a failed login and a security event for the user when
+// it locked the account. u is nil when no user has the email.
+func (h *Handler) loginFailed(r *http.Request, u *types.User, email string, now time.Time) {
+	locked, err := h.guard.LoginFailed(email, utils.GetClientIP(r), now)
+	if err != nil {
+		log.Printf("failed to record failed login: %v", err)
+		return
+	}
+	if !locked || u == nil {
 		return
 	}
+	err = h.sessions.RecordSecurityEvent(types.SecurityEvent{
+		UserID:    u.ID,
+		Type:      types.SecurityEventAccountLocked,
+		IP:        utils.GetClientIP(r),
+		UserAgent: r.UserAgent(),
+		Message:   "logins locked after too many failed attempts",
+	})
+	if err != nil {
+		log.Printf("failed to record security event: %v", err)
+	}
+}
 
-	hashedRefreshToken, err := auth.HashRefreshToken(tokens.RefreshToken)
+// FinishLogin completes the login of an identified user. Users with
+// two-factor authentication get a challenge for /login/mfa, everyone else a
+// new session.
+func (h *Handler) FinishLogin(w http.ResponseWriter, r *http.Request, userID int, deviceName string) {
+	m, err := h.mfa.GetMFA(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if m != nil && m.Enabled {
+		mfaToken, err := auth.CreateMFAToken(userID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, types.MFAChallenge{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(auth.MFAChallengeExpiration.Seconds()),
+		})
+		return
+	}
+
+	tokens, err := h.startSession(r, userID, deviceName)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	err = h.store.UpdateUser(u.ID, types.UpdateUserPayload{RefreshToken: &hashedRefreshToken})
+	utils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+// HandleLoginMFA   login-mfa
+//
+// @Summary     Complete Login with Two-Factor Authentication
+// @Description Exchange the challenge token of /login for tokens with a code from the authenticator app or a recovery code. Each code works once.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       LoginMFAPayload body     types.LoginMFAPayload true "Challenge token and code"
+// @Success     200             {object} types.Tokens
+// @Failure     400             {object} types.ErrorResponse
+// @Failure     401             {object} types.ErrorResponse
+// @Failure     429             {object} types.ErrorResponse
+// @Failure     500             {object} types.ErrorResponse
+// @Router      /login/mfa [post]
+func (h *Handler) handleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	var payload types.LoginMFAPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	claims, err := auth.ValidateJWT(payload.MFAToken, auth.TokenTypeMFA)
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid or expired challenge, log in again"))
+		return
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid challenge"))
+		return
+	}
+
+	u, err := h.store.GetUserByID(userID)
+	if err != nil || u == nil {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid challenge"))
+		return
+	}
+
+	// wrong codes count as failed logins, so codes cannot be guessed either
+	now := time.Now()
+	if !h.checkLoginAttempt(w, r, u.Email, now) {
+		return
+	}
+
+	m, err := h.mfa.GetMFA(userID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	valid, usedRecoveryCode, err := mfa.Verify(h.mfa, m, payload.Code, now)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !valid {
+		h.loginFailed(r, u, u.Email, now)
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid code"))
+		return
+	}
+	if err := h.guard.LoginSucceeded(u.Email); err != nil {
+		log.Printf("failed to reset failed logins of user %d: %v", u.ID, err)
+	}
+
+	if usedRecoveryCode {
+		err := h.sessions.RecordSecurityEvent(types.SecurityEvent{
+			UserID:    userID,
+			Type:      types.SecurityEventRecoveryCodeUsed,
+			IP:        utils.GetClientIP(r),
+			UserAgent: r.UserAgent(),
+			Message:   fmt.Sprintf("logged in with a recovery code, %d left", m.RecoveryCodesLeft-1),
+		})
+		if err != nil {
+			log.Printf("failed to record security event: %v", err)
+		}
+	}
 
+	tokens, err := h.startSession(r, userID, payload.DeviceName)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 	utils.WriteJSON(w, http.StatusOK, tokens)
 }
 
 // HandleRegister   register
 //
 // @Summary     Register to Account
-// @Description Register to Account using email and password
+// @Description Register to Account using email and password. The password has to follow the password policy; a 400 lists every rule it breaks. A verification token is emailed to the address.
 // @Tags        User
 // @Accept      json
 // @Produce     json
@@ -105,6 +310,10 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkPasswordPolicy(w, h.policy, payload.Password, payload.Email) {
+		return
+	}
+
 	hashedPassword, err := auth.HashValue(payload.Password)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
@@ -114,6 +323,7 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	err = h.store.CreateUser(types.User{
 		Email:    payload.Email,
 		Password: hashedPassword,
+		Role:     types.RoleMember,
 	})
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
@@ -126,19 +336,12 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := auth.CreateTokens(createdUser.ID)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	hashedRefreshToken, err := auth.HashRefreshToken(tokens.RefreshToken)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
+	// the account works without it; the user can ask for another email
+	if err := h.verifier.SendVerification(createdUser.ID, createdUser.Email); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", createdUser.ID, err)
 	}
 
-	err = h.store.UpdateUser(createdUser.ID, types.UpdateUserPayload{RefreshToken: &hashedRefreshToken})
+	tokens, err := h.startSession(r, createdUser.ID, payload.DeviceName)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -150,30 +353,37 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 // HandleLogout   logout
 //
 // @Summary     Logout from Account
-// @Description Removes refresh token from user, effectively logging out
+// @Description Revokes the session of the token and the access token itself, logging out on this device
 // @Tags        User
 // @Accept      json
 // @Produce     json
 // @Security    jwtKey
-// @Success     201 {object} nil
+// @Success     200 {object} nil
 // @Failure     400 {object} types.ErrorResponse
 // @Failure     401 {object} types.ErrorResponse
 // @Failure     403 {object} types.ErrorResponse
 // @Failure     500 {object} types.ErrorResponse
 // @Router      /logout [post]
 func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
-	log.Println("logout")
-	log.Println(types.UserKey)
+	userID := auth.GetUserIDFromContext(r.Context())
+	sessionID := auth.GetSessionIDFromContext(r.Context())
+	if sessionID < 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the token is not bound to a session"))
+		return
+	}
 
-	id := auth.GetUserIDFromContext(r.Context())
-	u, err := h.store.GetUserByID(id)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+	if err := h.sessions.RevokeSession(userID, sessionID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	err = h.store.UpdateUser(u.ID, types.UpdateUserPayload{RefreshToken: nil})
+	// the access token stays valid until it expires unless it is denied
+	claims, err := auth.ValidateJWT(utils.GetTokenFromRequest(r), auth.TokenTypeAccess)
 	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+	if err := h.denylist.Deny(claims.ID, claims.ExpiresAt.Time); err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -183,7 +393,7 @@ func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
 // HandleRefresh   refresh
 //
 // @Summary     Refresh Tokens
-// @Description Refresh tokens using refresh token
+// @Description Refresh tokens using refresh token. The refresh token is replaced, so each one can only be used once. Access tokens are rejected.
 // @Tags        User
 // @Accept      json
 // @Produce     json
@@ -191,48 +401,59 @@ func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
 // @Success     201 {object} types.Tokens
 // @Failure     400 {object} types.ErrorResponse
 // @Failure     401 {object} types.ErrorResponse
-// @Failure     403 {object} types.ErrorResponse
 // @Failure     500 {object} types.ErrorResponse
 // @Router      /refresh [post]
 //
-// refreshes access and refresh token using refresh token
+// refreshes access and refresh token of the session using its refresh token
 func (h *Handler) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 	tokenString := utils.GetTokenFromRequest(r)
 
-	userId := auth.GetUserIDFromContext(r.Context())
-	u, err := h.store.GetUserByID(userId)
+	userID := auth.GetUserIDFromContext(r.Context())
+	sessionID := auth.GetSessionIDFromContext(r.Context())
+	if sessionID < 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the token is not bound to a session, log in again"))
+		return
+	}
+
+	tokenID, err := auth.RefreshTokenID(tokenString)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
-	if u.RefreshToken == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("no refresh token found"))
+	issued, err := h.sessions.GetRefreshToken(tokenID)
+	if err != nil || issued.SessionID != sessionID {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid refresh token"))
 		return
 	}
 
-	if !auth.CompareRefreshToken(*u.RefreshToken, tokenString) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid refresh token"))
+	session, err := h.sessions.GetActiveSession(sessionID, time.Now())
+	if err != nil || session.UserID != userID {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("session not found or expired"))
 		return
 	}
 
-	_, err = auth.ValidateJWT(tokenString)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+	if issued.RotatedAt != nil {
+		h.revokeReusedFamily(w, r, userID, sessionID)
 		return
 	}
 
-	tokens, err := auth.CreateTokens(userId)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+	if !auth.CompareRefreshToken(session.RefreshToken, tokenString) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid refresh token"))
 		return
 	}
 
-	hashedRefreshToken, err := auth.HashRefreshToken(tokens.RefreshToken)
+	rotated, err := h.sessions.MarkRefreshTokenRotated(tokenID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	err = h.store.UpdateUser(userId, types.UpdateUserPayload{RefreshToken: &hashedRefreshToken})
+	if !rotated {
+		// another request used the same token first
+		h.revokeReusedFamily(w, r, userID, sessionID)
+		return
+	}
+
+	tokens, err := h.issueTokens(userID, sessionID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -240,10 +461,91 @@ func (h *Handler) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, tokens)
 }
 
+// revokeReusedFamily handles a refresh token that was used after it had
+// been rotated. Either the legitimate client or an attacker holds a copy, and
+// the server cannot tell which, so the whole session is revoked and both
+// have to log in again.
+func (h *Handler) revokeReusedFamily(w http.ResponseWriter, r *http.Request, userID int, sessionID int) {
+	if err := h.sessions.RevokeSession(userID, sessionID); err != nil {
+		log.Printf("failed to revoke session %d after refresh token reuse: %v", sessionID, err)
+	}
+
+	err := h.sessions.RecordSecurityEvent(types.SecurityEvent{
+		UserID:    userID,
+		Type:      types.SecurityEventRefreshTokenReuse,
+		SessionID: &sessionID,
+		IP:        utils.GetClientIP(r),
+		UserAgent: r.UserAgent(),
+		Message:   fmt.Sprintf("a rotated refresh token of session %d was used again, the session was revoked", sessionID),
+	})
+	if err != nil {
+		log.Printf("failed to record security event: %v", err)
+	}
+	log.Printf("refresh token reuse detected for user %d, session %d revoked", userID, sessionID)
+
+	utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("refresh token was already used, the session has been revoked"))
+}
+
+// startSession opens a session for the device making the request and issues
+// its first tokens.
+func (h *Handler) startSession(r *http.Request, userID int, deviceName string) (types.Tokens, error) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	if deviceName == "" {
+		deviceName = "Unknown device"
+	}
+
+	sessionID, err := h.sessions.CreateSession(types.Session{
+		UserID:     userID,
+		DeviceName: deviceName,
+		UserAgent:  userAgent,
+		IP:         utils.GetClientIP(r),
+	})
+	if err != nil {
+		return types.Tokens{}, err
+	}
+	return h.issueTokens(userID, sessionID)
+}
+
+// issueTokens creates tokens for a session and makes the new refresh token
+// the current one of the session. The access token carries the current role
+// of the user, so a changed role shows up in the claims on the next refresh.
+func (h *Handler) issueTokens(userID int, sessionID int) (types.Tokens, error) {
+	u, err := h.store.GetUserByID(userID)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+	if u == nil {
+		return types.Tokens{}, fmt.Errorf("user not found")
+	}
+
+	tokens, err := auth.CreateTokens(userID, sessionID, u.Role)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	tokenID, err := auth.RefreshTokenID(tokens.RefreshToken)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	hashedRefreshToken, err := auth.HashRefreshToken(tokens.RefreshToken)
+	if err != nil {
+		return types.Tokens{}, err
+	}
+
+	if err := h.sessions.RotateSession(sessionID, tokenID, hashedRefreshToken, auth.RefreshExpiration(time.Now())); err != nil {
+		return types.Tokens{}, err
+	}
+	return tokens, nil
+}
+
 // HandleChangePassword   change-password
 //
 // @Summary     Change Password
-// @Description Change password using old password
+// @Description Change password using old password. The new password has to follow the password policy; a 400 lists every rule it breaks.
 // @Tags        User
 // @Accept      json
 // @Produce     json
@@ -269,6 +571,15 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userId := auth.GetUserIDFromContext(r.Context())
+	u, err := h.store.GetUserByID(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !checkPasswordPolicy(w, h.policy, payload.NewPassword, u.Email) {
+		return
+	}
+
 	err = h.store.ChangePassword(userId, payload.OldPassword, payload.NewPassword)
 
 	if err != nil {
@@ -277,3 +588,70 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
+
+// checkPasswordPolicy answers 400 with the broken rules when a new password
+// does not follow the policy.
+func checkPasswordPolicy(w http.ResponseWriter, policy types.PasswordPolicy, password string, email string) bool {
+	err := policy.Check(password, email)
+	if err == nil {
+		return true
+	}
+	var policyErr *types.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		utils.WriteError(w, http.StatusBadRequest, err)
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+	return false
+}
+
+// HandleSetUserRole   set-user-role
+//
+// @Summary     Set User Role
+// @Description Set the role of a user to admin, member or viewer. Requires the user:admin permission. Admins cannot change their own role, so there is always at least one admin.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       id                 path     int                      true "User ID"
+// @Param       SetUserRolePayload body     types.SetUserRolePayload true "New role"
+// @Success     200                {object} nil
+// @Failure     400                {object} types.ErrorResponse
+// @Failure     401                {object} types.ErrorResponse
+// @Failure     403                {object} types.ErrorResponse
+// @Failure     404                {object} types.ErrorResponse
+// @Failure     500                {object} types.ErrorResponse
+// @Router      /users/{id}/role [put]
+func (h *Handler) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid user ID"))
+		return
+	}
+
+	var payload types.SetUserRolePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	if userID == auth.GetUserIDFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you cannot change your own role"))
+		return
+	}
+
+	if u, err := h.store.GetUserByID(userID); err != nil || u == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user not found"))
+		return
+	}
+
+	if err := h.store.SetUserRole(userID, payload.Role); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
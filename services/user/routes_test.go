@@ -2,18 +2,28 @@ package user
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/services/denylist"
 	"github.com/trsnaqe/gotask/types"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUser(t *testing.T) {
 	userStore := &mockUserStore{}
-	handler := NewHandler(userStore)
+	handler := NewHandler(userStore, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, &mockPasswordPolicy{})
 
 	t.Run("should fail if the user payload is invalid", func(t *testing.T) {
 		payload := types.RegisterUserPayload{
@@ -36,12 +46,168 @@ func TestUser(t *testing.T) {
 		}
 	})
 
+	t.Run("should email a verification token on registration", func(t *testing.T) {
+		verifier := &mockVerifier{}
+		handler := NewHandler(&mockUserStore{}, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, verifier, &mockLoginGuard{}, &mockPasswordPolicy{})
+
+		payloadJSON, _ := json.Marshal(types.RegisterUserPayload{Email: "new@example.com", Password: "secret123"})
+		req, err := http.NewRequest("POST", "/register", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleRegister(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, []string{"new@example.com"}, verifier.sent)
+	})
+}
+
+func TestUserSessions(t *testing.T) {
+	password, err := auth.HashValue("secret123")
+	assert.NoError(t, err)
+	userStore := &mockUserStore{user: &types.User{ID: 1, Email: "bob@example.com", Password: password, Role: types.RoleViewer}}
+	sessions := &mockSessionStore{}
+	deny := denylist.NewMemory()
+	handler := NewHandler(userStore, sessions, deny, &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, &mockPasswordPolicy{})
+
+	login := func(t *testing.T, device string) types.Tokens {
+		payloadJSON, _ := json.Marshal(types.LoginUserPayload{Email: "bob@example.com", Password: "secret123", DeviceName: device})
+		req, err := http.NewRequest("POST", "/login", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("User-Agent", "test-agent")
+		rr := httptest.NewRecorder()
+		handler.handleLogin(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var tokens types.Tokens
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tokens))
+		claims, err := auth.ValidateJWT(tokens.AccessToken, auth.TokenTypeAccess)
+		assert.NoError(t, err)
+		assert.Equal(t, types.RoleViewer, claims.Role, "the access token carries the role")
+		return tokens
+	}
+
+	refresh := func(t *testing.T, token string, sessionID int) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/refresh", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", token)
+		ctx := context.WithValue(req.Context(), types.UserKey, 1)
+		req = req.WithContext(context.WithValue(ctx, types.SessionKey, sessionID))
+		rr := httptest.NewRecorder()
+		handler.handleRefreshToken(rr, req)
+		return rr
+	}
+
+	t.Run("should open a session per login", func(t *testing.T) {
+		login(t, "laptop")
+		login(t, "phone")
+
+		assert.Len(t, sessions.sessions, 2)
+		assert.Equal(t, "laptop", sessions.sessions[0].DeviceName)
+		assert.Equal(t, "203.0.113.7", sessions.sessions[0].IP)
+		assert.Equal(t, "test-agent", sessions.sessions[0].UserAgent)
+	})
+
+	t.Run("should refresh one session without touching the other", func(t *testing.T) {
+		tokens := login(t, "tablet")
+		sessionID := len(sessions.sessions)
+		other := sessions.sessions[0].RefreshToken
+
+		rr := refresh(t, tokens.RefreshToken, sessionID)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, other, sessions.sessions[0].RefreshToken)
+
+	})
+
+	t.Run("should revoke the session when a rotated refresh token is replayed", func(t *testing.T) {
+		tokens := login(t, "desktop")
+		sessionID := len(sessions.sessions)
+
+		rr := refresh(t, tokens.RefreshToken, sessionID)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var rotated types.Tokens
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rotated))
+
+		rr = refresh(t, tokens.RefreshToken, sessionID)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, "a refresh token can only be used once")
+		assert.True(t, sessions.sessions[sessionID-1].revoked)
+		if assert.Len(t, sessions.events, 1) {
+			assert.Equal(t, types.SecurityEventRefreshTokenReuse, sessions.events[0].Type)
+			assert.Equal(t, sessionID, *sessions.events[0].SessionID)
+		}
+
+		rr = refresh(t, rotated.RefreshToken, sessionID)
+		assert.NotEqual(t, http.StatusOK, rr.Code, "the rest of the family is revoked too")
+		assert.False(t, sessions.sessions[0].revoked)
+	})
+
+	t.Run("should revoke the session and the access token on logout", func(t *testing.T) {
+		accessToken, err := auth.CreateAccessToken(1, 2, types.RoleMember)
+		assert.NoError(t, err)
+		claims, err := auth.ValidateJWT(accessToken, auth.TokenTypeAccess)
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest("POST", "/logout", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", accessToken)
+		ctx := context.WithValue(req.Context(), types.UserKey, 1)
+		req = req.WithContext(context.WithValue(ctx, types.SessionKey, 2))
+		rr := httptest.NewRecorder()
+		handler.handleLogout(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.True(t, sessions.sessions[1].revoked)
+		assert.False(t, sessions.sessions[0].revoked)
+
+		denied, err := deny.IsDenied(claims.ID, time.Now())
+		assert.NoError(t, err)
+		assert.True(t, denied)
+		denied, err = deny.IsDenied(claims.ID, claims.ExpiresAt.Time.Add(time.Second))
+		assert.NoError(t, err)
+		assert.False(t, denied, "entries expire with the token")
+	})
+}
+
+func TestUserRoles(t *testing.T) {
+	userStore := &mockUserStore{user: &types.User{ID: 2, Email: "alice@example.com", Role: types.RoleMember}}
+	handler := NewHandler(userStore, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, &mockPasswordPolicy{})
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}/role", middlewares.RequirePermission(types.PermissionUserAdmin, handler.handleSetUserRole)).Methods(http.MethodPut)
+
+	setRole := func(t *testing.T, callerRole types.Role, userID string, role types.Role) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(types.SetUserRolePayload{Role: role})
+		req, err := http.NewRequest(http.MethodPut, "/users/"+userID+"/role", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		ctx := context.WithValue(req.Context(), types.UserKey, 1)
+		req = req.WithContext(context.WithValue(ctx, types.RoleKey, callerRole))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should forbid non-admins", func(t *testing.T) {
+		rr := setRole(t, types.RoleMember, "2", types.RoleAdmin)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, types.RoleMember, userStore.user.Role)
+	})
+
+	t.Run("should let admins change roles", func(t *testing.T) {
+		rr := setRole(t, types.RoleAdmin, "2", types.RoleViewer)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, types.RoleViewer, userStore.user.Role)
+	})
+
+	t.Run("should reject unknown roles, unknown users and changing the own role", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, setRole(t, types.RoleAdmin, "2", "owner").Code)
+		assert.Equal(t, http.StatusNotFound, setRole(t, types.RoleAdmin, "3", types.RoleViewer).Code)
+		assert.Equal(t, http.StatusBadRequest, setRole(t, types.RoleAdmin, "1", types.RoleViewer).Code)
+	})
 }
 
 // try login with invalid email
 func TestUserLogin(t *testing.T) {
 	userStore := &mockUserStore{}
-	handler := NewHandler(userStore)
+	handler := NewHandler(userStore, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, &mockPasswordPolicy{})
 
 	t.Run("should fail if the user payload is invalid", func(t *testing.T) {
 		payload := types.LoginUserPayload{
@@ -67,26 +233,403 @@ func TestUserLogin(t *testing.T) {
 
 }
 
-type mockUserStore struct{}
+func TestUserLoginLockout(t *testing.T) {
+	password, err := auth.HashValue("secret123")
+	assert.NoError(t, err)
+	userStore := &mockUserStore{user: &types.User{ID: 1, Email: "bob@example.com", Password: password, Role: types.RoleMember}}
+	sessions := &mockSessionStore{}
+	guard := &mockLoginGuard{}
+	handler := NewHandler(userStore, sessions, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, guard, &mockPasswordPolicy{})
 
-func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	login := func(email string, password string) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(types.LoginUserPayload{Email: email, Password: password})
+		req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleLogin(rr, req)
+		return rr
+	}
 
-	return nil, nil
+	t.Run("should count failures for unknown emails and wrong passwords alike", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, login("nobody@example.com", "secret123").Code)
+		assert.Equal(t, http.StatusBadRequest, login("bob@example.com", "wrong-password").Code)
+		assert.Equal(t, []string{"nobody@example.com", "bob@example.com"}, guard.failures)
+	})
+
+	t.Run("should record an event when a failure locks the account", func(t *testing.T) {
+		guard.lock = true
+		defer func() { guard.lock = false }()
+		assert.Equal(t, http.StatusBadRequest, login("bob@example.com", "wrong-password").Code)
+		if assert.Len(t, sessions.events, 1) {
+			assert.Equal(t, types.SecurityEventAccountLocked, sessions.events[0].Type)
+		}
+	})
+
+	t.Run("should refuse logins while locked, even with the right password", func(t *testing.T) {
+		guard.wait = 90 * time.Second
+		defer func() { guard.wait = 0 }()
+		rr := login("bob@example.com", "secret123")
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("should reset the failures after a login", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, login("bob@example.com", "secret123").Code)
+		assert.Equal(t, []string{"bob@example.com"}, guard.succeeded)
+	})
 }
 
-func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+func TestUserLoginRehash(t *testing.T) {
+	legacy, err := (&auth.BcryptHasher{Cost: bcrypt.MinCost}).Hash("secret123")
+	assert.NoError(t, err)
+	userStore := &mockUserStore{user: &types.User{ID: 1, Email: "bob@example.com", Password: legacy, Role: types.RoleMember}}
+	handler := NewHandler(userStore, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, &mockPasswordPolicy{})
+
+	login := func(password string) int {
+		payloadJSON, _ := json.Marshal(types.LoginUserPayload{Email: "bob@example.com", Password: password})
+		req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleLogin(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, login("wrong-password"))
+	assert.Equal(t, legacy, userStore.user.Password, "a failed login keeps the hash")
+
+	assert.Equal(t, http.StatusOK, login("secret123"))
+	assert.True(t, strings.HasPrefix(userStore.user.Password, "$argon2id$"))
+	assert.False(t, auth.NeedsRehash(userStore.user.Password))
+
+	assert.Equal(t, http.StatusOK, login("secret123"), "the new hash works")
+}
 
+func TestDummyPasswordHash(t *testing.T) {
+	configured, err := auth.NewPasswordHasher(config.Envs.PasswordHasher)
+	assert.NoError(t, err)
+	auth.UsePasswordHasher(&auth.BcryptHasher{Cost: bcrypt.MinCost})
+	defer auth.UsePasswordHasher(configured)
+
+	handler := NewHandler(&mockUserStore{}, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, &mockPasswordPolicy{})
+	assert.True(t, strings.HasPrefix(handler.dummyPasswordHash, "$2a$"), "unknown emails are checked with the hasher of real users")
+	assert.False(t, auth.NeedsRehash(handler.dummyPasswordHash))
+}
+
+func TestUserLoginMFA(t *testing.T) {
+	password, err := auth.HashValue("secret123")
+	assert.NoError(t, err)
+	userStore := &mockUserStore{user: &types.User{ID: 1, Email: "bob@example.com", Password: password, Role: types.RoleAdmin}}
+	sessions := &mockSessionStore{}
+	secret, err := auth.GenerateTOTPSecret()
+	assert.NoError(t, err)
+	mfaStore := &mockMFAStore{
+		mfa:           &types.MFA{UserID: 1, Secret: secret, Enabled: true, RecoveryCodesLeft: 1},
+		recoveryCodes: map[string]bool{auth.HashRecoveryCode("abcde-fghij"): true},
+	}
+	handler := NewHandler(userStore, sessions, denylist.NewMemory(), mfaStore, &mockVerifier{}, &mockLoginGuard{}, &mockPasswordPolicy{})
+
+	challenge := func(t *testing.T) types.MFAChallenge {
+		payloadJSON, _ := json.Marshal(types.LoginUserPayload{Email: "bob@example.com", Password: "secret123"})
+		req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleLogin(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var c types.MFAChallenge
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &c))
+		return c
+	}
+	complete := func(t *testing.T, mfaToken string, code string) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(types.LoginMFAPayload{MFAToken: mfaToken, Code: code})
+		req, err := http.NewRequest(http.MethodPost, "/login/mfa", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.handleLoginMFA(rr, req)
+		return rr
+	}
+
+	t.Run("should return a challenge instead of tokens", func(t *testing.T) {
+		c := challenge(t)
+		assert.True(t, c.MFARequired)
+		assert.Empty(t, sessions.sessions, "no session before the second factor")
+
+		_, err := auth.ValidateJWT(c.MFAToken, auth.TokenTypeAccess)
+		assert.ErrorIs(t, err, auth.ErrWrongTokenType, "the challenge is not an access token")
+	})
+
+	t.Run("should issue tokens for a valid code once", func(t *testing.T) {
+		c := challenge(t)
+		code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, complete(t, c.MFAToken, "000000x").Code)
+		rr := complete(t, c.MFAToken, code)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var tokens types.Tokens
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tokens))
+		_, err = auth.ValidateJWT(tokens.AccessToken, auth.TokenTypeAccess)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, complete(t, c.MFAToken, code).Code, "codes cannot be replayed")
+	})
+
+	t.Run("should accept a recovery code once and record it", func(t *testing.T) {
+		c := challenge(t)
+		assert.Equal(t, http.StatusOK, complete(t, c.MFAToken, "ABCDE-FGHIJ").Code)
+		assert.Equal(t, http.StatusUnauthorized, complete(t, c.MFAToken, "abcde-fghij").Code)
+		if assert.Len(t, sessions.events, 1) {
+			assert.Equal(t, types.SecurityEventRecoveryCodeUsed, sessions.events[0].Type)
+		}
+	})
+
+	t.Run("should reject other tokens as the challenge", func(t *testing.T) {
+		tokens, err := auth.CreateTokens(1, 1, types.RoleAdmin)
+		assert.NoError(t, err)
+		code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, complete(t, tokens.AccessToken, code).Code)
+	})
+}
+
+func TestUserPasswordPolicy(t *testing.T) {
+	policy := &mockPasswordPolicy{rejected: "password1"}
+
+	serve := func(handlerFunc http.HandlerFunc, payload interface{}, userID int) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		if userID != 0 {
+			req = req.WithContext(context.WithValue(req.Context(), types.UserKey, userID))
+		}
+		rr := httptest.NewRecorder()
+		handlerFunc(rr, req)
+		return rr
+	}
+
+	t.Run("should refuse to register with a password breaking the policy", func(t *testing.T) {
+		userStore := &mockUserStore{}
+		handler := NewHandler(userStore, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, policy)
+
+		rr := serve(handler.handleRegister, types.RegisterUserPayload{Email: "new@example.com", Password: "password1"}, 0)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "is too easy to guess")
+		assert.Nil(t, userStore.user)
+	})
+
+	t.Run("should check the new password on a change", func(t *testing.T) {
+		userStore := &mockUserStore{user: &types.User{ID: 1, Email: "bob@example.com", Role: types.RoleMember}}
+		handler := NewHandler(userStore, &mockSessionStore{}, denylist.NewMemory(), &mockMFAStore{}, &mockVerifier{}, &mockLoginGuard{}, policy)
+		policy.emails = nil
+
+		rr := serve(handler.ChangePassword, types.ChangePasswordPayload{OldPassword: "secret123", NewPassword: "password1"}, 1)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = serve(handler.ChangePassword, types.ChangePasswordPayload{OldPassword: "secret123", NewPassword: "x7#kQ9!vLm2$"}, 1)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{"bob@example.com", "bob@example.com"}, policy.emails)
+	})
+}
+
+type mockUserStore struct {
+	user *types.User
+}
+
+func (m *mockUserStore) SetUserRole(userID int, role types.Role) error {
+	if m.user == nil || m.user.ID != userID {
+		return errors.New("user not found")
+	}
+	m.user.Role = role
+	return nil
+}
+
+func (m *mockUserStore) VerifyEmail(userID int, email string, now time.Time) error {
+	if m.user == nil || m.user.ID != userID {
+		return errors.New("user not found")
+	}
+	verifiedAt := now.Format(time.RFC3339)
+	m.user.Email = email
+	m.user.VerifiedAt = &verifiedAt
+	return nil
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	if m.user != nil && m.user.Email == email {
+		return m.user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	if m.user != nil && m.user.ID == id {
+		return m.user, nil
+	}
+	return nil, nil
+}
+func (m *mockUserStore) GetUsersByEmailLocalPart(localPart string) ([]types.User, error) {
 	return nil, nil
 }
-func (m *mockUserStore) CreateUser(types.User) error {
 
+func (m *mockUserStore) CreateUser(u types.User) error {
+	u.ID = 1
+	m.user = &u
 	return nil
 }
 
 func (m *mockUserStore) UpdateUser(userID int, updates types.UpdateUserPayload) error {
+	if m.user != nil && m.user.ID == userID && updates.Password != nil {
+		m.user.Password = *updates.Password
+	}
 	return nil
 }
 
 func (m *mockUserStore) ChangePassword(userID int, oldPassword string, newPassword string) error {
 	return nil
 }
+
+type mockSession struct {
+	types.Session
+	revoked bool
+}
+
+type mockSessionStore struct {
+	sessions []mockSession
+	tokens   map[string]*types.RefreshToken
+	events   []types.SecurityEvent
+}
+
+func (m *mockSessionStore) CreateSession(s types.Session) (int, error) {
+	s.ID = len(m.sessions) + 1
+	m.sessions = append(m.sessions, mockSession{Session: s})
+	return s.ID, nil
+}
+
+func (m *mockSessionStore) GetActiveSession(sessionID int, now time.Time) (*types.Session, error) {
+	if sessionID < 1 || sessionID > len(m.sessions) || m.sessions[sessionID-1].revoked {
+		return nil, errors.New("session not found or expired")
+	}
+	session := m.sessions[sessionID-1].Session
+	return &session, nil
+}
+
+func (m *mockSessionStore) GetActiveSessions(userID int, now time.Time) ([]types.Session, error) {
+	return nil, nil
+}
+
+func (m *mockSessionStore) RotateSession(sessionID int, tokenID string, refreshTokenHash string, expiresAt time.Time) error {
+	if m.sessions[sessionID-1].revoked {
+		return errors.New("session not found or revoked")
+	}
+	m.sessions[sessionID-1].RefreshToken = refreshTokenHash
+	if m.tokens == nil {
+		m.tokens = make(map[string]*types.RefreshToken)
+	}
+	m.tokens[tokenID] = &types.RefreshToken{ID: tokenID, SessionID: sessionID}
+	return nil
+}
+
+func (m *mockSessionStore) GetRefreshToken(tokenID string) (*types.RefreshToken, error) {
+	token, ok := m.tokens[tokenID]
+	if !ok {
+		return nil, errors.New("refresh token not found")
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *mockSessionStore) MarkRefreshTokenRotated(tokenID string) (bool, error) {
+	token, ok := m.tokens[tokenID]
+	if !ok || token.RotatedAt != nil {
+		return false, nil
+	}
+	now := time.Now().Format(time.RFC3339)
+	token.RotatedAt = &now
+	return true, nil
+}
+
+func (m *mockSessionStore) RecordSecurityEvent(e types.SecurityEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *mockSessionStore) RevokeSession(userID int, sessionID int) error {
+	m.sessions[sessionID-1].revoked = true
+	return nil
+}
+
+func (m *mockSessionStore) RevokeOtherSessions(userID int, keepSessionID int) (int, error) {
+	return 0, nil
+}
+
+type mockMFAStore struct {
+	types.MFAStore
+	mfa           *types.MFA
+	recoveryCodes map[string]bool
+}
+
+func (m *mockMFAStore) GetMFA(userID int) (*types.MFA, error) {
+	if m.mfa == nil || m.mfa.UserID != userID {
+		return nil, nil
+	}
+	mfa := *m.mfa
+	return &mfa, nil
+}
+
+func (m *mockMFAStore) UseTOTPStep(userID int, step int64) (bool, error) {
+	if step <= m.mfa.LastUsedStep {
+		return false, nil
+	}
+	m.mfa.LastUsedStep = step
+	return true, nil
+}
+
+func (m *mockMFAStore) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	if !m.recoveryCodes[codeHash] {
+		return false, nil
+	}
+	delete(m.recoveryCodes, codeHash)
+	return true, nil
+}
+
+type mockVerifier struct {
+	sent []string
+}
+
+func (m *mockVerifier) SendVerification(userID int, email string) error {
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+type mockLoginGuard struct {
+	wait      time.Duration
+	lock      bool
+	failures  []string
+	succeeded []string
+}
+
+func (m *mockLoginGuard) CheckLogin(email string, ip string, now time.Time) (time.Duration, error) {
+	return m.wait, nil
+}
+
+func (m *mockLoginGuard) LoginFailed(email string, ip string, now time.Time) (bool, error) {
+	m.failures = append(m.failures, email)
+	return m.lock, nil
+}
+
+func (m *mockLoginGuard) LoginSucceeded(email string) error {
+	m.succeeded = append(m.succeeded, email)
+	return nil
+}
+
+// mockPasswordPolicy refuses one password and accepts the others.
+type mockPasswordPolicy struct {
+	rejected string
+	emails   []string
+}
+
+func (m *mockPasswordPolicy) Check(password string, email string) error {
+	m.emails = append(m.emails, email)
+	if password == m.rejected {
+		return &types.PasswordPolicyError{Violations: []string{"is too easy to guess, avoid common passwords and words"}}
+	}
+	return nil
+}
@@ -38,9 +38,30 @@ func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 	return u, nil
 }
 
+// GetUsersByEmailLocalPart returns the users whose email address starts with
+// the given local part, e.g. "bob" matches bob@example.com.
+func (s *Store) GetUsersByEmailLocalPart(localPart string) ([]types.User, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(localPart)
+	rows, err := s.db.Query("SELECT * FROM users WHERE email LIKE ?", escaped+"@%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]types.User, 0)
+	for rows.Next() {
+		u, err := scanRowIntoUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	return users, nil
+}
+
 func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 	u := new(types.User)
-	err := rows.Scan(&u.ID, &u.Email, &u.Password, &u.RefreshToken, &u.CreatedAt, &u.UpdatedAt)
+	err := rows.Scan(&u.ID, &u.Email, &u.Password, &u.CreatedAt, &u.UpdatedAt, &u.Role, &u.VerifiedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -68,27 +89,55 @@ func (s *Store) GetUserByID(id int) (*types.User, error) {
 }
 
 func (s *Store) CreateUser(u types.User) error {
-	_, err := s.db.Exec("INSERT INTO users (email, password) VALUES (?, ?)", u.Email, u.Password)
+	if u.Role == "" {
+		u.Role = types.RoleMember
+	}
+	_, err := s.db.Exec("INSERT INTO users (email, password, role) VALUES (?, ?, ?)", u.Email, u.Password, u.Role)
 
 	return err
 }
 
+func (s *Store) SetUserRole(userID int, role types.Role) error {
+	res, err := s.db.Exec("UPDATE users SET role = ? WHERE id = ?", role, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// VerifyEmail marks the email of a user as verified, changing the address
+// first when the verification was for a new one.
+func (s *Store) VerifyEmail(userID int, email string, now time.Time) error {
+	res, err := s.db.Exec("UPDATE users SET email = ?, verified_at = ?, updated_at = ? WHERE id = ?", email, now.UTC(), now.UTC(), userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
 func (s *Store) UpdateUser(userID int, updates types.UpdateUserPayload) error {
 	var setValues []string
 	var args []interface{}
 
 	if updates.Email != nil {
-		setValues = append(setValues, "email = ?")
+		// a new address has not been verified yet
+		setValues = append(setValues, "email = ?", "verified_at = NULL")
 		args = append(args, updates.Email)
 	}
 	if updates.Password != nil {
 		setValues = append(setValues, "password = ?")
 		args = append(args, updates.Password)
 	}
-	if updates.RefreshToken != nil {
-		setValues = append(setValues, "refresh_token = ?")
-		args = append(args, updates.RefreshToken)
-	}
 	setValues = append(setValues, "updated_at = ?")
 	args = append(args, time.Now()) // current timestamp
 
@@ -20,16 +20,16 @@ func TestGetUserByEmail(t *testing.T) {
 	store := NewStore(db)
 	email := "test@example.com"
 	expectedUser := &types.User{
-		ID:           1,
-		Email:        email,
-		Password:     "password",
-		RefreshToken: nil,
-		CreatedAt:    time.Now().Format(time.RFC3339),
-		UpdatedAt:    time.Now().Format(time.RFC3339),
+		ID:        1,
+		Email:     email,
+		Password:  "password",
+		CreatedAt: time.Now().Format(time.RFC3339),
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		Role:      types.RoleMember,
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "email", "password", "refresh_token", "created_at", "updated_at"}).
-		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, expectedUser.RefreshToken, expectedUser.CreatedAt, expectedUser.UpdatedAt)
+	rows := sqlmock.NewRows([]string{"id", "email", "password", "created_at", "updated_at", "role", "verified_at"}).
+		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt, expectedUser.UpdatedAt, expectedUser.Role, nil)
 
 	mock.ExpectQuery("SELECT \\* FROM users WHERE email = ?").WithArgs(email).WillReturnRows(rows)
 
@@ -54,16 +54,16 @@ func TestGetUserByID(t *testing.T) {
 	store := NewStore(db)
 	userID := 1
 	expectedUser := &types.User{
-		ID:           userID,
-		Email:        "test@example.com",
-		Password:     "password",
-		RefreshToken: nil,
-		CreatedAt:    time.Now().Format(time.RFC3339),
-		UpdatedAt:    time.Now().Format(time.RFC3339),
+		ID:        userID,
+		Email:     "test@example.com",
+		Password:  "password",
+		CreatedAt: time.Now().Format(time.RFC3339),
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		Role:      types.RoleMember,
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "email", "password", "refresh_token", "created_at", "updated_at"}).
-		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, expectedUser.RefreshToken, expectedUser.CreatedAt, expectedUser.UpdatedAt)
+	rows := sqlmock.NewRows([]string{"id", "email", "password", "created_at", "updated_at", "role", "verified_at"}).
+		AddRow(expectedUser.ID, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt, expectedUser.UpdatedAt, expectedUser.Role, nil)
 
 	mock.ExpectQuery("SELECT \\* FROM users WHERE id = ?").WithArgs(userID).WillReturnRows(rows)
 
@@ -89,8 +89,8 @@ func TestCreateUser(t *testing.T) {
 	email := "test@example.com"
 	password := "password"
 
-	mock.ExpectExec("INSERT INTO users \\(email, password\\) VALUES \\(\\?, \\?\\)").
-		WithArgs(email, password).
+	mock.ExpectExec("INSERT INTO users \\(email, password, role\\) VALUES \\(\\?, \\?, \\?\\)").
+		WithArgs(email, password, types.RoleMember).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = store.CreateUser(types.User{Email: email, Password: password})
@@ -100,6 +100,33 @@ func TestCreateUser(t *testing.T) {
 	}
 }
 
+func TestSetUserRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	mock.ExpectExec("UPDATE users SET role = \\? WHERE id = \\?").
+		WithArgs(types.RoleAdmin, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET role = \\? WHERE id = \\?").
+		WithArgs(types.RoleViewer, 99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.SetUserRole(1, types.RoleAdmin); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := store.SetUserRole(99, types.RoleViewer); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -111,7 +138,7 @@ func TestUpdateUser(t *testing.T) {
 	userID := 1
 	email := "new_email"
 
-	mock.ExpectExec("UPDATE users SET email = \\?, updated_at = \\? WHERE id = ?").
+	mock.ExpectExec("UPDATE users SET email = \\?, verified_at = NULL, updated_at = \\? WHERE id = ?").
 		WithArgs(email, sqlmock.AnyArg(), userID). // Use sqlmock.AnyArg() for time argument
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
@@ -140,8 +167,8 @@ func TestChangePassword(t *testing.T) {
 		return
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "email", "password", "refresh_token", "created_at", "updated_at"}).
-		AddRow(userID, "test@example.com", oldHashedPassword, nil, time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "email", "password", "created_at", "updated_at", "role", "verified_at"}).
+		AddRow(userID, "test@example.com", oldHashedPassword, time.Now(), time.Now(), types.RoleMember, nil)
 
 	mock.ExpectQuery("SELECT \\* FROM users WHERE id = ?").WithArgs(userID).WillReturnRows(rows)
 	mock.ExpectExec("UPDATE users SET password = \\?, updated_at = \\?  WHERE id = ?").
@@ -179,3 +206,31 @@ func TestDeleteUser(t *testing.T) {
 		return
 	}
 }
+
+func TestVerifyEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec("UPDATE users SET email = \\?, verified_at = \\?, updated_at = \\? WHERE id = \\?").
+		WithArgs("new@example.com", now, now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET email = \\?, verified_at = \\?, updated_at = \\? WHERE id = \\?").
+		WithArgs("new@example.com", now, now, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := store.VerifyEmail(1, "new@example.com", now); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := store.VerifyEmail(2, "new@example.com", now); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,15 @@
+package verification
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/trsnaqe/gotask/middlewares"
+	"github.com/trsnaqe/gotask/types"
+)
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/verify-email", h.handleVerifyEmail).Methods(http.MethodPost)
+	router.HandleFunc("/verify-email/resend", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleResendVerification), h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/change-email", middlewares.AuthMiddleware(middlewares.RequirePermission(types.PermissionAccountManage, h.handleChangeEmail), h.userStore)).Methods(http.MethodPost)
+}
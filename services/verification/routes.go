@@ -0,0 +1,246 @@
+package verification
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/types"
+	"github.com/trsnaqe/gotask/utils"
+)
+
+type Handler struct {
+	store     types.EmailVerificationStore
+	userStore types.UserStore
+	mailer    types.Mailer
+}
+
+func NewHandler(store types.EmailVerificationStore, userStore types.UserStore, mailer types.Mailer) *Handler {
+	return &Handler{store: store, userStore: userStore, mailer: mailer}
+}
+
+// SendVerification emails a single-use verification token for the address
+// to the address itself.
+func (h *Handler) SendVerification(userID int, email string) error {
+	token, hash, err := auth.NewOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiration := time.Second * time.Duration(config.Envs.EmailVerificationExpiration)
+	err = h.store.CreateEmailVerificationToken(types.EmailVerification{
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: now.Add(expiration),
+		CreatedAt: now,
+	}, hash)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(types.Email{
+		To:      email,
+		Subject: "Verify your email address",
+		Body:    verificationEmailBody(token, expiration),
+	})
+}
+
+func verificationEmailBody(token string, expiration time.Duration) string {
+	instructions := fmt.Sprintf("Verify it with this token:\n\n%s\n\nby sending it to POST /api/v1/verify-email.", token)
+	if base := config.Envs.EmailVerificationURL; base != "" {
+		instructions = fmt.Sprintf("Verify it by opening this link:\n\n%s?token=%s", base, url.QueryEscape(token))
+	}
+	return fmt.Sprintf(
+		"Please confirm that this address belongs to your account. %s\n\nThe token works once and expires in %s. If you did not ask for this, ignore this email.\n",
+		instructions, expiration,
+	)
+}
+
+// HandleVerifyEmail   verify-email
+//
+// @Summary     Verify Email
+// @Description Verify an email address with the token from the verification email. A token sent for a new address changes the address of the account.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       VerifyEmailPayload body     types.VerifyEmailPayload true "Verification token"
+// @Success     200                {object} nil
+// @Failure     400                {object} types.ErrorResponse
+// @Failure     409                {object} types.ErrorResponse
+// @Failure     500                {object} types.ErrorResponse
+// @Router      /verify-email [post]
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload types.VerifyEmailPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	now := time.Now()
+	v, err := h.store.UseEmailVerificationToken(auth.HashOneTimeToken(payload.Token), now)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired verification token"))
+		return
+	}
+
+	if other, err := h.userStore.GetUserByEmail(v.Email); err == nil && other != nil && other.ID != v.UserID {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("%s is already used by another account", v.Email))
+		return
+	}
+
+	if err := h.userStore.VerifyEmail(v.UserID, v.Email, now); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if isBootstrapAdmin(v.Email) {
+		if err := h.userStore.SetUserRole(v.UserID, types.RoleAdmin); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// isBootstrapAdmin reports whether an email is listed in ADMIN_EMAILS. Users
+// become admins once they verify such an email, which is how the first admin
+// is created. Registering alone does not prove the mailbox is theirs.
+func isBootstrapAdmin(email string) bool {
+	for _, admin := range strings.Split(config.Envs.AdminEmails, ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleResendVerification   resend-verification
+//
+// @Summary     Resend Verification Email
+// @Description Send a new verification email for a pending email change or, without one, for the unverified address of the account. Can be used once per EMAIL_VERIFICATION_RESEND_INTERVAL seconds.
+// @Tags        User
+// @Produce     json
+// @Security    jwtKey
+// @Success     202 {object} nil
+// @Failure     400 {object} types.ErrorResponse
+// @Failure     401 {object} types.ErrorResponse
+// @Failure     403 {object} types.ErrorResponse
+// @Failure     429 {object} types.ErrorResponse
+// @Failure     500 {object} types.ErrorResponse
+// @Router      /verify-email/resend [post]
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	u, err := h.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil || u == nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to get user: %v", err))
+		return
+	}
+
+	now := time.Now()
+	latest, ok := h.checkThrottle(w, u.ID, now)
+	if !ok {
+		return
+	}
+
+	email := u.Email
+	if latest != nil && latest.UsedAt == nil && latest.ExpiresAt.After(now) && !strings.EqualFold(latest.Email, u.Email) {
+		email = latest.Email
+	} else if u.VerifiedAt != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("email address is already verified"))
+		return
+	}
+
+	if err := h.SendVerification(u.ID, email); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+// HandleChangeEmail   change-email
+//
+// @Summary     Change Email
+// @Description Start changing the email address. A verification email is sent to the new address, and the account keeps the current address until the new one is verified.
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Security    jwtKey
+// @Param       ChangeEmailPayload body     types.ChangeEmailPayload true "New email and current password"
+// @Success     202                {object} nil
+// @Failure     400                {object} types.ErrorResponse
+// @Failure     401                {object} types.ErrorResponse
+// @Failure     403                {object} types.ErrorResponse
+// @Failure     429                {object} types.ErrorResponse
+// @Failure     500                {object} types.ErrorResponse
+// @Router      /change-email [post]
+func (h *Handler) handleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var payload types.ChangeEmailPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err.(validator.ValidationErrors)))
+		return
+	}
+
+	u, err := h.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil || u == nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to get user: %v", err))
+		return
+	}
+	if !auth.CompareValue(u.Password, payload.Password) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("incorrect password"))
+		return
+	}
+	if strings.EqualFold(payload.NewEmail, u.Email) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the new email address is the current one"))
+		return
+	}
+	if other, err := h.userStore.GetUserByEmail(payload.NewEmail); err == nil && other != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%s is already used by another account", payload.NewEmail))
+		return
+	}
+
+	if _, ok := h.checkThrottle(w, u.ID, time.Now()); !ok {
+		return
+	}
+	if err := h.SendVerification(u.ID, payload.NewEmail); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+// checkThrottle answers 429 when the last verification email of the user
+// was sent less than the resend interval ago, and returns that
+// verification otherwise.
+func (h *Handler) checkThrottle(w http.ResponseWriter, userID int, now time.Time) (*types.EmailVerification, bool) {
+	latest, err := h.store.GetLatestEmailVerification(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if latest == nil {
+		return nil, true
+	}
+
+	interval := time.Second * time.Duration(config.Envs.EmailVerificationResendInterval)
+	if wait := latest.CreatedAt.Add(interval).Sub(now); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("a verification email was sent recently, try again later"))
+		return nil, false
+	}
+	return latest, true
+}
@@ -0,0 +1,190 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trsnaqe/gotask/config"
+	"github.com/trsnaqe/gotask/services/auth"
+	"github.com/trsnaqe/gotask/services/mailer"
+	"github.com/trsnaqe/gotask/types"
+)
+
+var tokenPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+func TestEmailVerification(t *testing.T) {
+	password, err := auth.HashValue("secret123")
+	assert.NoError(t, err)
+	userStore := &mockUserStore{users: map[int]*types.User{
+		1: {ID: 1, Email: "bob@example.com", Password: password, Role: types.RoleMember},
+		2: {ID: 2, Email: "taken@example.com"},
+	}}
+	store := &mockVerificationStore{tokens: make(map[string]*types.EmailVerification)}
+	mail := mailer.NewMemory()
+	handler := NewHandler(store, userStore, mail)
+
+	serve := func(handlerFunc http.HandlerFunc, payload interface{}) *httptest.ResponseRecorder {
+		payloadJSON, _ := json.Marshal(payload)
+		req, err := http.NewRequest(http.MethodPost, "/", bytes.NewBuffer(payloadJSON))
+		assert.NoError(t, err)
+		req = req.WithContext(context.WithValue(req.Context(), types.UserKey, 1))
+		rr := httptest.NewRecorder()
+		handlerFunc(rr, req)
+		return rr
+	}
+	lastToken := func() string {
+		sent := mail.Sent()
+		return tokenPattern.FindString(sent[len(sent)-1].Body)
+	}
+
+	t.Run("should verify the address of a new account", func(t *testing.T) {
+		assert.NoError(t, handler.SendVerification(1, "bob@example.com"))
+		assert.Equal(t, http.StatusBadRequest, serve(handler.handleVerifyEmail, types.VerifyEmailPayload{Token: "wrong"}).Code)
+		assert.Equal(t, http.StatusOK, serve(handler.handleVerifyEmail, types.VerifyEmailPayload{Token: lastToken()}).Code)
+		assert.NotNil(t, userStore.users[1].VerifiedAt)
+	})
+
+	t.Run("should make listed addresses admins once they are verified", func(t *testing.T) {
+		previous := config.Envs.AdminEmails
+		defer func() { config.Envs.AdminEmails = previous }()
+		config.Envs.AdminEmails = "boss@example.com, Ops@Example.com"
+
+		userStore.users[3] = &types.User{ID: 3, Email: "ops@example.com", Role: types.RoleMember}
+		assert.NoError(t, handler.SendVerification(3, "ops@example.com"))
+		assert.Equal(t, types.RoleMember, userStore.users[3].Role, "registering alone does not grant admin")
+		assert.Equal(t, http.StatusOK, serve(handler.handleVerifyEmail, types.VerifyEmailPayload{Token: lastToken()}).Code)
+		assert.Equal(t, types.RoleAdmin, userStore.users[3].Role)
+		assert.Equal(t, types.RoleMember, userStore.users[1].Role)
+	})
+
+	t.Run("should keep the old address until the new one is verified", func(t *testing.T) {
+		store.backdate(time.Hour)
+		rr := serve(handler.handleChangeEmail, types.ChangeEmailPayload{NewEmail: "taken@example.com", Password: "secret123"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = serve(handler.handleChangeEmail, types.ChangeEmailPayload{NewEmail: "robert@example.com", Password: "wrong"})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = serve(handler.handleChangeEmail, types.ChangeEmailPayload{NewEmail: "robert@example.com", Password: "secret123"})
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		sent := mail.Sent()
+		assert.Equal(t, "robert@example.com", sent[len(sent)-1].To)
+		assert.Equal(t, "bob@example.com", userStore.users[1].Email)
+
+		assert.Equal(t, http.StatusOK, serve(handler.handleVerifyEmail, types.VerifyEmailPayload{Token: lastToken()}).Code)
+		assert.Equal(t, "robert@example.com", userStore.users[1].Email)
+	})
+
+	t.Run("should throttle resending", func(t *testing.T) {
+		userStore.users[1].VerifiedAt = nil
+		store.backdate(time.Hour)
+
+		assert.Equal(t, http.StatusAccepted, serve(handler.handleResendVerification, nil).Code)
+		rr := serve(handler.handleResendVerification, nil)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+		sent := mail.Sent()
+		assert.Equal(t, "robert@example.com", sent[len(sent)-1].To)
+	})
+
+	t.Run("should not resend for a verified address", func(t *testing.T) {
+		verifiedAt := time.Now().Format(time.RFC3339)
+		userStore.users[1].VerifiedAt = &verifiedAt
+		store.backdate(time.Hour)
+		assert.Equal(t, http.StatusBadRequest, serve(handler.handleResendVerification, nil).Code)
+	})
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	previous := config.Envs.AdminEmails
+	defer func() { config.Envs.AdminEmails = previous }()
+	config.Envs.AdminEmails = "boss@example.com, Ops@Example.com"
+
+	assert.True(t, isBootstrapAdmin("boss@example.com"))
+	assert.True(t, isBootstrapAdmin("ops@example.com"))
+	assert.False(t, isBootstrapAdmin("bob@example.com"))
+
+	config.Envs.AdminEmails = ""
+	assert.False(t, isBootstrapAdmin(""))
+}
+
+type mockVerificationStore struct {
+	tokens map[string]*types.EmailVerification
+	latest *types.EmailVerification
+}
+
+// backdate moves every verification into the past, past the resend interval.
+func (m *mockVerificationStore) backdate(d time.Duration) {
+	for _, v := range m.tokens {
+		v.CreatedAt = v.CreatedAt.Add(-d)
+	}
+}
+
+func (m *mockVerificationStore) CreateEmailVerificationToken(v types.EmailVerification, tokenHash string) error {
+	m.tokens[tokenHash] = &v
+	m.latest = &v
+	return nil
+}
+
+func (m *mockVerificationStore) UseEmailVerificationToken(tokenHash string, now time.Time) (*types.EmailVerification, error) {
+	v, ok := m.tokens[tokenHash]
+	if !ok || v.UsedAt != nil || !v.ExpiresAt.After(now) {
+		return nil, errors.New("invalid or expired verification token")
+	}
+	for _, other := range m.tokens {
+		if other.UserID == v.UserID && other.UsedAt == nil {
+			other.UsedAt = &now
+		}
+	}
+	return v, nil
+}
+
+func (m *mockVerificationStore) GetLatestEmailVerification(userID int) (*types.EmailVerification, error) {
+	if m.latest == nil {
+		return nil, nil
+	}
+	v := *m.latest
+	return &v, nil
+}
+
+type mockUserStore struct {
+	types.UserStore
+	users map[int]*types.User
+}
+
+func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return u, nil
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (m *mockUserStore) SetUserRole(userID int, role types.Role) error {
+	m.users[userID].Role = role
+	return nil
+}
+
+func (m *mockUserStore) VerifyEmail(userID int, email string, now time.Time) error {
+	verifiedAt := now.Format(time.RFC3339)
+	m.users[userID].Email = email
+	m.users[userID].VerifiedAt = &verifiedAt
+	return nil
+}
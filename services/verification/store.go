@@ -0,0 +1,70 @@
+package verification
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/trsnaqe/gotask/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateEmailVerificationToken(v types.EmailVerification, tokenHash string) error {
+	_, err := s.db.Exec(
+		"INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		v.UserID, v.Email, tokenHash, v.ExpiresAt.UTC(), v.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// UseEmailVerificationToken returns the verification of an unused, unexpired
+// token and uses up every open token of the user, so that a confirmed
+// address also cancels other pending changes.
+func (s *Store) UseEmailVerificationToken(tokenHash string, now time.Time) (*types.EmailVerification, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	v := new(types.EmailVerification)
+	err = tx.QueryRow(
+		"SELECT user_id, email, expires_at, used_at, created_at FROM email_verification_tokens WHERE token_hash = ? AND used_at IS NULL AND expires_at > ? FOR UPDATE",
+		tokenHash, now.UTC(),
+	).Scan(&v.UserID, &v.Email, &v.ExpiresAt, &v.UsedAt, &v.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("invalid or expired verification token")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE email_verification_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL", now.UTC(), v.UserID); err != nil {
+		return nil, err
+	}
+	return v, tx.Commit()
+}
+
+// GetLatestEmailVerification returns the last verification sent to a user,
+// or nil when none was sent.
+func (s *Store) GetLatestEmailVerification(userID int) (*types.EmailVerification, error) {
+	v := new(types.EmailVerification)
+	err := s.db.QueryRow(
+		"SELECT user_id, email, expires_at, used_at, created_at FROM email_verification_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT 1",
+		userID,
+	).Scan(&v.UserID, &v.Email, &v.ExpiresAt, &v.UsedAt, &v.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,67 @@
+package verification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseEmailVerificationToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	columns := []string{"user_id", "email", "expires_at", "used_at", "created_at"}
+	query := "SELECT user_id, email, expires_at, used_at, created_at FROM email_verification_tokens WHERE token_hash = \\? AND used_at IS NULL AND expires_at > \\? FOR UPDATE"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("hash", now).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "new@example.com", now.Add(time.Hour), nil, now.Add(-time.Minute)))
+	mock.ExpectExec("UPDATE email_verification_tokens SET used_at = \\? WHERE user_id = \\? AND used_at IS NULL").
+		WithArgs(now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("hash", now).WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectRollback()
+
+	v, err := store.UseEmailVerificationToken("hash", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v.UserID)
+	assert.Equal(t, "new@example.com", v.Email)
+
+	_, err = store.UseEmailVerificationToken("hash", now)
+	assert.Error(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetLatestEmailVerification(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	mock.ExpectQuery("SELECT user_id, email, expires_at, used_at, created_at FROM email_verification_tokens WHERE user_id = \\? ORDER BY created_at DESC, id DESC LIMIT 1").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "email", "expires_at", "used_at", "created_at"}))
+
+	v, err := store.GetLatestEmailVerification(2)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -1,21 +1,217 @@
 package types
 
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
 type UserStore interface {
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(id int) (*User, error)
+	GetUsersByEmailLocalPart(localPart string) ([]User, error)
 	CreateUser(User) error
 	UpdateUser(userID int, updates UpdateUserPayload) error
 	ChangePassword(userID int, oldPassword string, newPassword string) error
+	SetUserRole(userID int, role Role) error
+	VerifyEmail(userID int, email string, now time.Time) error
+}
+
+type SessionStore interface {
+	CreateSession(s Session) (int, error)
+	GetActiveSession(sessionID int, now time.Time) (*Session, error)
+	GetActiveSessions(userID int, now time.Time) ([]Session, error)
+	RotateSession(sessionID int, tokenID string, refreshTokenHash string, expiresAt time.Time) error
+	RevokeSession(userID int, sessionID int) error
+	RevokeOtherSessions(userID int, keepSessionID int) (int, error)
+	GetRefreshToken(tokenID string) (*RefreshToken, error)
+	MarkRefreshTokenRotated(tokenID string) (bool, error)
+	RecordSecurityEvent(e SecurityEvent) error
+}
+
+type PersonalAccessTokenStore interface {
+	CreatePersonalAccessToken(t PersonalAccessToken) (int, error)
+	GetPersonalAccessTokens(userID int) ([]PersonalAccessToken, error)
+	GetActivePersonalAccessToken(tokenHash string, now time.Time) (*PersonalAccessToken, error)
+	RevokePersonalAccessToken(userID int, tokenID int) error
+	TouchPersonalAccessToken(tokenID int, now time.Time) error
+}
+
+type PasswordResetStore interface {
+	CreatePasswordResetToken(userID int, tokenHash string, expiresAt time.Time) error
+	// GetPasswordResetTokenUser returns the user of an unused, unexpired
+	// reset token without using it up.
+	GetPasswordResetTokenUser(tokenHash string, now time.Time) (int, error)
+	UsePasswordResetToken(tokenHash string, now time.Time) (int, error)
+}
+
+type LoginFailureStore interface {
+	GetLoginFailures(scope LoginFailureScope, subject string) (*LoginFailures, error)
+	RecordLoginFailure(scope LoginFailureScope, subject string, now time.Time, window time.Duration) (*LoginFailures, error)
+	LockLogin(scope LoginFailureScope, subject string, until time.Time) error
+	ClearLoginFailures(scope LoginFailureScope, subject string) error
+}
+
+// PasswordPolicy checks a new password of the user with the email. It
+// returns a *PasswordPolicyError when the password breaks rules of the
+// policy and other errors when it could not be checked.
+type PasswordPolicy interface {
+	Check(password string, email string) error
+}
+
+// LoginGuard slows down and locks out repeated failed logins for an account
+// and for the address they come from.
+type LoginGuard interface {
+	// CheckLogin returns how long a login for the email from the IP has to
+	// wait, or zero when it may go ahead.
+	CheckLogin(email string, ip string, now time.Time) (time.Duration, error)
+	// LoginFailed records a failed login and reports whether it locked the
+	// account.
+	LoginFailed(email string, ip string, now time.Time) (bool, error)
+	LoginSucceeded(email string) error
+}
+
+type EmailVerificationStore interface {
+	CreateEmailVerificationToken(v EmailVerification, tokenHash string) error
+	UseEmailVerificationToken(tokenHash string, now time.Time) (*EmailVerification, error)
+	GetLatestEmailVerification(userID int) (*EmailVerification, error)
+}
+
+type OIDCStore interface {
+	SaveOIDCLoginState(s OIDCLoginState) error
+	UseOIDCLoginState(stateHash string, now time.Time) (*OIDCLoginState, error)
+	GetUserIdentity(issuer string, subject string) (*UserIdentity, error)
+	CreateUserIdentity(i UserIdentity) error
+}
+
+// LoginFinisher finishes a login once the user is identified, by starting a
+// session or asking for the second factor, and writes the response.
+type LoginFinisher interface {
+	FinishLogin(w http.ResponseWriter, r *http.Request, userID int, deviceName string)
+}
+
+// EmailVerifier emails a user a token that proves they own an address.
+type EmailVerifier interface {
+	SendVerification(userID int, email string) error
+}
+
+// Mailer sends emails, over SMTP or to files and memory during development
+// and tests.
+type Mailer interface {
+	Send(e Email) error
+}
+
+type MFAStore interface {
+	GetMFA(userID int) (*MFA, error)
+	SaveMFASecret(userID int, secret string) error
+	EnableMFA(userID int, step int64, recoveryCodeHashes []string) error
+	DisableMFA(userID int) error
+	UseTOTPStep(userID int, step int64) (bool, error)
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+}
+
+// TokenDenylist holds the IDs of access tokens that were revoked before they
+// expired. Entries only need to be kept until the token expires.
+type TokenDenylist interface {
+	Deny(tokenID string, expiresAt time.Time) error
+	IsDenied(tokenID string, now time.Time) (bool, error)
+	Purge(now time.Time) (int, error)
 }
 
 type TaskStore interface {
 	GetTasks() ([]Task, error)
-	CreateTask(Task) error
-	UpdateTask(taskID int, updates UpdateTaskPayload) error
+	CreateTask(t Task, overrideWIP bool) (int, error)
+	UpdateTask(taskID int, updates UpdateTaskPayload, overrideWIP bool) error
 	GetTaskByID(taskID int) (*Task, error)
 	DeleteTask(taskID int) error
-	ProgressTask(taskID int) error
+	ProgressTask(taskID int, overrideWIP bool) error
+	RegressTask(taskID int, reason *string, overrideWIP bool) error
+	ReopenTask(taskID int, reason *string, overrideWIP bool) error
 	GetTasksByStatus(status TaskStatus) ([]Task, error)
+	FindTasks(q TaskQuery) ([]Task, error)
+	GetTaskMentions(taskIDs []int) (map[int]*TaskMentions, error)
+	SetTaskMentions(taskID int, userIDs []int, taskIDs []int) error
+	GetTaskLinks(taskID int) ([]TaskLink, error)
+	CreateTaskLink(taskID int, linkedTaskID int, linkType TaskLinkType) error
+	DeleteTaskLink(taskID int, linkID int) error
+	CloneTask(taskID int, options CloneTaskPayload, userID int) (int, error)
+	MergeTask(duplicateID int, originalID int) error
+	GetWIPLimits() ([]WIPLimit, error)
+	SetWIPLimit(status TaskStatus, maxTasks int, userID int) error
+	DeleteWIPLimit(status TaskStatus) error
+	GetStaleThresholds() ([]StaleThreshold, error)
+	SetStaleThreshold(projectID *int, status TaskStatus, days int, userID int) error
+	DeleteStaleThreshold(projectID *int, status TaskStatus) error
+	GetStaleTasks(now time.Time) ([]StaleTask, error)
+	RecordEscalation(e TaskEscalation) (bool, error)
+	GetTaskEscalations(taskID int) ([]TaskEscalation, error)
+	GetCustomFields() ([]CustomField, error)
+	CreateCustomField(f CustomField) (int, error)
+	DeleteCustomField(fieldID int) error
+	GetCustomFieldValues(taskIDs []int) ([]CustomFieldValue, error)
+	SetCustomFieldValues(taskID int, values map[int]*string) error
+	GetTaskLabels(taskIDs []int) (map[int][]string, error)
+	AddTaskLabel(taskID int, label string) (bool, error)
+	RemoveTaskLabel(taskID int, label string) error
+	SnoozeTask(taskID int, until time.Time, userID int) error
+	UnsnoozeTask(taskID int) error
+	WakeSnoozedTasks(now time.Time) ([]Task, error)
+	PinTask(userID int, taskID int) error
+	GetTasksByMilestone(milestoneID int) ([]Task, error)
+	SetTaskMilestone(taskID int, milestoneID *int) error
+	UnpinTask(userID int, taskID int) error
+	GetPinnedTaskIDs(userID int) ([]int, error)
+}
+
+type SprintStore interface {
+	GetSprints() ([]Sprint, error)
+	GetSprintByID(sprintID int) (*Sprint, error)
+	CreateSprint(Sprint) (int, error)
+	StartSprint(sprintID int) error
+	CloseSprint(sprintID int, nextSprintID *int) (*CloseSprintResult, error)
+	GetSprintTaskIDs(sprintID int) ([]int, error)
+	AddTaskToSprint(sprintID int, taskID int) error
+	RemoveTaskFromSprint(sprintID int, taskID int) error
+	GetSprintMemberships(sprintID int) ([]SprintMembership, error)
+	GetStatusChanges(taskIDs []int) ([]TaskStatusChange, error)
+}
+
+type MilestoneStore interface {
+	GetMilestones() ([]Milestone, error)
+	GetMilestoneByID(milestoneID int) (*Milestone, error)
+	CreateMilestone(Milestone) (int, error)
+	DeleteMilestone(milestoneID int) error
+}
+
+type ProjectStore interface {
+	GetProjects() ([]Project, error)
+	GetProjectByID(projectID int) (*Project, error)
+	CreateProject(Project) (int, error)
+	DeleteProject(projectID int) error
+}
+
+type AutomationStore interface {
+	GetRulesByOwner(ownerID int) ([]AutomationRule, error)
+	GetRuleByID(ruleID int) (*AutomationRule, error)
+	// GetEnabledRulesByTrigger returns the enabled rules for the trigger
+	// owned by one of the users.
+	GetEnabledRulesByTrigger(trigger AutomationTrigger, ownerIDs []int) ([]AutomationRule, error)
+	CreateRule(rule AutomationRule) (int, error)
+	UpdateRule(rule AutomationRule) error
+	DeleteRule(ruleID int) error
+	LogRun(run AutomationRun) error
+	GetRuns(ruleID int) ([]AutomationRun, error)
+	GetOverdueTasks(now time.Time) ([]TaskDueDate, error)
+	MarkDueDateHandled(taskID int, dueDate time.Time) error
+}
+
+type NotificationStore interface {
+	CreateNotification(Notification) error
+	GetNotificationsByUserID(userID int) ([]Notification, error)
+	MarkNotificationRead(notificationID int, userID int) error
 }
 type TaskStatus string
 
@@ -25,6 +221,15 @@ const (
 	StatusCompleted  TaskStatus = "completed"
 )
 
+type TaskPriority string
+
+const (
+	PriorityLow    TaskPriority = "low"
+	PriorityMedium TaskPriority = "medium"
+	PriorityHigh   TaskPriority = "high"
+	PriorityUrgent TaskPriority = "urgent"
+)
+
 type Task struct {
 	ID          int        `json:"id"`
 	Title       string     `json:"title"`
@@ -32,18 +237,520 @@ type Task struct {
 	Status      TaskStatus `json:"status"`
 	CreatedAt   string     `json:"created_at"`
 	UpdatedAt   string     `json:"updated_at"`
+
+	StartedAt     *string `json:"started_at"`
+	CompletedAt   *string `json:"completed_at"`
+	ReopenedCount int     `json:"reopened_count"`
+
+	DueDate    *string      `json:"due_date"`
+	Priority   TaskPriority `json:"priority"`
+	AssigneeID *int         `json:"assignee_id"`
+	Labels     []string     `json:"labels"`
+
+	SnoozedUntil *string `json:"snoozed_until"`
+	SnoozedBy    *int    `json:"snoozed_by"`
+	Pinned       bool    `json:"pinned"`
+	Stale        bool    `json:"stale"`
+
+	StartDate    *string `json:"start_date"`
+	EstimateDays *int    `json:"estimate_days"`
+	MilestoneID  *int    `json:"milestone_id"`
+
+	CreatedBy *int `json:"created_by"`
+	ParentID  *int `json:"parent_id"`
+	ProjectID *int `json:"project_id"`
+
+	Mentions     []int `json:"mentions"`
+	References   []int `json:"references"`
+	ReferencedBy []int `json:"referenced_by"`
+
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// TaskMentions holds the structured links parsed from a task description:
+// mentioned user IDs, referenced task IDs and the tasks referencing it back.
+type TaskMentions struct {
+	Users        []int `json:"users"`
+	Tasks        []int `json:"tasks"`
+	ReferencedBy []int `json:"referenced_by"`
+}
+
+type SnoozeTaskPayload struct {
+	Until time.Time `json:"until" validate:"required"`
+}
+
+type TransitionTaskPayload struct {
+	Reason *string `json:"reason" validate:"omitempty,max=255"`
+}
+
+// ErrTaskStatusChanged is returned when a task left the expected status while
+// a transition was being applied.
+var ErrTaskStatusChanged = errors.New("task status changed concurrently, please retry")
+
+// InvalidTransitionError is returned when an action is not allowed from the
+// current status of a task.
+type InvalidTransitionError struct {
+	Action string
+	Status TaskStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot %s a task that is %s", e.Action, e.Status)
+}
+
+// WIPLimit caps the number of tasks that can be in a workflow state at once.
+type WIPLimit struct {
+	Status    TaskStatus `json:"status"`
+	MaxTasks  int        `json:"max_tasks"`
+	Current   int        `json:"current"`
+	UpdatedAt string     `json:"updated_at"`
+}
+
+type SetWIPLimitPayload struct {
+	MaxTasks int `json:"max_tasks" validate:"required,gt=0"`
+}
+
+// StaleThreshold is the number of days a task can stay in a status without
+// being updated before it is considered stale. Thresholds of a project
+// replace the ones without a project for its tasks.
+type StaleThreshold struct {
+	ProjectID *int       `json:"project_id"`
+	Status    TaskStatus `json:"status"`
+	Days      int        `json:"days"`
+	UpdatedBy *int       `json:"updated_by"`
+	UpdatedAt string     `json:"updated_at"`
+}
+
+type SetStaleThresholdPayload struct {
+	Days int `json:"days" validate:"required,gt=0,max=365"`
+}
+
+// StaleTask is a task that was not updated for longer than the threshold of
+// its status, together with how far it was escalated since it went stale.
+type StaleTask struct {
+	TaskID         int
+	Title          string
+	AssigneeID     *int
+	ProjectOwnerID *int
+	UpdatedAt      time.Time
+	Threshold      StaleThreshold
+	Level          int
+}
+
+// Escalation levels of a stale task. The assignee is notified first and the
+// owner of the project of the task, or of the threshold for tasks without a
+// project, once the task stays stale for twice the threshold.
+const (
+	EscalationAssignee = 1
+	EscalationOwner    = 2
+)
+
+type TaskEscalation struct {
+	ID         int    `json:"id"`
+	TaskID     int    `json:"task_id"`
+	Level      int    `json:"level"`
+	UserID     *int   `json:"user_id"`
+	StaleSince string `json:"stale_since"`
+	Message    string `json:"message"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// WIPLimitError is returned when a transition would exceed a WIP limit.
+type WIPLimitError struct {
+	Status  TaskStatus
+	Limit   int
+	Current int
+}
+
+func (e *WIPLimitError) Error() string {
+	return fmt.Sprintf("WIP limit reached: %s allows at most %d tasks and already has %d", e.Status, e.Limit, e.Current)
+}
+
+type CustomFieldType string
+
+const (
+	CustomFieldText        CustomFieldType = "text"
+	CustomFieldNumber      CustomFieldType = "number"
+	CustomFieldDate        CustomFieldType = "date"
+	CustomFieldSelect      CustomFieldType = "select"
+	CustomFieldMultiSelect CustomFieldType = "multi_select"
+	CustomFieldUser        CustomFieldType = "user"
+)
+
+// DateLayout is the format of calendar dates without a time of day.
+const DateLayout = "2006-01-02"
+
+// CustomFieldDateLayout is the format of date custom field values.
+const CustomFieldDateLayout = DateLayout
+
+// CustomField is a typed field that can be set on tasks. Fields without a
+// project can be set on every task, the others only on tasks of the project.
+type CustomField struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Type      CustomFieldType `json:"type"`
+	Options   []string        `json:"options,omitempty"`
+	CreatedAt string          `json:"created_at"`
+	ProjectID *int            `json:"project_id"`
+}
+
+type CreateCustomFieldPayload struct {
+	Name      string          `json:"name" validate:"required,max=64,excludesall=.="`
+	Type      CustomFieldType `json:"type" validate:"required,oneof=text number date select multi_select user"`
+	Options   []string        `json:"options" validate:"omitempty,dive,required,max=64"`
+	ProjectID *int            `json:"project_id" validate:"omitempty,gt=0"`
+}
+
+// TaskQuery selects and orders tasks. Nil and empty fields do not filter.
+type TaskQuery struct {
+	Status       *TaskStatus
+	ProjectID    *int
+	CustomFields []CustomFieldFilter
+	Sort         *CustomFieldSort
+}
+
+// CustomFieldFilter matches tasks whose value of the field is Value, JSON
+// encoded like stored values. For multi select fields the option has to be
+// one of the selected ones.
+type CustomFieldFilter struct {
+	Field CustomField
+	Value string
+}
+
+// CustomFieldSort orders tasks by their value of the field. Tasks without a
+// value come last in both directions.
+type CustomFieldSort struct {
+	Field      CustomField
+	Descending bool
+}
+
+// CustomFieldValue is the JSON encoded value of a custom field on a task.
+type CustomFieldValue struct {
+	TaskID  int    `json:"task_id"`
+	FieldID int    `json:"field_id"`
+	Value   string `json:"value"`
+}
+
+type TaskLinkType string
+
+const (
+	LinkRelatesTo    TaskLinkType = "relates_to"
+	LinkDuplicates   TaskLinkType = "duplicates"
+	LinkDuplicatedBy TaskLinkType = "duplicated_by"
+	LinkClonedFrom   TaskLinkType = "cloned_from"
+	LinkClonedBy     TaskLinkType = "cloned_by"
+	LinkBlocks       TaskLinkType = "blocks"
+	LinkBlockedBy    TaskLinkType = "blocked_by"
+)
+
+// Inverse returns the link type stored on the other side of a link.
+func (t TaskLinkType) Inverse() TaskLinkType {
+	switch t {
+	case LinkDuplicates:
+		return LinkDuplicatedBy
+	case LinkDuplicatedBy:
+		return LinkDuplicates
+	case LinkClonedFrom:
+		return LinkClonedBy
+	case LinkClonedBy:
+		return LinkClonedFrom
+	case LinkBlocks:
+		return LinkBlockedBy
+	case LinkBlockedBy:
+		return LinkBlocks
+	}
+	return t
+}
+
+type TaskLink struct {
+	ID           int          `json:"id"`
+	TaskID       int          `json:"task_id"`
+	LinkedTaskID int          `json:"linked_task_id"`
+	Type         TaskLinkType `json:"type"`
+	CreatedAt    string       `json:"created_at"`
+}
+
+type CreateTaskLinkPayload struct {
+	TaskID int          `json:"task_id" validate:"required,gt=0"`
+	Type   TaskLinkType `json:"type" validate:"required,oneof=relates_to duplicates duplicated_by cloned_from cloned_by blocks blocked_by"`
+}
+
+// CloneTaskPayload selects what is copied along with a task. Custom field
+// values are always copied.
+type CloneTaskPayload struct {
+	Labels   bool `json:"labels"`
+	Children bool `json:"children"`
+}
+
+type MergeTaskPayload struct {
+	Into int `json:"into" validate:"required,gt=0"`
+}
+
+type SprintStatus string
+
+const (
+	SprintPlanned SprintStatus = "planned"
+	SprintActive  SprintStatus = "active"
+	SprintClosed  SprintStatus = "closed"
+)
+
+// SprintDateLayout is the layout of sprint start and end dates.
+const SprintDateLayout = DateLayout
+
+type Sprint struct {
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	Goal      string       `json:"goal"`
+	StartDate string       `json:"start_date"`
+	EndDate   string       `json:"end_date"`
+	Status    SprintStatus `json:"status"`
+	CreatedAt string       `json:"created_at"`
+	UpdatedAt string       `json:"updated_at"`
+}
+
+type CreateSprintPayload struct {
+	Name      string `json:"name" validate:"required,min=3,max=64"`
+	Goal      string `json:"goal" validate:"omitempty,max=255"`
+	StartDate string `json:"start_date" validate:"required"`
+	EndDate   string `json:"end_date" validate:"required"`
+}
+
+type CloseSprintPayload struct {
+	NextSprintID *int `json:"next_sprint_id" validate:"omitempty,gt=0"`
+}
+
+type CloseSprintResult struct {
+	CarriedTaskIDs []int `json:"carried_task_ids"`
+	NextSprintID   *int  `json:"next_sprint_id"`
+}
+
+type SprintTaskPayload struct {
+	TaskID int `json:"task_id" validate:"required,gt=0"`
+}
+
+// SprintMembership records when a task was scheduled into a sprint and,
+// if it left, when it was removed or carried over.
+type SprintMembership struct {
+	TaskID    int
+	AddedAt   time.Time
+	RemovedAt *time.Time
+}
+
+type TaskStatusChange struct {
+	TaskID    int
+	Status    TaskStatus
+	ChangedAt time.Time
+}
+
+type BurndownPoint struct {
+	Date      string `json:"date"`
+	Remaining int    `json:"remaining"`
+}
+
+type Burndown struct {
+	SprintID int             `json:"sprint_id"`
+	Total    int             `json:"total"`
+	Points   []BurndownPoint `json:"points"`
+}
+
+type Milestone struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	TargetDate string `json:"target_date"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+type CreateMilestonePayload struct {
+	Name       string `json:"name" validate:"required,min=3,max=64"`
+	TargetDate string `json:"target_date" validate:"required,len=10"`
+}
+
+// Project groups tasks. Its owner manages the custom fields of the project.
+type Project struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	OwnerID   int    `json:"owner_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+type CreateProjectPayload struct {
+	Name string `json:"name" validate:"required,min=3,max=64"`
+}
+
+type MilestoneTaskPayload struct {
+	TaskID int `json:"task_id" validate:"required,gt=0"`
+}
+
+// ScheduledTask is a task of a milestone as placed by the schedule. Dates use
+// DateLayout and finish dates are the last working day of the task.
+type ScheduledTask struct {
+	TaskID         int        `json:"task_id"`
+	Title          string     `json:"title"`
+	Status         TaskStatus `json:"status"`
+	EstimateDays   int        `json:"estimate_days"`
+	Unestimated    bool       `json:"unestimated"`
+	DependsOn      []int      `json:"depends_on"`
+	EarliestStart  string     `json:"earliest_start"`
+	EarliestFinish string     `json:"earliest_finish"`
+	LatestStart    string     `json:"latest_start"`
+	LatestFinish   string     `json:"latest_finish"`
+	SlackDays      int        `json:"slack_days"`
+	Critical       bool       `json:"critical"`
+	AtRisk         bool       `json:"at_risk"`
+}
+
+type MilestoneSchedule struct {
+	MilestoneID     int             `json:"milestone_id"`
+	TargetDate      string          `json:"target_date"`
+	ProjectedFinish string          `json:"projected_finish"`
+	AtRisk          bool            `json:"at_risk"`
+	CriticalPath    []int           `json:"critical_path"`
+	Tasks           []ScheduledTask `json:"tasks"`
+}
+
+type AutomationTrigger string
+
+const (
+	TriggerTaskCreated   AutomationTrigger = "task_created"
+	TriggerStatusChanged AutomationTrigger = "status_changed"
+	TriggerDueDatePassed AutomationTrigger = "due_date_passed"
+	TriggerLabelAdded    AutomationTrigger = "label_added"
+	TriggerTaskDeleted   AutomationTrigger = "task_deleted"
+	// TriggerCustomFieldChanged fires once per custom field whose value was
+	// set, changed or removed.
+	TriggerCustomFieldChanged AutomationTrigger = "custom_field_changed"
+)
+
+type AutomationActionType string
+
+const (
+	ActionSetStatus  AutomationActionType = "set_status"
+	ActionAddLabel   AutomationActionType = "add_label"
+	ActionAssign     AutomationActionType = "assign"
+	ActionCreateTask AutomationActionType = "create_task"
+	ActionWebhook    AutomationActionType = "webhook"
+)
+
+// AutomationConditions must all hold for a rule to run. Empty conditions
+// always hold.
+type AutomationConditions struct {
+	Labels     []string      `json:"labels,omitempty"`
+	Priority   *TaskPriority `json:"priority,omitempty" validate:"omitempty,oneof=low medium high urgent"`
+	AssigneeID *int          `json:"assignee_id,omitempty"`
+	ProjectID  *int          `json:"project_id,omitempty"`
+}
+
+// AutomationAction is one step of a rule. Which fields are used depends on
+// the type: status for set_status, label for add_label, assignee_id for
+// assign, title for create_task and url for webhook.
+type AutomationAction struct {
+	Type       AutomationActionType `json:"type" validate:"required,oneof=set_status add_label assign create_task webhook"`
+	Status     TaskStatus           `json:"status,omitempty" validate:"omitempty,oneof=pending in_progress completed"`
+	Label      string               `json:"label,omitempty" validate:"omitempty,max=64"`
+	AssigneeID *int                 `json:"assignee_id,omitempty"`
+	Title      string               `json:"title,omitempty" validate:"omitempty,min=3,max=32"`
+	URL        string               `json:"url,omitempty" validate:"omitempty,url,max=255"`
+}
+
+type AutomationRule struct {
+	ID            int                  `json:"id"`
+	OwnerID       int                  `json:"owner_id"`
+	Name          string               `json:"name"`
+	Enabled       bool                 `json:"enabled"`
+	Trigger       AutomationTrigger    `json:"trigger"`
+	TriggerStatus *TaskStatus          `json:"trigger_status,omitempty"`
+	TriggerLabel  *string              `json:"trigger_label,omitempty"`
+	TriggerField  *string              `json:"trigger_field,omitempty"`
+	Conditions    AutomationConditions `json:"conditions"`
+	Actions       []AutomationAction   `json:"actions"`
+	CreatedAt     string               `json:"created_at"`
+	UpdatedAt     string               `json:"updated_at"`
+}
+
+type AutomationRulePayload struct {
+	Name          string               `json:"name" validate:"required,min=3,max=64"`
+	Enabled       *bool                `json:"enabled"`
+	Trigger       AutomationTrigger    `json:"trigger" validate:"required,oneof=task_created status_changed due_date_passed label_added task_deleted custom_field_changed"`
+	TriggerStatus *TaskStatus          `json:"trigger_status" validate:"omitempty,oneof=pending in_progress completed"`
+	TriggerLabel  *string              `json:"trigger_label" validate:"omitempty,max=64"`
+	TriggerField  *string              `json:"trigger_field" validate:"omitempty,max=64"`
+	Conditions    AutomationConditions `json:"conditions"`
+	Actions       []AutomationAction   `json:"actions" validate:"required,min=1,max=10,dive"`
+}
+
+// AutomationEvent is a change to a task that rules can react to. Rules see
+// the current task, except for deletions, which carry the task as it was.
+type AutomationEvent struct {
+	Trigger AutomationTrigger `json:"trigger"`
+	TaskID  int               `json:"task_id"`
+	Status  TaskStatus        `json:"status,omitempty"`
+	Label   string            `json:"label,omitempty"`
+	Field   string            `json:"field,omitempty"`
+	Task    *Task             `json:"-"`
+}
+
+type AutomationOutcome string
+
+const (
+	OutcomeSuccess AutomationOutcome = "success"
+	OutcomeFailed  AutomationOutcome = "failed"
+	OutcomeSkipped AutomationOutcome = "skipped"
+)
+
+type AutomationRun struct {
+	ID        int               `json:"id"`
+	RuleID    int               `json:"rule_id"`
+	TaskID    int               `json:"task_id"`
+	Trigger   AutomationTrigger `json:"trigger"`
+	Outcome   AutomationOutcome `json:"outcome"`
+	Message   string            `json:"message"`
+	CreatedAt string            `json:"created_at"`
+}
+
+type TaskDueDate struct {
+	TaskID  int
+	DueDate time.Time
+}
+
+type NotificationType string
+
+const (
+	NotificationMention     NotificationType = "mention"
+	NotificationSnoozeEnded NotificationType = "snooze_ended"
+	NotificationStaleTask   NotificationType = "stale_task"
+)
+
+type Notification struct {
+	ID        int              `json:"id"`
+	UserID    int              `json:"user_id"`
+	Type      NotificationType `json:"type"`
+	Message   string           `json:"message"`
+	TaskID    *int             `json:"task_id"`
+	ReadAt    *string          `json:"read_at"`
+	CreatedAt string           `json:"created_at"`
 }
 
 type UpdateTaskPayload struct {
 	Title       *string     `json:"title" validate:"omitempty,min=3,max=32"`
 	Description *string     `json:"description" validate:"omitempty,min=3,max=255"`
 	Status      *TaskStatus `json:"status" validate:"omitempty,oneof=pending in_progress completed"`
+
+	DueDate      *time.Time `json:"due_date"`
+	StartDate    *string    `json:"start_date" validate:"omitempty,len=10"`
+	EstimateDays *int       `json:"estimate_days" validate:"omitempty,min=0,max=365"`
+
+	Priority   *TaskPriority `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	AssigneeID *int          `json:"assignee_id"`
+}
+
+type AddTaskLabelPayload struct {
+	Label string `json:"label" validate:"required,max=64"`
 }
 
 type UpdateUserPayload struct {
-	Email        *string `json:"email" validate:"omitempty,email"`
-	Password     *string `json:"password" validate:"omitempty,min=6,max=32"`
-	RefreshToken *string `json:"refresh_token" validate:"omitempty"`
+	Email    *string `json:"email" validate:"omitempty,email"`
+	Password *string `json:"password"`
 }
 
 type Tokens struct {
@@ -51,34 +758,296 @@ type Tokens struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// MFAChallenge is returned by a login with the correct password when the
+// user has two-factor authentication enabled. The token is exchanged for
+// Tokens at POST /login/mfa together with a code.
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type LoginMFAPayload struct {
+	MFAToken   string `json:"mfa_token" validate:"required"`
+	Code       string `json:"code" validate:"required,max=16"`
+	DeviceName string `json:"device_name" validate:"omitempty,max=64"`
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordPayload struct {
+	Token       string `json:"token" validate:"required,max=128"`
+	NewPassword string `json:"new_password" validate:"required,max=256"`
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+type ChangeEmailPayload struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password" validate:"required,max=256"`
+}
+
+// EmailVerification is a verification token sent to an address. While it is
+// pending the user keeps their current address; a token for another address
+// than the current one changes the address once it is used.
+type EmailVerification struct {
+	UserID    int
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// OIDCLoginState is kept between redirecting to the identity provider and
+// its callback. The state itself is only stored as a hash.
+type OIDCLoginState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// UserIdentity links an account at an OpenID Connect provider, identified by
+// issuer and subject, to a user.
+type UserIdentity struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Issuer    string `json:"issuer"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+type LoginFailureScope string
+
+const (
+	LoginFailureScopeAccount LoginFailureScope = "account"
+	LoginFailureScopeIP      LoginFailureScope = "ip"
+)
+
+// LoginFailures counts the recent failed logins for an account or an IP.
+// Failures older than the lockout duration are forgotten.
+type LoginFailures struct {
+	Scope        LoginFailureScope `json:"scope"`
+	Subject      string            `json:"subject"`
+	Failures     int               `json:"failures"`
+	LastFailedAt time.Time         `json:"last_failed_at"`
+	LockedUntil  *time.Time        `json:"locked_until"`
+}
+
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
 type RegisterUserPayload struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6,max=32"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,max=256"`
+	DeviceName string `json:"device_name" validate:"omitempty,max=64"`
 }
 
 type LoginUserPayload struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6,max=32"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,max=256"`
+	DeviceName string `json:"device_name" validate:"omitempty,max=64"`
 }
 
 type CreateTaskPayload struct {
 	Title       string     `json:"title" validate:"required,min=3,max=32"`
 	Description string     `json:"description" validate:"required,min=3,max=255"`
 	Status      TaskStatus `json:"status" validate:"required,oneof=pending in_progress completed"`
+
+	DueDate    *time.Time   `json:"due_date"`
+	Priority   TaskPriority `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	AssigneeID *int         `json:"assignee_id"`
+	Labels     []string     `json:"labels" validate:"omitempty,dive,required,max=64"`
+	ParentID   *int         `json:"parent_id" validate:"omitempty,gt=0"`
+	ProjectID  *int         `json:"project_id" validate:"omitempty,gt=0"`
+}
+
+type QuickAddPayload struct {
+	Text     string `json:"text" validate:"required,max=255"`
+	Timezone string `json:"timezone" validate:"omitempty,max=64"`
+}
+
+// QuickAddResult is the task parsed from a quick-add line. ID is only set
+// when the task was created.
+type QuickAddResult struct {
+	ID         int          `json:"id,omitempty"`
+	Title      string       `json:"title"`
+	DueDate    *string      `json:"due_date"`
+	Labels     []string     `json:"labels"`
+	Priority   TaskPriority `json:"priority"`
+	AssigneeID *int         `json:"assignee_id"`
+	DryRun     bool         `json:"dry_run"`
 }
 
 type ChangePasswordPayload struct {
-	OldPassword string `json:"old_password" validate:"required,min=6,max=32"`
-	NewPassword string `json:"new_password" validate:"required,min=6,max=32"`
+	OldPassword string `json:"old_password" validate:"required,max=256"`
+	NewPassword string `json:"new_password" validate:"required,max=256"`
 }
 
 type User struct {
-	ID           int     `json:"id"`
-	Email        string  `json:"email"`
-	Password     string  `json:"password"`
-	RefreshToken *string `json:"refresh_token"`
-	CreatedAt    string  `json:"created_at"`
-	UpdatedAt    string  `json:"updated_at"`
+	ID         int     `json:"id"`
+	Email      string  `json:"email"`
+	Password   string  `json:"password"`
+	CreatedAt  string  `json:"created_at"`
+	UpdatedAt  string  `json:"updated_at"`
+	Role       Role    `json:"role"`
+	VerifiedAt *string `json:"verified_at"`
+}
+
+// Role decides what a user may do. Every user has exactly one.
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+// Permission is a single action guarded by RequirePermission.
+type Permission string
+
+const (
+	PermissionTaskRead         Permission = "task:read"
+	PermissionTaskWrite        Permission = "task:write"
+	PermissionTaskOverrideWIP  Permission = "task:override_wip"
+	PermissionUserAdmin        Permission = "user:admin"
+	PermissionLogRead          Permission = "log:read"
+	PermissionLogWrite         Permission = "log:write"
+	PermissionMetricsRead      Permission = "metrics:read"
+	PermissionSystemAdmin      Permission = "system:admin"
+	PermissionNotificationRead Permission = "notification:read"
+	PermissionAutomationManage Permission = "automation:manage"
+	// PermissionWorkflowManage covers settings that apply to every task, such
+	// as WIP limits.
+	PermissionWorkflowManage Permission = "workflow:manage"
+	// PermissionAccountManage covers sessions, passwords and access tokens.
+	// It is never granted to personal access tokens.
+	PermissionAccountManage Permission = "account:manage"
+)
+
+// PersonalAccessToken lets scripts call the API as a user without their
+// password. It only grants its scopes, and only as far as the role of the
+// user allows. The token itself is only shown when it is created; the
+// store keeps its SHA-256 hash and a short prefix to tell tokens apart.
+type PersonalAccessToken struct {
+	ID         int          `json:"id"`
+	UserID     int          `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	TokenHash  string       `json:"-"`
+	Scopes     []Permission `json:"scopes"`
+	ExpiresAt  *string      `json:"expires_at"`
+	LastUsedAt *string      `json:"last_used_at"`
+	CreatedAt  string       `json:"created_at"`
+}
+
+type CreatePersonalAccessTokenPayload struct {
+	Name          string       `json:"name" validate:"required,max=64"`
+	Scopes        []Permission `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays *int         `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+// CreatedPersonalAccessToken is returned once, when a token is created.
+type CreatedPersonalAccessToken struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// MFA is the TOTP two-factor authentication of a user. It is enabled once
+// the first code from the authenticator app was confirmed.
+type MFA struct {
+	UserID            int     `json:"user_id"`
+	Secret            string  `json:"-"`
+	Enabled           bool    `json:"enabled"`
+	EnabledAt         *string `json:"enabled_at"`
+	LastUsedStep      int64   `json:"-"`
+	RecoveryCodesLeft int     `json:"recovery_codes_left"`
+}
+
+// MFAEnrollment is the secret to add to an authenticator app, also as an
+// otpauth URI for QR codes.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFACodePayload carries a code from the authenticator app or, where
+// accepted, a recovery code.
+type MFACodePayload struct {
+	Code string `json:"code" validate:"required,max=16"`
+}
+
+// MFARecoveryCodes are only returned when they are created.
+type MFARecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type SetUserRolePayload struct {
+	Role Role `json:"role" validate:"required,oneof=admin member viewer"`
+}
+
+// Session is a login on one device. Each session has its own refresh token,
+// so logging in elsewhere does not end it.
+type Session struct {
+	ID           int    `json:"id"`
+	UserID       int    `json:"user_id"`
+	DeviceName   string `json:"device_name"`
+	UserAgent    string `json:"user_agent"`
+	IP           string `json:"ip"`
+	RefreshToken string `json:"-"`
+	CreatedAt    string `json:"created_at"`
+	LastUsedAt   string `json:"last_used_at"`
+	ExpiresAt    string `json:"expires_at"`
+	Current      bool   `json:"current"`
+}
+
+// RefreshToken records a refresh token issued for a session. All refresh
+// tokens of a session form one rotation family: each use rotates to a new
+// token, and using a rotated token again revokes the session.
+type RefreshToken struct {
+	ID        string  `json:"id"`
+	SessionID int     `json:"session_id"`
+	CreatedAt string  `json:"created_at"`
+	RotatedAt *string `json:"rotated_at"`
+}
+
+type SecurityEventType string
+
+const (
+	SecurityEventRefreshTokenReuse SecurityEventType = "refresh_token_reuse"
+	SecurityEventRecoveryCodeUsed  SecurityEventType = "mfa_recovery_code_used"
+	SecurityEventPasswordReset     SecurityEventType = "password_reset"
+	SecurityEventAccountLocked     SecurityEventType = "account_locked"
+	SecurityEventAccountUnlocked   SecurityEventType = "account_unlocked"
+)
+
+type SecurityEvent struct {
+	ID        int               `json:"id"`
+	UserID    int               `json:"user_id"`
+	Type      SecurityEventType `json:"type"`
+	SessionID *int              `json:"session_id"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent"`
+	Message   string            `json:"message"`
+	CreatedAt string            `json:"created_at"`
+}
+
+// PasswordPolicyError lists every rule of the password policy a password
+// breaks.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
 }
 
 type ErrorResponse struct {
@@ -88,4 +1057,12 @@ type ErrorResponse struct {
 
 type contextKey string
 
-const UserKey contextKey = "userID"
+const (
+	UserKey    contextKey = "userID"
+	SessionKey contextKey = "sessionID"
+	RoleKey    contextKey = "role"
+	ScopesKey  contextKey = "scopes"
+	// EmailVerifiedKey tells whether the authenticated user verified their
+	// email address.
+	EmailVerifiedKey contextKey = "emailVerified"
+)